@@ -1,14 +1,21 @@
 package main
 
 import (
+	"time"
+
 	"github.com/cneill/mon/internal/config"
+	"github.com/cneill/mon/pkg/files"
+	"github.com/cneill/mon/pkg/mon"
 	"github.com/urfave/cli/v3"
 )
 
 func allFlags() []cli.Flag {
 	flags := make([]cli.Flag, 0, len(generalFlags()))
 	flags = append(flags, generalFlags()...)
+	flags = append(flags, fileFlags()...)
+	flags = append(flags, gitFlags()...)
 	flags = append(flags, detailsFlags()...)
+	flags = append(flags, exportFlags()...)
 
 	return flags
 }
@@ -22,6 +29,43 @@ const (
 	EnvNoColor  = "MON_NO_COLOR"
 	FlagAudio   = "audio"
 	EnvAudio    = "MON_AUDIO"
+
+	FlagCheckpointInterval = "checkpoint-interval"
+	EnvCheckpointInterval  = "MON_CHECKPOINT_INTERVAL"
+	FlagDisplay            = "display"
+	EnvDisplay             = "MON_DISPLAY"
+	FlagDisplayInterval    = "display-interval"
+	EnvDisplayInterval     = "MON_DISPLAY_INTERVAL"
+	FlagSaveWindow         = "save-window"
+	EnvSaveWindow          = "MON_SAVE_WINDOW"
+	FlagHashContents       = "hash-contents"
+	EnvHashContents        = "MON_HASH_CONTENTS"
+	FlagTurnGap            = "turn-gap"
+	EnvTurnGap             = "MON_TURN_GAP"
+	FlagIgnore             = "ignore"
+	EnvIgnore              = "MON_IGNORE"
+	FlagPoll               = "poll"
+	EnvPoll                = "MON_POLL"
+	FlagDebounceWindow     = "debounce-window"
+	EnvDebounceWindow      = "MON_DEBOUNCE_WINDOW"
+	FlagFollowSymlinks     = "follow-symlinks"
+	EnvFollowSymlinks      = "MON_FOLLOW_SYMLINKS"
+	FlagMaxDepth           = "max-depth"
+	EnvMaxDepth            = "MON_MAX_DEPTH"
+	FlagMaxFiles           = "max-files"
+	EnvMaxFiles            = "MON_MAX_FILES"
+	FlagLazyWatchDepth     = "lazy-watch-depth"
+	EnvLazyWatchDepth      = "MON_LAZY_WATCH_DEPTH"
+	FlagSnapshotRefs       = "snapshot-refs"
+	EnvSnapshotRefs        = "MON_SNAPSHOT_REFS"
+	FlagOverlayServer      = "overlay-server"
+	EnvOverlayServer       = "MON_OVERLAY_SERVER"
+	FlagProjectDir         = "project-dir"
+	EnvProjectDir          = "MON_PROJECT_DIR"
+	FlagCheckCommits       = "check-commits"
+	EnvCheckCommits        = "MON_CHECK_COMMITS"
+	FlagCheckCommand       = "check-command"
+	EnvCheckCommand        = "MON_CHECK_COMMAND"
 )
 
 func generalFlags() []cli.Flag {
@@ -54,12 +98,130 @@ func generalFlags() []cli.Flag {
 			Value:   false,
 			Usage:   "Enable audio notifications for events.",
 		},
+		&cli.DurationFlag{
+			Name:    FlagCheckpointInterval,
+			Sources: cli.EnvVars(EnvCheckpointInterval),
+			Value:   time.Second * 30,
+			Usage:   "How often to save session state for 'mon resume' after a crash. Set to 0 to disable.",
+		},
+		&cli.StringFlag{
+			Name:    FlagDisplay,
+			Sources: cli.EnvVars(EnvDisplay),
+			Value:   "auto",
+			Usage:   "How to show live status: line, ndjson, quiet, plain (labeled lines for screen readers), or auto (line on a terminal, ndjson otherwise).",
+		},
+		&cli.DurationFlag{
+			Name:    FlagDisplayInterval,
+			Sources: cli.EnvVars(EnvDisplayInterval),
+			Usage: "How often to check the status line for changes when no events arrive (default 1s). It is only redrawn when it changed. " +
+				"With --display plain, it's the least time between updates (default 15s).",
+		},
+		&cli.DurationFlag{
+			Name:    FlagTurnGap,
+			Sources: cli.EnvVars(EnvTurnGap),
+			Value:   time.Second * 20,
+			Usage:   "Start a new agent turn when writes resume after pausing for longer than this.",
+		},
+		&cli.StringSliceFlag{
+			Name:    FlagProjectDir,
+			Sources: cli.EnvVars(EnvProjectDir),
+			Usage:   "Also watch this project directory in the same session, e.g. a backend repo next to the frontend one. Can be repeated.",
+		},
+		&cli.StringFlag{
+			Name:    FlagOverlayServer,
+			Sources: cli.EnvVars(EnvOverlayServer),
+			Usage:   "Serve a live overlay page for streaming software on this address (e.g. 127.0.0.1:8080).",
+		},
+	}
+}
+
+// fileFlags control how files are watched and counted.
+func fileFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.DurationFlag{
+			Name:    FlagSaveWindow,
+			Sources: cli.EnvVars(EnvSaveWindow),
+			Value:   time.Millisecond * 100,
+			Usage:   "Count writes to the same file within this window as a single save. Set to 0 to count every write.",
+		},
+		&cli.DurationFlag{
+			Name:    FlagDebounceWindow,
+			Sources: cli.EnvVars(EnvDebounceWindow),
+			Value:   files.DefaultDebounceWindow,
+			Usage:   "Handle bursts of writes to the same file within this window as one event, for fewer redraws and sounds. Set to 0 to handle every write.",
+		},
+		&cli.BoolFlag{
+			Name:    FlagHashContents,
+			Sources: cli.EnvVars(EnvHashContents),
+			Usage:   "Hash file contents to tell saves that change a file from ones that don't (e.g. touch). Reads every file at startup.",
+		},
+		&cli.StringSliceFlag{
+			Name:    FlagIgnore,
+			Sources: cli.EnvVars(EnvIgnore),
+			Usage:   "Don't count or watch paths matching this glob (e.g. 'dist/**' or '*.log'). Can be repeated.",
+		},
+		&cli.DurationFlag{
+			Name:    FlagPoll,
+			Sources: cli.EnvVars(EnvPoll),
+			Usage: "Find file changes by rescanning this often instead of waiting for events, " +
+				"for network mounts (NFS, SSHFS) and container volumes that don't deliver them.",
+		},
+		&cli.BoolFlag{
+			Name:    FlagFollowSymlinks,
+			Sources: cli.EnvVars(EnvFollowSymlinks),
+			Usage:   "Watch the directories that symlinks in the project point to. Links that would loop back into the project are skipped.",
+		},
+		&cli.IntFlag{
+			Name:    FlagMaxDepth,
+			Sources: cli.EnvVars(EnvMaxDepth),
+			Usage:   "Don't count or watch paths more than this many levels below the project, e.g. 1 for only what's directly in it. 0 means no limit.",
+		},
+		&cli.IntFlag{
+			Name:    FlagMaxFiles,
+			Sources: cli.EnvVars(EnvMaxFiles),
+			Usage:   "Stop the initial scan of each project directory after this many files and directories, leaving the rest of it out. 0 means no limit.",
+		},
+		&cli.IntFlag{
+			Name:    FlagLazyWatchDepth,
+			Sources: cli.EnvVars(EnvLazyWatchDepth),
+			Usage:   "Start with only this many directory levels watched, and watch deeper ones in the background. 0 watches everything first.",
+		},
+	}
+}
+
+// gitFlags control what mon records in and checks about the project's git history.
+func gitFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.BoolFlag{
+			Name:    FlagSnapshotRefs,
+			Sources: cli.EnvVars(EnvSnapshotRefs),
+			Value:   false,
+			Usage:   "Record the session's starting and final commits as refs under refs/mon/.",
+		},
+		&cli.BoolFlag{
+			Name:    FlagCheckCommits,
+			Sources: cli.EnvVars(EnvCheckCommits),
+			Usage:   "Build and vet each new commit in a copy of its files (" + mon.DefaultCheckCommand + "), and report which ones pass.",
+		},
+		&cli.StringFlag{
+			Name:    FlagCheckCommand,
+			Sources: cli.EnvVars(EnvCheckCommand),
+			Usage:   "Check each new commit with this shell command instead, e.g. 'make test'. Implies --check-commits.",
+		},
 	}
 }
 
 const (
 	FlagShowAllFiles = "all-files"
 	EnvShowAllFiles  = "MON_SHOW_ALL_FILES"
+	FlagUTC          = "utc"
+	EnvUTC           = "MON_UTC"
+	FlagGoalCommits  = "goal-commits"
+	EnvGoalCommits   = "MON_GOAL_COMMITS"
+	FlagTimeBudget   = "time-budget"
+	EnvTimeBudget    = "MON_TIME_BUDGET"
+	FlagTopNewFiles  = "top-new-files"
+	EnvTopNewFiles   = "MON_TOP_NEW_FILES"
 )
 
 func detailsFlags() []cli.Flag {
@@ -74,5 +236,133 @@ func detailsFlags() []cli.Flag {
 			Value:    false,
 			Usage:    "Show all new, deleted, and written file paths in final session stats.",
 		},
+		&cli.BoolFlag{
+			Name:     FlagUTC,
+			Category: category,
+			Sources:  cli.EnvVars(EnvUTC),
+			Usage:    "Show times in reports in UTC instead of the local time zone or the config file's \"times\" setting.",
+		},
+		&cli.Int64Flag{
+			Name:     FlagGoalCommits,
+			Category: category,
+			Sources:  cli.EnvVars(EnvGoalCommits),
+			Usage:    "Show progress toward making this many commits in the status line.",
+		},
+		&cli.DurationFlag{
+			Name:     FlagTimeBudget,
+			Category: category,
+			Sources:  cli.EnvVars(EnvTimeBudget),
+			Usage:    "Show how much of this time budget (e.g. 2h) the session has used in the status line.",
+		},
+		&cli.IntFlag{
+			Name:     FlagTopNewFiles,
+			Category: category,
+			Sources:  cli.EnvVars(EnvTopNewFiles),
+			Value:    files.DefaultTopNewFiles,
+			Usage:    "List this many of the largest new files in final session stats. Set to 0 to list none.",
+		},
+	}
+}
+
+const (
+	FlagChangelogOut   = "changelog-out"
+	EnvChangelogOut    = "MON_CHANGELOG_OUT"
+	FlagStatusOut      = "status-out"
+	EnvStatusOut       = "MON_STATUS_OUT"
+	FlagStatusInterval = "status-interval"
+	EnvStatusInterval  = "MON_STATUS_INTERVAL"
+	FlagEventsCSV      = "events-csv"
+	EnvEventsCSV       = "MON_EVENTS_CSV"
+	FlagCSVDir         = "csv-dir"
+	EnvCSVDir          = "MON_CSV_DIR"
+	FlagEventsParquet  = "events-parquet"
+	EnvEventsParquet   = "MON_EVENTS_PARQUET"
+	FlagPatchOut       = "patch-out"
+	EnvPatchOut        = "MON_PATCH_OUT"
+)
+
+func exportFlags() []cli.Flag {
+	category := "export"
+
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:     FlagChangelogOut,
+			Category: category,
+			Sources:  cli.EnvVars(EnvChangelogOut),
+			Usage:    "Write the session's commits as a CHANGELOG-style Markdown fragment to this path on exit.",
+		},
+		&cli.StringFlag{
+			Name:     FlagStatusOut,
+			Category: category,
+			Sources:  cli.EnvVars(EnvStatusOut),
+			Usage:    "Append status snapshots to this path as JSON lines while the session runs, for external tools.",
+		},
+		&cli.DurationFlag{
+			Name:     FlagStatusInterval,
+			Category: category,
+			Sources:  cli.EnvVars(EnvStatusInterval),
+			Value:    time.Second,
+			Usage:    "How often to append a status snapshot to the --status-out file.",
+		},
+		&cli.StringFlag{
+			Name:     FlagEventsCSV,
+			Category: category,
+			Sources:  cli.EnvVars(EnvEventsCSV),
+			Usage:    "Append file, git, and dependency events to this path as CSV rows while the session runs.",
+		},
+		&cli.StringFlag{
+			Name:     FlagCSVDir,
+			Category: category,
+			Sources:  cli.EnvVars(EnvCSVDir),
+			Usage:    "Write the final per-file, per-extension, and per-commit stats as CSV files to this directory on exit.",
+		},
+		&cli.StringFlag{
+			Name:     FlagEventsParquet,
+			Category: category,
+			Sources:  cli.EnvVars(EnvEventsParquet),
+			Usage:    "Write file, git, and dependency events to a Parquet file in this directory, one file per session.",
+		},
+		&cli.StringFlag{
+			Name:     FlagPatchOut,
+			Category: category,
+			Sources:  cli.EnvVars(EnvPatchOut),
+			Usage:    "Write the session's committed changes to this path as a unified diff on exit, and its uncommitted changes next to it.",
+		},
+	}
+}
+
+const (
+	FlagSSH           = "ssh"
+	EnvSSH            = "MON_SSH"
+	FlagRemoteCommand = "remote-command"
+	EnvRemoteCommand  = "MON_REMOTE_COMMAND"
+)
+
+func remoteFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:    FlagSSH,
+			Sources: cli.EnvVars(EnvSSH),
+			Value:   "ssh",
+			Usage:   "The ssh client used to reach the remote machine.",
+		},
+		&cli.StringFlag{
+			Name:    FlagRemoteCommand,
+			Sources: cli.EnvVars(EnvRemoteCommand),
+			Value:   "mon",
+			Usage:   "How to run mon on the remote machine, e.g. a full path if it isn't on the remote PATH.",
+		},
+	}
+}
+
+const FlagAPIWrite = "write"
+
+func apiCheckFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.BoolFlag{
+			Name:  FlagAPIWrite,
+			Value: false,
+			Usage: "Record the current stable API instead of checking against it, e.g. after adding to it.",
+		},
 	}
 }
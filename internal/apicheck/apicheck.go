@@ -0,0 +1,414 @@
+// Package apicheck guards the exported API of mon's stable packages, in the spirit of the api/ files in the Go
+// repository. A package opts in with a "Stability: stable" line in its package doc comment; every other package is
+// experimental and may change in any release. The exported API of the stable packages is recorded one declaration per
+// line, so a declaration that disappears or changes from the recorded file is a breaking change, and a new one is a
+// compatible addition.
+package apicheck
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+)
+
+const (
+	StabilityStable       = "stable"
+	StabilityExperimental = "experimental"
+)
+
+// APIFile is where the stable API is recorded, relative to the module root.
+const APIFile = "api/stable.txt"
+
+// ErrBreakingChanges is returned by Check when a recorded declaration was removed or changed.
+var ErrBreakingChanges = errors.New("stable API has breaking changes")
+
+// Package is one package's exported API.
+type Package struct {
+	ImportPath string
+	Stability  string
+	API        []string
+}
+
+// Diff is the difference between the recorded stable API and the current one.
+type Diff struct {
+	Removed []string // recorded declarations that no longer exist as recorded
+	Added   []string // declarations that aren't recorded yet
+}
+
+// Load parses every package under moduleDir, skipping tests, testdata, and hidden directories, and returns their
+// exported APIs sorted by import path.
+func Load(moduleDir string) ([]*Package, error) {
+	modulePath, err := readModulePath(moduleDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []*Package
+
+	err = filepath.WalkDir(moduleDir, func(dir string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !entry.IsDir() {
+			return nil
+		}
+
+		if name := entry.Name(); dir != moduleDir && (name == "testdata" || strings.HasPrefix(name, ".")) {
+			return filepath.SkipDir
+		}
+
+		pkg, err := loadPackage(dir)
+		if err != nil || pkg == nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(moduleDir, dir)
+		if err != nil {
+			return fmt.Errorf("failed to get package path for %q: %w", dir, err)
+		}
+
+		pkg.ImportPath = path.Join(modulePath, filepath.ToSlash(rel))
+		results = append(results, pkg)
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load packages: %w", err)
+	}
+
+	slices.SortFunc(results, func(a, b *Package) int { return strings.Compare(a.ImportPath, b.ImportPath) })
+
+	return results, nil
+}
+
+// StableAPI returns the recorded form of the stable packages' APIs: one sorted line per declaration.
+func StableAPI(pkgs []*Package) []string {
+	var results []string
+
+	for _, pkg := range pkgs {
+		if pkg.Stability != StabilityStable {
+			continue
+		}
+
+		for _, decl := range pkg.API {
+			results = append(results, "pkg "+pkg.ImportPath+", "+decl)
+		}
+	}
+
+	slices.Sort(results)
+
+	return results
+}
+
+// Check compares the stable API of the module at moduleDir to its recorded API file. It returns ErrBreakingChanges
+// along with the diff if any recorded declaration was removed or changed.
+func Check(moduleDir string) (*Diff, error) {
+	pkgs, err := Load(moduleDir)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := os.ReadFile(filepath.Join(moduleDir, APIFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read recorded API: %w", err)
+	}
+
+	recorded := strings.Split(strings.TrimSpace(string(content)), "\n")
+	current := StableAPI(pkgs)
+	diff := &Diff{}
+
+	for _, line := range recorded {
+		if line != "" && !slices.Contains(current, line) {
+			diff.Removed = append(diff.Removed, line)
+		}
+	}
+
+	for _, line := range current {
+		if !slices.Contains(recorded, line) {
+			diff.Added = append(diff.Added, line)
+		}
+	}
+
+	if len(diff.Removed) > 0 {
+		return diff, ErrBreakingChanges
+	}
+
+	return diff, nil
+}
+
+// Write records the current stable API of the module at moduleDir in its API file.
+func Write(moduleDir string) error {
+	pkgs, err := Load(moduleDir)
+	if err != nil {
+		return err
+	}
+
+	apiPath := filepath.Join(moduleDir, APIFile)
+	if err := os.MkdirAll(filepath.Dir(apiPath), 0o755); err != nil {
+		return fmt.Errorf("failed to create API directory: %w", err)
+	}
+
+	content := strings.Join(StableAPI(pkgs), "\n") + "\n"
+	if err := os.WriteFile(apiPath, []byte(content), 0o644); err != nil { //nolint:gosec
+		return fmt.Errorf("failed to write recorded API: %w", err)
+	}
+
+	return nil
+}
+
+func readModulePath(moduleDir string) (string, error) {
+	content, err := os.ReadFile(filepath.Join(moduleDir, "go.mod"))
+	if err != nil {
+		return "", fmt.Errorf("failed to read go.mod: %w", err)
+	}
+
+	modulePath := modfile.ModulePath(content)
+	if modulePath == "" {
+		return "", fmt.Errorf("no module path in %s", filepath.Join(moduleDir, "go.mod"))
+	}
+
+	return modulePath, nil
+}
+
+// loadPackage parses the non-test Go files in dir. It returns nil if there are none, or if they make up a main
+// package, which has no importable API. Files for every platform are included, whatever their build constraints.
+func loadPackage(dir string) (*Package, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %q: %w", dir, err)
+	}
+
+	fset := token.NewFileSet()
+
+	var pkg *Package
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+
+		file, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, parser.ParseComments)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %q: %w", filepath.Join(dir, name), err)
+		}
+
+		if file.Name.Name == "main" {
+			return nil, nil //nolint:nilnil
+		}
+
+		if pkg == nil {
+			pkg = &Package{Stability: StabilityExperimental}
+		}
+
+		if stability := docStability(file.Doc); stability != "" {
+			pkg.Stability = stability
+		}
+
+		pkg.API = append(pkg.API, fileAPI(fset, file)...)
+	}
+
+	if pkg != nil {
+		// Platform-specific files may declare the same API
+		slices.Sort(pkg.API)
+		pkg.API = slices.Compact(pkg.API)
+	}
+
+	return pkg, nil
+}
+
+// docStability returns the stability declared with a "Stability: ..." line in a package doc comment, if any.
+func docStability(doc *ast.CommentGroup) string {
+	if doc == nil {
+		return ""
+	}
+
+	for line := range strings.SplitSeq(doc.Text(), "\n") {
+		if value, found := strings.CutPrefix(strings.TrimSpace(line), "Stability:"); found {
+			return strings.ToLower(strings.TrimSpace(value))
+		}
+	}
+
+	return ""
+}
+
+// fileAPI describes each exported declaration in a file on its own line.
+func fileAPI(fset *token.FileSet, file *ast.File) []string {
+	var results []string
+
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			if line := funcAPI(fset, decl); line != "" {
+				results = append(results, line)
+			}
+		case *ast.GenDecl:
+			results = append(results, genDeclAPI(fset, decl)...)
+		}
+	}
+
+	return results
+}
+
+func funcAPI(fset *token.FileSet, decl *ast.FuncDecl) string {
+	if !decl.Name.IsExported() {
+		return ""
+	}
+
+	signature := strings.TrimPrefix(exprString(fset, withoutNames(decl.Type)), "func")
+
+	if decl.Recv == nil || len(decl.Recv.List) == 0 {
+		return "func " + decl.Name.Name + signature
+	}
+
+	receiver := exprString(fset, decl.Recv.List[0].Type)
+	if !ast.IsExported(strings.TrimLeft(strings.SplitN(receiver, "[", 2)[0], "*")) {
+		return ""
+	}
+
+	return "method (" + receiver + ") " + decl.Name.Name + signature
+}
+
+func genDeclAPI(fset *token.FileSet, decl *ast.GenDecl) []string {
+	var results []string
+
+	for _, spec := range decl.Specs {
+		switch spec := spec.(type) {
+		case *ast.TypeSpec:
+			results = append(results, typeAPI(fset, spec)...)
+		case *ast.ValueSpec:
+			for _, name := range spec.Names {
+				if !name.IsExported() {
+					continue
+				}
+
+				line := decl.Tok.String() + " " + name.Name
+				if spec.Type != nil {
+					line += " " + exprString(fset, spec.Type)
+				}
+
+				results = append(results, line)
+			}
+		}
+	}
+
+	return results
+}
+
+// typeAPI describes an exported type, and separately each of its exported struct fields or interface methods, so that
+// adding one is a compatible change.
+func typeAPI(fset *token.FileSet, spec *ast.TypeSpec) []string {
+	if !spec.Name.IsExported() {
+		return nil
+	}
+
+	prefix := "type " + spec.Name.Name
+	if spec.TypeParams != nil {
+		prefix += exprString(fset, &ast.IndexListExpr{X: ast.NewIdent(""), Indices: typeParams(spec.TypeParams)})
+	}
+
+	if spec.Assign.IsValid() {
+		prefix += " ="
+	}
+
+	switch typ := spec.Type.(type) {
+	case *ast.StructType:
+		results := []string{prefix + " struct"}
+
+		for _, field := range typ.Fields.List {
+			fieldType := exprString(fset, field.Type)
+			if len(field.Names) == 0 {
+				// Embedded fields are part of the API whether or not they're exported, since they promote methods
+				results = append(results, prefix+" struct, embedded "+fieldType)
+				continue
+			}
+
+			for _, name := range field.Names {
+				if name.IsExported() {
+					results = append(results, prefix+" struct, "+name.Name+" "+fieldType)
+				}
+			}
+		}
+
+		return results
+	case *ast.InterfaceType:
+		results := []string{prefix + " interface"}
+
+		for _, method := range typ.Methods.List {
+			if len(method.Names) == 0 {
+				results = append(results, prefix+" interface, embedded "+exprString(fset, method.Type))
+				continue
+			}
+
+			for _, name := range method.Names {
+				signature := strings.TrimPrefix(exprString(fset, withoutNames(method.Type)), "func")
+				results = append(results, prefix+" interface, "+name.Name+signature)
+			}
+		}
+
+		return results
+	default:
+		return []string{prefix + " " + exprString(fset, spec.Type)}
+	}
+}
+
+func typeParams(fields *ast.FieldList) []ast.Expr {
+	var results []ast.Expr
+
+	for _, field := range fields.List {
+		for range field.Names {
+			results = append(results, field.Type)
+		}
+	}
+
+	return results
+}
+
+// withoutNames returns a copy of a function type without parameter and result names, which callers can't depend on.
+func withoutNames(expr ast.Expr) ast.Expr {
+	funcType, ok := expr.(*ast.FuncType)
+	if !ok {
+		return expr
+	}
+
+	strip := func(fields *ast.FieldList) *ast.FieldList {
+		if fields == nil {
+			return nil
+		}
+
+		result := &ast.FieldList{}
+
+		for _, field := range fields.List {
+			for range max(1, len(field.Names)) {
+				result.List = append(result.List, &ast.Field{Type: field.Type})
+			}
+		}
+
+		return result
+	}
+
+	return &ast.FuncType{Params: strip(funcType.Params), Results: strip(funcType.Results)}
+}
+
+func exprString(fset *token.FileSet, node ast.Node) string {
+	buf := &bytes.Buffer{}
+	if err := printer.Fprint(buf, fset, node); err != nil {
+		return "<invalid>"
+	}
+
+	// Collapse multi-line types, like inline structs, onto the declaration's line
+	return strings.Join(strings.Fields(buf.String()), " ")
+}
@@ -0,0 +1,95 @@
+package apicheck_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/cneill/mon/internal/apicheck"
+)
+
+func TestCheck(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	writeFile := func(name, content string) {
+		t.Helper()
+
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+
+	writeFile("go.mod", "module example.com/lib\n\ngo 1.25\n")
+	writeFile("stable/stable.go", `// Package stable is stable.
+//
+// Stability: stable
+package stable
+
+type Opts struct {
+	Name    string
+	private int
+}
+
+func Open(path string, opts *Opts) error { return nil }
+`)
+	writeFile("experimental/experimental.go", "package experimental\n\nfunc Anything() {}\n")
+
+	if err := apicheck.Write(dir); err != nil {
+		t.Fatalf("failed to record API: %v", err)
+	}
+
+	// Renaming a parameter and adding a field are compatible
+	writeFile("stable/stable.go", `// Stability: stable
+package stable
+
+type Opts struct {
+	Name    string
+	Verbose bool
+}
+
+func Open(name string, opts *Opts) error { return nil }
+`)
+	writeFile("experimental/experimental.go", "package experimental\n\nfunc Anything(int) {}\n")
+
+	diff, err := apicheck.Check(dir)
+	if err != nil {
+		t.Fatalf("expected compatible changes, got %v: %+v", err, diff)
+	}
+
+	if !slices.Equal(diff.Added, []string{"pkg example.com/lib/stable, type Opts struct, Verbose bool"}) {
+		t.Errorf("unexpected additions: %q", diff.Added)
+	}
+
+	// Changing a signature is not
+	writeFile("stable/stable.go", "// Stability: stable\npackage stable\n\ntype Opts struct{ Name string }\n\nfunc Open(string) error { return nil }\n")
+
+	diff, err = apicheck.Check(dir)
+	if !errors.Is(err, apicheck.ErrBreakingChanges) {
+		t.Fatalf("expected breaking changes, got %v", err)
+	}
+
+	if !slices.Equal(diff.Removed, []string{"pkg example.com/lib/stable, func Open(string, *Opts) error"}) {
+		t.Errorf("unexpected removals: %q", diff.Removed)
+	}
+}
+
+// TestStableAPI fails when a change breaks the recorded API of mon's own stable packages. If the break is intended
+// (for a major version), re-record the API with "mon api-check --write".
+func TestStableAPI(t *testing.T) {
+	t.Parallel()
+
+	diff, err := apicheck.Check(filepath.Join("..", ".."))
+	if err != nil {
+		t.Fatalf("%v:\n%s", err, strings.Join(diff.Removed, "\n"))
+	}
+}
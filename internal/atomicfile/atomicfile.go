@@ -0,0 +1,27 @@
+// Package atomicfile writes files that must never be seen half-written, like the state saved for resuming a session.
+package atomicfile
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Write writes data to path, creating its directory if needed. It's written to a temporary file next to path first and
+// moved into place, so a crash mid-write leaves the previous contents rather than a truncated file.
+func Write(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create directory for %q: %w", path, err)
+	}
+
+	tempPath := path + ".tmp"
+	if err := os.WriteFile(tempPath, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write %q: %w", tempPath, err)
+	}
+
+	if err := os.Rename(tempPath, path); err != nil {
+		return fmt.Errorf("failed to move %q into place: %w", tempPath, err)
+	}
+
+	return nil
+}
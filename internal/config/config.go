@@ -1,6 +1,8 @@
 package config
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -8,12 +10,148 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
+	"slices"
+	"time"
 
 	"github.com/cneill/mon/pkg/audio"
+	"github.com/cneill/mon/pkg/files"
+	"github.com/cneill/mon/pkg/listeners"
+	"github.com/cneill/mon/pkg/mon"
 )
 
 type Config struct {
 	Audio *audio.Config `json:"audio"`
+	// Listeners restricts where each listener's manifests are honored, keyed by listener name (e.g. "Node.JS").
+	Listeners map[string]*listeners.Scope `json:"listeners"`
+	// Manifests are absolute paths to manifests outside the project directory to watch, like a shared constraints file.
+	Manifests []string `json:"manifests"`
+	// Numbers controls how counters are formatted.
+	Numbers *Numbers `json:"numbers"`
+	// Times controls how timestamps are shown.
+	Times *Times `json:"times"`
+	// Editors tunes how editors' saves are recognized.
+	Editors *Editors `json:"editors"`
+	// Sessions limits how many interrupted sessions are kept for "mon resume".
+	Sessions *Sessions `json:"sessions"`
+	// Redact hashes paths and strips commit details from exports, for sharing session metrics.
+	Redact *mon.Redaction `json:"redact"`
+	// Service configures the background session installed by "mon service install".
+	Service *Service `json:"service"`
+}
+
+// Service configures the background session that "mon service install" sets up.
+type Service struct {
+	// Projects are the absolute paths of the project directories the service watches, all in one session.
+	Projects []string `json:"projects"`
+	// Args are extra flags for the service's mon, like ["--status-out", "/tmp/mon.ndjson"].
+	Args []string `json:"args"`
+}
+
+func (s *Service) OK() error {
+	for _, path := range s.Projects {
+		if !filepath.IsAbs(path) {
+			return fmt.Errorf("project path %q must be absolute", path)
+		}
+	}
+
+	return nil
+}
+
+// Sessions limits the interrupted sessions kept in the checkpoint directory. Unset fields use the defaults from
+// mon.DefaultRetentionPolicy, and 0 turns a limit off.
+type Sessions struct {
+	// MaxSessions is the most interrupted sessions kept.
+	MaxSessions *int `json:"max_sessions"`
+	// MaxAge is how long an interrupted session is kept, like "168h".
+	MaxAge string `json:"max_age"`
+	// MaxSizeMB is the most space, in MiB, that interrupted sessions can take up.
+	MaxSizeMB *int64 `json:"max_size_mb"`
+}
+
+// Policy applies the configured limits to the defaults.
+func (s *Sessions) Policy() mon.RetentionPolicy {
+	policy := mon.DefaultRetentionPolicy()
+
+	if s.MaxSessions != nil {
+		policy.MaxSessions = *s.MaxSessions
+	}
+
+	if age, err := time.ParseDuration(s.MaxAge); err == nil {
+		policy.MaxAge = age
+	}
+
+	if s.MaxSizeMB != nil {
+		policy.MaxBytes = *s.MaxSizeMB << 20
+	}
+
+	return policy
+}
+
+func (s *Sessions) OK() error {
+	if s.MaxAge != "" {
+		if _, err := time.ParseDuration(s.MaxAge); err != nil {
+			return fmt.Errorf("invalid max age %q", s.MaxAge)
+		}
+	}
+
+	return s.Policy().OK()
+}
+
+// Editors tunes how saves are told apart from real creates and deletes, for editors that save through temporary files.
+type Editors struct {
+	// Profiles are the built-in editor profiles whose temporary files are ignored (see files.EditorProfileNames).
+	// Defaults to all of them.
+	Profiles []string `json:"profiles"`
+	// TempPatterns are more globs for the names of temporary files to ignore, like "*.bak".
+	TempPatterns []string `json:"temp_patterns"`
+	// DeleteTimeout is how long a removed file has to reappear before it counts as deleted, like "1s". Defaults to
+	// files.DefaultDeleteTimeout.
+	DeleteTimeout string `json:"delete_timeout"`
+}
+
+// Timeout parses DeleteTimeout, returning 0 if it's empty or invalid.
+func (e *Editors) Timeout() time.Duration {
+	timeout, _ := time.ParseDuration(e.DeleteTimeout)
+
+	return timeout
+}
+
+func (e *Editors) OK() error {
+	if e.DeleteTimeout != "" {
+		if timeout, err := time.ParseDuration(e.DeleteTimeout); err != nil || timeout < 0 {
+			return fmt.Errorf("invalid delete timeout %q", e.DeleteTimeout)
+		}
+	}
+
+	for _, profile := range e.Profiles {
+		if !slices.Contains(files.EditorProfileNames(), profile) {
+			return fmt.Errorf("unknown editor profile %q, expected one of %v", profile, files.EditorProfileNames())
+		}
+	}
+
+	return nil
+}
+
+// Numbers controls how counters are formatted in the display and reports.
+type Numbers struct {
+	// Locale picks the thousands separator and decimal mark (e.g. "de_DE"). Defaults to the environment's locale.
+	Locale string `json:"locale"`
+	// Compact abbreviates large numbers, e.g. 12.4k.
+	Compact bool `json:"compact"`
+}
+
+// Times controls how timestamps are shown in the display and reports.
+type Times struct {
+	// TimeZone is "Local", "UTC", or an IANA name like "Europe/Berlin". Defaults to the local time zone.
+	TimeZone string `json:"time_zone"`
+}
+
+func (t *Times) OK() error {
+	if _, err := mon.TimeFormatForZone(t.TimeZone); err != nil {
+		return err
+	}
+
+	return nil
 }
 
 func (c *Config) OK() error {
@@ -23,6 +161,46 @@ func (c *Config) OK() error {
 		}
 	}
 
+	for name, scope := range c.Listeners {
+		if scope == nil {
+			continue
+		}
+
+		if err := scope.OK(); err != nil {
+			return fmt.Errorf("error with scope for listener %q: %w", name, err)
+		}
+	}
+
+	if c.Editors != nil {
+		if err := c.Editors.OK(); err != nil {
+			return fmt.Errorf("error with editors config: %w", err)
+		}
+	}
+
+	if c.Times != nil {
+		if err := c.Times.OK(); err != nil {
+			return fmt.Errorf("error with times config: %w", err)
+		}
+	}
+
+	if c.Sessions != nil {
+		if err := c.Sessions.OK(); err != nil {
+			return fmt.Errorf("error with sessions config: %w", err)
+		}
+	}
+
+	if c.Service != nil {
+		if err := c.Service.OK(); err != nil {
+			return fmt.Errorf("error with service config: %w", err)
+		}
+	}
+
+	for _, path := range c.Manifests {
+		if !filepath.IsAbs(path) {
+			return fmt.Errorf("manifest path %q must be absolute", path)
+		}
+	}
+
 	return nil
 }
 
@@ -78,3 +256,37 @@ func DefaultConfigPath() string {
 
 	return filepath.Join(dir, "config.json")
 }
+
+// DefaultSoundsDir returns the directory scanned for custom sounds ($HOME/.config/mon/sounds)
+func DefaultSoundsDir() string {
+	dir := DefaultConfigDir()
+	if dir == "" {
+		return ""
+	}
+
+	return filepath.Join(dir, "sounds")
+}
+
+// DefaultSessionsDir returns the directory that sessions are checkpointed to ($XDG_CACHE_HOME/mon/sessions)
+func DefaultSessionsDir() string {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		slog.Error("Failed to locate user cache directory", "error", err)
+		return ""
+	}
+
+	return filepath.Join(cacheDir, "mon", "sessions")
+}
+
+// DefaultCheckpointPath returns the path used to checkpoint sessions for the given project directory
+// ($XDG_CACHE_HOME/mon/sessions/[hash of project dir].json)
+func DefaultCheckpointPath(projectDir string) string {
+	dir := DefaultSessionsDir()
+	if dir == "" {
+		return ""
+	}
+
+	sum := sha256.Sum256([]byte(projectDir))
+
+	return filepath.Join(dir, hex.EncodeToString(sum[:8])+".json")
+}
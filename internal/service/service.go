@@ -0,0 +1,230 @@
+// Package service runs mon in the background as a per-user service: a systemd user unit on Linux, or a launchd agent
+// on macOS. The service runs mon with --display quiet, so it keeps watching its projects without a terminal and writes
+// its report to the service log when it's stopped.
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+const (
+	// SystemdUnit is the name of the systemd user unit.
+	SystemdUnit = "mon.service"
+	// LaunchdLabel is the label of the launchd agent.
+	LaunchdLabel = "com.github.cneill.mon"
+)
+
+// ErrUnsupported is returned on platforms without a supported service manager.
+var ErrUnsupported = errors.New("services are only supported with systemd on Linux and launchd on macOS")
+
+// ErrNotInstalled is returned by Status and Uninstall when the service isn't installed.
+var ErrNotInstalled = errors.New("mon service is not installed")
+
+// Spec is what the service runs.
+type Spec struct {
+	// Executable is the absolute path of the mon binary to run. For a Homebrew install, this should be the linked path
+	// (e.g. /opt/homebrew/bin/mon) rather than the versioned one in the Cellar, so the service survives upgrades.
+	Executable string
+	// Projects are the absolute paths of the project directories to watch, all in one session.
+	Projects []string
+	// Args are extra flags for mon, like ["--status-out", "/tmp/mon.ndjson"].
+	Args []string
+	// LogPath is where launchd writes the service's output. systemd sends it to the journal instead.
+	LogPath string
+}
+
+func (s *Spec) OK() error {
+	if !filepath.IsAbs(s.Executable) {
+		return fmt.Errorf("executable path %q must be absolute", s.Executable)
+	}
+
+	if len(s.Projects) == 0 {
+		return fmt.Errorf("must supply at least one project directory")
+	}
+
+	for _, project := range s.Projects {
+		if !filepath.IsAbs(project) {
+			return fmt.Errorf("project path %q must be absolute", project)
+		}
+	}
+
+	return nil
+}
+
+// command is the command line the service runs.
+func (s *Spec) command() []string {
+	result := []string{s.Executable, "--display", "quiet", "--no-color"}
+	result = append(result, s.Args...)
+	result = append(result, s.Projects[0])
+
+	for _, project := range s.Projects[1:] {
+		result = append(result, "--project-dir", project)
+	}
+
+	return result
+}
+
+// manager is how a platform's service manager installs, inspects, and removes the service.
+type manager struct {
+	path      string // where the service definition is written
+	render    func(*Spec) string
+	install   [][]string // commands run after writing the definition
+	uninstall [][]string // commands run before removing the definition
+	reload    [][]string // commands run after removing the definition
+	status    []string
+}
+
+// current returns the service manager for this platform.
+func current() (*manager, error) {
+	switch runtime.GOOS {
+	case "linux":
+		configDir, err := os.UserConfigDir()
+		if err != nil {
+			return nil, fmt.Errorf("failed to locate user config directory: %w", err)
+		}
+
+		return &manager{
+			path:   filepath.Join(configDir, "systemd", "user", SystemdUnit),
+			render: systemdUnit,
+			install: [][]string{
+				{"systemctl", "--user", "daemon-reload"},
+				{"systemctl", "--user", "enable", "--now", SystemdUnit},
+			},
+			uninstall: [][]string{{"systemctl", "--user", "disable", "--now", SystemdUnit}},
+			reload:    [][]string{{"systemctl", "--user", "daemon-reload"}},
+			status:    []string{"systemctl", "--user", "status", "--no-pager", SystemdUnit},
+		}, nil
+	case "darwin":
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("failed to locate user home directory: %w", err)
+		}
+
+		path := filepath.Join(home, "Library", "LaunchAgents", LaunchdLabel+".plist")
+		domain := fmt.Sprintf("gui/%d", os.Getuid())
+
+		return &manager{
+			path:      path,
+			render:    launchdPlist,
+			install:   [][]string{{"launchctl", "bootstrap", domain, path}},
+			uninstall: [][]string{{"launchctl", "bootout", domain + "/" + LaunchdLabel}},
+			status:    []string{"launchctl", "print", domain + "/" + LaunchdLabel},
+		}, nil
+	}
+
+	return nil, ErrUnsupported
+}
+
+// DefaultLogPath returns where launchd writes the service's output on macOS ($HOME/Library/Logs/mon.log), or an empty
+// string elsewhere.
+func DefaultLogPath() string {
+	if runtime.GOOS != "darwin" {
+		return ""
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(home, "Library", "Logs", "mon.log")
+}
+
+// Install writes the service definition and starts the service, replacing it if it's already installed. It returns
+// the path of the definition.
+func Install(ctx context.Context, spec *Spec) (string, error) {
+	if err := spec.OK(); err != nil {
+		return "", fmt.Errorf("invalid service spec: %w", err)
+	}
+
+	mgr, err := current()
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := os.Stat(mgr.path); err == nil {
+		// Stop the old service first, or the new definition won't take effect until it's restarted
+		_ = runAll(ctx, mgr.uninstall)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(mgr.path), 0o755); err != nil {
+		return "", fmt.Errorf("failed to create service directory: %w", err)
+	}
+
+	data := []byte(mgr.render(spec))
+
+	if err := os.WriteFile(mgr.path, data, 0o644); err != nil { //nolint:gosec // read by the service manager
+		return "", fmt.Errorf("failed to write service definition: %w", err)
+	}
+
+	if err := runAll(ctx, mgr.install); err != nil {
+		return mgr.path, fmt.Errorf("failed to start service: %w", err)
+	}
+
+	return mgr.path, nil
+}
+
+// Status returns the service manager's description of the service, like whether it's running and its recent output.
+func Status(ctx context.Context) (string, error) {
+	mgr, err := current()
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := os.Stat(mgr.path); errors.Is(err, fs.ErrNotExist) {
+		return "", ErrNotInstalled
+	}
+
+	output, err := exec.CommandContext(ctx, mgr.status[0], mgr.status[1:]...).CombinedOutput() //nolint:gosec
+	if err != nil && len(output) == 0 {
+		return "", fmt.Errorf("failed to get service status: %w", err)
+	}
+
+	// systemctl exits non-zero for a stopped service, which is still a status worth showing
+	return string(output), nil
+}
+
+// Uninstall stops the service and removes its definition.
+func Uninstall(ctx context.Context) error {
+	mgr, err := current()
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(mgr.path); errors.Is(err, fs.ErrNotExist) {
+		return ErrNotInstalled
+	}
+
+	if err := runAll(ctx, mgr.uninstall); err != nil {
+		return fmt.Errorf("failed to stop service: %w", err)
+	}
+
+	if err := os.Remove(mgr.path); err != nil {
+		return fmt.Errorf("failed to remove service definition: %w", err)
+	}
+
+	if err := runAll(ctx, mgr.reload); err != nil {
+		return fmt.Errorf("failed to reload services: %w", err)
+	}
+
+	return nil
+}
+
+func runAll(ctx context.Context, commands [][]string) error {
+	for _, command := range commands {
+		output, err := exec.CommandContext(ctx, command[0], command[1:]...).CombinedOutput() //nolint:gosec
+		if err != nil {
+			return fmt.Errorf("%s: %w: %s", strings.Join(command, " "), err, strings.TrimSpace(string(output)))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,92 @@
+package service
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+)
+
+// systemdUnit renders the systemd user unit. The service restarts after a crash, but not after a clean exit, so
+// stopping it ends the session with a report like Ctrl-C would.
+func systemdUnit(spec *Spec) string {
+	args := make([]string, 0, len(spec.command()))
+	for _, arg := range spec.command() {
+		args = append(args, systemdQuote(arg))
+	}
+
+	return `[Unit]
+Description=mon: watch coding agent sessions
+
+[Service]
+Type=simple
+ExecStart=` + strings.Join(args, " ") + `
+WorkingDirectory=` + strings.ReplaceAll(spec.Projects[0], "%", "%%") + `
+Restart=on-failure
+RestartSec=10
+
+[Install]
+WantedBy=default.target
+`
+}
+
+// systemdQuote quotes an argument for ExecStart if it needs it, escaping the specifiers and variables systemd would
+// otherwise expand.
+func systemdQuote(arg string) string {
+	arg = strings.NewReplacer("%", "%%", "$", "$$").Replace(arg)
+
+	if arg != "" && !strings.ContainsAny(arg, " \t\"'\\;") {
+		return arg
+	}
+
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(arg) + `"`
+}
+
+// launchdPlist renders the launchd agent. Like the systemd unit, it's restarted after a crash but not a clean exit.
+func launchdPlist(spec *Spec) string {
+	builder := &strings.Builder{}
+
+	builder.WriteString(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>Label</key>
+	<string>` + LaunchdLabel + `</string>
+	<key>ProgramArguments</key>
+	<array>
+`)
+
+	for _, arg := range spec.command() {
+		builder.WriteString("\t\t<string>" + xmlEscape(arg) + "</string>\n")
+	}
+
+	builder.WriteString(`	</array>
+	<key>WorkingDirectory</key>
+	<string>` + xmlEscape(spec.Projects[0]) + `</string>
+	<key>RunAtLoad</key>
+	<true/>
+	<key>KeepAlive</key>
+	<dict>
+		<key>SuccessfulExit</key>
+		<false/>
+	</dict>
+`)
+
+	if spec.LogPath != "" {
+		builder.WriteString(`	<key>StandardOutPath</key>
+	<string>` + xmlEscape(spec.LogPath) + `</string>
+	<key>StandardErrorPath</key>
+	<string>` + xmlEscape(spec.LogPath) + `</string>
+`)
+	}
+
+	builder.WriteString("</dict>\n</plist>\n")
+
+	return builder.String()
+}
+
+func xmlEscape(s string) string {
+	buf := &bytes.Buffer{}
+	_ = xml.EscapeText(buf, []byte(s))
+
+	return buf.String()
+}
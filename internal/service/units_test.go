@@ -0,0 +1,72 @@
+package service //nolint:testpackage // the unit renderers are unexported
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSpecOK(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		spec  Spec
+		valid bool
+	}{
+		{"valid", Spec{Executable: "/usr/local/bin/mon", Projects: []string{"/src/app"}}, true},
+		{"no projects", Spec{Executable: "/usr/local/bin/mon"}, false},
+		{"relative project", Spec{Executable: "/usr/local/bin/mon", Projects: []string{"app"}}, false},
+		{"relative executable", Spec{Executable: "mon", Projects: []string{"/src/app"}}, false},
+	}
+
+	for _, test := range tests {
+		if err := test.spec.OK(); (err == nil) != test.valid {
+			t.Errorf("%s: expected valid == %t, got error %v", test.name, test.valid, err)
+		}
+	}
+}
+
+func TestSystemdUnit(t *testing.T) {
+	t.Parallel()
+
+	spec := &Spec{
+		Executable: "/usr/local/bin/mon",
+		Projects:   []string{"/src/my app", "/src/api"},
+		Args:       []string{"--status-out", "/tmp/100%.ndjson"},
+	}
+
+	unit := systemdUnit(spec)
+
+	expected := `ExecStart=/usr/local/bin/mon --display quiet --no-color --status-out /tmp/100%%.ndjson "/src/my app" ` +
+		"--project-dir /src/api\n"
+	if !strings.Contains(unit, expected) {
+		t.Errorf("expected unit to contain %q, got:\n%s", expected, unit)
+	}
+
+	if !strings.Contains(unit, "WorkingDirectory=/src/my app\n") {
+		t.Errorf("expected working directory to be the first project, got:\n%s", unit)
+	}
+}
+
+func TestLaunchdPlist(t *testing.T) {
+	t.Parallel()
+
+	spec := &Spec{
+		Executable: "/opt/homebrew/bin/mon",
+		Projects:   []string{"/src/R&D"},
+		LogPath:    "/Users/me/Library/Logs/mon.log",
+	}
+
+	plist := launchdPlist(spec)
+
+	for _, expected := range []string{
+		"<string>" + LaunchdLabel + "</string>",
+		"\t\t<string>/opt/homebrew/bin/mon</string>\n\t\t<string>--display</string>\n\t\t<string>quiet</string>\n",
+		"\t\t<string>/src/R&amp;D</string>\n\t</array>",
+		"<key>StandardOutPath</key>\n\t<string>/Users/me/Library/Logs/mon.log</string>",
+	} {
+		if !strings.Contains(plist, expected) {
+			t.Errorf("expected plist to contain %q, got:\n%s", expected, plist)
+		}
+	}
+}
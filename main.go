@@ -9,11 +9,16 @@ import (
 	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/urfave/cli/v3"
 
+	"github.com/cneill/mon/internal/apicheck"
 	"github.com/cneill/mon/internal/config"
+	"github.com/cneill/mon/internal/service"
 	"github.com/cneill/mon/internal/version"
+	"github.com/cneill/mon/pkg/audio"
+	"github.com/cneill/mon/pkg/git"
 	"github.com/cneill/mon/pkg/listeners"
 	"github.com/cneill/mon/pkg/listeners/golang"
 	"github.com/cneill/mon/pkg/listeners/npm"
@@ -30,6 +35,61 @@ func run(ctx context.Context) error {
 		Flags:     allFlags(),
 		Action:    setupMon,
 		ArgsUsage: "[PROJECT_DIRECTORY]",
+		Commands: []*cli.Command{
+			{
+				Name:      "resume",
+				Usage:     "Resume a session that was interrupted by a crash, using its last checkpoint.",
+				Action:    resumeMon,
+				ArgsUsage: "[PROJECT_DIRECTORY]",
+			},
+			{
+				Name:      "remote",
+				Usage:     "Run mon on another machine over SSH and show its status here.",
+				Action:    remoteMon,
+				Flags:     remoteFlags(),
+				ArgsUsage: "[USER@]HOST:PROJECT_DIRECTORY",
+			},
+			{
+				Name:  "sessions",
+				Usage: "Manage the interrupted sessions saved for \"mon resume\".",
+				Commands: []*cli.Command{
+					{
+						Name:   "prune",
+						Usage:  "Remove saved sessions beyond the limits in the config file's \"sessions\" settings.",
+						Action: pruneSessions,
+					},
+				},
+			},
+			{
+				Name:  "service",
+				Usage: "Run mon in the background for the projects in the config file's \"service\" settings.",
+				Commands: []*cli.Command{
+					{
+						Name:      "install",
+						Usage:     "Install and start mon as a per-user service (systemd on Linux, launchd on macOS).",
+						Action:    installService,
+						ArgsUsage: "[PROJECT_DIRECTORY...]",
+					},
+					{
+						Name:   "status",
+						Usage:  "Show whether the service is running, and its recent output.",
+						Action: serviceStatus,
+					},
+					{
+						Name:   "uninstall",
+						Usage:  "Stop the service and remove it.",
+						Action: uninstallService,
+					},
+				},
+			},
+			{
+				Name:      "api-check",
+				Usage:     "Check mon's source for breaking changes to the API of its stable packages.",
+				Action:    apiCheck,
+				Flags:     apiCheckFlags(),
+				ArgsUsage: "[MODULE_DIRECTORY]",
+			},
+		},
 	}
 
 	if err := cmd.Run(ctx, os.Args); err != nil {
@@ -40,13 +100,14 @@ func run(ctx context.Context) error {
 }
 
 func setupMon(ctx context.Context, cmd *cli.Command) error {
-	args := cmd.Args()
-	rawProjectDir := "."
+	return startMon(ctx, cmd, false)
+}
 
-	if args.Len() > 0 {
-		rawProjectDir = strings.TrimSpace(args.First())
-	}
+func resumeMon(ctx context.Context, cmd *cli.Command) error {
+	return startMon(ctx, cmd, true)
+}
 
+func startMon(ctx context.Context, cmd *cli.Command, resume bool) error {
 	color.NoColor = cmd.Bool(FlagNoColor)
 
 	if cmd.Bool(FlagDebug) {
@@ -58,30 +119,53 @@ func setupMon(ctx context.Context, cmd *cli.Command) error {
 		defer file.Close()
 	}
 
-	projectDir, err := filepath.Abs(filepath.Clean(rawProjectDir))
+	projectDirs, err := resolveProjectDirs(cmd)
 	if err != nil {
-		return fmt.Errorf("invalid project path %q: %w", rawProjectDir, err)
+		return err
 	}
 
+	projectDir := projectDirs[0]
+
 	cfg := loadConfig(cmd.String(FlagConfig))
 
 	opts := &mon.Opts{
 		NoColor:      cmd.Bool(FlagNoColor),
 		AudioEnabled: cmd.Bool(FlagAudio),
+		AudioConfig:  audioConfig(cfg),
 		ProjectDir:   projectDir,
+		ProjectDirs:  projectDirs[1:],
+		Version:      version.String(),
 		Listeners: []listeners.Listener{
 			golang.New(),
 			npm.New(),
 			python.New(),
 		},
 
-		DetailsOpts: &mon.DetailsOpts{
-			ShowAllFiles: cmd.Bool(FlagShowAllFiles),
-		},
+		CheckpointPath:     config.DefaultCheckpointPath(projectDir),
+		CheckpointInterval: cmd.Duration(FlagCheckpointInterval),
+		TurnGap:            cmd.Duration(FlagTurnGap),
+		DisplayMode:        displayMode(cmd.String(FlagDisplay)),
+		DisplayInterval:    cmd.Duration(FlagDisplayInterval),
+		Resume:             resume,
+		OverlayAddr:        cmd.String(FlagOverlayServer),
+
+		DetailsOpts: detailsOpts(cmd, cfg),
+		ExportOpts:  exportOpts(cmd, cfg),
 	}
 
-	if cfg != nil && cfg.Audio != nil {
-		opts.AudioConfig = cfg.Audio
+	setFileOpts(opts, cmd, cfg)
+	setGitOpts(opts, cmd)
+
+	if cfg != nil {
+		opts.ListenerScopes = cfg.Listeners
+		opts.ExternalManifests = cfg.Manifests
+	}
+
+	// Interrupted sessions pile up otherwise, since only a clean exit removes their checkpoints
+	if removed, err := mon.PruneSessions(config.DefaultSessionsDir(), retentionPolicy(cfg), opts.CheckpointPath, time.Now()); err != nil {
+		slog.Warn("failed to prune saved sessions", "error", err)
+	} else if len(removed) > 0 {
+		slog.Debug("pruned saved sessions", "removed", removed)
 	}
 
 	mon, err := mon.New(opts) //nolint:contextcheck
@@ -89,7 +173,9 @@ func setupMon(ctx context.Context, cmd *cli.Command) error {
 		return fmt.Errorf("failed to set up mon: %w", err)
 	}
 
-	mon.AudioManager.Run(ctx)
+	if mon.AudioManager != nil {
+		mon.AudioManager.Run(ctx)
+	}
 
 	defer mon.Teardown()
 
@@ -100,6 +186,321 @@ func setupMon(ctx context.Context, cmd *cli.Command) error {
 	return nil
 }
 
+// resolveProjectDirs returns the absolute paths of the project directories to watch, from the argument and
+// --project-dir. Without either, it's the git repository around the working directory, or the working directory itself.
+func resolveProjectDirs(cmd *cli.Command) ([]string, error) {
+	args := cmd.Args()
+	rawProjectDirs := cmd.StringSlice(FlagProjectDir)
+
+	if args.Len() > 0 {
+		rawProjectDirs = append([]string{strings.TrimSpace(args.First())}, rawProjectDirs...)
+	} else if len(rawProjectDirs) == 0 {
+		rawProjectDirs = []string{"."}
+
+		if root, err := git.FindRoot("."); err == nil {
+			// Running from a subdirectory monitors the whole project
+			rawProjectDirs = []string{root}
+		}
+	}
+
+	results := make([]string, 0, len(rawProjectDirs))
+
+	for _, rawProjectDir := range rawProjectDirs {
+		projectDir, err := filepath.Abs(filepath.Clean(strings.TrimSpace(rawProjectDir)))
+		if err != nil {
+			return nil, fmt.Errorf("invalid project path %q: %w", rawProjectDir, err)
+		}
+
+		results = append(results, projectDir)
+	}
+
+	return results, nil
+}
+
+// setFileOpts sets the options for watching and counting files from the flags and the config file.
+func setFileOpts(opts *mon.Opts, cmd *cli.Command, cfg *config.Config) {
+	opts.SaveWindow = cmd.Duration(FlagSaveWindow)
+	opts.HashContents = cmd.Bool(FlagHashContents)
+	opts.IgnorePatterns = cmd.StringSlice(FlagIgnore)
+	opts.PollInterval = cmd.Duration(FlagPoll)
+	opts.FollowSymlinks = cmd.Bool(FlagFollowSymlinks)
+	opts.MaxDepth = cmd.Int(FlagMaxDepth)
+	opts.MaxTrackedFiles = cmd.Int(FlagMaxFiles)
+	opts.LazyWatchDepth = cmd.Int(FlagLazyWatchDepth)
+	opts.DebounceWindow = cmd.Duration(FlagDebounceWindow)
+	opts.TopNewFiles = cmd.Int(FlagTopNewFiles)
+
+	// 0 turns debouncing off on the command line, like --save-window, but means the default to the file monitor
+	if opts.DebounceWindow == 0 {
+		opts.DebounceWindow = -1
+	}
+
+	if opts.TopNewFiles == 0 {
+		opts.TopNewFiles = -1
+	}
+
+	if cfg != nil && cfg.Editors != nil {
+		opts.DeleteTimeout = cfg.Editors.Timeout()
+		opts.EditorProfiles = cfg.Editors.Profiles
+		opts.TempPatterns = cfg.Editors.TempPatterns
+	}
+}
+
+// setGitOpts sets the options for session refs and commit checks from the flags.
+func setGitOpts(opts *mon.Opts, cmd *cli.Command) {
+	opts.SnapshotRefs = cmd.Bool(FlagSnapshotRefs)
+	opts.CheckCommand = cmd.String(FlagCheckCommand)
+
+	if opts.CheckCommand == "" && cmd.Bool(FlagCheckCommits) {
+		opts.CheckCommand = mon.DefaultCheckCommand
+	}
+}
+
+// detailsOpts picks what the display and reports show, and how they format numbers and times.
+func detailsOpts(cmd *cli.Command, cfg *config.Config) *mon.DetailsOpts {
+	return &mon.DetailsOpts{
+		ShowAllFiles: cmd.Bool(FlagShowAllFiles),
+		Numbers:      numberFormat(cfg),
+		Times:        timeFormat(cfg, cmd.Bool(FlagUTC)),
+		Goals: mon.Goals{
+			Commits:    cmd.Int64(FlagGoalCommits),
+			TimeBudget: cmd.Duration(FlagTimeBudget),
+		},
+	}
+}
+
+// exportOpts picks the files written during and at the end of the session.
+func exportOpts(cmd *cli.Command, cfg *config.Config) *mon.ExportOpts {
+	result := &mon.ExportOpts{
+		ChangelogPath:    cmd.String(FlagChangelogOut),
+		StatusPath:       cmd.String(FlagStatusOut),
+		EventsCSVPath:    cmd.String(FlagEventsCSV),
+		CSVDir:           cmd.String(FlagCSVDir),
+		EventsParquetDir: cmd.String(FlagEventsParquet),
+		PatchPath:        cmd.String(FlagPatchOut),
+		StatusInterval:   cmd.Duration(FlagStatusInterval),
+	}
+
+	if cfg != nil {
+		result.Redaction = cfg.Redact
+	}
+
+	return result
+}
+
+func remoteMon(ctx context.Context, cmd *cli.Command) error {
+	if cmd.Args().Len() != 1 {
+		return fmt.Errorf("must supply a remote target like user@host:/path/to/project")
+	}
+
+	host, dir, err := mon.ParseRemoteTarget(strings.TrimSpace(cmd.Args().First()))
+	if err != nil {
+		return fmt.Errorf("invalid remote target: %w", err)
+	}
+
+	color.NoColor = cmd.Bool(FlagNoColor)
+
+	cfg := loadConfig(cmd.String(FlagConfig))
+
+	opts := &mon.RemoteOpts{
+		Host:          host,
+		ProjectDir:    dir,
+		SSHCommand:    cmd.String(FlagSSH),
+		RemoteCommand: cmd.String(FlagRemoteCommand),
+
+		DetailsOpts: &mon.DetailsOpts{
+			ShowAllFiles: cmd.Bool(FlagShowAllFiles),
+			Numbers:      numberFormat(cfg),
+			Times:        timeFormat(cfg, cmd.Bool(FlagUTC)),
+		},
+	}
+
+	if err := mon.RunRemote(ctx, opts); err != nil {
+		return fmt.Errorf("mon remote error: %w", err)
+	}
+
+	return nil
+}
+
+func pruneSessions(_ context.Context, cmd *cli.Command) error {
+	removed, err := mon.PruneSessions(config.DefaultSessionsDir(), retentionPolicy(loadConfig(cmd.String(FlagConfig))), "",
+		time.Now())
+
+	for _, path := range removed {
+		fmt.Println("Removed " + path)
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to prune saved sessions: %w", err)
+	}
+
+	fmt.Printf("Removed %d saved sessions\n", len(removed))
+
+	return nil
+}
+
+func installService(ctx context.Context, cmd *cli.Command) error {
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate the mon executable: %w", err)
+	}
+
+	spec := &service.Spec{
+		Executable: executable,
+		LogPath:    service.DefaultLogPath(),
+	}
+
+	if cfg := loadConfig(cmd.String(FlagConfig)); cfg != nil && cfg.Service != nil {
+		spec.Projects = cfg.Service.Projects
+		spec.Args = cfg.Service.Args
+	}
+
+	// Directories on the command line replace the configured ones
+	if cmd.Args().Len() > 0 {
+		spec.Projects = nil
+
+		for _, rawProjectDir := range cmd.Args().Slice() {
+			projectDir, err := filepath.Abs(filepath.Clean(strings.TrimSpace(rawProjectDir)))
+			if err != nil {
+				return fmt.Errorf("invalid project path %q: %w", rawProjectDir, err)
+			}
+
+			spec.Projects = append(spec.Projects, projectDir)
+		}
+	}
+
+	path, err := service.Install(ctx, spec)
+	if err != nil {
+		return fmt.Errorf("failed to install service: %w", err)
+	}
+
+	fmt.Println("Installed and started mon service from " + path)
+
+	return nil
+}
+
+func serviceStatus(ctx context.Context, _ *cli.Command) error {
+	status, err := service.Status(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get service status: %w", err)
+	}
+
+	fmt.Print(status)
+
+	return nil
+}
+
+func uninstallService(ctx context.Context, _ *cli.Command) error {
+	if err := service.Uninstall(ctx); err != nil {
+		return fmt.Errorf("failed to uninstall service: %w", err)
+	}
+
+	fmt.Println("Stopped and removed mon service")
+
+	return nil
+}
+
+func apiCheck(_ context.Context, cmd *cli.Command) error {
+	moduleDir := "."
+	if cmd.Args().Len() > 0 {
+		moduleDir = strings.TrimSpace(cmd.Args().First())
+	}
+
+	if cmd.Bool(FlagAPIWrite) {
+		if err := apicheck.Write(moduleDir); err != nil {
+			return fmt.Errorf("failed to record stable API: %w", err)
+		}
+
+		fmt.Println("Recorded stable API in " + filepath.Join(moduleDir, apicheck.APIFile))
+
+		return nil
+	}
+
+	diff, err := apicheck.Check(moduleDir)
+	if diff != nil {
+		for _, line := range diff.Removed {
+			fmt.Println(color.RedString("- " + line))
+		}
+
+		for _, line := range diff.Added {
+			fmt.Println(color.GreenString("+ " + line))
+		}
+
+		if err == nil && len(diff.Added) > 0 {
+			fmt.Println("Compatible additions found; record them with --" + FlagAPIWrite)
+		}
+	}
+
+	if err != nil {
+		return fmt.Errorf("api check: %w", err)
+	}
+
+	return nil
+}
+
+// audioConfig returns the audio settings from the config file, if any, falling back to the default sounds directory.
+func audioConfig(cfg *config.Config) *audio.Config {
+	result := &audio.Config{}
+	if cfg != nil && cfg.Audio != nil {
+		result = cfg.Audio
+	}
+
+	if result.SoundsDir == "" {
+		result.SoundsDir = config.DefaultSoundsDir()
+	}
+
+	return result
+}
+
+// numberFormat picks how counters are formatted from the config file, falling back to the locale environment.
+func numberFormat(cfg *config.Config) mon.NumberFormat {
+	locale, compact := mon.LocaleFromEnv(), false
+
+	if cfg != nil && cfg.Numbers != nil {
+		compact = cfg.Numbers.Compact
+
+		if cfg.Numbers.Locale != "" {
+			locale = cfg.Numbers.Locale
+		}
+	}
+
+	return mon.NumberFormatForLocale(locale, compact)
+}
+
+// timeFormat picks the time zone for timestamps from the --utc flag or the config file, falling back to local time.
+func timeFormat(cfg *config.Config, utc bool) mon.TimeFormat {
+	if utc {
+		return mon.TimeFormat{Location: time.UTC}
+	}
+
+	if cfg == nil || cfg.Times == nil {
+		return mon.TimeFormat{}
+	}
+
+	// Already checked when the config file was loaded
+	format, _ := mon.TimeFormatForZone(cfg.Times.TimeZone)
+
+	return format
+}
+
+// retentionPolicy returns the limits on saved sessions from the config file, falling back to the defaults.
+func retentionPolicy(cfg *config.Config) mon.RetentionPolicy {
+	if cfg != nil && cfg.Sessions != nil {
+		return cfg.Sessions.Policy()
+	}
+
+	return mon.DefaultRetentionPolicy()
+}
+
+// displayMode maps the --display flag to a mon.DisplayMode, leaving unknown values for mon.Opts.OK to reject.
+func displayMode(value string) mon.DisplayMode {
+	if value == "auto" {
+		return mon.DisplayModeAuto
+	}
+
+	return mon.DisplayMode(value)
+}
+
 func loadConfig(configPath string) *config.Config {
 	cfg, err := config.Load(configPath)
 	if err != nil {
@@ -7,9 +7,27 @@ import (
 )
 
 type Config struct {
+	// Hooks maps events to the sounds played for them, either as a path to a sound file or as the name of a sound in
+	// SoundsDir or one of the built-ins, with or without its extension (e.g. "chime" for chime.ogg).
 	Hooks map[EventType]string `json:"hooks"`
+	// SoundsDir is scanned for custom sounds at startup. It's fine if it doesn't exist.
+	SoundsDir string `json:"sounds_dir"`
+	// SampleRate is the speaker's sample rate in Hz. Defaults to DefaultSampleRate.
+	SampleRate int `json:"sample_rate"`
+	// Normalize levels sounds so they play at a similar volume. Defaults to NormalizeLoudness.
+	Normalize Normalization `json:"normalize"`
+	// Preload lists sounds, by name or file name like hooks, to decode at startup instead of when they're first played.
+	Preload []string `json:"preload"`
+	// Summary chimes once for each commit made during the session, after the session end sound.
+	Summary bool `json:"summary"`
 }
 
+// The range of sample rates audio hardware commonly supports.
+const (
+	minSampleRate = 8000
+	maxSampleRate = 192000
+)
+
 func DefaultConfig() *Config {
 	return &Config{
 		Hooks: map[EventType]string{
@@ -21,23 +39,38 @@ func DefaultConfig() *Config {
 			EventPackageCreate:   "",
 			EventPackageRemove:   "",
 			EventPackageUpgrade:  "",
+			EventSessionStart:    "",
+			EventSessionEnd:      "",
 		},
 	}
 }
 
 func (c *Config) OK() error {
-	if c.Hooks == nil {
-		return nil
+	errors := []string{}
+
+	if c.SampleRate != 0 && (c.SampleRate < minSampleRate || c.SampleRate > maxSampleRate) {
+		errors = append(errors, fmt.Sprintf("sample rate %d must be between %d and %d", c.SampleRate, minSampleRate,
+			maxSampleRate))
 	}
 
-	errors := []string{}
+	if !validNormalization(c.Normalize) {
+		errors = append(errors, fmt.Sprintf("unknown normalization %q: must be %s, %s, or %s", c.Normalize,
+			NormalizeLoudness, NormalizePeak, NormalizeOff))
+	}
+
+	if c.SoundsDir != "" {
+		if stat, err := os.Stat(c.SoundsDir); err == nil && !stat.IsDir() {
+			errors = append(errors, fmt.Sprintf("sounds dir %s is not a directory", c.SoundsDir))
+		}
+	}
 
 	for eventType, path := range c.Hooks {
 		if !ValidEventType(eventType) {
 			errors = append(errors, fmt.Sprintf("unknown event type: %s", eventType))
 		}
 
-		if path == "" {
+		// Sound names are resolved once the sounds are loaded
+		if path == "" || !isSoundPath(path) {
 			continue
 		}
 
@@ -2,8 +2,10 @@ package audio
 
 import (
 	"context"
+	"errors"
 	"log/slog"
 	"slices"
+	"sync"
 	"time"
 )
 
@@ -19,48 +21,197 @@ const (
 	EventPackageCreate   EventType = "package_create"
 	EventPackageUpgrade  EventType = "package_upgrade"
 	EventPackageRemove   EventType = "package_remove"
+	EventSessionStart    EventType = "session_start"
+	EventSessionEnd      EventType = "session_end"
 )
 
 func ValidEventType(eventType EventType) bool {
 	return slices.Contains([]EventType{
 		EventInit, EventGitCommitCreate, EventGitCommitPush, EventFileCreate, EventFileWrite, EventFileRemove,
-		EventPackageCreate, EventPackageUpgrade, EventPackageRemove,
+		EventPackageCreate, EventPackageUpgrade, EventPackageRemove, EventSessionStart, EventSessionEnd,
 	}, eventType)
 }
 
+// Priority decides which events are played when they arrive faster than sounds can be played.
+type Priority int
+
+const (
+	// PriorityDefault uses the event type's usual priority (see DefaultPriority).
+	PriorityDefault Priority = iota
+	// PriorityLow events are the first to be dropped under load, and are cut off by high-priority ones.
+	PriorityLow
+	PriorityNormal
+	// PriorityHigh events skip the rate limit and the line, and cut off any low-priority sounds still playing.
+	PriorityHigh
+)
+
+// DefaultPriority returns the usual priority for events of eventType: frequent events like file writes are low
+// priority, while rare milestones like pushes are high.
+func DefaultPriority(eventType EventType) Priority {
+	switch eventType { //nolint:exhaustive
+	case EventFileWrite, EventFileCreate, EventFileRemove:
+		return PriorityLow
+	case EventGitCommitPush, EventSessionStart, EventSessionEnd:
+		return PriorityHigh
+	default:
+		return PriorityNormal
+	}
+}
+
 type Event struct {
-	Type EventType
-	Time time.Time
+	Type     EventType
+	Time     time.Time
+	Priority Priority
+}
+
+// maxQueuedEvents is how many events can wait to be played before the lowest-priority ones are dropped.
+const maxQueuedEvents = 16
+
+// eventQueue holds events waiting to be played, highest priority first and oldest first within a priority.
+type eventQueue struct {
+	mutex  sync.Mutex
+	events []Event
+	ready  chan struct{} // has a value when events are waiting
+}
+
+func newEventQueue() *eventQueue {
+	return &eventQueue{
+		events: []Event{},
+		ready:  make(chan struct{}, 1),
+	}
+}
+
+// Push queues an event, making room by dropping the lowest-priority queued event if it's lower than event's priority.
+// It returns false if event was dropped instead.
+func (q *eventQueue) Push(event Event) bool {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if len(q.events) >= maxQueuedEvents {
+		// The newest of the lowest-priority events is at the end
+		if q.events[len(q.events)-1].Priority >= event.Priority {
+			return false
+		}
+
+		q.events = q.events[:len(q.events)-1]
+	}
+
+	idx := slices.IndexFunc(q.events, func(queued Event) bool { return queued.Priority < event.Priority })
+	if idx < 0 {
+		idx = len(q.events)
+	}
+
+	q.events = slices.Insert(q.events, idx, event)
+
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+
+	return true
+}
+
+// Pop returns the next event to play, if any.
+func (q *eventQueue) Pop() (Event, bool) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if len(q.events) == 0 {
+		return Event{}, false
+	}
+
+	event := q.events[0]
+	q.events = q.events[1:]
+
+	if len(q.events) > 0 {
+		select {
+		case q.ready <- struct{}{}:
+		default:
+		}
+	}
+
+	return event, true
 }
 
+// SendEvent queues a sound for event without waiting for it to play. Low- and normal-priority events are rate limited,
+// while high-priority ones always go through.
 func (m *Manager) SendEvent(ctx context.Context, event Event) {
-	if !m.limiter.Allow() {
+	if ctx.Err() != nil {
 		return
 	}
 
-	select {
-	case <-ctx.Done():
+	if event.Priority == PriorityDefault {
+		event.Priority = DefaultPriority(event.Type)
+	}
+
+	if event.Priority < PriorityHigh && !m.limiter.Allow() {
+		m.dropped.Add(1)
+		return
+	}
+
+	if !m.queue.Push(event) {
+		m.dropped.Add(1)
 		return
-	case m.eventChan <- event:
-		slog.Debug("sent sound event", "event", event)
 	}
+
+	slog.Debug("sent sound event", "event", event)
+}
+
+// Dropped returns how many events were skipped because sounds were already playing too often.
+func (m *Manager) Dropped() int64 {
+	return m.dropped.Load()
+}
+
+// preemptible is the context low-priority sounds play under, cancelled to cut them off when a high-priority one starts.
+type preemptible struct {
+	ctx    context.Context //nolint:containedctx
+	cancel context.CancelFunc
+}
+
+func newPreemptible(ctx context.Context) *preemptible {
+	result := &preemptible{}
+	result.ctx, result.cancel = context.WithCancel(ctx)
+
+	return result
 }
 
 func (m *Manager) eventLoop(ctx context.Context) {
-	for event := range m.eventChan {
+	low := newPreemptible(ctx)
+
+	defer func() { low.cancel() }()
+
+	for {
 		select {
 		case <-ctx.Done():
 			return
-		default:
+		case <-m.queue.ready:
 		}
 
+		event, ok := m.queue.Pop()
+		if !ok {
+			continue
+		}
+
+		m.hookMutex.RLock()
 		soundName, ok := m.hookMap[event.Type]
+		m.hookMutex.RUnlock()
+
 		if !ok {
-			return
+			continue
+		}
+
+		playCtx := ctx
+
+		switch event.Priority { //nolint:exhaustive
+		case PriorityHigh:
+			low.cancel()
+			low = newPreemptible(ctx)
+		case PriorityLow:
+			playCtx = low.ctx
 		}
 
 		go func() {
-			if err := m.PlaySound(ctx, soundName); err != nil {
+			if err := m.play(playCtx, soundName); err != nil && !errors.Is(err, context.Canceled) {
 				slog.Error("Failed to play sound", "name", soundName, "error", err)
 			}
 		}()
@@ -0,0 +1,154 @@
+package audio //nolint:testpackage // exercises the unexported event queue and loop
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordPlays replaces mgr's player with one that sends the names of the sounds it's asked to play on the returned
+// channel.
+func recordPlays(mgr *Manager) <-chan string {
+	played := make(chan string, maxQueuedEvents)
+
+	mgr.play = func(_ context.Context, name string) error {
+		played <- name
+		return nil
+	}
+
+	return played
+}
+
+func waitForPlay(t *testing.T, played <-chan string) string {
+	t.Helper()
+
+	select {
+	case name := <-played:
+		return name
+	case <-time.After(time.Second * 5):
+		t.Fatalf("timed out waiting for a sound to play")
+		return ""
+	}
+}
+
+func TestManager_eventLoopHookMiss(t *testing.T) {
+	t.Parallel()
+
+	mgr := newTestManager()
+	played := recordPlays(mgr)
+
+	mgr.hookMap[EventFileWrite] = "file_write.mp3"
+
+	mgr.Run(t.Context())
+
+	// An event without a hook plays nothing, but later events still play
+	mgr.SendEvent(t.Context(), Event{Type: EventPackageRemove})
+	mgr.SendEvent(t.Context(), Event{Type: EventFileWrite})
+
+	if name := waitForPlay(t, played); name != "file_write.mp3" {
+		t.Errorf("expected file_write.mp3 to play, got %q", name)
+	}
+}
+
+func TestManager_concurrentHooks(t *testing.T) {
+	t.Parallel()
+
+	mgr := newTestManager("chime.ogg")
+	played := recordPlays(mgr)
+
+	mgr.hookMap[EventInit] = "init.mp3"
+
+	mgr.Run(t.Context())
+
+	var wg sync.WaitGroup
+
+	for idx := range 50 {
+		wg.Go(func() {
+			name := "init.mp3"
+			if idx%2 == 0 {
+				name = "chime.ogg"
+			}
+
+			if err := mgr.AddEventHook(name, EventInit); err != nil {
+				t.Errorf("failed to add hook: %v", err)
+			}
+		})
+
+		wg.Go(func() {
+			mgr.SendEvent(t.Context(), Event{Type: EventInit, Priority: PriorityHigh})
+		})
+	}
+
+	wg.Wait()
+
+	// High-priority events skip the rate limit, so only those that found the queue full were dropped
+	for range 50 - int(mgr.Dropped()) {
+		if name := waitForPlay(t, played); name != "init.mp3" && name != "chime.ogg" {
+			t.Errorf("expected one of the hooked sounds to play, got %q", name)
+		}
+	}
+
+	if err := mgr.AddEventHook("missing.ogg", EventInit); err == nil {
+		t.Errorf("expected error hooking a missing sound")
+	}
+
+	if name := mgr.hookMap[EventInit]; name != "init.mp3" && name != "chime.ogg" {
+		t.Errorf("expected a failed hook to leave the existing one, got %q", name)
+	}
+}
+
+func TestEventQueue(t *testing.T) {
+	t.Parallel()
+
+	queue := newEventQueue()
+
+	for range maxQueuedEvents - 1 {
+		if !queue.Push(Event{Type: EventFileWrite, Priority: PriorityLow}) {
+			t.Fatalf("expected low-priority event to be queued while there's room")
+		}
+	}
+
+	queue.Push(Event{Type: EventPackageCreate, Priority: PriorityNormal})
+
+	// The queue is full, so another low-priority event is dropped, but a high-priority one replaces the newest
+	// low-priority event and skips the line
+	if queue.Push(Event{Type: EventFileWrite, Priority: PriorityLow}) {
+		t.Errorf("expected low-priority event to be dropped from a full queue")
+	}
+
+	if !queue.Push(Event{Type: EventGitCommitPush, Priority: PriorityHigh}) {
+		t.Errorf("expected high-priority event to make room in a full queue")
+	}
+
+	expected := []EventType{EventGitCommitPush, EventPackageCreate}
+	for range maxQueuedEvents - 2 {
+		expected = append(expected, EventFileWrite)
+	}
+
+	for idx, eventType := range expected {
+		event, ok := queue.Pop()
+		if !ok || event.Type != eventType {
+			t.Fatalf("event %d: expected %s, got %s (ok: %t)", idx, eventType, event.Type, ok)
+		}
+	}
+
+	if event, ok := queue.Pop(); ok {
+		t.Errorf("expected empty queue, got %s", event.Type)
+	}
+}
+
+func TestDefaultPriority(t *testing.T) {
+	t.Parallel()
+
+	for eventType, expected := range map[EventType]Priority{
+		EventFileWrite:       PriorityLow,
+		EventPackageUpgrade:  PriorityNormal,
+		EventGitCommitCreate: PriorityNormal,
+		EventGitCommitPush:   PriorityHigh,
+	} {
+		if priority := DefaultPriority(eventType); priority != expected {
+			t.Errorf("%s: expected priority %d, got %d", eventType, expected, priority)
+		}
+	}
+}
@@ -10,8 +10,10 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"sync"
-	"time"
+	"sync/atomic"
 
 	"github.com/gopxl/beep/v2"
 	"github.com/gopxl/beep/v2/mp3"
@@ -26,9 +28,6 @@ var builtinAssets embed.FS
 
 var ErrSoundNotFound = errors.New("sound not found")
 
-//nolint:gochecknoglobals
-var speakerSampleRate beep.SampleRate
-
 type Manager struct {
 	soundMutex sync.RWMutex
 	soundMap   map[string]*Sound
@@ -36,8 +35,16 @@ type Manager struct {
 	hookMutex sync.RWMutex
 	hookMap   map[EventType]string // value = sound name
 
-	eventChan chan Event
-	limiter   *rate.Limiter
+	sampleRate beep.SampleRate     // of the speaker, which every sound is resampled to
+	userSounds map[string][]string // sound names from the user's sounds directory, by stem
+	summary    bool
+
+	normalization Normalization // applied to sounds as they're decoded
+
+	queue   *eventQueue
+	play    func(ctx context.Context, name string) error // PlaySound, unless replaced in tests
+	limiter *rate.Limiter
+	dropped atomic.Int64 // events skipped by the rate limiter
 }
 
 func NewManager(cfg *Config) (*Manager, error) {
@@ -48,36 +55,63 @@ func NewManager(cfg *Config) (*Manager, error) {
 	}
 
 	mgr := &Manager{
-		soundMap:  map[string]*Sound{},
-		hookMap:   map[EventType]string{},
-		eventChan: make(chan Event),
-		limiter:   rate.NewLimiter(5, 1),
+		soundMap:   map[string]*Sound{},
+		hookMap:    map[EventType]string{},
+		userSounds: map[string][]string{},
+		queue:      newEventQueue(),
+		limiter:    rate.NewLimiter(5, 1),
+
+		normalization: NormalizeLoudness,
+	}
+
+	mgr.play = mgr.PlaySound
+
+	sampleRate := beep.SampleRate(DefaultSampleRate)
+	if cfg != nil && cfg.SampleRate > 0 {
+		sampleRate = beep.SampleRate(cfg.SampleRate)
+	}
+
+	if cfg != nil && cfg.Normalize != "" {
+		mgr.normalization = cfg.Normalize
+	}
+
+	var err error
+	if mgr.sampleRate, err = initSpeaker(sampleRate); err != nil {
+		return nil, err
 	}
 
 	if err := mgr.loadBuiltins(); err != nil {
 		return nil, fmt.Errorf("failed to load built-in sounds: %w", err)
 	}
 
+	if cfg != nil && cfg.SoundsDir != "" {
+		if err := mgr.loadSoundsDir(cfg.SoundsDir); err != nil {
+			return nil, fmt.Errorf("failed to load user sounds: %w", err)
+		}
+	}
+
 	mgr.applyDefaults()
 
 	// Apply user overrides from config
 	if cfg != nil {
-		for eventType, path := range cfg.Hooks {
-			if path == "" {
-				continue
-			}
+		mgr.summary = cfg.Summary
 
-			if err := mgr.AddSound(path); err != nil {
-				return nil, fmt.Errorf("failed to add sound %q: %w", path, err)
+		for eventType, ref := range cfg.Hooks {
+			if ref == "" {
+				continue
 			}
 
-			if err := mgr.AddEventHook(filepath.Base(path), eventType); err != nil {
+			if err := mgr.applyHook(eventType, ref); err != nil {
 				return nil, fmt.Errorf("failed to add event hook for %q: %w", eventType, err)
 			}
 		}
+
+		if err := mgr.preload(cfg.Preload); err != nil {
+			return nil, fmt.Errorf("failed to preload sounds: %w", err)
+		}
 	}
 
-	go mgr.SendEvent(context.Background(), Event{Type: EventInit})
+	mgr.SendEvent(context.Background(), Event{Type: EventInit})
 
 	return mgr, nil
 }
@@ -86,23 +120,20 @@ func (m *Manager) Run(ctx context.Context) {
 	go m.eventLoop(ctx)
 }
 
-// AddSound takes the path to a sound and stores it for use by the Manager based on event hooks.
+// AddSound takes the path to a sound and stores it for use by the Manager based on event hooks. The file isn't decoded
+// until the sound is first played.
 func (m *Manager) AddSound(path string) error {
-	file, err := os.Open(path)
-	if err != nil {
-		return fmt.Errorf("failed to read file: %w", err)
+	if extension := filepath.Ext(path); !slices.Contains(soundExtensions, strings.ToLower(extension)) {
+		return fmt.Errorf("unknown file format/extension: %s", extension)
 	}
 
-	name := filepath.Base(path)
-
-	stream, format, err := m.getStream(name, file)
-	if err != nil {
-		return fmt.Errorf("failed to get stream: %w", err)
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
 	}
 
-	if err := m.addSound(name, stream, format); err != nil {
-		return err
-	}
+	m.addSound(filepath.Base(path), func() (io.ReadCloser, error) {
+		return os.Open(path)
+	})
 
 	return nil
 }
@@ -137,36 +168,85 @@ func (m *Manager) GetSound(name string) (*Sound, error) {
 	return sound, nil
 }
 
-func (m *Manager) PlaySound(ctx context.Context, name string) error {
+// LoadSound returns the named sound, decoding it if this is its first use.
+func (m *Manager) LoadSound(name string) (*Sound, error) {
 	sound, err := m.GetSound(name)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	// TODO: beep.Ctrl to kill w/ ctx
+	sound.once.Do(func() {
+		sound.err = m.decode(sound)
+	})
+
+	if sound.err != nil {
+		return nil, fmt.Errorf("failed to decode sound %q: %w", name, sound.err)
+	}
+
+	return sound, nil
+}
+
+func (m *Manager) PlaySound(ctx context.Context, name string) error {
+	sound, err := m.LoadSound(name)
+	if err != nil {
+		return err
+	}
 
-	done := make(chan struct{})
+	// Buffered so that the speaker isn't blocked if ctx is done before the sound is
+	done := make(chan struct{}, 1)
 	stream := sound.Buffer.Streamer(0, sound.Buffer.Len())
-	seq := beep.Seq(stream, beep.Callback(func() {
+	ctrl := &beep.Ctrl{Streamer: beep.Seq(stream, beep.Callback(func() {
 		done <- struct{}{}
-	}))
+	}))}
 
-	// speaker.PlayAndWait(stream)
-
-	speaker.Play(seq)
+	speaker.Play(ctrl)
 
 	select {
 	case <-ctx.Done():
-		if err := ctx.Err(); err != nil {
-			return fmt.Errorf("context error: %w", ctx.Err())
-		}
+		// Stop the sound where it is; the speaker drops streamers once they're nil
+		speaker.Lock()
+		ctrl.Streamer = nil
+		speaker.Unlock()
 
-		return nil
+		return fmt.Errorf("context error: %w", ctx.Err())
 	case <-done:
 		return nil
 	}
 }
 
+// PlayEvent plays the sound hooked to eventType and waits for it to finish, regardless of the rate limit. It's meant for
+// the end of a session, when mon would otherwise exit before the sound is played.
+func (m *Manager) PlayEvent(ctx context.Context, eventType EventType) error {
+	m.hookMutex.RLock()
+	name, ok := m.hookMap[eventType]
+	m.hookMutex.RUnlock()
+
+	if !ok {
+		return nil
+	}
+
+	return m.PlaySound(ctx, name)
+}
+
+// maxSummaryChimes is the most chimes played by PlaySummary, however many commits were made.
+const maxSummaryChimes = 10
+
+// PlaySummary chimes once for each of the session's commits, with the git_commit_create sound, and waits for the
+// chimes to finish. It does nothing unless the summary is enabled in the config.
+func (m *Manager) PlaySummary(ctx context.Context, commits int64) error {
+	if !m.summary {
+		return nil
+	}
+
+	for range min(commits, maxSummaryChimes) {
+		if err := m.PlayEvent(ctx, EventGitCommitCreate); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (m *Manager) Close() {
 	m.soundMutex.Lock()
 	defer m.soundMutex.Unlock()
@@ -180,34 +260,35 @@ func (m *Manager) loadBuiltins() error {
 		return fmt.Errorf("failed to list audio assets: %w", err)
 	}
 
-	for entryIdx, entry := range entries {
+	for _, entry := range entries {
 		path := filepath.Join(baseDir, entry.Name())
 
-		contents, err := builtinAssets.ReadFile(path)
-		if err != nil {
-			slog.Error("Failed to read builtin audio file", "path", path, "error", err)
-			continue
-		}
+		m.addSound(entry.Name(), func() (io.ReadCloser, error) {
+			contents, err := builtinAssets.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read built-in audio file: %w", err)
+			}
 
-		reader := io.NopCloser(bytes.NewReader(contents))
+			return io.NopCloser(bytes.NewReader(contents)), nil
+		})
+	}
 
-		stream, format, err := m.getStream(entry.Name(), reader)
+	return nil
+}
+
+// preload decodes the sounds that refs refer to (see resolveSound) up front, rather than when they're first played.
+func (m *Manager) preload(refs []string) error {
+	for _, ref := range refs {
+		name, err := m.resolveSound(ref)
 		if err != nil {
-			slog.Error("Failed to get stream", "path", path, "error", err)
-			continue
+			return err
 		}
 
-		if entryIdx == 0 {
-			if err := speaker.Init(format.SampleRate, format.SampleRate.N(time.Second/20)); err != nil {
-				return fmt.Errorf("failed to initialize speaker: %w", err)
-			}
-
-			speakerSampleRate = format.SampleRate
+		if _, err := m.LoadSound(name); err != nil {
+			return err
 		}
 
-		if err := m.addSound(entry.Name(), stream, format); err != nil {
-			slog.Error("Failed to add built-in sound", "name", entry.Name(), "error", err)
-		}
+		slog.Debug("preloaded sound", "name", name)
 	}
 
 	return nil
@@ -227,6 +308,43 @@ func (m *Manager) applyDefaults() {
 	m.hookMap[EventPackageCreate] = "package_create.mp3"
 	m.hookMap[EventPackageRemove] = "package_remove.mp3"
 	m.hookMap[EventPackageUpgrade] = "package_upgrade.mp3"
+	m.hookMap[EventSessionStart] = "session_start.wav"
+	m.hookMap[EventSessionEnd] = "session_end.wav"
+
+	// User sounds named after an event, or after the built-in sound for it, replace the built-in one
+	for eventType, name := range m.hookMap {
+		for _, stem := range []string{string(eventType), soundStem(name)} {
+			names := m.userSounds[stem]
+			if len(names) > 1 {
+				slog.Warn("Ignoring ambiguous user sounds", "event", eventType, "sounds", names)
+				break
+			} else if len(names) == 1 {
+				m.hookMap[eventType] = names[0]
+				break
+			}
+		}
+	}
+}
+
+// applyHook plays the sound that ref refers to for events of eventType. A path is loaded as a new sound, while a name
+// is looked up among the loaded sounds (see resolveSound).
+func (m *Manager) applyHook(eventType EventType, ref string) error {
+	name := filepath.Base(ref)
+
+	if isSoundPath(ref) {
+		if err := m.AddSound(ref); err != nil {
+			return fmt.Errorf("failed to add sound %q: %w", ref, err)
+		}
+	} else {
+		resolved, err := m.resolveSound(ref)
+		if err != nil {
+			return err
+		}
+
+		name = resolved
+	}
+
+	return m.AddEventHook(name, eventType)
 }
 
 func (m *Manager) getStream(name string, reader io.ReadCloser) (beep.StreamSeekCloser, beep.Format, error) {
@@ -238,7 +356,7 @@ func (m *Manager) getStream(name string, reader io.ReadCloser) (beep.StreamSeekC
 
 	extension := filepath.Ext(name)
 
-	switch extension {
+	switch strings.ToLower(extension) {
 	case ".mp3":
 		stream, format, err = mp3.Decode(reader)
 		if err != nil {
@@ -261,23 +379,41 @@ func (m *Manager) getStream(name string, reader io.ReadCloser) (beep.StreamSeekC
 	return stream, format, nil
 }
 
-func (m *Manager) addSound(name string, stream beep.StreamSeekCloser, format beep.Format) error {
-	buffer := beep.NewBuffer(format)
-	resampledStream := beep.Resample(4, format.SampleRate, speakerSampleRate, stream)
+// addSound stores a sound to be decoded from whatever open returns when it's first used. It replaces any sound with the
+// same name.
+func (m *Manager) addSound(name string, open func() (io.ReadCloser, error)) {
+	m.soundMutex.Lock()
+	m.soundMap[name] = &Sound{
+		Name: name,
+		open: open,
+	}
+	m.soundMutex.Unlock()
+}
 
-	buffer.Append(resampledStream)
+// decode buffers the whole sound, resampled to the speaker's rate and normalized. LoadSound makes sure it's only done once per sound.
+func (m *Manager) decode(sound *Sound) error {
+	reader, err := sound.open()
+	if err != nil {
+		return err
+	}
+
+	stream, format, err := m.getStream(sound.Name, reader)
+	if err != nil {
+		return fmt.Errorf("failed to get stream: %w", err)
+	}
+
+	samples := readSamples(beep.Resample(4, format.SampleRate, m.sampleRate, stream))
+	normalizeSamples(samples, m.normalization)
+
+	buffer := beep.NewBuffer(format)
+	buffer.Append(sliceStreamer(samples))
 
 	if err := stream.Close(); err != nil {
 		return fmt.Errorf("failed to close audio stream after buffering: %w", err)
 	}
 
-	m.soundMutex.Lock()
-	m.soundMap[name] = &Sound{
-		Name:   name,
-		Format: format,
-		Buffer: buffer,
-	}
-	m.soundMutex.Unlock()
+	sound.Format = format
+	sound.Buffer = buffer
 
 	return nil
 }
@@ -0,0 +1,134 @@
+package audio
+
+import (
+	"math"
+
+	"github.com/gopxl/beep/v2"
+)
+
+// Normalization is how sounds are leveled when they're decoded, so that built-in and user sounds play at a similar
+// volume.
+type Normalization string
+
+const (
+	// NormalizeLoudness levels sounds by their average loudness, ignoring silence, without letting peaks clip. It's a
+	// rough approximation of EBU R128, and the default.
+	NormalizeLoudness Normalization = "loudness"
+	// NormalizePeak scales sounds so their loudest sample is just under full scale.
+	NormalizePeak Normalization = "peak"
+	// NormalizeOff plays sounds as they are.
+	NormalizeOff Normalization = "off"
+)
+
+func validNormalization(normalization Normalization) bool {
+	switch normalization {
+	case "", NormalizeLoudness, NormalizePeak, NormalizeOff:
+		return true
+	}
+
+	return false
+}
+
+const (
+	targetLoudness = -20.0 // dBFS RMS that NormalizeLoudness aims for
+	peakCeiling    = -1.0  // dBFS that no normalized sample goes over
+	silenceGate    = -60.0 // dBFS RMS under which a block counts as silence for NormalizeLoudness
+	gateBlockSize  = 2048  // samples per block when measuring loudness, about 40ms at 48 kHz
+)
+
+// dbfs converts decibels relative to full scale to a linear amplitude.
+func dbfs(decibels float64) float64 {
+	return math.Pow(10, decibels/20)
+}
+
+// normalizeSamples scales samples in place according to normalization. Silent sounds are left alone.
+func normalizeSamples(samples [][2]float64, normalization Normalization) {
+	if normalization == NormalizeOff {
+		return
+	}
+
+	peak := 0.0
+
+	for _, sample := range samples {
+		peak = max(peak, math.Abs(sample[0]), math.Abs(sample[1]))
+	}
+
+	if peak == 0 {
+		return
+	}
+
+	gain := dbfs(peakCeiling) / peak
+
+	if normalization != NormalizePeak {
+		if rms := gatedRMS(samples); rms > 0 {
+			gain = min(gain, dbfs(targetLoudness)/rms)
+		}
+	}
+
+	for idx := range samples {
+		samples[idx][0] *= gain
+		samples[idx][1] *= gain
+	}
+}
+
+// gatedRMS returns the RMS level of samples, skipping blocks quieter than silenceGate so that leading and trailing
+// silence doesn't make a sound seem quieter than it is. It returns 0 if every block is silent.
+func gatedRMS(samples [][2]float64) float64 {
+	var (
+		sum   float64
+		count int
+	)
+
+	for start := 0; start < len(samples); start += gateBlockSize {
+		block := samples[start:min(start+gateBlockSize, len(samples))]
+
+		var blockSum float64
+		for _, sample := range block {
+			blockSum += (sample[0]*sample[0] + sample[1]*sample[1]) / 2
+		}
+
+		if math.Sqrt(blockSum/float64(len(block))) < dbfs(silenceGate) {
+			continue
+		}
+
+		sum += blockSum
+		count += len(block)
+	}
+
+	if count == 0 {
+		return 0
+	}
+
+	return math.Sqrt(sum / float64(count))
+}
+
+// readSamples reads a streamer to the end.
+func readSamples(streamer beep.Streamer) [][2]float64 {
+	var (
+		results [][2]float64
+		chunk   = make([][2]float64, 512)
+	)
+
+	for {
+		n, ok := streamer.Stream(chunk)
+		results = append(results, chunk[:n]...)
+
+		if !ok {
+			return results
+		}
+	}
+}
+
+// sliceStreamer streams samples once.
+func sliceStreamer(samples [][2]float64) beep.Streamer {
+	return beep.StreamerFunc(func(out [][2]float64) (int, bool) {
+		if len(samples) == 0 {
+			return 0, false
+		}
+
+		n := copy(out, samples)
+		samples = samples[n:]
+
+		return n, true
+	})
+}
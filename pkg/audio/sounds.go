@@ -1,11 +1,101 @@
 package audio
 
 import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
 	"github.com/gopxl/beep/v2"
 )
 
+// ErrAmbiguousSound is returned when a hook names a sound by its stem and the sounds directory has several files with
+// that stem (e.g. chime.ogg and chime.wav).
+var ErrAmbiguousSound = errors.New("ambiguous sound name")
+
+// Sound is decoded the first time it's played (or when it's preloaded), and the decoded buffer is shared by every hook
+// that plays it.
 type Sound struct {
 	Name   string
 	Format beep.Format
 	Buffer *beep.Buffer
+
+	open func() (io.ReadCloser, error)
+	once sync.Once
+	err  error // from decoding
+}
+
+// soundExtensions are the audio formats that can be decoded.
+//
+//nolint:gochecknoglobals
+var soundExtensions = []string{".mp3", ".ogg", ".wav"}
+
+// soundStem returns a sound's name without its extension, e.g. "chime" for "chime.ogg".
+func soundStem(name string) string {
+	return strings.TrimSuffix(name, filepath.Ext(name))
+}
+
+// isSoundPath reports whether a hook refers to a file by path, rather than to a sound by name.
+func isSoundPath(ref string) bool {
+	return filepath.IsAbs(ref) || strings.ContainsRune(ref, '/') || strings.ContainsRune(ref, filepath.Separator)
+}
+
+// loadSoundsDir adds every sound file in dir, without descending into subdirectories. A user sound replaces a built-in
+// one with the same file name. A missing directory is not an error, since most users won't have one.
+func (m *Manager) loadSoundsDir(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		slog.Debug("no user sounds directory", "path", dir)
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to read sounds directory: %w", err)
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !slices.Contains(soundExtensions, strings.ToLower(filepath.Ext(name))) {
+			continue
+		}
+
+		if err := m.AddSound(filepath.Join(dir, name)); err != nil {
+			return fmt.Errorf("failed to add sound %q: %w", name, err)
+		}
+
+		stem := soundStem(name)
+		m.userSounds[stem] = append(m.userSounds[stem], name)
+
+		slog.Debug("added user sound", "name", name, "dir", dir)
+	}
+
+	return nil
+}
+
+// resolveSound finds the sound a hook refers to by name: an exact file name, or a stem, like "chime" for "chime.ogg".
+// Sounds from the user's sounds directory win over built-in ones with the same stem. A stem matching more than one
+// user sound is ambiguous.
+func (m *Manager) resolveSound(ref string) (string, error) {
+	if _, err := m.GetSound(ref); err == nil {
+		return ref, nil
+	}
+
+	switch names := m.userSounds[soundStem(ref)]; {
+	case len(names) == 1:
+		return names[0], nil
+	case len(names) > 1:
+		return "", fmt.Errorf("%w: %q could be any of %s", ErrAmbiguousSound, ref, strings.Join(names, ", "))
+	}
+
+	for _, extension := range soundExtensions {
+		if _, err := m.GetSound(ref + extension); err == nil {
+			return ref + extension, nil
+		}
+	}
+
+	return "", fmt.Errorf("%w: %s", ErrSoundNotFound, ref)
 }
@@ -0,0 +1,199 @@
+package audio //nolint:testpackage // exercises sound resolution without initializing the speaker
+
+import (
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"golang.org/x/time/rate"
+)
+
+// newTestManager returns a Manager with the built-in sound names and the given user sounds loaded, without decoding
+// any audio.
+func newTestManager(userSounds ...string) *Manager {
+	mgr := &Manager{
+		soundMap:   map[string]*Sound{},
+		hookMap:    map[EventType]string{},
+		userSounds: map[string][]string{},
+		queue:      newEventQueue(),
+		limiter:    rate.NewLimiter(rate.Inf, 1),
+	}
+
+	for _, name := range []string{"init.mp3", "file_write.mp3", "git_commit_push.mp3"} {
+		mgr.soundMap[name] = &Sound{Name: name}
+	}
+
+	for _, name := range userSounds {
+		mgr.soundMap[name] = &Sound{Name: name}
+
+		stem := soundStem(name)
+		mgr.userSounds[stem] = append(mgr.userSounds[stem], name)
+	}
+
+	return mgr
+}
+
+func TestManager_resolveSound(t *testing.T) {
+	t.Parallel()
+
+	mgr := newTestManager("chime.ogg", "init.wav", "beep.ogg", "beep.wav")
+
+	tests := []struct {
+		ref      string
+		expected string
+		err      error
+	}{
+		{ref: "chime.ogg", expected: "chime.ogg"},
+		{ref: "chime", expected: "chime.ogg"},
+		{ref: "file_write", expected: "file_write.mp3"},
+		{ref: "init", expected: "init.wav"},     // user sounds win over built-ins
+		{ref: "init.mp3", expected: "init.mp3"}, // unless the built-in is named exactly
+		{ref: "beep.wav", expected: "beep.wav"}, // exact names are never ambiguous
+		{ref: "beep", err: ErrAmbiguousSound},   // but stems can be
+		{ref: "missing", err: ErrSoundNotFound},
+		{ref: "chime.mp3", expected: "chime.ogg"}, // stems ignore the extension
+		{ref: "git_push", err: ErrSoundNotFound},  // event types aren't sound names
+		{ref: "git_commit_push", expected: "git_commit_push.mp3"},
+	}
+
+	for _, test := range tests {
+		result, err := mgr.resolveSound(test.ref)
+		if test.err != nil {
+			if !errors.Is(err, test.err) {
+				t.Errorf("%q: expected error %v, got %q, %v", test.ref, test.err, result, err)
+			}
+
+			continue
+		}
+
+		if err != nil || result != test.expected {
+			t.Errorf("%q: expected %q, got %q, %v", test.ref, test.expected, result, err)
+		}
+	}
+}
+
+func TestManager_applyDefaults(t *testing.T) {
+	t.Parallel()
+
+	mgr := newTestManager("git_push.ogg", "file_write.wav", "init.ogg", "init.wav")
+	mgr.applyDefaults()
+
+	expected := map[EventType]string{
+		EventGitCommitPush: "git_push.ogg",   // named after the event
+		EventFileWrite:     "file_write.wav", // named after the built-in sound
+		EventInit:          "init.mp3",       // ambiguous, so the built-in is kept
+		EventFileCreate:    "file_create.mp3",
+	}
+
+	for eventType, name := range expected {
+		if mgr.hookMap[eventType] != name {
+			t.Errorf("%s: expected %q, got %q", eventType, name, mgr.hookMap[eventType])
+		}
+	}
+}
+
+func TestManager_LoadSound(t *testing.T) {
+	t.Parallel()
+
+	mgr := newTestManager()
+	mgr.sampleRate = DefaultSampleRate
+
+	if err := mgr.loadBuiltins(); err != nil {
+		t.Fatalf("failed to load built-in sounds: %v", err)
+	}
+
+	builtin, err := mgr.GetSound("session_end.wav")
+	if err != nil {
+		t.Fatalf("failed to get built-in sound: %v", err)
+	}
+
+	if builtin.Buffer != nil {
+		t.Errorf("expected built-in sound not to be decoded before it's used")
+	}
+
+	loaded, err := mgr.LoadSound("session_end.wav")
+	if err != nil {
+		t.Fatalf("failed to load built-in sound: %v", err)
+	}
+
+	if loaded.Buffer == nil || loaded.Buffer.Len() == 0 {
+		t.Errorf("expected loaded sound to be decoded")
+	}
+
+	again, err := mgr.LoadSound("session_end.wav")
+	if err != nil || again.Buffer != loaded.Buffer {
+		t.Errorf("expected the decoded buffer to be reused, got %v", err)
+	}
+
+	// Broken files are found when they're first used, not when they're added
+	broken := filepath.Join(t.TempDir(), "broken.wav")
+	if err := os.WriteFile(broken, []byte("not a wav file"), 0o600); err != nil {
+		t.Fatalf("failed to write sound: %v", err)
+	}
+
+	if err := mgr.AddSound(broken); err != nil {
+		t.Fatalf("failed to add sound: %v", err)
+	}
+
+	if err := mgr.preload([]string{"broken"}); err == nil {
+		t.Errorf("expected error preloading a broken sound")
+	}
+
+	if _, err := mgr.LoadSound("broken.wav"); err == nil {
+		t.Errorf("expected error loading a broken sound")
+	}
+}
+
+func TestNormalizeSamples(t *testing.T) {
+	t.Parallel()
+
+	// A quiet tone with a single loud click, surrounded by silence
+	samples := make([][2]float64, gateBlockSize*4)
+	for idx := gateBlockSize; idx < gateBlockSize*3; idx++ {
+		samples[idx] = [2]float64{0.01, -0.01}
+	}
+
+	samples[gateBlockSize*2] = [2]float64{0.5, 0.5}
+
+	peakOf := func(samples [][2]float64) float64 {
+		peak := 0.0
+		for _, sample := range samples {
+			peak = max(peak, math.Abs(sample[0]), math.Abs(sample[1]))
+		}
+
+		return peak
+	}
+
+	peak := slices.Clone(samples)
+	normalizeSamples(peak, NormalizePeak)
+
+	if got := peakOf(peak); math.Abs(got-dbfs(peakCeiling)) > 1e-9 {
+		t.Errorf("expected peak normalization to bring the peak to %f, got %f", dbfs(peakCeiling), got)
+	}
+
+	// The click keeps the tone from reaching the target loudness, since it would clip
+	loudness := slices.Clone(samples)
+	normalizeSamples(loudness, NormalizeLoudness)
+
+	if got := peakOf(loudness); got > dbfs(peakCeiling)+1e-9 {
+		t.Errorf("expected loudness normalization not to push the peak over %f, got %f", dbfs(peakCeiling), got)
+	}
+
+	// Without the click, the tone is brought up to the target, and the silence doesn't count against it
+	samples[gateBlockSize*2] = [2]float64{0.01, -0.01}
+	normalizeSamples(samples, NormalizeLoudness)
+
+	if got := gatedRMS(samples); math.Abs(got-dbfs(targetLoudness)) > 1e-9 {
+		t.Errorf("expected loudness %f, got %f", dbfs(targetLoudness), got)
+	}
+
+	silent := make([][2]float64, 16)
+	normalizeSamples(silent, NormalizeLoudness)
+
+	if peakOf(silent) != 0 {
+		t.Errorf("expected silence to stay silent")
+	}
+}
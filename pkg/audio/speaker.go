@@ -0,0 +1,49 @@
+package audio
+
+import (
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/gopxl/beep/v2"
+	"github.com/gopxl/beep/v2/speaker"
+)
+
+// DefaultSampleRate is the speaker's sample rate when Config.SampleRate is not set. Sounds at other rates are
+// resampled to it when they're loaded.
+const DefaultSampleRate = 48000
+
+// speakerBuffer is how much audio the speaker buffers. Shorter is more responsive, longer is less likely to skip.
+const speakerBuffer = time.Second / 20
+
+//nolint:gochecknoglobals
+var (
+	speakerMutex sync.Mutex
+	speakerRate  beep.SampleRate // zero until the speaker is initialized
+)
+
+// initSpeaker initializes the speaker at rate, and returns the rate it's playing at. The audio driver can only be set
+// up once per process, so if the speaker was already initialized by an earlier Manager at a different rate, it keeps
+// that rate and sounds are resampled to it instead.
+func initSpeaker(rate beep.SampleRate) (beep.SampleRate, error) {
+	speakerMutex.Lock()
+	defer speakerMutex.Unlock()
+
+	if speakerRate != 0 {
+		if speakerRate != rate {
+			slog.Warn("Speaker already initialized at a different sample rate, keeping it", "rate", int(speakerRate),
+				"requested", int(rate))
+		}
+
+		return speakerRate, nil
+	}
+
+	if err := speaker.Init(rate, rate.N(speakerBuffer)); err != nil {
+		return 0, fmt.Errorf("failed to initialize speaker at %d Hz: %w", int(rate), err)
+	}
+
+	speakerRate = rate
+
+	return speakerRate, nil
+}
@@ -0,0 +1,50 @@
+// Package clock abstracts the passage of time so timing-dependent code, like debouncers and timeouts, can be driven
+// deterministically in tests. It is stable because the stable monitors accept a Clock.
+//
+// Stability: stable
+package clock
+
+import "time"
+
+// Clock is the subset of the time package used by mon.
+type Clock interface {
+	Now() time.Time
+	Since(t time.Time) time.Duration
+	After(d time.Duration) <-chan time.Time
+	Sleep(d time.Duration)
+	NewTicker(d time.Duration) Ticker
+}
+
+// Ticker is the equivalent of time.Ticker. C is a method so fake tickers can satisfy it.
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// Or returns clock if it is non-nil, and the real clock otherwise, so a nil Clock in options means "use real time".
+func Or(clock Clock) Clock {
+	if clock == nil {
+		return Real{}
+	}
+
+	return clock
+}
+
+// Real is a Clock backed by the time package.
+type Real struct{}
+
+func (Real) Now() time.Time                         { return time.Now() }
+func (Real) Since(t time.Time) time.Duration        { return time.Since(t) }
+func (Real) After(d time.Duration) <-chan time.Time { return time.After(d) }
+func (Real) Sleep(d time.Duration)                  { time.Sleep(d) }
+
+func (Real) NewTicker(d time.Duration) Ticker {
+	return realTicker{ticker: time.NewTicker(d)}
+}
+
+type realTicker struct {
+	ticker *time.Ticker
+}
+
+func (r realTicker) C() <-chan time.Time { return r.ticker.C }
+func (r realTicker) Stop()               { r.ticker.Stop() }
@@ -0,0 +1,146 @@
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Fake is a Clock that only moves when Advance is called.
+//
+// Unlike real tickers, which drop ticks nobody is ready for, a fake ticker's tick blocks Advance until it is received
+// or the ticker is stopped. Advancing twice therefore guarantees that every goroutine ticked by the first Advance has
+// finished handling it and is back to waiting on its ticker.
+type Fake struct {
+	mutex   sync.Mutex
+	now     time.Time
+	waiters []*fakeWaiter
+}
+
+type fakeWaiter struct {
+	deadline time.Time
+	period   time.Duration // zero for one-shot waiters (After, Sleep)
+	ch       chan time.Time
+	stopped  chan struct{}
+}
+
+// NewFake returns a Fake clock starting at an arbitrary fixed time.
+func NewFake() *Fake {
+	return &Fake{
+		now: time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+func (f *Fake) Now() time.Time {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return f.now
+}
+
+func (f *Fake) Since(t time.Time) time.Duration {
+	return f.Now().Sub(t)
+}
+
+func (f *Fake) After(d time.Duration) <-chan time.Time {
+	waiter := &fakeWaiter{
+		ch:      make(chan time.Time, 1),
+		stopped: make(chan struct{}),
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	waiter.deadline = f.now.Add(d)
+	f.waiters = append(f.waiters, waiter)
+
+	return waiter.ch
+}
+
+func (f *Fake) Sleep(d time.Duration) {
+	<-f.After(d)
+}
+
+func (f *Fake) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("non-positive interval for clock.Fake.NewTicker")
+	}
+
+	waiter := &fakeWaiter{
+		period:  d,
+		ch:      make(chan time.Time),
+		stopped: make(chan struct{}),
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	waiter.deadline = f.now.Add(d)
+	f.waiters = append(f.waiters, waiter)
+
+	return &fakeTicker{clock: f, waiter: waiter}
+}
+
+// Advance moves the clock forward, firing any timers and tickers that come due along the way. A ticker that comes due
+// several times during one Advance only ticks once, like a real ticker whose reader fell behind.
+func (f *Fake) Advance(d time.Duration) {
+	f.mutex.Lock()
+
+	f.now = f.now.Add(d)
+	now := f.now
+
+	due := []*fakeWaiter{}
+	remaining := f.waiters[:0]
+
+	for _, waiter := range f.waiters {
+		if waiter.deadline.After(now) {
+			remaining = append(remaining, waiter)
+			continue
+		}
+
+		due = append(due, waiter)
+
+		if waiter.period > 0 {
+			for !waiter.deadline.After(now) {
+				waiter.deadline = waiter.deadline.Add(waiter.period)
+			}
+
+			remaining = append(remaining, waiter)
+		}
+	}
+
+	f.waiters = remaining
+
+	f.mutex.Unlock()
+
+	// Send without holding the lock, since receivers will likely want to call Now
+	for _, waiter := range due {
+		select {
+		case waiter.ch <- now:
+		case <-waiter.stopped:
+		}
+	}
+}
+
+type fakeTicker struct {
+	clock    *Fake
+	waiter   *fakeWaiter
+	stopOnce sync.Once
+}
+
+func (f *fakeTicker) C() <-chan time.Time { return f.waiter.ch }
+
+func (f *fakeTicker) Stop() {
+	f.stopOnce.Do(func() {
+		close(f.waiter.stopped)
+
+		f.clock.mutex.Lock()
+		defer f.clock.mutex.Unlock()
+
+		for i, waiter := range f.clock.waiters {
+			if waiter == f.waiter {
+				f.clock.waiters = append(f.clock.waiters[:i], f.clock.waiters[i+1:]...)
+				break
+			}
+		}
+	})
+}
@@ -0,0 +1,130 @@
+package deps
+
+import (
+	"cmp"
+	"slices"
+)
+
+type ChangeType string
+
+const (
+	ChangeCreate ChangeType = "create"
+	// ChangeUpgrade is any other change to a dependency's version or requirement. Versions aren't compared, so moving to
+	// an older release counts too.
+	ChangeUpgrade ChangeType = "upgrade"
+	ChangeRemove  ChangeType = "remove"
+	// ChangeRevert is a dependency updated during the session going back to the version it started at.
+	ChangeRevert ChangeType = "revert"
+)
+
+// Change is a single dependency change between two points in a session, e.g. one write to a manifest.
+type Change struct {
+	Path       string
+	Type       ChangeType
+	Dependency Dependency
+}
+
+type depStatus int
+
+const (
+	statusUnchanged depStatus = iota
+	statusNew
+	statusDeleted
+	statusUpdated
+)
+
+type depState struct {
+	status     depStatus
+	dependency Dependency
+	initial    Dependency // only set for updated dependencies
+}
+
+func (d depState) equal(other depState) bool {
+	return d.status == other.status && d.dependency.Equal(other.dependency) && d.initial.Equal(other.initial)
+}
+
+// ChangesSince returns the changes that turn the previous session diffs into these ones. Both are diffs against the
+// same initial manifests, so any dependency whose status or version differs between them was changed in between.
+// Removing a dependency that was added during the session counts as a removal, re-adding one that was deleted counts
+// as a creation, and undoing an update counts as a revert.
+func (f FileDiffs) ChangesSince(previous FileDiffs) []Change {
+	previousStates := previous.states()
+	latestStates := f.states()
+
+	keys := map[[2]string]struct{}{}
+	for key := range previousStates {
+		keys[key] = struct{}{}
+	}
+
+	for key := range latestStates {
+		keys[key] = struct{}{}
+	}
+
+	var results []Change
+
+	for key := range keys {
+		before, after := previousStates[key], latestStates[key]
+		if before.equal(after) {
+			continue
+		}
+
+		change := Change{
+			Path:       key[0],
+			Dependency: after.dependency,
+		}
+
+		switch {
+		case after.status == statusDeleted:
+			change.Type = ChangeRemove
+		case before.status == statusNew && after.status == statusUnchanged:
+			change.Type = ChangeRemove
+		case before.status == statusUnchanged && after.status == statusNew, before.status == statusDeleted:
+			change.Type = ChangeCreate
+		case before.status == statusUpdated && after.status == statusUnchanged:
+			change.Type = ChangeRevert
+		default:
+			change.Type = ChangeUpgrade
+		}
+
+		if after.status == statusUnchanged {
+			// Back at its initial version, which only the previous diff knows about
+			change.Dependency = before.dependency
+			if before.status == statusUpdated {
+				change.Dependency = before.initial
+			}
+		}
+
+		results = append(results, change)
+	}
+
+	slices.SortFunc(results, func(a, b Change) int {
+		return cmp.Or(cmp.Compare(a.Path, b.Path), cmp.Compare(a.Dependency.Package(), b.Dependency.Package()))
+	})
+
+	return results
+}
+
+// states returns the status of every dependency mentioned in the diffs, keyed by file path and package.
+func (f FileDiffs) states() map[[2]string]depState {
+	results := map[[2]string]depState{}
+
+	for _, diff := range f {
+		for _, dep := range diff.NewDependencies {
+			results[[2]string{diff.Path, dep.Package()}] = depState{status: statusNew, dependency: dep}
+		}
+
+		for _, dep := range diff.DeletedDependencies {
+			results[[2]string{diff.Path, dep.Package()}] = depState{status: statusDeleted, dependency: dep}
+		}
+
+		for _, dep := range diff.UpdatedDependencies {
+			results[[2]string{diff.Path, dep.Latest.Package()}] = depState{
+				status:     statusUpdated,
+				dependency: dep.Latest,
+				initial:    dep.Initial,
+			}
+		}
+	}
+
+	return results
+}
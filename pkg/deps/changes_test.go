@@ -0,0 +1,71 @@
+package deps_test
+
+import (
+	"testing"
+
+	"github.com/cneill/mon/pkg/deps"
+)
+
+func TestFileDiffs_ChangesSince(t *testing.T) {
+	t.Parallel()
+
+	initial := deps.Dependency{Name: "lodash", Constraint: "4.17.20"}
+	bumped := deps.Dependency{Name: "lodash", Constraint: "4.17.21"}
+	older := deps.Dependency{Name: "lodash", Constraint: "4.17.19"}
+
+	unchanged := deps.FileDiff{Path: "package.json"}
+	added := func(dep deps.Dependency) deps.FileDiff {
+		return deps.FileDiff{Path: "package.json", NewDependencies: deps.Dependencies{dep}}
+	}
+	deleted := deps.FileDiff{Path: "package.json", DeletedDependencies: deps.Dependencies{initial}}
+	updated := func(dep deps.Dependency) deps.FileDiff {
+		return deps.FileDiff{
+			Path:                "package.json",
+			UpdatedDependencies: deps.UpdatedDependencies{{Initial: initial, Latest: dep}},
+		}
+	}
+
+	tests := []struct {
+		name             string
+		previous, latest deps.FileDiff
+		expectedType     deps.ChangeType
+		expectedDep      deps.Dependency
+	}{
+		{"nothing", unchanged, unchanged, "", deps.Dependency{}},
+		{"added", unchanged, added(bumped), deps.ChangeCreate, bumped},
+		{"new then gone", added(bumped), unchanged, deps.ChangeRemove, bumped},
+		{"version bump while new", added(initial), added(bumped), deps.ChangeUpgrade, bumped},
+		{"deleted", unchanged, deleted, deps.ChangeRemove, initial},
+		{"deleted then re-added", deleted, unchanged, deps.ChangeCreate, initial},
+		{"deleted then re-added at a new version", deleted, updated(bumped), deps.ChangeCreate, bumped},
+		{"updated", unchanged, updated(bumped), deps.ChangeUpgrade, bumped},
+		{"updated again", updated(bumped), updated(older), deps.ChangeUpgrade, older},
+		{"updated then reverted", updated(bumped), unchanged, deps.ChangeRevert, initial},
+		{"updated then deleted", updated(bumped), deleted, deps.ChangeRemove, initial},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			changes := deps.FileDiffs{test.latest}.ChangesSince(deps.FileDiffs{test.previous})
+
+			if test.expectedType == "" {
+				if len(changes) != 0 {
+					t.Fatalf("expected no changes, got %+v", changes)
+				}
+
+				return
+			}
+
+			if len(changes) != 1 {
+				t.Fatalf("expected 1 change, got %+v", changes)
+			}
+
+			if changes[0].Type != test.expectedType || changes[0].Path != "package.json" ||
+				!changes[0].Dependency.Equal(test.expectedDep) {
+				t.Errorf("expected %s of %s in package.json, got %+v", test.expectedType, test.expectedDep, changes[0])
+			}
+		})
+	}
+}
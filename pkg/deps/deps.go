@@ -1,9 +1,29 @@
+// Package deps describes dependencies and the differences between two versions of a manifest's dependencies. It is
+// stable because the listeners interface is built on its types.
+//
+// Stability: stable
 package deps
 
+import (
+	"cmp"
+	"slices"
+	"strings"
+)
+
 type Dependency struct {
-	Name    string
-	URL     string
-	Version string
+	Name string `json:"name,omitempty"`
+	URL  string `json:"url,omitempty"`
+	// Version is the exact release the dependency resolves to, when that's known: a lockfile entry, a go.mod
+	// requirement, or a manifest constraint that pins one release.
+	Version string `json:"version,omitempty"`
+	// Constraint is the version requirement exactly as the manifest states it, e.g. "^1.2" or ">=2.9,<3.0". It is
+	// empty for ecosystems whose manifests only record exact versions.
+	Constraint string `json:"constraint,omitempty"`
+	// Extras are the optional feature sets requested along with the package, e.g. "security" in requests[security].
+	Extras []string `json:"extras,omitempty"`
+	// Registry is the package index the dependency is resolved from, when the manifest points somewhere other than
+	// the ecosystem's default registry.
+	Registry string `json:"registry,omitempty"`
 }
 
 func (d Dependency) Package() string {
@@ -19,8 +39,139 @@ func (d Dependency) Package() string {
 	}
 }
 
+// Equal reports whether two dependencies are identical, including their extras.
+func (d Dependency) Equal(other Dependency) bool {
+	return d.Name == other.Name && d.URL == other.URL && d.Version == other.Version &&
+		d.Constraint == other.Constraint && d.Registry == other.Registry && slices.Equal(d.Extras, other.Extras)
+}
+
+// Requirement returns what the manifest asks for: the constraint if there is one, or else the exact version.
+func (d Dependency) Requirement() string {
+	if d.Constraint != "" {
+		return d.Constraint
+	}
+
+	return d.Version
+}
+
+// Resolved reports whether Version records what a constraint resolved to, rather than restating an exact pin.
+func (d Dependency) Resolved() bool {
+	return d.Version != "" && d.Constraint != "" && strings.TrimLeft(d.Constraint, "=v") != d.Version
+}
+
 func (d Dependency) String() string {
-	return d.Package() + " @ " + d.Version
+	if d.Resolved() {
+		return d.Package() + " @ " + d.Constraint + " (" + d.Version + ")"
+	}
+
+	return d.Package() + " @ " + d.Requirement()
+}
+
+// unpinnedVersions are version strings that always float to whatever release is newest.
+//
+//nolint:gochecknoglobals
+var unpinnedVersions = []string{"", "*", "x", "latest", "next"}
+
+// rangeMarkers are substrings that mark a version as a range rather than one exact release, across npm semver ranges
+// (^1.2, ~1.2, 1.x, >=1 <2, 1 - 2, 1 || 2) and PEP 440 specifiers (>=1.0, ~=1.0, !=1.1, ==1.*).
+//
+//nolint:gochecknoglobals
+var rangeMarkers = []string{"^", "~", ">", "<", "*", "!=", "||", " - ", ".x", ".X"}
+
+// IsPinned reports whether the manifest asks for a single, exact release rather than an empty version, a wildcard, or
+// a version range. A range is unpinned even when a lockfile resolves it, and so is a bare version that leaves out the
+// minor or patch number, like npm's "1" or "1.2". Direct references to an archive URL are considered pinned; git
+// references need a revision.
+func (d Dependency) IsPinned() bool {
+	version := strings.TrimSpace(d.Requirement())
+
+	if version == "" && d.URL != "" && !strings.HasPrefix(d.URL, "git+") && strings.Contains(d.URL, "://") {
+		return true
+	}
+
+	if slices.Contains(unpinnedVersions, strings.ToLower(version)) || isPartialVersion(version) {
+		return false
+	}
+
+	if isGitVersion(version) {
+		if _, revision, _ := strings.Cut(version, "#"); revision == "" {
+			return false
+		}
+	}
+
+	for _, marker := range rangeMarkers {
+		if strings.Contains(version, marker) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isPartialVersion reports whether version is a bare version with fewer than three numbers, which npm treats as a range
+// over the numbers left out ("1.2" is 1.2.x). The versions of the other ecosystems are never bare and partial.
+func isPartialVersion(version string) bool {
+	parts := strings.Split(strings.TrimPrefix(strings.TrimPrefix(version, "="), "v"), ".")
+	if len(parts) >= 3 {
+		return false
+	}
+
+	for _, part := range parts {
+		if part == "" || strings.Trim(part, "0123456789") != "" {
+			return false
+		}
+	}
+
+	return true
+}
+
+// gitVersionPrefixes mark npm-style version strings that reference a git repository.
+//
+//nolint:gochecknoglobals
+var gitVersionPrefixes = []string{"git+", "git:", "github:", "gitlab:", "bitbucket:", "gist:"}
+
+// directVersionPrefixes mark npm-style version strings that reference a source directly instead of a registry release.
+//
+//nolint:gochecknoglobals
+var directVersionPrefixes = slices.Concat(gitVersionPrefixes, []string{"http:", "https:", "file:", "link:"})
+
+// isGitVersion reports whether version references a git repository, including the "user/repo" shorthand for GitHub.
+func isGitVersion(version string) bool {
+	for _, prefix := range gitVersionPrefixes {
+		if strings.HasPrefix(version, prefix) {
+			return true
+		}
+	}
+
+	return strings.Contains(version, "/") && !strings.Contains(version, ":")
+}
+
+// ExternalSource returns where the dependency comes from if that is not the ecosystem's default registry: a
+// non-default registry, a direct URL (git+https://..., https://.../pkg.whl), or a direct reference in the version
+// field (github:user/repo, file:../pkg). It returns an empty string for ordinary registry dependencies.
+func (d Dependency) ExternalSource() string {
+	if strings.HasPrefix(d.URL, "git+") || strings.Contains(d.URL, "://") {
+		return d.URL
+	}
+
+	if d.Registry != "" {
+		return d.Registry
+	}
+
+	version := strings.TrimSpace(d.Requirement())
+
+	for _, prefix := range directVersionPrefixes {
+		if strings.HasPrefix(version, prefix) {
+			return version
+		}
+	}
+
+	// Registry versions never contain slashes, so this catches shorthand like "user/repo#branch"
+	if strings.Contains(version, "/") {
+		return version
+	}
+
+	return ""
 }
 
 type Dependencies []Dependency
@@ -30,10 +181,22 @@ type UpdatedDependency struct {
 	Latest  Dependency
 }
 
+// ConstraintChanged reports whether the manifest's requirement was edited, as opposed to the same requirement
+// resolving to a different release.
+func (u UpdatedDependency) ConstraintChanged() bool {
+	return u.Initial.Requirement() != u.Latest.Requirement()
+}
+
+// VersionChanged reports whether the dependency resolves to a different release than it did initially.
+func (u UpdatedDependency) VersionChanged() bool {
+	return u.Initial.Version != u.Latest.Version
+}
+
 type UpdatedDependencies []UpdatedDependency
 
 type FileDiff struct {
 	Path                string
+	Ecosystem           Ecosystem
 	NewDependencies     Dependencies
 	DeletedDependencies Dependencies
 	UpdatedDependencies UpdatedDependencies
@@ -57,8 +220,53 @@ func (f FileDiff) NumUpdatedDependencies() int64 {
 	return int64(len(f.UpdatedDependencies))
 }
 
+// UnpinnedDependencies returns the new dependencies, and the latest versions of updated dependencies, that are not
+// pinned to an exact release.
+func (f FileDiff) UnpinnedDependencies() Dependencies {
+	var results Dependencies
+
+	for _, dep := range f.NewDependencies {
+		if !dep.IsPinned() {
+			results = append(results, dep)
+		}
+	}
+
+	for _, dep := range f.UpdatedDependencies {
+		if !dep.Latest.IsPinned() {
+			results = append(results, dep.Latest)
+		}
+	}
+
+	return results
+}
+
+// ExternalDependencies returns the new dependencies, and the latest versions of updated dependencies, that come from
+// somewhere other than the ecosystem's default registry.
+func (f FileDiff) ExternalDependencies() Dependencies {
+	var results Dependencies
+
+	for _, dep := range f.NewDependencies {
+		if dep.ExternalSource() != "" {
+			results = append(results, dep)
+		}
+	}
+
+	for _, dep := range f.UpdatedDependencies {
+		if dep.Latest.ExternalSource() != "" && dep.Latest.ExternalSource() != dep.Initial.ExternalSource() {
+			results = append(results, dep.Latest)
+		}
+	}
+
+	return results
+}
+
 type FileDiffs []FileDiff
 
+// Sort orders the diffs by file path.
+func (f FileDiffs) Sort() {
+	slices.SortFunc(f, func(a, b FileDiff) int { return cmp.Compare(a.Path, b.Path) })
+}
+
 func (f FileDiffs) AllEmpty() bool {
 	for _, diff := range f {
 		if !diff.IsEmpty() {
@@ -120,7 +328,7 @@ func (d Dependencies) Diff(name string, initial Dependencies) FileDiff {
 		initialDep, existed := uniqueInitial[pkg]
 		if !existed {
 			added = append(added, latestDep)
-		} else if initialDep.Version != latestDep.Version {
+		} else if initialDep.Version != latestDep.Version || initialDep.Constraint != latestDep.Constraint {
 			bumped = append(bumped, UpdatedDependency{
 				Initial: initialDep,
 				Latest:  latestDep,
@@ -135,6 +343,11 @@ func (d Dependencies) Diff(name string, initial Dependencies) FileDiff {
 		}
 	}
 
+	byPackage := func(a, b Dependency) int { return cmp.Compare(a.Package(), b.Package()) }
+	slices.SortFunc(added, byPackage)
+	slices.SortFunc(removed, byPackage)
+	slices.SortFunc(bumped, func(a, b UpdatedDependency) int { return byPackage(a.Latest, b.Latest) })
+
 	return FileDiff{
 		Path:                name,
 		NewDependencies:     added,
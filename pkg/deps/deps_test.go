@@ -0,0 +1,69 @@
+package deps_test
+
+import (
+	"testing"
+
+	"github.com/cneill/mon/pkg/deps"
+)
+
+func TestDependency_IsPinned(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		dep      deps.Dependency
+		expected bool
+	}{
+		// Exact versions
+		{deps.Dependency{Name: "lodash", Constraint: "4.17.21", Version: "4.17.21"}, true},
+		{deps.Dependency{Name: "lodash", Constraint: "=4.17.21"}, true},
+		{deps.Dependency{Name: "lodash", Constraint: "v4.17.21"}, true},
+		{deps.Dependency{Name: "react", Constraint: "19.0.0-rc.1"}, true},
+		{deps.Dependency{Name: "requests", Constraint: "==2.32.3", Version: "2.32.3"}, true},
+		{deps.Dependency{Name: "django", Constraint: "==5.1"}, true},
+		{deps.Dependency{URL: "github.com/spf13/cobra", Version: "v1.8.1"}, true},
+
+		// Empty versions and wildcards
+		{deps.Dependency{Name: "lodash"}, false},
+		{deps.Dependency{Name: "lodash", Constraint: "*"}, false},
+		{deps.Dependency{Name: "lodash", Constraint: "latest"}, false},
+		{deps.Dependency{Name: "lodash", Constraint: "X"}, false},
+
+		// Ranges
+		{deps.Dependency{Name: "lodash", Constraint: "^4.17.21"}, false},
+		{deps.Dependency{Name: "lodash", Constraint: "~4.17.21"}, false},
+		{deps.Dependency{Name: "lodash", Constraint: "4.x"}, false},
+		{deps.Dependency{Name: "lodash", Constraint: ">=4 <5"}, false},
+		{deps.Dependency{Name: "lodash", Constraint: "1 - 2"}, false},
+		{deps.Dependency{Name: "lodash", Constraint: "3 || 4"}, false},
+		{deps.Dependency{Name: "requests", Constraint: ">=2.0,<3.0"}, false},
+		{deps.Dependency{Name: "requests", Constraint: "~=2.32"}, false},
+		{deps.Dependency{Name: "requests", Constraint: "==2.*"}, false},
+
+		// Partial versions
+		{deps.Dependency{Name: "lodash", Constraint: "4"}, false},
+		{deps.Dependency{Name: "lodash", Constraint: "4.17"}, false},
+		{deps.Dependency{Name: "lodash", Constraint: "=4.17"}, false},
+		{deps.Dependency{Name: "lodash", Constraint: "v4"}, false},
+
+		// Direct references
+		{deps.Dependency{Name: "pkg", URL: "https://example.com/pkg-1.0.tar.gz"}, true},
+		{deps.Dependency{Name: "pkg", Constraint: "https://example.com/pkg-1.0.tgz"}, true},
+		{deps.Dependency{Name: "pkg", Constraint: "file:../pkg"}, true},
+		{deps.Dependency{Name: "pkg", URL: "git+https://github.com/user/repo.git"}, false},
+		{deps.Dependency{Name: "pkg", URL: "git+https://github.com/user/repo.git@v1.0.0", Version: "v1.0.0"}, true},
+		{deps.Dependency{Name: "pkg", Constraint: "github:user/repo"}, false},
+		{deps.Dependency{Name: "pkg", Constraint: "github:user/repo#"}, false},
+		{deps.Dependency{Name: "pkg", Constraint: "github:user/repo#4f2a1c9"}, true},
+		{deps.Dependency{Name: "pkg", Constraint: "user/repo"}, false},
+		{deps.Dependency{Name: "pkg", Constraint: "user/repo#4f2a1c9"}, true},
+		{deps.Dependency{Name: "pkg", Constraint: "git+ssh://git@github.com/user/repo.git"}, false},
+		{deps.Dependency{Name: "pkg", Constraint: "git+ssh://git@github.com/user/repo.git#4f2a1c9"}, true},
+		{deps.Dependency{Name: "pkg", Constraint: "github:user/repo#semver:^1.0"}, false},
+	}
+
+	for _, test := range tests {
+		if actual := test.dep.IsPinned(); actual != test.expected {
+			t.Errorf("expected %s to be pinned: %t, got %t", test.dep, test.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package deps
+
+import (
+	"bufio"
+	"bytes"
+	"embed"
+	"log/slog"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Ecosystem identifies the package ecosystem a dependency belongs to.
+type Ecosystem string
+
+const (
+	EcosystemUnknown Ecosystem = ""
+	EcosystemGo      Ecosystem = "go"
+	EcosystemNPM     Ecosystem = "npm"
+	EcosystemPyPI    Ecosystem = "pypi"
+)
+
+//go:embed popular/*.txt
+var popularFS embed.FS
+
+//nolint:gochecknoglobals
+var (
+	popularOnce     sync.Once
+	popularPackages map[Ecosystem][]string
+)
+
+// pypiNormalizeRegex matches the runs of separators that PEP 503 treats as equivalent in package names.
+var pypiNormalizeRegex = regexp.MustCompile(`[-_.]+`)
+
+// goVersionSuffixRegex matches the major version at the end of a Go module path, e.g. "/v5" or gopkg.in's ".v3".
+var goVersionSuffixRegex = regexp.MustCompile(`[/.]v[0-9]+$`)
+
+// TyposquatMatch is a new dependency whose name is suspiciously close to a popular package's.
+type TyposquatMatch struct {
+	Dependency Dependency
+	Similar    string
+}
+
+// PossibleTyposquat checks the dependency's name against a bundled list of popular packages for its ecosystem, and
+// returns the popular package it is one edit (insertion, deletion, substitution, or transposition) away from, or
+// differs from only by letter case. Exact matches and short names are never flagged, and neither are other major
+// versions of a popular Go module.
+func PossibleTyposquat(ecosystem Ecosystem, dep Dependency) (string, bool) {
+	popularOnce.Do(loadPopularPackages)
+
+	name := normalizePackageName(ecosystem, dep.Package())
+	if len(name) < 4 {
+		return "", false
+	}
+
+	candidates := popularPackages[ecosystem]
+
+	for _, popular := range candidates {
+		if normalizePackageName(ecosystem, popular) == name {
+			return "", false
+		}
+	}
+
+	for _, popular := range candidates {
+		normalized := normalizePackageName(ecosystem, popular)
+
+		if strings.EqualFold(normalized, name) || editDistance(normalized, name) == 1 {
+			return popular, true
+		}
+	}
+
+	return "", false
+}
+
+// PossibleTyposquats returns the new dependencies in the diff whose names are close to popular packages.
+func (f FileDiff) PossibleTyposquats() []TyposquatMatch {
+	var results []TyposquatMatch
+
+	for _, dep := range f.NewDependencies {
+		if similar, ok := PossibleTyposquat(f.Ecosystem, dep); ok {
+			results = append(results, TyposquatMatch{
+				Dependency: dep,
+				Similar:    similar,
+			})
+		}
+	}
+
+	return results
+}
+
+func normalizePackageName(ecosystem Ecosystem, name string) string {
+	switch ecosystem {
+	case EcosystemPyPI:
+		return pypiNormalizeRegex.ReplaceAllString(strings.ToLower(name), "-")
+	case EcosystemGo:
+		return goVersionSuffixRegex.ReplaceAllString(name, "")
+	case EcosystemUnknown, EcosystemNPM:
+	}
+
+	return name
+}
+
+func loadPopularPackages() {
+	popularPackages = map[Ecosystem][]string{}
+
+	for _, ecosystem := range []Ecosystem{EcosystemGo, EcosystemNPM, EcosystemPyPI} {
+		contents, err := popularFS.ReadFile("popular/" + string(ecosystem) + ".txt")
+		if err != nil {
+			slog.Error("failed to read popular package list", "ecosystem", ecosystem, "error", err)
+			continue
+		}
+
+		scanner := bufio.NewScanner(bytes.NewReader(contents))
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+
+			popularPackages[ecosystem] = append(popularPackages[ecosystem], line)
+		}
+	}
+}
+
+// editDistance returns the optimal string alignment distance between a and b: the Levenshtein distance, plus
+// transpositions of adjacent characters counting as a single edit.
+func editDistance(a, b string) int {
+	first, second := []rune(a), []rune(b)
+	rows := make([][]int, len(first)+1)
+
+	for i := range rows {
+		rows[i] = make([]int, len(second)+1)
+		rows[i][0] = i
+	}
+
+	for j := range rows[0] {
+		rows[0][j] = j
+	}
+
+	for i := 1; i <= len(first); i++ {
+		for j := 1; j <= len(second); j++ {
+			cost := 1
+			if first[i-1] == second[j-1] {
+				cost = 0
+			}
+
+			rows[i][j] = min(rows[i-1][j]+1, rows[i][j-1]+1, rows[i-1][j-1]+cost)
+
+			if i > 1 && j > 1 && first[i-1] == second[j-2] && first[i-2] == second[j-1] {
+				rows[i][j] = min(rows[i][j], rows[i-2][j-2]+1)
+			}
+		}
+	}
+
+	return rows[len(first)][len(second)]
+}
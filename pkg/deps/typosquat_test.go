@@ -0,0 +1,81 @@
+package deps //nolint:testpackage // exercises the unexported edit distance
+
+import "testing"
+
+func TestEditDistance(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{"", "", 0},
+		{"", "abc", 3},
+		{"lodash", "lodash", 0},
+		{"requests", "requets", 1},
+		{"lodash", "lodahs", 1},
+		{"lodash", "lodashh", 1},
+		{"lodash", "lodazh", 1},
+		{"express", "epxress", 1},
+		{"react", "vue", 5},
+		{"ca", "abc", 3},
+	}
+
+	for _, test := range tests {
+		if actual := editDistance(test.a, test.b); actual != test.expected {
+			t.Errorf("expected distance %d between %q and %q, got %d", test.expected, test.a, test.b, actual)
+		}
+	}
+}
+
+func TestPossibleTyposquat(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		ecosystem Ecosystem
+		name      string
+		similar   string
+	}{
+		{EcosystemPyPI, "requets", "requests"},
+		{EcosystemNPM, "lodahs", "lodash"},
+		{EcosystemNPM, "Lodash", "lodash"},
+		{EcosystemGo, "github.com/spf13/cobar", "github.com/spf13/cobra"},
+		{EcosystemGo, "github.com/jackc/pgz/v5", "github.com/jackc/pgx/v5"},
+
+		// Exact matches
+		{EcosystemPyPI, "requests", ""},
+		{EcosystemNPM, "lodash", ""},
+		{EcosystemGo, "github.com/spf13/cobra", ""},
+
+		// Short names
+		{EcosystemNPM, "wss", ""},
+		{EcosystemPyPI, "sx", ""},
+
+		// PEP 503 normalization
+		{EcosystemPyPI, "Typing_Extensions", ""},
+		{EcosystemPyPI, "typing.extensions", ""},
+		{EcosystemPyPI, "PyYAML", ""},
+		{EcosystemPyPI, "python__dateutil", ""},
+		{EcosystemPyPI, "Request", "requests"},
+
+		// Other major versions of popular Go modules
+		{EcosystemGo, "gopkg.in/yaml.v2", ""},
+		{EcosystemGo, "github.com/jackc/pgx/v4", ""},
+		{EcosystemGo, "github.com/labstack/echo/v3", ""},
+		{EcosystemGo, "github.com/golang-jwt/jwt/v4", ""},
+		{EcosystemGo, "github.com/urfave/cli/v4", ""},
+		{EcosystemGo, "github.com/urfave/cli", ""},
+
+		// Not in the ecosystem's list
+		{EcosystemNPM, "djang", ""},
+		{EcosystemUnknown, "lodahs", ""},
+	}
+
+	for _, test := range tests {
+		similar, ok := PossibleTyposquat(test.ecosystem, Dependency{Name: test.name})
+		if ok != (test.similar != "") || similar != test.similar {
+			t.Errorf("expected %s package %q to be similar to %q, got %q (%t)", test.ecosystem, test.name, test.similar,
+				similar, ok)
+		}
+	}
+}
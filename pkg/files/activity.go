@@ -0,0 +1,99 @@
+package files
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+// ActivityInterval is how much time each count in a Timeline covers.
+const ActivityInterval = time.Minute
+
+// Timeline counts the file events handled in each minute of a session: creates, writes, deletes, moves, and permission
+// changes. Events that weren't counted in the session's stats, like those ignored while paused, aren't in it either.
+type Timeline struct {
+	// Start is the beginning of the minute of the first event. It's zero if nothing has happened yet.
+	Start time.Time `json:"start"`
+	// Counts has the number of events in each minute from Start, including minutes without any.
+	Counts []int64 `json:"counts"`
+}
+
+// IsEmpty reports whether the timeline has no events.
+func (t Timeline) IsEmpty() bool {
+	return len(t.Counts) == 0
+}
+
+// End is the end of the timeline's last minute.
+func (t Timeline) End() time.Time {
+	return t.Start.Add(ActivityInterval * time.Duration(len(t.Counts)))
+}
+
+// activity builds a FileMap's Timeline as events are recorded.
+type activity struct {
+	mutex    sync.Mutex
+	timeline Timeline
+}
+
+// record counts an event at now. Events from before the first one, if the clock goes backwards, count toward the first
+// minute.
+func (a *activity) record(now time.Time) {
+	minute := now.Truncate(ActivityInterval)
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if a.timeline.IsEmpty() {
+		a.timeline.Start = minute
+	}
+
+	idx := max(int(minute.Sub(a.timeline.Start)/ActivityInterval), 0)
+	for len(a.timeline.Counts) <= idx {
+		a.timeline.Counts = append(a.timeline.Counts, 0)
+	}
+
+	a.timeline.Counts[idx]++
+}
+
+// until returns a copy of the timeline, with empty minutes added up to now so quiet stretches at the end show up.
+func (a *activity) until(now time.Time) Timeline {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	result := Timeline{Start: a.timeline.Start, Counts: slices.Clone(a.timeline.Counts)}
+	if result.IsEmpty() {
+		return result
+	}
+
+	for !now.Before(result.End()) {
+		result.Counts = append(result.Counts, 0)
+	}
+
+	return result
+}
+
+// restore replaces the timeline with a saved one.
+func (a *activity) restore(timeline Timeline) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.timeline = Timeline{Start: timeline.Start, Counts: slices.Clone(timeline.Counts)}
+}
+
+// recordEvent notes an event for a file at the current time, in the file's first and last event times and the
+// timeline. The caller must hold the file's shard's write lock.
+func (f *FileMap) recordEvent(file *FileInfo) {
+	now := f.clock.Now()
+
+	if file.FirstEvent.IsZero() {
+		file.FirstEvent = now
+	}
+
+	file.LastEvent = now
+
+	f.activity.record(now)
+}
+
+// Activity returns the timeline of events recorded so far, up to the current minute.
+func (f *FileMap) Activity() Timeline {
+	return f.activity.until(f.clock.Now())
+}
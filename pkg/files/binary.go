@@ -0,0 +1,85 @@
+package files
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"slices"
+)
+
+// sniffSize is how much of the start of a file is checked for NUL bytes to tell if it's binary, the same as git checks.
+const sniffSize = 8000
+
+// fileOpener is implemented by FSs that can open a file to read part of it, like os.Open. FSs without it have whole
+// files read instead, if they can read them at all (see fileReader).
+type fileOpener interface {
+	Open(path string) (fs.File, error)
+}
+
+// binaryFiles returns the new and written files that are binary now, e.g. images and compiled artifacts, sorted.
+func (m *Monitor) binaryFiles(newFiles []string, writtenFiles map[string]int64) []string {
+	results := []string{}
+
+	for _, path := range newFiles {
+		if isBinary(m.fs, path) {
+			results = append(results, path)
+		}
+	}
+
+	for path := range writtenFiles {
+		if !slices.Contains(results, path) && isBinary(m.fs, path) {
+			results = append(results, path)
+		}
+	}
+
+	slices.Sort(results)
+
+	return results
+}
+
+// isBinary reports whether a regular file has a NUL byte near its start. Files that can't be read count as text.
+func isBinary(fileSystem FS, path string) bool {
+	info, err := lstat(fileSystem, path)
+	if err != nil || !info.Mode().IsRegular() {
+		return false
+	}
+
+	header, err := readHeader(fileSystem, path)
+	if err != nil {
+		return false
+	}
+
+	return bytes.IndexByte(header, 0) >= 0
+}
+
+// readHeader reads up to sniffSize bytes from the start of a file.
+func readHeader(fileSystem FS, path string) ([]byte, error) {
+	if opener, ok := fileSystem.(fileOpener); ok {
+		file, err := opener.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+
+		header := make([]byte, sniffSize)
+
+		n, err := io.ReadFull(file, header)
+		if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+
+		return header[:n], nil
+	}
+
+	if reader, ok := fileSystem.(fileReader); ok {
+		contents, err := reader.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		return contents[:min(len(contents), sniffSize)], nil
+	}
+
+	return nil, errors.ErrUnsupported
+}
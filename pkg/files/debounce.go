@@ -0,0 +1,78 @@
+package files
+
+import (
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// DefaultDebounceWindow is used when MonitorOpts.DebounceWindow is not set.
+const DefaultDebounceWindow = time.Millisecond * 100
+
+// writeBurst is the writes to one file within the current debounce window that haven't been sent yet.
+type writeBurst struct {
+	start time.Time
+	count int64
+}
+
+// pushWrite sends a write event, coalescing it with other writes to the same file within DebounceWindow. The first
+// write of a burst is sent right away, so readers still see it without delay, and the rest are sent as one event with
+// their Count when the window closes (see flushWrites).
+func (m *Monitor) pushWrite(event Event) {
+	event.Count = 1
+
+	if m.debounceWindow < 0 {
+		m.pushEvent(event)
+		return
+	}
+
+	m.debounceMutex.Lock()
+
+	burst, ok := m.bursts[event.Name]
+
+	switch {
+	case !ok:
+		m.bursts[event.Name] = &writeBurst{start: m.clock.Now()}
+	case m.clock.Since(burst.start) < m.debounceWindow:
+		burst.count++
+		m.debounceMutex.Unlock()
+
+		return
+	default:
+		// The window closed before flushWrites got to it, so this write takes the held ones with it
+		event.Count += burst.count
+		burst.start, burst.count = m.clock.Now(), 0
+	}
+
+	m.debounceMutex.Unlock()
+
+	m.pushEvent(event)
+}
+
+// flushWrites sends the held writes of every burst whose window has closed. A file that's still being written to
+// starts a new window right away, so a steady stream of writes comes out as one event per window.
+func (m *Monitor) flushWrites() {
+	m.debounceMutex.Lock()
+
+	flushed := []Event{}
+
+	for name, burst := range m.bursts {
+		if m.clock.Since(burst.start) < m.debounceWindow {
+			continue
+		}
+
+		if burst.count == 0 {
+			delete(m.bursts, name)
+			continue
+		}
+
+		flushed = append(flushed, Event{Name: name, Op: fsnotify.Write, Count: burst.count})
+		burst.start, burst.count = m.clock.Now(), 0
+	}
+
+	m.debounceMutex.Unlock()
+
+	for _, event := range flushed {
+		m.pushEvent(event)
+	}
+}
@@ -0,0 +1,102 @@
+package files
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+// DefaultDeleteTimeout is used when MonitorOpts.DeleteTimeout is not set.
+const DefaultDeleteTimeout = time.Millisecond * 250
+
+// editorProfile is the temporary files one editor leaves around while saving.
+type editorProfile struct {
+	patterns []string // globs for file names
+	numeric  bool     // names that are all digits
+}
+
+// editorProfiles are the built-in profiles for MonitorOpts.EditorProfiles.
+//
+//nolint:gochecknoglobals
+var editorProfiles = map[string]editorProfile{
+	// Backups, swap files, and the numbered file written to check that the directory is writable (4913)
+	"vim": {patterns: []string{"*~", "*.swp", "*.swo", "*.swx"}, numeric: true},
+	// Backups, auto-saves, and lock files
+	"emacs": {patterns: []string{"*~", "#*#", ".#*"}},
+	// Atomic saves
+	"vscode": {patterns: []string{"*.vsctmp"}},
+	// "Safe write", which writes a temporary file and renames the original out of the way before replacing it
+	"jetbrains": {patterns: []string{"*___jb_tmp___", "*___jb_old___"}},
+}
+
+// EditorProfileNames returns the names of the built-in editor profiles, sorted.
+func EditorProfileNames() []string {
+	return slices.Sorted(maps.Keys(editorProfiles))
+}
+
+// tempMatcher recognizes editor temporary files by name.
+type tempMatcher struct {
+	patterns []string
+	numeric  bool
+}
+
+// newTempMatcher combines the named profiles, or every profile if names is nil, with extra patterns.
+func newTempMatcher(names, patterns []string) (*tempMatcher, error) {
+	if names == nil {
+		names = EditorProfileNames()
+	}
+
+	matcher := &tempMatcher{}
+
+	for _, name := range names {
+		profile, ok := editorProfiles[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown editor profile %q, expected one of %v", name, EditorProfileNames())
+		}
+
+		matcher.patterns = append(matcher.patterns, profile.patterns...)
+		matcher.numeric = matcher.numeric || profile.numeric
+	}
+
+	for _, pattern := range patterns {
+		if !doublestar.ValidatePattern(pattern) {
+			return nil, fmt.Errorf("invalid temporary file pattern %q", pattern)
+		}
+
+		matcher.patterns = append(matcher.patterns, pattern)
+	}
+
+	return matcher, nil
+}
+
+// match reports whether a file name is an editor temporary file.
+func (t *tempMatcher) match(base string) bool {
+	if t.numeric && isNumeric(base) {
+		return true
+	}
+
+	for _, pattern := range t.patterns {
+		if doublestar.MatchUnvalidated(pattern, base) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isNumeric(s string) bool {
+	if len(s) == 0 {
+		return false
+	}
+
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,33 @@
+package files
+
+// MonitorError is a problem that leaves a monitor degraded but still running, such as a directory that couldn't be
+// watched.
+type MonitorError struct {
+	Op   string // what the monitor was doing, e.g. "watch directory"
+	Path string // the affected path, if any
+	Err  error
+}
+
+func (e *MonitorError) Error() string {
+	if e.Path == "" {
+		return e.Op + ": " + e.Err.Error()
+	}
+
+	return e.Op + " " + e.Path + ": " + e.Err.Error()
+}
+
+func (e *MonitorError) Unwrap() error { return e.Err }
+
+// Errors returns a channel of problems that degrade monitoring, like directories that couldn't be watched or events
+// lost to a kernel queue overflow. Errors are dropped if nobody reads them quickly enough, so the monitor never blocks
+// on them.
+func (m *Monitor) Errors() <-chan error {
+	return m.errors
+}
+
+func (m *Monitor) reportError(op, path string, err error) {
+	select {
+	case m.errors <- &MonitorError{Op: op, Path: path, Err: err}:
+	default:
+	}
+}
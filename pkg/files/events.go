@@ -1,9 +1,14 @@
 package files
 
 import (
-	"context"
+	"errors"
 	"fmt"
+	"io/fs"
 	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
@@ -18,15 +23,89 @@ const (
 	EventTypeRemove  EventType = "remove"
 	EventTypeRename  EventType = "rename"
 	EventTypeWrite   EventType = "write"
+	// EventTypeMove is a file or directory moved from OldName to Name within the watched tree.
+	EventTypeMove EventType = "move"
 )
 
 type Event struct {
-	Name string
+	Name string // absolute path
+	// RelPath is Name relative to the monitor's RootPath, for showing to people. See RelPath, and RelPathIn for
+	// monitors with several roots.
+	RelPath string
+	// Root is the root directory that Name is under, or empty for a file outside every root, like an external
+	// manifest.
+	Root string
 	Op   fsnotify.Op
+	// RenamedFrom is the old path of a create caused by a rename within the watched tree, on platforms that pair the
+	// two halves of a rename (Linux and Windows). It is empty otherwise.
+	RenamedFrom string
+	// OldName is the previous path of a move (see EventTypeMove); Name is the new one. It is empty for other events.
+	OldName string
+	// Count is how many writes to Name a write event stands for, more than one when a burst of writes was coalesced
+	// (see MonitorOpts.DebounceWindow). It is zero for other events.
+	Count int64
+}
+
+// RelPath returns path relative to root, which is shorter and the same on every machine. Paths outside root, like
+// external manifests, are returned unchanged.
+func RelPath(root, path string) string {
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return path
+	}
+
+	return rel
+}
+
+// RootOf returns the root that path is or is under, or an empty string if it's outside all of them.
+func RootOf(roots []string, path string) string {
+	for _, root := range roots {
+		rel, err := filepath.Rel(root, path)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return root
+		}
+	}
+
+	return ""
+}
+
+// RelPathIn is RelPath for a session with several roots, like MonitorOpts.RootPaths: path relative to the root it's
+// under, prefixed with that root's directory name if there's more than one root so the roots can be told apart.
+func RelPathIn(roots []string, path string) string {
+	root := RootOf(roots, path)
+
+	switch {
+	case root == "":
+		return path
+	case len(roots) == 1:
+		return RelPath(root, path)
+	}
+
+	return filepath.Join(filepath.Base(root), RelPath(root, path))
+}
+
+// renamedFrom digs the old path of a rename out of an fsnotify event. fsnotify tracks it, but only exposes it through
+// Event.String().
+func renamedFrom(event fsnotify.Event) string {
+	prefix := fmt.Sprintf("%-13s %q ← ", event.Op.String(), event.Name)
+
+	quoted, ok := strings.CutPrefix(event.String(), prefix)
+	if !ok {
+		return ""
+	}
+
+	oldPath, err := strconv.Unquote(quoted)
+	if err != nil {
+		return ""
+	}
+
+	return oldPath
 }
 
 func (e Event) Type() EventType {
 	switch {
+	case e.OldName != "":
+		return EventTypeMove
 	case e.Op.Has(fsnotify.Create):
 		return EventTypeCreate
 	case e.Op.Has(fsnotify.Remove):
@@ -42,7 +121,15 @@ func (e Event) Type() EventType {
 	return EventTypeUnknown
 }
 
-func (m *Monitor) handleCreate(ctx context.Context, event Event) error {
+func (m *Monitor) handleCreate(event Event) error {
+	if moved, err := m.handleMove(event); moved || err != nil {
+		return err
+	}
+
+	if event.RenamedFrom != "" {
+		m.confirmRename(event.RenamedFrom)
+	}
+
 	m.pendingDeleteMutex.Lock()
 
 	if _, ok := m.pendingDeletes[event.Name]; ok {
@@ -50,60 +137,189 @@ func (m *Monitor) handleCreate(ctx context.Context, event Event) error {
 		m.pendingDeleteMutex.Unlock()
 
 		// Editor swap detected - count this as a write to the file
-		if m.opts.TrackWrites {
-			if err := m.fileMap.AddSwapWrite(event.Name); err != nil {
-				slog.Error("failed to record swap write", "name", event.Name, "error", err)
-			}
-
-			m.pushEvent(ctx, Event{
-				Name: event.Name,
-				Op:   fsnotify.Write,
-			})
-		}
-
-		slog.Debug("detected editor swap, counted as write", "name", event.Name)
+		m.recordSwap(event.Name)
 
 		return nil
 	}
 
 	m.pendingDeleteMutex.Unlock()
 
-	if m.fileMap.Has(event.Name) {
-		slog.Debug("got duplicate creation request, ignoring", "name", event.Name)
-		return nil
+	// Editors that save by renaming a temp file over the original never remove the original first
+	if event.RenamedFrom != "" {
+		if file, err := m.fileMap.Get(event.Name); err == nil && !file.WasDeleted {
+			m.recordSwap(event.Name)
+
+			return nil
+		}
 	}
 
-	if err := m.fileMap.AddNewPath(event.Name); err != nil {
+	info, err := m.fileMap.AddNewPath(event.Name)
+	if errors.Is(err, ErrFileTracked) {
+		slog.Debug("got duplicate creation request, ignoring", "name", event.Name)
+		return nil
+	} else if err != nil {
 		return err
 	}
 
 	slog.Debug("Added new file after creation event", "name", event.Name)
 
-	if m.fileMap.IsDir(event.Name) {
-		go func() {
-			// We want to try to catch e.g. mkdir -p calls that rapidly create nested directories
-			time.Sleep(time.Millisecond * 250)
-
-			if err := m.WatchDirRecursive(event.Name, false); err != nil {
-				slog.Error("failed to monitor new directory", "path", event.Name, "error", err)
-				// return err
-			}
-		}()
+	if info.IsDir() || (info.Mode()&fs.ModeSymlink != 0 && m.opts.FollowSymlinks) {
+		m.watchNewDir(event.Name)
 	}
 
-	m.pushEvent(ctx, event)
+	m.pushEvent(event)
 
 	return nil
 }
 
-func (m *Monitor) handleRemoveOrRename(_ context.Context, event Event) error {
+// watchNewDir starts watching a directory that appeared after the initial scan, along with everything under it.
+func (m *Monitor) watchNewDir(path string) {
+	// We want to try to catch e.g. mkdir -p calls that rapidly create nested directories. The delay starts now rather
+	// than whenever the goroutine gets scheduled.
+	delay := m.clock.After(time.Millisecond * 250)
+
+	go func() {
+		<-delay
+
+		if err := m.WatchDirRecursive(path, false); err != nil {
+			slog.Error("failed to monitor new directory", "path", path, "error", err)
+			m.reportError("watch directory", path, err)
+		}
+	}()
+}
+
+// handleMove treats a create as the second half of a move if it matches a pending delete (see matchMove), and sends a
+// single move event instead of a delete and a create. It reports whether the create was a move.
+func (m *Monitor) handleMove(event Event) (bool, error) {
+	// Renaming over a tracked file is an atomic save, which handleCreate counts as a write
+	if m.fileMap.Has(event.Name) {
+		return false, nil
+	}
+
+	stat, err := m.fs.Stat(event.Name)
+	if err != nil {
+		// Already gone again, which handleCreate reports
+		return false, nil //nolint:nilerr
+	}
+
+	m.pendingDeleteMutex.Lock()
+
+	oldPath, ok := m.matchMove(event, stat)
+	if ok {
+		delete(m.pendingDeletes, oldPath)
+	}
+
+	m.pendingDeleteMutex.Unlock()
+
+	if !ok {
+		return false, nil
+	}
+
+	if err := m.fileMap.Move(oldPath, event.Name); err != nil {
+		return true, fmt.Errorf("failed to move %q to %q: %w", oldPath, event.Name, err)
+	}
+
+	slog.Debug("confirmed move", "old_name", oldPath, "name", event.Name)
+
+	// Directory watches are by path, so the new path needs watching
+	if stat.IsDir() {
+		m.watchNewDir(event.Name)
+	}
+
+	m.pushEvent(Event{Name: event.Name, Op: fsnotify.Rename, OldName: oldPath})
+
+	return true, nil
+}
+
+// matchMove finds the pending delete that a create of a path with the given info completes as a move: the old path
+// fsnotify paired with it, or otherwise a pending rename of the same file. Without pairing, the same file is found by
+// inode where the platform has them, or else as the only pending rename of the same size. The caller must hold the
+// pending delete lock.
+func (m *Monitor) matchMove(event Event, stat fs.FileInfo) (string, bool) {
+	if event.RenamedFrom != "" {
+		_, ok := m.pendingDeletes[event.RenamedFrom]
+
+		return event.RenamedFrom, ok
+	}
+
+	var candidates []string
+
+	for oldPath, pd := range m.pendingDeletes {
+		if pd.event.Type() != EventTypeRename {
+			continue
+		}
+
+		old, err := m.fileMap.Get(oldPath)
+		if err != nil || old.IsDir() != stat.IsDir() {
+			continue
+		}
+
+		if os.SameFile(old.FileInfo, stat) {
+			return oldPath, true
+		}
+
+		if old.Size() == stat.Size() {
+			candidates = append(candidates, oldPath)
+		}
+	}
+
+	if len(candidates) == 1 {
+		return candidates[0], true
+	}
+
+	return "", false
+}
+
+// recordSwap counts an editor swap of the file as a single write.
+func (m *Monitor) recordSwap(name string) {
+	if m.opts.TrackWrites {
+		if err := m.fileMap.AddSwapWrite(name); err != nil {
+			slog.Error("failed to record swap write", "name", name, "error", err)
+		}
+	}
+
+	m.pushWrite(Event{
+		Name: name,
+		Op:   fsnotify.Write,
+	})
+
+	slog.Debug("detected editor swap, counted as write", "name", name)
+}
+
+// confirmRename settles the pending delete for a path that is known to have been renamed, instead of waiting for the
+// pending delete to time out.
+func (m *Monitor) confirmRename(oldPath string) {
+	m.pendingDeleteMutex.Lock()
+
+	pd, ok := m.pendingDeletes[oldPath]
+	delete(m.pendingDeletes, oldPath)
+
+	m.pendingDeleteMutex.Unlock()
+
+	if !ok {
+		return
+	}
+
+	if err := m.fileMap.Delete(oldPath); err != nil {
+		slog.Error("failed to process renamed file", "name", oldPath, "error", err)
+		return
+	}
+
+	m.unfollow(oldPath)
+
+	slog.Debug("confirmed rename", "old_name", oldPath)
+
+	m.pushEvent(pd.event)
+}
+
+func (m *Monitor) handleRemoveOrRename(event Event) error {
 	file, err := m.fileMap.Get(event.Name)
 	if err != nil {
 		return fmt.Errorf("got remove/rename event for unknown file %q", event.Name)
 	}
 
 	pd := pendingDelete{
-		timestamp:   time.Now(),
+		timestamp:   m.clock.Now(),
 		event:       event,
 		initialFile: file.IsInitial(),
 	}
@@ -112,7 +328,9 @@ func (m *Monitor) handleRemoveOrRename(_ context.Context, event Event) error {
 
 	// Mark file as potentially being swapped - this prevents counting writes
 	// that happen between the delete and create events of an editor swap
-	m.fileMap.MarkPendingSwap(event.Name)
+	if m.opts.TrackWrites {
+		m.fileMap.MarkPendingSwap(event.Name)
+	}
 
 	m.pendingDeleteMutex.Lock()
 	m.pendingDeletes[event.Name] = pd
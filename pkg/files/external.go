@@ -0,0 +1,70 @@
+package files
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+// WatchExternalFile watches a single file outside the roots, like a shared constraints file, without tracking the rest
+// of its directory. The parent directory is watched (non-recursively) so the file keeps being followed when editors
+// replace it, but events for the directory's other entries are dropped. Watching the same file twice is a no-op.
+func (m *Monitor) WatchExternalFile(path string) error {
+	path = filepath.Clean(path)
+	dir := filepath.Dir(path)
+
+	if m.inRoot(path) {
+		return fmt.Errorf("file %q is inside a monitored directory", path)
+	}
+
+	m.externalMutex.Lock()
+	defer m.externalMutex.Unlock()
+
+	if _, ok := m.externalFiles[path]; ok {
+		return nil
+	}
+
+	if _, ok := m.externalDirs[dir]; !ok {
+		if err := m.watcher.Add(dir); err != nil {
+			return fmt.Errorf("failed to monitor directory %q of external file: %w", dir, err)
+		}
+
+		m.externalDirs[dir] = struct{}{}
+	}
+
+	m.externalFiles[path] = struct{}{}
+
+	stat, err := m.fs.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to stat external file %q: %w", path, err)
+	}
+
+	if err := m.fileMap.AddFile(path, FileInfo{FileInfo: stat, FileType: FileTypeInitial}); err != nil {
+		return fmt.Errorf("failed to add external file to map: %w", err)
+	}
+
+	return nil
+}
+
+// inRoot reports whether path is one of the roots or somewhere beneath one.
+func (m *Monitor) inRoot(path string) bool {
+	return RootOf(m.roots, path) != ""
+}
+
+// unwantedExternal reports whether an event is for a sibling of an external file, which is only seen because the
+// external file's directory is watched.
+func (m *Monitor) unwantedExternal(path string) bool {
+	m.externalMutex.RLock()
+	defer m.externalMutex.RUnlock()
+
+	if len(m.externalDirs) == 0 {
+		return false
+	}
+
+	if _, ok := m.externalDirs[filepath.Dir(path)]; !ok {
+		return false
+	}
+
+	_, wanted := m.externalFiles[path]
+
+	return !wanted
+}
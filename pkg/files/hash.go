@@ -0,0 +1,45 @@
+package files
+
+import "github.com/cespare/xxhash/v2"
+
+// maxHashSize is the largest file whose contents are hashed. Writes to bigger files always count as meaningful.
+const maxHashSize = 16 << 20
+
+// contentHash returns the hash of a regular file's contents, or 0 if hashing is off or the file can't be hashed, e.g.
+// because it's too big or already gone.
+func (f *FileMap) contentHash(path string) uint64 {
+	if !f.hashContents {
+		return 0
+	}
+
+	reader, ok := f.fs.(fileReader)
+	if !ok {
+		return 0
+	}
+
+	info, err := f.fs.Stat(path)
+	if err != nil || !info.Mode().IsRegular() || info.Size() > maxHashSize {
+		return 0
+	}
+
+	contents, err := reader.ReadFile(path)
+	if err != nil {
+		return 0
+	}
+
+	return xxhash.Sum64(contents)
+}
+
+// recordContent updates a file's hash after a write that's part of its current save, counting the save as meaningful
+// the first time one of its writes changes the contents. A write whose contents can't be compared counts as a change.
+// The caller must hold the file's shard lock.
+func (f *FileMap) recordContent(file *FileInfo, hash uint64) {
+	changed := hash == 0 || file.hash == 0 || hash != file.hash
+	file.hash = hash
+
+	if changed && !file.meaningfulSave {
+		file.meaningfulSave = true
+		file.MeaningfulWrites++
+		f.meaningfulWrites.Add(1)
+	}
+}
@@ -0,0 +1,29 @@
+//go:build freebsd || openbsd || netbsd || dragonfly || darwin
+
+package files
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// watchLimitHeadroom is how many file descriptors to leave for everything other than watches, like git objects, sounds,
+// and logs.
+const watchLimitHeadroom = 256
+
+// checkWatchLimit warns when the tree is too big to watch. kqueue, the fsnotify backend on macOS and the BSDs, holds an
+// open file descriptor for every watched file and directory, so a tree bigger than the open file limit silently
+// misses events. Go already raises the soft limit as far as the system allows at startup.
+func checkWatchLimit(numWatched int) error {
+	var limit syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &limit); err != nil {
+		return fmt.Errorf("failed to read open file limit: %w", err)
+	}
+
+	if available := int64(limit.Cur) - watchLimitHeadroom; int64(numWatched) > available {
+		return fmt.Errorf("%d files and directories need watching, but the open file limit is %d: raise it "+
+			"(e.g. \"sudo launchctl limit maxfiles\" on macOS) or some changes will be missed", numWatched, limit.Cur)
+	}
+
+	return nil
+}
@@ -0,0 +1,8 @@
+//go:build !(freebsd || openbsd || netbsd || dragonfly || darwin)
+
+package files
+
+// checkWatchLimit is a no-op where fsnotify doesn't need a file descriptor per watched file.
+func checkWatchLimit(_ int) error {
+	return nil
+}
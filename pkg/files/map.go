@@ -3,11 +3,17 @@ package files
 import (
 	"errors"
 	"fmt"
+	"hash/maphash"
 	"io/fs"
-	"os"
+	"maps"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cneill/mon/pkg/clock"
 )
 
 var (
@@ -28,31 +34,180 @@ type FileInfo struct {
 	FileType      FileType
 	WasDeleted    bool // This is to track the deletion of initial files. New files will be removed from the map with Delete()
 	Writes        int64
+	RawWrites     int64 // Every write event, before bursts are coalesced into saves
 	PreSwapWrites int64 // Writes that occurred before editor swaps (not counted in final total)
 	PendingSwap   bool  // True if file has a pending delete that might be part of an editor swap
+	// MeaningfulWrites counts saves that changed the file's contents. Without content hashing, that's every save.
+	MeaningfulWrites int64
+	// PermissionChanges counts changes to the file's permission bits or owner.
+	PermissionChanges int64
+	// FirstEvent and LastEvent are when the file was first and last created, written, moved, or otherwise changed
+	// during the session. They're zero for files that haven't been.
+	FirstEvent time.Time
+	LastEvent  time.Time
+
+	lastSave       time.Time // When the current save (burst of writes) started
+	meaningfulSave bool      // Whether the current save has changed the contents yet
+	hash           uint64    // Of the contents after the last write, or 0 if unknown
 }
 
 func (f FileInfo) IsInitial() bool { return f.FileType == FileTypeInitial }
 
+// IsSymlink reports whether the path is a symbolic link. Symlinks are counted separately from files and directories,
+// so a farm of links to existing files doesn't look like a burst of new ones.
+func (f FileInfo) IsSymlink() bool { return f.FileInfo != nil && f.Mode()&fs.ModeSymlink != 0 }
+
+// numShards is how many independently locked pieces a FileMap is split into.
+const numShards = 64
+
+// FileMap tracks every file and directory under the monitored root. Paths are sharded by their parent directory so
+// events in different directories don't contend for the same lock, and so a directory's children can be found
+// without scanning the whole map.
 type FileMap struct {
-	files map[string]*FileInfo
-	mutex sync.RWMutex
+	seed   maphash.Seed
+	shards [numShards]*fileShard
+
+	baseMutex sync.RWMutex
+	byBase    map[string]map[string]struct{} // base name -> paths with that base name
+
+	filesCreated    atomic.Int64
+	filesDeleted    atomic.Int64
+	filesMoved      atomic.Int64
+	symlinksCreated atomic.Int64
+	symlinksDeleted atomic.Int64
+
+	meaningfulWrites  atomic.Int64
+	permissionChanges atomic.Int64
+
+	activity activity
+
+	// saveWindow is how long after a counted write further writes to the same file are treated as part of the same
+	// save. Zero counts every write event.
+	saveWindow time.Duration
+	// hashContents compares each write's contents with the last one's, so writes that change nothing (a touch, an
+	// editor re-saving an unchanged buffer) aren't counted as meaningful.
+	hashContents bool
+	clock        clock.Clock
+	fs           FS
+}
 
-	filesCreated int64
-	filesDeleted int64
+type fileShard struct {
+	mutex sync.RWMutex
+	dirs  map[string]map[string]*FileInfo // parent directory -> path -> info
 }
 
 func NewFileMap() *FileMap {
-	return &FileMap{
-		files: map[string]*FileInfo{},
+	fileMap := &FileMap{
+		seed:   maphash.MakeSeed(),
+		byBase: map[string]map[string]struct{}{},
+		clock:  clock.Real{},
+		fs:     osFS{},
+	}
+
+	for i := range fileMap.shards {
+		fileMap.shards[i] = &fileShard{
+			dirs: map[string]map[string]*FileInfo{},
+		}
+	}
+
+	return fileMap
+}
+
+// shard returns the shard holding the direct children of dir.
+func (f *FileMap) shard(dir string) *fileShard {
+	return f.shards[maphash.String(f.seed, dir)%numShards]
+}
+
+// lookup returns the shard for path, along with its parent directory to use with the shard's methods.
+func (f *FileMap) lookup(path string) (*fileShard, string) {
+	dir := filepath.Dir(path)
+
+	return f.shard(dir), dir
+}
+
+// get returns the info for a path in the shard. The caller must hold the shard's lock.
+func (s *fileShard) get(dir, path string) (*FileInfo, bool) {
+	info, ok := s.dirs[dir][path]
+
+	return info, ok
+}
+
+// set stores the info for a path in the shard, indexing its base name if it is new. The caller must hold the shard's
+// write lock.
+func (f *FileMap) set(shard *fileShard, dir, path string, info *FileInfo) {
+	children, ok := shard.dirs[dir]
+	if !ok {
+		children = map[string]*FileInfo{}
+		shard.dirs[dir] = children
+	}
+
+	if _, exists := children[path]; !exists {
+		base := filepath.Base(path)
+
+		f.baseMutex.Lock()
+
+		if f.byBase[base] == nil {
+			f.byBase[base] = map[string]struct{}{}
+		}
+
+		f.byBase[base][path] = struct{}{}
+
+		f.baseMutex.Unlock()
+	}
+
+	children[path] = info
+}
+
+// remove drops a path from the shard and the base name index. The caller must hold the shard's write lock.
+func (f *FileMap) remove(shard *fileShard, dir, path string) {
+	delete(shard.dirs[dir], path)
+
+	if len(shard.dirs[dir]) == 0 {
+		delete(shard.dirs, dir)
+	}
+
+	base := filepath.Base(path)
+
+	f.baseMutex.Lock()
+
+	delete(f.byBase[base], path)
+
+	if len(f.byBase[base]) == 0 {
+		delete(f.byBase, base)
+	}
+
+	f.baseMutex.Unlock()
+}
+
+// each calls fn for every tracked path, one shard at a time with that shard's read lock held.
+func (f *FileMap) each(fn func(path string, info *FileInfo)) {
+	for _, shard := range f.shards {
+		shard.mutex.RLock()
+
+		for _, children := range shard.dirs {
+			for path, info := range children {
+				fn(path, info)
+			}
+		}
+
+		shard.mutex.RUnlock()
 	}
 }
 
 func (f *FileMap) AddFile(path string, info FileInfo) error {
-	f.mutex.Lock()
-	defer f.mutex.Unlock()
+	// Hash outside the lock, like AddNewPath's stat, so writes to initial files have something to compare with. New
+	// files aren't hashed: their contents may already be written by the time they're added, and that first write has
+	// to count.
+	if info.IsInitial() && !info.IsDir() {
+		info.hash = f.contentHash(path)
+	}
+
+	shard, dir := f.lookup(path)
+
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
 
-	file, ok := f.files[path]
+	file, ok := shard.get(dir, path)
 	if ok {
 		if !file.WasDeleted {
 			return ErrFileTracked
@@ -61,59 +216,84 @@ func (f *FileMap) AddFile(path string, info FileInfo) error {
 		file.WasDeleted = false
 
 		if !file.IsInitial() {
-			f.filesCreated++
+			f.countCreated(&info, 1)
+			f.recordEvent(&info)
 		}
 	} else if info.FileType != FileTypeInitial {
-		f.filesCreated++
+		f.countCreated(&info, 1)
+		f.recordEvent(&info)
 	}
 
-	f.files[path] = &info
+	f.set(shard, dir, path, &info)
 
 	return nil
 }
 
-// AddNewPath will stat the given path and add it to the map if it is not already known. This should not be used for
-// initial files. Calling this with a known path will return ErrFileTracked.
-func (f *FileMap) AddNewPath(path string) error {
-	f.mutex.Lock()
-	defer f.mutex.Unlock()
-
-	_, ok := f.files[path]
-	if ok {
-		return ErrFileTracked
+// AddNewPath will stat the given path and add it to the map if it is not already known, returning its info. Symlinks
+// are stat'ed themselves, rather than what they point to. This should not be used for initial files. Calling this with
+// a known path will return ErrFileTracked.
+func (f *FileMap) AddNewPath(path string) (fs.FileInfo, error) {
+	if f.Has(path) {
+		return nil, ErrFileTracked
 	}
 
-	fi, err := os.Stat(path)
+	// Stat outside the lock so a burst of creations doesn't serialize every other map operation behind syscalls
+	fi, err := lstat(f.fs, path)
 	if err != nil {
-		return fmt.Errorf("failed to stat new file %q: %w", path, err)
+		return nil, fmt.Errorf("failed to stat new file %q: %w", path, err)
 	}
 
-	info := FileInfo{
+	shard, dir := f.lookup(path)
+
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
+
+	if _, ok := shard.get(dir, path); ok {
+		return nil, ErrFileTracked
+	}
+
+	info := &FileInfo{
 		FileInfo: fi,
 		FileType: FileTypeNew,
 	}
 
-	f.files[path] = &info
-	f.filesCreated++
+	f.set(shard, dir, path, info)
+	f.countCreated(info, 1)
+	f.recordEvent(info)
 
-	return nil
+	return fi, nil
 }
 
 func (f *FileMap) AddWrite(path string) error {
-	f.mutex.Lock()
-	defer f.mutex.Unlock()
+	hash := f.contentHash(path)
+	shard, dir := f.lookup(path)
+
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
 
-	file, ok := f.files[path]
+	file, ok := shard.get(dir, path)
 	if !ok {
 		return ErrUnknownFile
 	}
 
+	file.RawWrites++
+	f.recordEvent(file)
+
 	// Don't count writes that happen right before a swap - the swap will be counted instead
 	if file.PendingSwap {
 		return nil
 	}
 
-	file.Writes++
+	// Editors and agents often emit several write events for a single save
+	now := f.clock.Now()
+	if f.saveWindow == 0 || now.Sub(file.lastSave) >= f.saveWindow {
+		file.Writes++
+		file.lastSave = now
+		file.meaningfulSave = false
+	}
+
+	// Every write is hashed, even within a save, so the next save is compared with the final contents
+	f.recordContent(file, hash)
 
 	return nil
 }
@@ -121,10 +301,13 @@ func (f *FileMap) AddWrite(path string) error {
 // AddSwapWrite records a write from an editor swap (delete+create pair).
 // It also clears any writes that occurred just before the swap to avoid double-counting.
 func (f *FileMap) AddSwapWrite(path string) error {
-	f.mutex.Lock()
-	defer f.mutex.Unlock()
+	hash := f.contentHash(path)
+	shard, dir := f.lookup(path)
 
-	file, ok := f.files[path]
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
+
+	file, ok := shard.get(dir, path)
 	if !ok {
 		return ErrUnknownFile
 	}
@@ -132,7 +315,13 @@ func (f *FileMap) AddSwapWrite(path string) error {
 	// Clear pre-swap writes and count the swap as a single write
 	file.PreSwapWrites += file.Writes
 	file.Writes = 1
+	file.RawWrites++
+	f.recordEvent(file)
 	file.PendingSwap = false
+	file.lastSave = f.clock.Now()
+	file.meaningfulSave = false
+
+	f.recordContent(file, hash)
 
 	return nil
 }
@@ -140,20 +329,19 @@ func (f *FileMap) AddSwapWrite(path string) error {
 // MarkPendingSwap marks a file as potentially being swapped by an editor.
 // This prevents writes from being counted until we know if a swap occurred.
 func (f *FileMap) MarkPendingSwap(path string) {
-	f.mutex.Lock()
-	defer f.mutex.Unlock()
+	shard, dir := f.lookup(path)
+
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
 
-	if file, ok := f.files[path]; ok {
+	if file, ok := shard.get(dir, path); ok {
 		file.PendingSwap = true
 	}
 }
 
 func (f *FileMap) IsInitial(path string) bool {
-	f.mutex.RLock()
-	defer f.mutex.RUnlock()
-
-	file, ok := f.files[path]
-	if !ok {
+	file, err := f.Get(path)
+	if err != nil {
 		return false
 	}
 
@@ -161,11 +349,8 @@ func (f *FileMap) IsInitial(path string) bool {
 }
 
 func (f *FileMap) IsDir(path string) bool {
-	f.mutex.RLock()
-	defer f.mutex.RUnlock()
-
-	file, ok := f.files[path]
-	if !ok {
+	file, err := f.Get(path)
+	if err != nil {
 		return false
 	}
 
@@ -177,19 +362,18 @@ func (f *FileMap) Delete(path string) error {
 }
 
 func (f *FileMap) Has(path string) bool {
-	f.mutex.RLock()
-	defer f.mutex.RUnlock()
-
-	_, ok := f.files[path]
+	_, err := f.Get(path)
 
-	return ok
+	return err == nil
 }
 
 func (f *FileMap) Get(path string) (FileInfo, error) {
-	f.mutex.RLock()
-	defer f.mutex.RUnlock()
+	shard, dir := f.lookup(path)
+
+	shard.mutex.RLock()
+	defer shard.mutex.RUnlock()
 
-	file, ok := f.files[path]
+	file, ok := shard.get(dir, path)
 	if !ok {
 		return FileInfo{}, ErrUnknownFile
 	}
@@ -198,119 +382,312 @@ func (f *FileMap) Get(path string) (FileInfo, error) {
 }
 
 func (f *FileMap) FilePathsByBase(name string) []string {
-	f.mutex.RLock()
-	defer f.mutex.RUnlock()
+	f.baseMutex.RLock()
+	defer f.baseMutex.RUnlock()
 
+	return slices.Collect(maps.Keys(f.byBase[name]))
+}
+
+func (f *FileMap) NewFiles() []string {
 	results := []string{}
 
-	for file := range f.files {
-		if base := filepath.Base(file); base == name {
-			results = append(results, file)
+	f.each(func(path string, info *FileInfo) {
+		if info.FileType == FileTypeNew && !info.IsSymlink() {
+			results = append(results, path)
 		}
-	}
+	})
 
 	return results
 }
 
-func (f *FileMap) NewFiles() []string {
-	f.mutex.RLock()
-	defer f.mutex.RUnlock()
+func (f *FileMap) DeletedFiles() []string {
+	results := []string{}
 
-	results := make([]string, 0, len(f.files))
+	f.each(func(path string, info *FileInfo) {
+		if info.WasDeleted && !info.IsSymlink() {
+			results = append(results, path)
+		}
+	})
 
-	for name, info := range f.files {
-		if info.FileType == FileTypeNew {
-			results = append(results, name)
+	return results
+}
+
+func (f *FileMap) WrittenFiles() map[string]int64 {
+	results := map[string]int64{}
+
+	f.each(func(path string, info *FileInfo) {
+		if info.Writes > 0 {
+			results[path] = info.Writes
 		}
-	}
+	})
 
 	return results
 }
 
-func (f *FileMap) DeletedFiles() []string {
-	f.mutex.RLock()
-	defer f.mutex.RUnlock()
+// ExtensionStats totals the activity on files with one extension.
+type ExtensionStats struct {
+	Created int64 `json:"created"` // New files, as in NewFiles
+	Deleted int64 `json:"deleted"` // Deleted initial files, as in DeletedFiles
+	Written int64 `json:"written"` // Files with at least one save
+	Writes  int64 `json:"writes"`  // Saves, as in WrittenFiles
+}
+
+// ByExtension totals NewFiles, DeletedFiles, and WrittenFiles by lowercased file extension, like ".go". Files without
+// an extension are under "". Directories aren't counted.
+func (f *FileMap) ByExtension() map[string]ExtensionStats {
+	return f.groupStats(func(path string) string {
+		return strings.ToLower(filepath.Ext(path))
+	})
+}
+
+// groupStats totals NewFiles, DeletedFiles, and WrittenFiles by the key that group returns for each file's path.
+// Directories aren't counted.
+func (f *FileMap) groupStats(group func(path string) string) map[string]ExtensionStats {
+	results := map[string]ExtensionStats{}
+
+	f.each(func(path string, info *FileInfo) {
+		if info.IsDir() || info.IsSymlink() || (info.FileType != FileTypeNew && !info.WasDeleted && info.Writes == 0) {
+			return
+		}
+
+		key := group(path)
+		stats := results[key]
 
-	results := make([]string, 0, len(f.files))
+		if info.FileType == FileTypeNew {
+			stats.Created++
+		}
 
-	for name, info := range f.files {
 		if info.WasDeleted {
-			results = append(results, name)
+			stats.Deleted++
 		}
-	}
+
+		if info.Writes > 0 {
+			stats.Written++
+			stats.Writes += info.Writes
+		}
+
+		results[key] = stats
+	})
 
 	return results
 }
 
-func (f *FileMap) WrittenFiles() map[string]int64 {
-	f.mutex.RLock()
-	defer f.mutex.RUnlock()
-
+// RawWrittenFiles is like WrittenFiles, but counts every write event instead of coalesced saves.
+func (f *FileMap) RawWrittenFiles() map[string]int64 {
 	results := map[string]int64{}
 
-	for name, info := range f.files {
-		if info.Writes > 0 {
-			results[name] = info.Writes
+	f.each(func(path string, info *FileInfo) {
+		if info.RawWrites > 0 {
+			results[path] = info.RawWrites
 		}
-	}
+	})
 
 	return results
 }
 
-func (f *FileMap) FilesCreated() int64 {
-	f.mutex.RLock()
-	defer f.mutex.RUnlock()
+// Len returns the number of tracked paths, including deleted initial files.
+func (f *FileMap) Len() int {
+	result := 0
+
+	for _, shard := range f.shards {
+		shard.mutex.RLock()
+
+		for _, children := range shard.dirs {
+			result += len(children)
+		}
+
+		shard.mutex.RUnlock()
+	}
+
+	return result
+}
 
-	return f.filesCreated
+func (f *FileMap) FilesCreated() int64 {
+	return f.filesCreated.Load()
 }
 
 func (f *FileMap) FilesDeleted() int64 {
-	f.mutex.RLock()
-	defer f.mutex.RUnlock()
+	return f.filesDeleted.Load()
+}
+
+func (f *FileMap) FilesMoved() int64 {
+	return f.filesMoved.Load()
+}
+
+func (f *FileMap) SymlinksCreated() int64 {
+	return f.symlinksCreated.Load()
+}
+
+func (f *FileMap) SymlinksDeleted() int64 {
+	return f.symlinksDeleted.Load()
+}
+
+// MeaningfulWrites returns how many saves changed a file's contents, across every file.
+func (f *FileMap) MeaningfulWrites() int64 {
+	return f.meaningfulWrites.Load()
+}
+
+// Move re-keys a tracked path to newPath, along with everything under it if it's a directory. The moved paths keep
+// their type and write counts, so moving a file is neither a create nor a delete; it's counted in FilesMoved instead.
+func (f *FileMap) Move(oldPath, newPath string) error {
+	if f.Has(newPath) {
+		return ErrFileTracked
+	}
+
+	if err := f.move(oldPath, newPath); err != nil {
+		return err
+	}
+
+	f.filesMoved.Add(1)
+
+	shard, dir := f.lookup(newPath)
+
+	shard.mutex.Lock()
+
+	if file, ok := shard.get(dir, newPath); ok {
+		f.recordEvent(file)
+	}
+
+	shard.mutex.Unlock()
+
+	return nil
+}
+
+func (f *FileMap) move(oldPath, newPath string) error {
+	// Stat outside the lock, like AddNewPath. Keep the old info if the file has already moved on again.
+	stat, statErr := lstat(f.fs, newPath)
+
+	oldShard, oldDir := f.lookup(oldPath)
+
+	oldShard.mutex.Lock()
+
+	file, ok := oldShard.get(oldDir, oldPath)
+	if !ok {
+		oldShard.mutex.Unlock()
+		return ErrUnknownFile
+	}
+
+	f.remove(oldShard, oldDir, oldPath)
+
+	oldShard.mutex.Unlock()
+
+	moved := *file
+	moved.PendingSwap = false
+
+	if statErr == nil {
+		moved.FileInfo = stat
+	}
+
+	newShard, newDir := f.lookup(newPath)
+
+	newShard.mutex.Lock()
+	f.set(newShard, newDir, newPath, &moved)
+	newShard.mutex.Unlock()
+
+	// Followed symlinks have children too
+	if !moved.IsDir() && !moved.IsSymlink() {
+		return nil
+	}
+
+	childShard := f.shard(oldPath)
+
+	childShard.mutex.RLock()
+	children := slices.Collect(maps.Keys(childShard.dirs[oldPath]))
+	childShard.mutex.RUnlock()
+
+	for _, child := range children {
+		if err := f.move(child, filepath.Join(newPath, filepath.Base(child))); err != nil {
+			return fmt.Errorf("failed to move child path %q of %q: %w", child, oldPath, err)
+		}
+	}
 
-	return f.filesDeleted
+	return nil
 }
 
 func (f *FileMap) deleteIndividual(path string, recursive bool) error {
-	f.mutex.RLock()
-	file, ok := f.files[path]
-	f.mutex.RUnlock()
+	shard, dir := f.lookup(path)
+
+	shard.mutex.Lock()
 
+	file, ok := shard.get(dir, path)
 	if !ok {
+		shard.mutex.Unlock()
 		return ErrUnknownFile
 	}
 
-	if file.IsInitial() {
+	isDir, isSymlink := file.IsDir(), file.IsSymlink()
+
+	switch {
+	case !file.IsInitial():
+		f.remove(shard, dir, path)
+		f.countCreated(file, -1)
+		f.recordEvent(file)
+	case !file.WasDeleted:
+		// Children of a removed directory may already have been deleted individually
 		file.WasDeleted = true
-		f.filesDeleted++
-	} else {
-		f.mutex.Lock()
-		delete(f.files, path)
-		f.mutex.Unlock()
-		f.filesCreated--
+		f.countDeleted(file, 1)
+		f.recordEvent(file)
 	}
 
-	if recursive && file.IsDir() {
+	shard.mutex.Unlock()
+
+	switch {
+	case recursive && isDir:
 		return f.deleteChildren(path)
+	case isSymlink:
+		// What a followed symlink pointed to is still there, so its contents weren't deleted
+		f.forgetChildren(path)
 	}
 
 	return nil
 }
 
-func (f *FileMap) deleteChildren(parentPath string) error {
-	toDelete := make([]string, 0, len(f.files))
-	f.mutex.RLock()
+// forgetChildren drops everything below parentPath without counting it as deleted.
+func (f *FileMap) forgetChildren(parentPath string) {
+	shard := f.shard(parentPath)
 
-	for path := range f.files {
-		if strings.HasPrefix(path, parentPath) {
-			toDelete = append(toDelete, path)
-		}
+	shard.mutex.Lock()
+	children := slices.Collect(maps.Keys(shard.dirs[parentPath]))
+
+	for _, path := range children {
+		f.remove(shard, parentPath, path)
+	}
+
+	shard.mutex.Unlock()
+
+	for _, path := range children {
+		f.forgetChildren(path)
 	}
+}
+
+// countCreated adds delta to the files or symlinks created, depending on what info is.
+func (f *FileMap) countCreated(info *FileInfo, delta int64) {
+	if info.IsSymlink() {
+		f.symlinksCreated.Add(delta)
+	} else {
+		f.filesCreated.Add(delta)
+	}
+}
+
+// countDeleted adds delta to the files or symlinks deleted, depending on what info is.
+func (f *FileMap) countDeleted(info *FileInfo, delta int64) {
+	if info.IsSymlink() {
+		f.symlinksDeleted.Add(delta)
+	} else {
+		f.filesDeleted.Add(delta)
+	}
+}
+
+// deleteChildren deletes everything below parentPath, one directory level at a time.
+func (f *FileMap) deleteChildren(parentPath string) error {
+	shard := f.shard(parentPath)
 
-	f.mutex.RUnlock()
+	shard.mutex.RLock()
+	children := slices.Collect(maps.Keys(shard.dirs[parentPath]))
+	shard.mutex.RUnlock()
 
-	for _, path := range toDelete {
-		if err := f.deleteIndividual(path, false); err != nil {
+	for _, path := range children {
+		if err := f.deleteIndividual(path, true); err != nil {
 			return fmt.Errorf("failed to delete child path %q of %q: %w", path, parentPath, err)
 		}
 	}
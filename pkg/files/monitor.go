@@ -1,22 +1,103 @@
+// Package files watches a project directory for created, written, renamed, and deleted files, and keeps counts of
+// them for the session.
+//
+// Stability: stable
 package files
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io/fs"
 	"log/slog"
-	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/cneill/mon/pkg/clock"
 	"github.com/fsnotify/fsnotify"
 )
 
 type MonitorOpts struct {
-	RootPath    string
-	WatchRoot   bool
+	RootPath string
+	// RootPaths are more directories monitored along with RootPath, like a frontend and a backend repository worked
+	// on together. Their files share the one file map, and Stats.ByRoot breaks the counts down by root. Roots can't
+	// be inside one another.
+	RootPaths []string
+	WatchRoot bool
+	// TrackWrites enables per-file write counting (WrittenFiles in Stats). Monitors that only need the event stream,
+	// like the git monitor's, should leave it off to skip that bookkeeping entirely. Write events are sent on Events
+	// either way.
 	TrackWrites bool
+	// SaveWindow coalesces write events to the same file within this long of each other into a single save. Zero
+	// counts every write event.
+	SaveWindow time.Duration
+	// HashContents hashes files (with xxhash) when they're added and written, so saves that leave the contents as they
+	// were, like a touch or an editor re-saving an unchanged buffer, aren't counted in Stats.NumMeaningfulWrites. It
+	// reads every file under RootPath at startup. Files over 16 MiB aren't hashed. It only applies with TrackWrites.
+	HashContents bool
+	// IgnorePatterns are doublestar globs, like "dist/**" or "*.log", for paths under RootPath that aren't counted or
+	// watched at all. They're matched against slash-separated paths relative to RootPath, and a pattern without a slash
+	// matches a file or directory name at any depth, as in .gitignore.
+	IgnorePatterns []string
+	// DeleteTimeout is how long a removed file has to reappear before its removal counts as a delete, so editors that
+	// save by replacing a file aren't seen as deleting it. Slow editors or filesystems may need longer. Defaults to
+	// DefaultDeleteTimeout.
+	DeleteTimeout time.Duration
+	// EditorProfiles names the built-in sets of editor temporary files to ignore (see EditorProfileNames), like vim's
+	// swap files or JetBrains' "safe write" files. Nil uses every profile, and an empty slice none.
+	EditorProfiles []string
+	// TempPatterns are more globs for the names of temporary files to ignore, like "*.bak", for editors without a
+	// profile.
+	TempPatterns []string
+	// ReconcileInterval is how often the whole tree under RootPath is rescanned for paths that were never reported,
+	// like files created in a new directory before it could be watched. Those are added as if they had just been
+	// created. Zero disables rescanning.
+	ReconcileInterval time.Duration
+	// PollInterval switches to finding changes by rescanning the watched directories this often, instead of with
+	// fsnotify, for filesystems that don't deliver change events, like NFS, SSHFS, and some container volume mounts.
+	// Zero uses fsnotify. It has no effect if Watcher is set.
+	PollInterval time.Duration
+	// FollowSymlinks watches the directories that symlinks under the roots point to, as if they were under the link.
+	// Links into a root, or into a directory that's already followed, aren't followed, so loops are impossible.
+	// Either way, symlinks themselves are counted separately from files (see Stats.NumSymlinksCreated), and the
+	// contents of a followed directory aren't counted as created or deleted along with the link.
+	FollowSymlinks bool
+	// EventBufferSize is how many events can wait on Events for a reader. When it's full, the oldest event is dropped to
+	// make room and counted in Stats.EventsDropped, so a slow reader never holds up the monitor, and the monitor's
+	// own counts stay accurate. Defaults to DefaultEventBufferSize.
+	EventBufferSize int
+	// DebounceWindow coalesces write events to the same file within this long of each other on Events: the first is
+	// sent right away, and the rest as one event with their Count at the end of the window. Unlike SaveWindow, it
+	// doesn't change what's counted. Defaults to DefaultDebounceWindow, and a negative window sends every write.
+	DebounceWindow time.Duration
+	// TopNewFiles is how many of the largest new files are listed in Stats.TopNewFilesBySize. Defaults to
+	// DefaultTopNewFiles, and a negative number lists none.
+	TopNewFiles int
+	// MaxDepth leaves out paths more than this many levels below a root, e.g. 1 for only the entries directly in it.
+	// They aren't tracked or watched, during the initial scan or later, as if they matched an ignore pattern. Zero
+	// means no limit.
+	MaxDepth int
+	// MaxTrackedFiles stops the initial scan of each root once this many files and directories under it are tracked,
+	// so a huge tree doesn't take long to scan or use a lot of memory. Everything the scan didn't get to, in the order
+	// it walks the tree, is left out for the rest of the session as if it matched an ignore pattern. Paths created
+	// later in the scanned part of the tree are still tracked. Zero means no limit. Either limit is reported on Errors
+	// as ErrScanLimit for each root it leaves something out of.
+	MaxTrackedFiles int
+	// LazyWatchDepth watches only the directories up to this many levels below each root before Run is ready, and the
+	// deeper ones in the background after, so changes near the top of a deep tree are seen sooner. Changes in a deep
+	// directory before it's watched are missed, unless ReconcileInterval finds them. Zero watches everything first.
+	LazyWatchDepth int
+	// Clock is used for delete and save timing. Nil uses real time.
+	Clock clock.Clock
+	// Watcher and FS replace fsnotify and the OS filesystem, e.g. with the fakes from the montest package. Nil uses
+	// the real ones.
+	Watcher Watcher
+	FS      FS
 }
 
 func (m *MonitorOpts) OK() error {
@@ -24,21 +105,124 @@ func (m *MonitorOpts) OK() error {
 		return fmt.Errorf("must supply root path")
 	}
 
+	if slices.Contains(m.RootPaths, "") {
+		return fmt.Errorf("must not supply an empty root path")
+	}
+
+	roots := m.roots()
+	for idx, root := range roots {
+		for _, other := range roots[idx+1:] {
+			if RootOf([]string{root}, other) != "" || RootOf([]string{other}, root) != "" {
+				return fmt.Errorf("root paths %q and %q overlap", root, other)
+			}
+		}
+	}
+
+	for _, pattern := range m.IgnorePatterns {
+		if !doublestar.ValidatePattern(pattern) {
+			return fmt.Errorf("invalid ignore pattern %q", pattern)
+		}
+	}
+
+	if m.DeleteTimeout < 0 {
+		return fmt.Errorf("delete timeout must not be negative")
+	}
+
+	if m.EventBufferSize < 0 {
+		return fmt.Errorf("event buffer size must not be negative")
+	}
+
+	if m.MaxDepth < 0 || m.MaxTrackedFiles < 0 || m.LazyWatchDepth < 0 {
+		return fmt.Errorf("scan limits must not be negative")
+	}
+
+	if _, err := newTempMatcher(m.EditorProfiles, m.TempPatterns); err != nil {
+		return err
+	}
+
 	return nil
 }
 
+// roots returns RootPath followed by RootPaths, cleaned.
+func (m *MonitorOpts) roots() []string {
+	results := []string{filepath.Clean(m.RootPath)}
+	for _, root := range m.RootPaths {
+		results = append(results, filepath.Clean(root))
+	}
+
+	return results
+}
+
+// eventBufferSize is how many events can queue up from fsnotify before the monitor falls behind. Bursts like
+// "npm install" easily generate thousands of events per second.
+const eventBufferSize = 4096
+
+// DefaultEventBufferSize is used when MonitorOpts.EventBufferSize is not set.
+const DefaultEventBufferSize = eventBufferSize
+
 type Monitor struct {
+	// Events receives every event. Consumers that only want some events, or that shouldn't compete with each other for
+	// them, can each have their own stream from Subscribe instead.
 	Events chan Event
 
-	opts *MonitorOpts
+	errors chan error
+	ready  chan struct{}
+
+	opts  *MonitorOpts
+	clock clock.Clock
+	roots []string // RootPath, then RootPaths
 
-	watcher *fsnotify.Watcher
+	watcher Watcher
+	fs      FS
 	fileMap *FileMap
 
+	ignorePatterns []string
+	editorTemps    *tempMatcher
+
+	// Events that never made it to Events or a subscriber, or never made it out of the kernel
+	droppedEvents atomic.Int64
+	ignoredEvents atomic.Int64
+	overflows     atomic.Int64
+
+	// Set by Pause, while changes are tracked without being counted
+	paused atomic.Bool
+
+	// Directories under the roots being watched, and the subtrees pruned with Unwatch
+	watchedDirs map[string]struct{}
+	unwatched   []string
+	watchMutex  sync.RWMutex
+
+	// Where the initial scan of each root stopped for MaxTrackedFiles, and the roots that went past MaxDepth. Only
+	// written during the initial scan.
+	scanCutoffs map[string][]string // root -> names of the first path skipped, as from pathParts
+	deepRoots   []string
+
+	// Single files watched outside RootPath, and the directories watched to follow them
+	externalFiles map[string]struct{}
+	externalDirs  map[string]struct{}
+	externalMutex sync.RWMutex
+
+	// Symlinks followed with FollowSymlinks, and the roots with their own symlinks resolved to compare with them
+	followed     map[string]string // link -> directory it points to
+	realRoots    []string
+	symlinkMutex sync.Mutex
+
 	pendingDeletes     map[string]pendingDelete // key: name
 	pendingDeleteMutex sync.RWMutex
 	deleteTimeout      time.Duration
 
+	bursts         map[string]*writeBurst // key: name
+	debounceMutex  sync.Mutex
+	debounceWindow time.Duration
+
+	topNewFiles int
+
+	// Streams from Subscribe, which each get a buffer of bufferSize events like Events
+	subscribers     map[*subscription]struct{}
+	subscriberMutex sync.RWMutex
+	bufferSize      int
+	closed          bool
+
 	wg sync.WaitGroup
 }
 
@@ -47,23 +231,69 @@ func NewMonitor(opts *MonitorOpts) (*Monitor, error) {
 		return nil, fmt.Errorf("invalid file monitor options: %w", err)
 	}
 
-	watcher, err := fsnotify.NewWatcher()
+	editorTemps, err := newTempMatcher(opts.EditorProfiles, opts.TempPatterns)
 	if err != nil {
-		return nil, fmt.Errorf("failed to initialize fsnotify watcher: %w", err)
+		return nil, fmt.Errorf("invalid file monitor options: %w", err)
+	}
+
+	fileSystem := opts.FS
+	if fileSystem == nil {
+		fileSystem = osFS{}
+	}
+
+	watcher, err := newWatcher(opts, fileSystem)
+	if err != nil {
+		return nil, err
+	}
+
+	bufferSize := opts.EventBufferSize
+	if bufferSize == 0 {
+		bufferSize = DefaultEventBufferSize
 	}
 
 	monitor := &Monitor{
-		Events: make(chan Event),
+		Events: make(chan Event, bufferSize),
+		errors: make(chan error, 64),
+		ready:  make(chan struct{}),
 
-		opts: opts,
+		opts:  opts,
+		clock: clock.Or(opts.Clock),
+		roots: opts.roots(),
 
 		watcher: watcher,
+		fs:      fileSystem,
 		fileMap: NewFileMap(),
 
+		ignorePatterns: ignorePatterns(opts.IgnorePatterns),
+		editorTemps:    editorTemps,
+
+		watchedDirs: map[string]struct{}{},
+		scanCutoffs: map[string][]string{},
+
+		externalFiles: map[string]struct{}{},
+		externalDirs:  map[string]struct{}{},
+
+		followed:  map[string]string{},
+		realRoots: resolveRoots(fileSystem, opts.roots()),
+
 		pendingDeletes: map[string]pendingDelete{},
-		deleteTimeout:  time.Millisecond * 250,
+		deleteTimeout:  opts.DeleteTimeout,
+
+		bursts:         map[string]*writeBurst{},
+		debounceWindow: opts.DebounceWindow,
+		topNewFiles:    opts.TopNewFiles,
+
+		subscribers: map[*subscription]struct{}{},
+		bufferSize:  bufferSize,
 	}
 
+	monitor.setDefaults()
+
+	monitor.fileMap.saveWindow = opts.SaveWindow
+	monitor.fileMap.hashContents = opts.HashContents && opts.TrackWrites
+	monitor.fileMap.clock = monitor.clock
+	monitor.fileMap.fs = fileSystem
+
 	if err := monitor.populateInitialFiles(); err != nil {
 		return nil, err
 	}
@@ -71,8 +301,40 @@ func NewMonitor(opts *MonitorOpts) (*Monitor, error) {
 	return monitor, nil
 }
 
+// newWatcher returns the watcher from opts, or a polling or fsnotify watcher, depending on PollInterval.
+func newWatcher(opts *MonitorOpts, fileSystem FS) (Watcher, error) {
+	switch {
+	case opts.Watcher != nil:
+		return opts.Watcher, nil
+	case opts.PollInterval > 0:
+		return newPollingWatcher(fileSystem, clock.Or(opts.Clock), opts.PollInterval), nil
+	}
+
+	fsWatcher, err := fsnotify.NewBufferedWatcher(eventBufferSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize fsnotify watcher: %w", err)
+	}
+
+	return &fsnotifyWatcher{watcher: fsWatcher}, nil
+}
+
+// setDefaults fills in the defaults for the options that weren't set.
+func (m *Monitor) setDefaults() {
+	if m.deleteTimeout == 0 {
+		m.deleteTimeout = DefaultDeleteTimeout
+	}
+
+	if m.debounceWindow == 0 {
+		m.debounceWindow = DefaultDebounceWindow
+	}
+
+	if m.topNewFiles == 0 {
+		m.topNewFiles = DefaultTopNewFiles
+	}
+}
+
 func (m *Monitor) WatchDirRecursive(path string, initial bool) error {
-	err := filepath.WalkDir(path, func(walkPath string, dirEntry os.DirEntry, err error) error {
+	err := m.walk(path, func(walkPath string, dirEntry fs.DirEntry, err error, linked bool) error {
 		if err != nil {
 			return err
 		}
@@ -81,8 +343,12 @@ func (m *Monitor) WatchDirRecursive(path string, initial bool) error {
 			return filepath.SkipDir
 		}
 
+		if m.ignored(walkPath) {
+			return skipEntry(dirEntry)
+		}
+
 		if !initial && !m.fileMap.Has(walkPath) {
-			if err := m.fileMap.AddNewPath(walkPath); err != nil {
+			if err := m.addWalkedPath(walkPath, dirEntry, linked); err != nil {
 				return fmt.Errorf("failed to add new path %q to file map during watch walk: %w", walkPath, err)
 			}
 
@@ -93,11 +359,7 @@ func (m *Monitor) WatchDirRecursive(path string, initial bool) error {
 			return nil
 		}
 
-		if err := m.watcher.Add(walkPath); err != nil {
-			return fmt.Errorf("failed to monitor directory %q: %w", walkPath, err)
-		}
-
-		return nil
+		return m.watchDir(walkPath)
 	})
 	if err != nil {
 		return fmt.Errorf("failed to set up recursive directory watching for %q: %w", path, err)
@@ -109,12 +371,29 @@ func (m *Monitor) WatchDirRecursive(path string, initial bool) error {
 	return nil
 }
 
+// addWalkedPath adds a path found while walking a new directory. Paths reached through a newly followed symlink were
+// already there, so they're added as initial files rather than new ones, as are paths found while paused.
+func (m *Monitor) addWalkedPath(path string, entry fs.DirEntry, linked bool) error {
+	if !linked && !m.paused.Load() {
+		_, err := m.fileMap.AddNewPath(path)
+
+		return err
+	}
+
+	info, err := entry.Info()
+	if err != nil {
+		return fmt.Errorf("failed to get file info: %w", err)
+	}
+
+	return m.fileMap.AddFile(path, FileInfo{FileInfo: info, FileType: FileTypeInitial})
+}
+
 func (m *Monitor) WatchFile(path string, initial bool) error {
 	if err := m.watcher.Add(path); err != nil {
 		return fmt.Errorf("failed to monitor file %q: %w", path, err)
 	}
 
-	stat, err := os.Stat(path)
+	stat, err := m.fs.Stat(path)
 	if err != nil {
 		return fmt.Errorf("failed to stat file %q: %w", path, err)
 	}
@@ -136,52 +415,139 @@ func (m *Monitor) WatchFile(path string, initial bool) error {
 	return nil
 }
 
+// UnwatchFile stops watching a file added with WatchFile. Files that were already removed are no longer watched anyway,
+// so they aren't an error.
+func (m *Monitor) UnwatchFile(path string) error {
+	if err := m.watcher.Remove(path); err != nil && !errors.Is(err, fsnotify.ErrNonExistentWatch) {
+		return fmt.Errorf("failed to stop monitoring file %q: %w", path, err)
+	}
+
+	if err := m.fileMap.Delete(path); err != nil && !errors.Is(err, ErrUnknownFile) {
+		return fmt.Errorf("failed to remove watched file from map: %w", err)
+	}
+
+	return nil
+}
+
 func (m *Monitor) Run(ctx context.Context) {
-	if m.opts.WatchRoot {
-		if err := m.WatchDirRecursive(m.opts.RootPath, true); err != nil {
-			slog.Error("failed to watch root directory", "error", err)
-			return
-		}
+	if m.opts.WatchRoot && !m.watchRoots() {
+		close(m.ready)
+		return
 	}
 
 	m.wg.Add(2)
 
+	// Start the tickers before signaling readiness, so a test clock advanced right after Ready is seen by them
+	ticker := m.clock.NewTicker(100 * time.Millisecond)
+
 	go func() {
 		defer m.wg.Done()
 
-		m.processPendingDeletes(ctx)
+		m.processPendingDeletes(ctx, ticker)
 	}()
 
+	if m.opts.WatchRoot && m.opts.ReconcileInterval > 0 {
+		reconcileTicker := m.clock.NewTicker(m.opts.ReconcileInterval)
+
+		m.wg.Add(1)
+
+		go func() {
+			defer m.wg.Done()
+
+			m.reconcileLoop(ctx, reconcileTicker)
+		}()
+	}
+
+	if m.opts.WatchRoot && m.opts.LazyWatchDepth > 0 {
+		m.wg.Add(1)
+
+		go func() {
+			defer m.wg.Done()
+
+			m.watchDeep(ctx)
+		}()
+	}
+
 	defer m.wg.Done()
 
+	close(m.ready)
+
 	for {
 		select {
 		case <-ctx.Done():
 			return
-		case event, ok := <-m.watcher.Events:
+		case event, ok := <-m.watcher.Events():
 			if !ok {
 				return
 			}
 
-			if m.ignoreEvent(event) {
-				continue
-			}
+			m.handleWatcherEvent(event)
 
-			wrapped := Event{
-				Name: event.Name,
-				Op:   event.Op,
-			}
-
-			m.handleEvent(ctx, wrapped)
-
-		case err, ok := <-m.watcher.Errors:
+		case err, ok := <-m.watcher.Errors():
 			if !ok {
 				return
 			}
 
+			if errors.Is(err, fsnotify.ErrEventOverflow) {
+				m.overflows.Add(1)
+			}
+
 			slog.Error("watcher error", "error", err)
+			m.reportError("watch", "", err)
+		}
+	}
+}
+
+// watchRoots starts watching the roots before Run is ready, returning false if it couldn't.
+func (m *Monitor) watchRoots() bool {
+	for _, root := range m.roots {
+		if err := m.watchLevels(root, 0, m.opts.LazyWatchDepth); err != nil {
+			slog.Error("failed to watch root directory", "root", root, "error", err)
+			m.reportError("watch directory", root, err)
+
+			return false
+		}
+	}
+
+	// Polling holds nothing open for each watch, so it has no limit
+	if _, polling := m.watcher.(*pollingWatcher); !polling {
+		if err := checkWatchLimit(m.fileMap.Len()); err != nil {
+			slog.Warn("project may be too large to watch reliably", "error", err)
+			m.reportError("watch directory", m.opts.RootPath, err)
 		}
 	}
+
+	return true
+}
+
+// handleWatcherEvent handles an event from the watcher, unless it's ignored.
+func (m *Monitor) handleWatcherEvent(event fsnotify.Event) {
+	if m.ignoreEvent(event) {
+		m.ignoredEvents.Add(1)
+		return
+	}
+
+	if m.ignored(event.Name) || m.unwantedExternal(event.Name) {
+		return
+	}
+
+	wrapped := Event{
+		Name:        event.Name,
+		Op:          event.Op,
+		RenamedFrom: renamedFrom(event),
+	}
+
+	if m.paused.Load() {
+		m.handlePausedEvent(wrapped)
+		return
+	}
+
+	m.handleEvent(wrapped)
+}
+
+// Ready is closed once Run has started watching, so changes made after it is closed will be seen.
+func (m *Monitor) Ready() <-chan struct{} {
+	return m.ready
 }
 
 func (m *Monitor) FileMap() *FileMap {
@@ -195,16 +561,19 @@ func (m *Monitor) Close() {
 
 	m.wg.Wait()
 	close(m.Events)
+	m.closeSubscribers()
 }
 
-func (m *Monitor) handleEvent(ctx context.Context, event Event) {
+func (m *Monitor) handleEvent(event Event) {
 	switch event.Type() {
 	case EventTypeCreate:
-		if err := m.handleCreate(ctx, event); err != nil {
+		if err := m.handleCreate(event); err != nil {
 			slog.Error("failed to handle create event", "name", event.Name, "error", err)
 		}
 	case EventTypeRemove, EventTypeRename:
-		if err := m.handleRemoveOrRename(ctx, event); err != nil {
+		m.dropWatches(event.Name)
+
+		if err := m.handleRemoveOrRename(event); err != nil {
 			slog.Error("failed to handle remove or rename event", "name", event.Name, "error", err)
 		}
 	case EventTypeWrite:
@@ -214,31 +583,48 @@ func (m *Monitor) handleEvent(ctx context.Context, event Event) {
 			}
 		}
 
-		m.pushEvent(ctx, event)
-	case EventTypeChmod, EventTypeUnknown:
-		m.pushEvent(ctx, event)
+		m.pushWrite(event)
+	case EventTypeChmod:
+		if _, err := m.fileMap.AddPermissionChange(event.Name); err != nil && !errors.Is(err, ErrUnknownFile) {
+			slog.Debug("failed to check permissions of file", "name", event.Name, "error", err)
+		}
+
+		m.pushEvent(event)
+	case EventTypeUnknown:
+		m.pushEvent(event)
 	}
 }
 
-func (m *Monitor) pushEvent(ctx context.Context, event Event) {
-	ctx, cancel := context.WithTimeout(ctx, time.Second*5)
-	defer cancel()
+// pushEvent sends event on Events and to the matching subscribers without waiting for a reader.
+func (m *Monitor) pushEvent(event Event) {
+	event.Root = RootOf(m.roots, event.Name)
+	event.RelPath = RelPathIn(m.roots, event.Name)
+
+	m.send(m.Events, event)
+	m.publish(event)
+}
 
-	select {
-	case <-ctx.Done():
-		if err := ctx.Err(); err != nil {
-			slog.Error("context error pushing event from file monitor", "error", err)
+// send sends event on events without waiting for a reader. If events is full, the oldest queued event is dropped to
+// make room.
+func (m *Monitor) send(events chan Event, event Event) {
+	for {
+		select {
+		case events <- event:
+			return
+		default:
 		}
 
-		return
-	case m.Events <- event:
+		// A reader may have made room since, in which case there's nothing to drop
+		select {
+		case <-events:
+			m.droppedEvents.Add(1)
+		default:
+		}
 	}
 }
 
 func (m *Monitor) ignoreEvent(event fsnotify.Event) bool {
-	// Ignore VIM temp files: backups (~, .swp), swap (numeric names)
-	base := filepath.Base(event.Name)
-	if strings.HasSuffix(base, "~") || strings.HasSuffix(base, ".swp") || isNumeric(base) {
+	if m.editorTemps.match(filepath.Base(event.Name)) {
 		slog.Debug("ignoring editor file swaps")
 		return true
 	}
@@ -246,23 +632,80 @@ func (m *Monitor) ignoreEvent(event fsnotify.Event) bool {
 	return false
 }
 
-func isNumeric(s string) bool {
-	if len(s) == 0 {
+// ignorePatterns prepares IgnorePatterns for matching, anchoring those without a slash at any depth.
+func ignorePatterns(patterns []string) []string {
+	results := make([]string, 0, len(patterns))
+
+	for _, pattern := range patterns {
+		if !strings.Contains(pattern, "/") {
+			pattern = "**/" + pattern
+		}
+
+		results = append(results, pattern)
+	}
+
+	return results
+}
+
+// ignored reports whether path matches one of the ignore patterns, relative to the root it's under, is in a directory
+// passed to Unwatch, or is left out by MaxDepth or MaxTrackedFiles. Paths outside the roots, like external files, are
+// never ignored.
+func (m *Monitor) ignored(path string) bool {
+	if m.unwatchedPath(path) {
+		return true
+	}
+
+	root := RootOf(m.roots, path)
+	if root == "" || root == path {
 		return false
 	}
 
-	for _, c := range s {
-		if c < '0' || c > '9' {
-			return false
+	if m.pastLimits(root, path) {
+		return true
+	}
+
+	if len(m.ignorePatterns) == 0 {
+		return false
+	}
+
+	rel := RelPath(root, path)
+
+	rel = filepath.ToSlash(rel)
+
+	for _, pattern := range m.ignorePatterns {
+		if doublestar.MatchUnvalidated(pattern, rel) {
+			return true
 		}
 	}
 
-	return true
+	return false
+}
+
+// skipEntry skips an ignored entry during a walk, along with everything under it if it's a directory.
+func skipEntry(entry fs.DirEntry) error {
+	if entry.IsDir() {
+		return filepath.SkipDir
+	}
+
+	return nil
 }
 
 func (m *Monitor) populateInitialFiles() error {
+	for _, root := range m.roots {
+		if err := m.populateRoot(root); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *Monitor) populateRoot(root string) error {
+	// MaxTrackedFiles applies to each root separately
+	start := m.fileMap.Len()
+
 	// Scan initial files (non-dirs, skip .git)
-	err := filepath.WalkDir(m.opts.RootPath, func(path string, de os.DirEntry, err error) error {
+	err := m.walk(root, func(path string, de fs.DirEntry, err error, _ bool) error {
 		if err != nil {
 			return err
 		}
@@ -271,6 +714,14 @@ func (m *Monitor) populateInitialFiles() error {
 			return filepath.SkipDir
 		}
 
+		if err := m.checkScanLimits(root, path, de, m.fileMap.Len()-start); err != nil {
+			return err
+		}
+
+		if m.ignored(path) {
+			return skipEntry(de)
+		}
+
 		info, err := de.Info()
 		if err != nil {
 			slog.Error("failed to get file info for file", "path", path, "error", err)
@@ -282,14 +733,99 @@ func (m *Monitor) populateInitialFiles() error {
 			FileType: FileTypeInitial,
 		}
 
-		if err := m.fileMap.AddFile(path, fi); err != nil {
+		// Followed symlinks come up again as the directory they point to
+		if err := m.fileMap.AddFile(path, fi); err != nil && !errors.Is(err, ErrFileTracked) {
 			return fmt.Errorf("failed to add file %q to map: %w", path, err)
 		}
 
 		return nil
 	})
 	if err != nil {
-		return fmt.Errorf("failed to scan initial files: %w", err)
+		return fmt.Errorf("failed to scan initial files in %q: %w", root, err)
+	}
+
+	return nil
+}
+
+func (m *Monitor) reconcileLoop(ctx context.Context, ticker clock.Ticker) {
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+			for _, root := range m.roots {
+				if err := m.reconcile(root); err != nil {
+					slog.Error("failed to rescan root directory", "root", root, "error", err)
+					m.reportError("rescan directory", root, err)
+				}
+			}
+		}
+	}
+}
+
+// reconcile walks the tree under a root for paths that no event was received for, adds them to the file map,
+// watches the directories among them, and sends a create event for each. fsnotify has to watch every directory
+// separately, so anything created in a new directory before its watch was added would otherwise be missed.
+func (m *Monitor) reconcile(root string) error {
+	var found []string
+
+	paused := m.paused.Load()
+
+	err := m.fs.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) {
+			// Removed since the walk listed it
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if entry.IsDir() && entry.Name() == ".git" {
+			return filepath.SkipDir
+		}
+
+		if m.ignored(path) {
+			return skipEntry(entry)
+		}
+
+		if m.fileMap.Has(path) || m.editorTemps.match(entry.Name()) {
+			return nil
+		}
+
+		// While paused, what's found is tracked without being counted or reported
+		add := m.fileMap.AddNewPath
+		if paused {
+			add = m.fileMap.addInitialPath
+		}
+
+		if _, err := add(path); errors.Is(err, ErrFileTracked) {
+			// An event for it was handled in the meantime
+			return nil
+		} else if err != nil {
+			slog.Debug("failed to add path found by rescan", "path", path, "error", err)
+			return nil
+		}
+
+		if entry.IsDir() {
+			if err := m.watchDir(path); err != nil {
+				return err
+			}
+		}
+
+		if !paused {
+			found = append(found, path)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to rescan %q: %w", root, err)
+	}
+
+	for _, path := range found {
+		slog.Debug("found unreported path during rescan", "path", path)
+		m.pushEvent(Event{Name: path, Op: fsnotify.Create})
 	}
 
 	return nil
@@ -301,35 +837,35 @@ type pendingDelete struct {
 	initialFile bool
 }
 
-func (m *Monitor) processPendingDeletes(ctx context.Context) {
-	ticker := time.NewTicker(100 * time.Millisecond)
+func (m *Monitor) processPendingDeletes(ctx context.Context, ticker clock.Ticker) {
 	defer ticker.Stop()
 
 	for {
 		select {
 		case <-ctx.Done():
 			return
-		case <-ticker.C:
-			m.processExpiredDeletes(ctx)
+		case <-ticker.C():
+			m.processExpiredDeletes()
+			m.flushWrites()
 		}
 	}
 }
 
-func (m *Monitor) processExpiredDeletes(ctx context.Context) {
+func (m *Monitor) processExpiredDeletes() {
 	// Collect expired deletes while holding the lock briefly
 	m.pendingDeleteMutex.Lock()
 
 	expired := make([]pendingDelete, 0, len(m.pendingDeletes))
 
 	for fileName, pd := range m.pendingDeletes {
-		if time.Since(pd.timestamp) < m.deleteTimeout {
+		if m.clock.Since(pd.timestamp) < m.deleteTimeout {
 			continue
 		}
 
 		delete(m.pendingDeletes, fileName)
 
 		// Check if file still exists - if so, this was an editor swap, not a real delete
-		if _, err := os.Stat(fileName); err == nil {
+		if _, err := m.fs.Stat(fileName); err == nil {
 			// Editor swap detected via file still existing - count as a write
 			if m.opts.TrackWrites {
 				if err := m.fileMap.AddSwapWrite(fileName); err != nil {
@@ -353,6 +889,10 @@ func (m *Monitor) processExpiredDeletes(ctx context.Context) {
 			continue
 		}
 
+		if info.IsSymlink() {
+			m.unfollow(fileName)
+		}
+
 		slog.Debug("confirmed delete", "name", fileName, "type", info.FileType)
 
 		expired = append(expired, pd)
@@ -361,6 +901,6 @@ func (m *Monitor) processExpiredDeletes(ctx context.Context) {
 	m.pendingDeleteMutex.Unlock()
 
 	for _, pd := range expired {
-		m.pushEvent(ctx, pd.event)
+		m.pushEvent(pd.event)
 	}
 }
@@ -2,43 +2,191 @@ package files_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"runtime"
 	"slices"
 	"testing"
 	"time"
 
+	"github.com/cneill/mon/pkg/clock"
 	"github.com/cneill/mon/pkg/files"
+	"github.com/cneill/mon/pkg/montest"
 )
 
-func TestMonitor_CreatingFiles(t *testing.T) {
-	t.Parallel()
+// testTimeout bounds how long a test waits on the monitor before failing. Tests never wait this long unless something
+// is broken.
+const testTimeout = time.Second * 5
+
+// harness runs a monitor on a fake clock. Instead of sleeping while the kernel delivers events, tests call sync, and
+// instead of sleeping out timeouts, they advance the clock.
+type harness struct {
+	t       *testing.T
+	monitor *files.Monitor
+	clock   *clock.Fake
+	barrier string
+	cancel  context.CancelFunc
+
+	// touchBarrier writes to the barrier file in a way that produces exactly one write event
+	touchBarrier func() error
+}
 
-	tempDir := t.TempDir()
+func startMonitor(t *testing.T, opts *files.MonitorOpts) *harness {
+	t.Helper()
 
-	var numToCreate int64 = 200
+	barrier, err := os.Create(filepath.Join(opts.RootPath, "barrier"))
+	if err != nil {
+		t.Fatalf("failed to create barrier file: %v", err)
+	}
 
-	monitor, err := files.NewMonitor(&files.MonitorOpts{
-		RootPath:  tempDir,
-		WatchRoot: true,
+	t.Cleanup(func() { barrier.Close() })
+
+	return start(t, opts, barrier.Name(), func() error {
+		// A single write syscall, so exactly one event
+		_, err := barrier.Write([]byte{'.'})
+
+		return err
+	})
+}
+
+// startSimulated runs a monitor on an in-memory filesystem rooted at root.
+func startSimulated(t *testing.T, root string, opts *files.MonitorOpts) (*harness, *montest.FS, *montest.Watcher) {
+	t.Helper()
+
+	simFS := montest.NewFS(root)
+	watcher := simFS.NewWatcher()
+	barrier := filepath.Join(root, "barrier")
+
+	if err := simFS.WriteFile(barrier, nil); err != nil {
+		t.Fatalf("failed to create barrier file: %v", err)
+	}
+
+	opts.RootPath = root
+	opts.FS = simFS
+	opts.Watcher = watcher
+
+	h := start(t, opts, barrier, func() error {
+		return simFS.WriteFile(barrier, []byte{'.'})
 	})
+
+	return h, simFS, watcher
+}
+
+func start(t *testing.T, opts *files.MonitorOpts, barrier string, touchBarrier func() error) *harness {
+	t.Helper()
+
+	fakeClock := clock.NewFake()
+
+	opts.WatchRoot = true
+	opts.Clock = fakeClock
+
+	// sync waits for each barrier write to come out, which debouncing would hold until the clock is advanced
+	if opts.DebounceWindow == 0 {
+		opts.DebounceWindow = -1
+	}
+
+	monitor, err := files.NewMonitor(opts)
 	if err != nil {
 		t.Fatalf("failed to start file monitor: %v", err)
 	}
 
-	// Drain events
-	go func() {
-		for range monitor.Events {
-			continue
-		}
-	}()
-
 	ctx, cancel := context.WithCancel(t.Context())
 
 	go monitor.Run(ctx)
 
-	time.Sleep(time.Millisecond * 50)
+	<-monitor.Ready()
+
+	return &harness{
+		t:            t,
+		monitor:      monitor,
+		clock:        fakeClock,
+		barrier:      barrier,
+		cancel:       cancel,
+		touchBarrier: touchBarrier,
+	}
+}
+
+// waitFor reads events until one matches.
+func (h *harness) waitFor(match func(files.Event) bool) {
+	h.t.Helper()
+
+	deadline := time.After(testTimeout)
+
+	for {
+		select {
+		case event, ok := <-h.monitor.Events:
+			if !ok {
+				h.t.Fatalf("monitor closed before the expected event arrived")
+			}
+
+			if match(event) {
+				return
+			}
+		case <-deadline:
+			h.t.Fatalf("timed out waiting for event")
+		}
+	}
+}
+
+// sync waits until the monitor has handled every change made so far. The kernel reports events in order and the
+// monitor handles them in order, so once a write to the barrier file comes out, everything before it has too.
+func (h *harness) sync() {
+	h.t.Helper()
+
+	if err := h.touchBarrier(); err != nil {
+		h.t.Fatalf("failed to write barrier file: %v", err)
+	}
+
+	h.waitFor(func(event files.Event) bool {
+		return event.Name == h.barrier && event.Type() == files.EventTypeWrite
+	})
+}
+
+// expireDeletes settles every pending delete, as if the delete timeout had passed.
+func (h *harness) expireDeletes() {
+	h.t.Helper()
+
+	h.sync()
+
+	// Fake ticks block until they're received, so the second Advance returns only once the first tick is handled
+	h.clock.Advance(time.Second)
+	h.clock.Advance(time.Second)
+}
+
+// eventually waits for changes the monitor makes without sending an event, like paths found while walking a new
+// directory.
+func (h *harness) eventually(cond func() bool, msg string) {
+	h.t.Helper()
+
+	deadline := time.Now().Add(testTimeout)
+
+	for !cond() {
+		if time.Now().After(deadline) {
+			h.t.Fatalf("timed out waiting for %s", msg)
+		}
+
+		runtime.Gosched()
+	}
+}
+
+func (h *harness) stop() *files.Stats {
+	h.cancel()
+	h.monitor.Close()
+
+	return h.monitor.Stats(true)
+}
+
+func TestMonitor_CreatingFiles(t *testing.T) {
+	t.Parallel()
+
+	tempDir := t.TempDir()
+
+	var numToCreate int64 = 200
+
+	h := startMonitor(t, &files.MonitorOpts{RootPath: tempDir})
 
 	for fileNum := range numToCreate {
 		fileName := filepath.Join(tempDir, fmt.Sprintf("temp_file_%d.txt", fileNum))
@@ -47,12 +195,9 @@ func TestMonitor_CreatingFiles(t *testing.T) {
 		}
 	}
 
-	time.Sleep(time.Millisecond * 100)
-	cancel()
-
-	monitor.Close()
+	h.sync()
 
-	stats := monitor.Stats(true)
+	stats := h.stop()
 
 	if stats.NumFilesCreated != numToCreate {
 		t.Errorf("expected %d in NumFilesCreated, got %d", numToCreate, stats.NumFilesCreated)
@@ -63,6 +208,201 @@ func TestMonitor_CreatingFiles(t *testing.T) {
 	}
 }
 
+func TestMonitor_RelPath(t *testing.T) {
+	t.Parallel()
+
+	tempDir := t.TempDir()
+	fileName := filepath.Join(tempDir, "notes.txt")
+
+	h := startMonitor(t, &files.MonitorOpts{RootPath: tempDir})
+
+	if err := os.WriteFile(fileName, []byte("notes\n"), 0o600); err != nil {
+		t.Fatalf("failed to create file %q: %v", fileName, err)
+	}
+
+	h.waitFor(func(event files.Event) bool {
+		if event.Name != fileName {
+			return false
+		}
+
+		if event.RelPath != "notes.txt" {
+			t.Errorf("expected relative path %q, got %q", "notes.txt", event.RelPath)
+		}
+
+		return true
+	})
+
+	h.stop()
+}
+
+func TestMonitor_MultipleRoots(t *testing.T) {
+	t.Parallel()
+
+	tempDir := t.TempDir()
+	frontend := filepath.Join(tempDir, "frontend")
+	backend := filepath.Join(tempDir, "backend")
+
+	for _, dir := range []string{frontend, backend} {
+		if err := os.Mkdir(dir, 0o700); err != nil {
+			t.Fatalf("failed to create directory %q: %v", dir, err)
+		}
+	}
+
+	// Nested roots would track the same files twice
+	if _, err := files.NewMonitor(&files.MonitorOpts{RootPath: tempDir, RootPaths: []string{backend}}); err == nil {
+		t.Errorf("expected an error for overlapping roots")
+	}
+
+	h := startMonitor(t, &files.MonitorOpts{RootPath: frontend, RootPaths: []string{backend}, TrackWrites: true})
+
+	fileName := filepath.Join(backend, "api.go")
+	if err := os.WriteFile(fileName, []byte("package api\n"), 0o600); err != nil {
+		t.Fatalf("failed to create file %q: %v", fileName, err)
+	}
+
+	h.waitFor(func(event files.Event) bool {
+		if event.Name != fileName || event.Type() != files.EventTypeCreate {
+			return false
+		}
+
+		if event.Root != backend {
+			t.Errorf("expected root %q, got %q", backend, event.Root)
+		}
+
+		if expected := filepath.Join("backend", "api.go"); event.RelPath != expected {
+			t.Errorf("expected relative path %q, got %q", expected, event.RelPath)
+		}
+
+		return true
+	})
+
+	h.sync()
+
+	stats := h.stop()
+
+	if created := stats.ByRoot[backend].Created; created != 1 {
+		t.Errorf("expected 1 file created in %q, got %d", backend, created)
+	}
+
+	if created := stats.ByRoot[frontend].Created; created != 0 {
+		t.Errorf("expected no files created in %q, got %d", frontend, created)
+	}
+
+	// Files at the top of a root are grouped under the root's name
+	if created := stats.ByDirectory["backend"].Created; created != 1 {
+		t.Errorf("expected 1 file created directly in backend, got %d", created)
+	}
+
+	if stats.NumFilesCreated != 1 {
+		t.Errorf("expected 1 file created in total, got %d", stats.NumFilesCreated)
+	}
+}
+
+func TestMonitor_IgnorePatterns(t *testing.T) {
+	t.Parallel()
+
+	tempDir := t.TempDir()
+
+	for _, dir := range []string{"dist", "sub"} {
+		if err := os.Mkdir(filepath.Join(tempDir, dir), 0o700); err != nil {
+			t.Fatalf("failed to create directory %q: %v", dir, err)
+		}
+	}
+
+	for _, name := range []string{"dist/old.js", "app.log"} {
+		if err := os.WriteFile(filepath.Join(tempDir, name), nil, 0o600); err != nil {
+			t.Fatalf("failed to create file %q: %v", name, err)
+		}
+	}
+
+	h := startMonitor(t, &files.MonitorOpts{
+		RootPath:       tempDir,
+		TrackWrites:    true,
+		IgnorePatterns: []string{"dist/**", "*.log"},
+	})
+
+	if h.monitor.FileMap().Has(filepath.Join(tempDir, "dist", "old.js")) {
+		t.Errorf("expected ignored file from the initial scan to be left out of the file map")
+	}
+
+	for _, name := range []string{"dist/new.js", "sub/debug.log", "sub/keep.txt", "app.log"} {
+		if err := os.WriteFile(filepath.Join(tempDir, name), []byte("content\n"), 0o600); err != nil {
+			t.Fatalf("failed to write file %q: %v", name, err)
+		}
+	}
+
+	h.sync()
+
+	stats := h.stop()
+
+	if expected := []string{filepath.Join(tempDir, "sub", "keep.txt")}; !slices.Equal(stats.NewFiles, expected) {
+		t.Errorf("expected NewFiles %v, got %v", expected, stats.NewFiles)
+	}
+
+	if _, ok := stats.WrittenFiles[filepath.Join(tempDir, "app.log")]; ok {
+		t.Errorf("expected writes to ignored file to be left out of WrittenFiles, got %v", stats.WrittenFiles)
+	}
+}
+
+func TestMonitor_FollowSymlinks(t *testing.T) {
+	t.Parallel()
+
+	tempDir := t.TempDir()
+	outsideDir := t.TempDir()
+	outsideFile := filepath.Join(outsideDir, "outside.txt")
+
+	if err := os.WriteFile(outsideFile, nil, 0o600); err != nil {
+		t.Fatalf("failed to create file %q outside the root: %v", outsideFile, err)
+	}
+
+	h := startMonitor(t, &files.MonitorOpts{RootPath: tempDir, FollowSymlinks: true})
+
+	dirLink := filepath.Join(tempDir, "outside")
+	links := map[string]string{
+		dirLink:                              outsideDir,
+		filepath.Join(tempDir, "file_link"):  outsideFile,
+		filepath.Join(tempDir, "loop"):       tempDir,
+		filepath.Join(tempDir, "other_link"): outsideDir,
+	}
+
+	for link, target := range links {
+		if err := os.Symlink(target, link); err != nil {
+			t.Fatalf("failed to create symlink %q: %v", link, err)
+		}
+	}
+
+	// Followed directories are walked after the same delay as new ones
+	h.sync()
+	h.clock.Advance(time.Second)
+
+	linkedFile := filepath.Join(dirLink, "outside.txt")
+	otherLinkedFile := filepath.Join(tempDir, "other_link", "outside.txt")
+
+	h.eventually(func() bool {
+		return h.monitor.FileMap().Has(linkedFile) || h.monitor.FileMap().Has(otherLinkedFile)
+	}, "linked file to be tracked")
+
+	// Only one of the links to the same directory is followed, and the loop back to the root isn't followed at all
+	if h.monitor.FileMap().Has(linkedFile) && h.monitor.FileMap().Has(otherLinkedFile) {
+		t.Errorf("both links to %q were followed", outsideDir)
+	}
+
+	if h.monitor.FileMap().Has(filepath.Join(tempDir, "loop", "barrier")) {
+		t.Errorf("symlink back to the root was followed")
+	}
+
+	stats := h.stop()
+
+	if stats.NumSymlinksCreated != 4 {
+		t.Errorf("expected NumSymlinksCreated == 4, got %d", stats.NumSymlinksCreated)
+	}
+
+	// Neither the links nor the files found through them were created in the session
+	if stats.NumFilesCreated != 0 {
+		t.Errorf("expected NumFilesCreated == 0, got %d (%v)", stats.NumFilesCreated, stats.NewFiles)
+	}
+}
+
 func TestMonitor_DeletingFiles(t *testing.T) {
 	t.Parallel()
 
@@ -81,25 +421,7 @@ func TestMonitor_DeletingFiles(t *testing.T) {
 		}
 	}
 
-	monitor, err := files.NewMonitor(&files.MonitorOpts{
-		RootPath:  tempDir,
-		WatchRoot: true,
-	})
-	if err != nil {
-		t.Fatalf("failed to start file monitor: %v", err)
-	}
-
-	// Drain events
-	go func() {
-		for range monitor.Events {
-			continue
-		}
-	}()
-
-	ctx, cancel := context.WithCancel(t.Context())
-	go monitor.Run(ctx)
-
-	time.Sleep(time.Millisecond * 100)
+	h := startMonitor(t, &files.MonitorOpts{RootPath: tempDir})
 
 	for _, fileName := range toDelete {
 		if err := os.Remove(fileName); err != nil {
@@ -107,13 +429,9 @@ func TestMonitor_DeletingFiles(t *testing.T) {
 		}
 	}
 
-	// Allow pending deletes to settle
-	time.Sleep(time.Millisecond * 500)
-
-	cancel()
-	monitor.Close()
+	h.expireDeletes()
 
-	stats := monitor.Stats(true)
+	stats := h.stop()
 	numToDelete := int64(len(toDelete))
 
 	if stats.NumFilesDeleted != numToDelete {
@@ -125,36 +443,17 @@ func TestMonitor_DeletingFiles(t *testing.T) {
 	}
 }
 
-func TestMonitor_NewDir(t *testing.T) { //nolint:cyclop,funlen // not worth breaking this up
+func TestMonitor_NewDir(t *testing.T) { //nolint:cyclop // not worth breaking this up
 	t.Parallel()
 
 	tempDir := t.TempDir()
 
-	monitor, err := files.NewMonitor(&files.MonitorOpts{
-		RootPath:  tempDir,
-		WatchRoot: true,
-	})
-	if err != nil {
-		t.Fatalf("failed to start file monitor: %v", err)
-	}
+	h := startMonitor(t, &files.MonitorOpts{RootPath: tempDir})
 
 	testFile := filepath.Join(tempDir, "test_file.txt")
 	nestedDir := filepath.Join(tempDir, "path", "to", "new", "dir")
 	nestedTestFile := filepath.Join(nestedDir, "nested_test_file.txt")
 
-	// Drain events
-	go func() {
-		for event := range monitor.Events {
-			fmt.Printf("%q event for %q\n", event.Op.String(), event.Name)
-		}
-	}()
-
-	ctx, cancel := context.WithCancel(t.Context())
-
-	go monitor.Run(ctx)
-
-	time.Sleep(time.Millisecond * 100)
-
 	if _, err := os.Create(testFile); err != nil {
 		t.Fatalf("failed to create test file %q: %v", testFile, err)
 	}
@@ -163,16 +462,18 @@ func TestMonitor_NewDir(t *testing.T) { //nolint:cyclop,funlen // not worth brea
 		t.Fatalf("failed to create nested directory %q in temp dir: %v", nestedDir, err)
 	}
 
-	// Make sure we have started monitoring all the nested directories first...
-	time.Sleep(time.Millisecond * 500)
+	// New directories are walked after a delay, to catch e.g. mkdir -p calls that rapidly create nested directories
+	h.sync()
+	h.clock.Advance(time.Second)
 
 	if _, err := os.Create(nestedTestFile); err != nil {
 		t.Fatalf("failed to create test file %q in nested dir: %v", nestedTestFile, err)
 	}
 
-	time.Sleep(time.Millisecond * 200)
+	// The nested file is picked up either by the walk or by the watch the walk sets up, depending on which comes first
+	h.eventually(func() bool { return h.monitor.FileMap().Has(nestedTestFile) }, "nested file to be tracked")
 
-	stats := monitor.Stats(true)
+	stats := h.monitor.Stats(true)
 
 	if stats.NumFilesCreated != 6 {
 		t.Errorf("expected NumFilesCreated == 6, got %d", stats.NumFilesCreated)
@@ -194,13 +495,9 @@ func TestMonitor_NewDir(t *testing.T) { //nolint:cyclop,funlen // not worth brea
 		t.Fatalf("failed to delete test file %q: %v", testFile, err)
 	}
 
-	// Make sure we process the delete event...
-	time.Sleep(time.Millisecond * 500)
-
-	cancel()
-	monitor.Close()
+	h.expireDeletes()
 
-	stats = monitor.Stats(true)
+	stats = h.stop()
 
 	if stats.NumFilesCreated != 5 {
 		t.Errorf("expected NumFilesCreated == 5 after delete, got %d", stats.NumFilesCreated)
@@ -230,26 +527,7 @@ func TestMonitor_EditorSwapIgnored(t *testing.T) {
 		t.Fatalf("failed to create test file: %v", err)
 	}
 
-	monitor, err := files.NewMonitor(&files.MonitorOpts{
-		RootPath:  tempDir,
-		WatchRoot: true,
-	})
-	if err != nil {
-		t.Fatalf("failed to start file monitor: %v", err)
-	}
-
-	// Drain events
-	go func() {
-		for range monitor.Events {
-			continue
-		}
-	}()
-
-	ctx, cancel := context.WithCancel(t.Context())
-	go monitor.Run(ctx)
-
-	// Let the monitor start up
-	time.Sleep(time.Millisecond * 100)
+	h := startMonitor(t, &files.MonitorOpts{RootPath: tempDir})
 
 	// Simulate editor swap pattern (like vim or vscode):
 	// 1. Write new content to a temp file
@@ -269,13 +547,9 @@ func TestMonitor_EditorSwapIgnored(t *testing.T) {
 		t.Fatalf("failed to rename swap file to original: %v", err)
 	}
 
-	// Wait for pending deletes to be processed (deleteTimeout is 250ms, check interval is 100ms)
-	time.Sleep(time.Millisecond * 500)
-
-	cancel()
-	monitor.Close()
+	h.expireDeletes()
 
-	stats := monitor.Stats(true)
+	stats := h.stop()
 
 	// The original file should NOT be counted as deleted since it still exists
 	if stats.NumFilesDeleted != 0 {
@@ -287,7 +561,7 @@ func TestMonitor_EditorSwapIgnored(t *testing.T) {
 	}
 }
 
-func TestMonitor_EditorSwapMultipleEdits(t *testing.T) { //nolint:cyclop
+func TestMonitor_EditorSwapMultipleEdits(t *testing.T) {
 	t.Parallel()
 
 	tempDir := t.TempDir()
@@ -302,24 +576,7 @@ func TestMonitor_EditorSwapMultipleEdits(t *testing.T) { //nolint:cyclop
 		}
 	}
 
-	monitor, err := files.NewMonitor(&files.MonitorOpts{
-		RootPath:  tempDir,
-		WatchRoot: true,
-	})
-	if err != nil {
-		t.Fatalf("failed to start file monitor: %v", err)
-	}
-
-	go func() {
-		for range monitor.Events {
-			continue
-		}
-	}()
-
-	ctx, cancel := context.WithCancel(t.Context())
-	go monitor.Run(ctx)
-
-	time.Sleep(time.Millisecond * 100)
+	h := startMonitor(t, &files.MonitorOpts{RootPath: tempDir})
 
 	// Simulate multiple rapid editor saves on both files
 	for i := range 5 {
@@ -339,17 +596,12 @@ func TestMonitor_EditorSwapMultipleEdits(t *testing.T) { //nolint:cyclop
 			}
 		}
 
-		// Small delay between edits
-		time.Sleep(time.Millisecond * 50)
+		h.sync()
 	}
 
-	// Wait for all pending deletes to be processed
-	time.Sleep(time.Millisecond * 500)
-
-	cancel()
-	monitor.Close()
+	h.expireDeletes()
 
-	stats := monitor.Stats(true)
+	stats := h.stop()
 
 	if stats.NumFilesDeleted != 0 {
 		t.Errorf("expected NumFilesDeleted == 0 after multiple editor swaps, got %d", stats.NumFilesDeleted)
@@ -360,7 +612,7 @@ func TestMonitor_EditorSwapMultipleEdits(t *testing.T) { //nolint:cyclop
 	}
 }
 
-func TestMonitor_RealDeleteStillCounted(t *testing.T) { //nolint:cyclop
+func TestMonitor_RealDeleteStillCounted(t *testing.T) {
 	t.Parallel()
 
 	tempDir := t.TempDir()
@@ -375,24 +627,7 @@ func TestMonitor_RealDeleteStillCounted(t *testing.T) { //nolint:cyclop
 		}
 	}
 
-	monitor, err := files.NewMonitor(&files.MonitorOpts{
-		RootPath:  tempDir,
-		WatchRoot: true,
-	})
-	if err != nil {
-		t.Fatalf("failed to start file monitor: %v", err)
-	}
-
-	go func() {
-		for range monitor.Events {
-			continue
-		}
-	}()
-
-	ctx, cancel := context.WithCancel(t.Context())
-	go monitor.Run(ctx)
-
-	time.Sleep(time.Millisecond * 100)
+	h := startMonitor(t, &files.MonitorOpts{RootPath: tempDir})
 
 	// Simulate editor swap on keepFile
 	swapFile := keepFile + ".swp"
@@ -413,13 +648,9 @@ func TestMonitor_RealDeleteStillCounted(t *testing.T) { //nolint:cyclop
 		t.Fatalf("failed to remove deleteFile: %v", err)
 	}
 
-	// Wait for pending deletes to process
-	time.Sleep(time.Millisecond * 500)
-
-	cancel()
-	monitor.Close()
+	h.expireDeletes()
 
-	stats := monitor.Stats(true)
+	stats := h.stop()
 
 	// Only the real delete should be counted
 	if stats.NumFilesDeleted != 1 {
@@ -430,3 +661,1176 @@ func TestMonitor_RealDeleteStillCounted(t *testing.T) { //nolint:cyclop
 		t.Errorf("expected DeletedFiles to contain only %q, got %v", deleteFile, stats.DeletedFiles)
 	}
 }
+
+func TestMonitor_EditorProfiles(t *testing.T) {
+	t.Parallel()
+
+	root := filepath.FromSlash("/project")
+	temps := []string{"#notes.txt#", "main.go___jb_tmp___", "main.go.vsctmp", "4913", "notes.bak"}
+
+	tests := []struct {
+		name    string
+		opts    *files.MonitorOpts
+		created int64 // of temps
+	}{
+		{name: "every profile", opts: &files.MonitorOpts{}, created: 1},
+		{name: "vim only", opts: &files.MonitorOpts{EditorProfiles: []string{"vim"}}, created: 4},
+		{name: "extra patterns", opts: &files.MonitorOpts{EditorProfiles: []string{}, TempPatterns: []string{"*.bak"}}, created: 4},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			test.opts.TrackWrites = true
+			h, simFS, _ := startSimulated(t, root, test.opts)
+
+			for _, name := range temps {
+				if err := simFS.WriteFile(filepath.Join(root, name), nil); err != nil {
+					t.Fatalf("failed to create file %q: %v", name, err)
+				}
+			}
+
+			h.sync()
+
+			if stats := h.stop(); stats.NumFilesCreated != test.created {
+				t.Errorf("expected %d files created, got %d (%v)", test.created, stats.NumFilesCreated, stats.NewFiles)
+			}
+		})
+	}
+
+	if _, err := files.NewMonitor(&files.MonitorOpts{RootPath: root, EditorProfiles: []string{"ed"}}); err == nil {
+		t.Errorf("expected an error for an unknown editor profile")
+	}
+}
+
+func TestMonitor_DeleteTimeout(t *testing.T) {
+	t.Parallel()
+
+	root := filepath.FromSlash("/project")
+	h, simFS, _ := startSimulated(t, root, &files.MonitorOpts{DeleteTimeout: time.Second * 5})
+
+	doomedFile := filepath.Join(root, "doomed.txt")
+	if err := simFS.WriteFile(doomedFile, nil); err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+
+	h.sync()
+
+	if err := simFS.Remove(doomedFile); err != nil {
+		t.Fatalf("failed to remove file: %v", err)
+	}
+
+	// Two seconds in, well past the default timeout, the delete is still pending
+	h.expireDeletes()
+
+	if deleted := h.monitor.Stats(false).NumFilesDeleted; deleted != 0 {
+		t.Errorf("expected the delete to still be pending, got %d deleted", deleted)
+	}
+
+	// Fake ticks block until they're received, so the last Advance returns once the one before it is handled
+	for range 5 {
+		h.clock.Advance(time.Second)
+	}
+
+	h.waitFor(func(event files.Event) bool {
+		return event.Name == doomedFile && event.Type() == files.EventTypeRemove
+	})
+
+	h.stop()
+}
+
+func TestMonitor_EventBufferOverflow(t *testing.T) {
+	t.Parallel()
+
+	root := filepath.FromSlash("/project")
+	h, simFS, _ := startSimulated(t, root, &files.MonitorOpts{EventBufferSize: 2})
+
+	// Nothing reads Events until all six events are handled, so only the newest two are left by then
+	for idx := range 5 {
+		if err := simFS.WriteFile(filepath.Join(root, fmt.Sprintf("generated%d.txt", idx)), nil); err != nil {
+			t.Fatalf("failed to create file: %v", err)
+		}
+	}
+
+	if err := simFS.WriteFile(h.barrier, []byte{'.'}); err != nil {
+		t.Fatalf("failed to write barrier file: %v", err)
+	}
+
+	h.eventually(func() bool {
+		return h.monitor.Stats(false).EventsDropped == 4
+	}, "events to be dropped")
+
+	event := <-h.monitor.Events
+	if want := filepath.Join(root, "generated4.txt"); event.Name != want {
+		t.Errorf("expected the oldest event left to be for %q, got %q", want, event.Name)
+	}
+
+	if event := <-h.monitor.Events; event.Name != h.barrier {
+		t.Errorf("expected the barrier event last, got %q", event.Name)
+	}
+
+	stats := h.stop()
+
+	if stats.NumFilesCreated != 5 {
+		t.Errorf("expected 5 files created despite the dropped events, got %d", stats.NumFilesCreated)
+	}
+
+	if stats.EventsDropped != 4 {
+		t.Errorf("expected 4 dropped events, got %d", stats.EventsDropped)
+	}
+}
+
+func TestMonitor_DebounceWindow(t *testing.T) {
+	t.Parallel()
+
+	root := filepath.FromSlash("/project")
+	h, simFS, _ := startSimulated(t, root, &files.MonitorOpts{TrackWrites: true, DebounceWindow: time.Second})
+	fileName := filepath.Join(root, "busy.txt")
+
+	for idx := range 10 {
+		if err := simFS.WriteFile(fileName, []byte{byte(idx)}); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+
+	if err := h.touchBarrier(); err != nil {
+		t.Fatalf("failed to write barrier file: %v", err)
+	}
+
+	// Only the first write of the burst comes out before the window closes
+	var sent, events int64
+
+	h.waitFor(func(event files.Event) bool {
+		if event.Name == fileName && event.Type() == files.EventTypeWrite {
+			sent += event.Count
+			events++
+		}
+
+		return event.Name == h.barrier
+	})
+
+	if events != 1 || sent != 1 {
+		t.Errorf("expected one write event for the first write, got %d standing for %d writes", events, sent)
+	}
+
+	// Fake ticks block until they're received, so the second Advance returns only once the first tick is handled
+	h.clock.Advance(time.Second)
+	h.clock.Advance(time.Second)
+
+	var held int64
+
+	h.waitFor(func(event files.Event) bool {
+		held = event.Count
+		return event.Name == fileName && event.Type() == files.EventTypeWrite
+	})
+
+	// Every write is still counted, whether or not it was sent on its own
+	if raw := h.stop().RawWrittenFiles[fileName]; sent+held != raw {
+		t.Errorf("expected the write events to stand for all %d writes, got %d + %d", raw, sent, held)
+	}
+}
+
+func TestMonitor_Subscribe(t *testing.T) {
+	t.Parallel()
+
+	root := filepath.FromSlash("/project")
+	h, simFS, _ := startSimulated(t, root, &files.MonitorOpts{EventBufferSize: 5})
+
+	creates, cancelCreates := h.monitor.Subscribe(files.EventTypes(files.EventTypeCreate))
+	defer cancelCreates()
+
+	// Never read, so it falls behind along with Events
+	_, cancelStalled := h.monitor.Subscribe(nil)
+
+	for idx := range 5 {
+		if err := simFS.WriteFile(filepath.Join(root, fmt.Sprintf("new%d.txt", idx)), nil); err != nil {
+			t.Fatalf("failed to create file: %v", err)
+		}
+	}
+
+	// Writes overflow the unread buffers, but aren't sent to the creates subscriber at all
+	for idx := range 3 {
+		if err := simFS.WriteFile(filepath.Join(root, "new0.txt"), []byte{byte(idx)}); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+
+	h.eventually(func() bool {
+		return h.monitor.Stats(false).EventsDropped > 0
+	}, "events to be dropped")
+
+	for idx := range 5 {
+		want := filepath.Join(root, fmt.Sprintf("new%d.txt", idx))
+		if event := <-creates; event.Name != want || event.Type() != files.EventTypeCreate {
+			t.Errorf("expected a create for %q, got %s for %q", want, event.Type(), event.Name)
+		}
+	}
+
+	cancelStalled()
+	cancelStalled()
+
+	h.stop()
+
+	if event, ok := <-creates; ok {
+		t.Errorf("expected the stream to be closed by Close, got %+v", event)
+	}
+
+	late, _ := h.monitor.Subscribe(nil)
+	if _, ok := <-late; ok {
+		t.Errorf("expected a subscription after Close to be closed")
+	}
+}
+
+func TestMonitor_SaveCoalescing(t *testing.T) {
+	t.Parallel()
+
+	tempDir := t.TempDir()
+
+	testFile := filepath.Join(tempDir, "document.txt")
+	if err := os.WriteFile(testFile, []byte("initial content"), 0o644); err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+
+	h := startMonitor(t, &files.MonitorOpts{
+		RootPath:    tempDir,
+		TrackWrites: true,
+		SaveWindow:  time.Millisecond * 300,
+	})
+
+	// Two bursts of writes, separated by more than the save window
+	for burst := range 2 {
+		for i := range 3 {
+			if err := os.WriteFile(testFile, fmt.Appendf([]byte{}, "content %d-%d", burst, i), 0o644); err != nil {
+				t.Fatalf("failed to write test file: %v", err)
+			}
+		}
+
+		h.sync()
+		h.clock.Advance(time.Millisecond * 500)
+	}
+
+	stats := h.stop()
+
+	if writes := stats.WrittenFiles[testFile]; writes != 2 {
+		t.Errorf("expected 2 coalesced writes, got %d", writes)
+	}
+
+	// inotify may merge identical unread events, so the exact raw count isn't deterministic
+	if rawWrites := stats.RawWrittenFiles[testFile]; rawWrites < 2 {
+		t.Errorf("expected at least 2 raw writes, got %d", rawWrites)
+	}
+}
+
+func TestMonitor_RenamePairing(t *testing.T) {
+	t.Parallel()
+
+	if runtime.GOOS != "linux" && runtime.GOOS != "windows" {
+		t.Skip("renames are only paired on Linux and Windows")
+	}
+
+	tempDir := t.TempDir()
+
+	movedFile := filepath.Join(tempDir, "old_name.txt")
+	if err := os.WriteFile(movedFile, []byte("content"), 0o644); err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+
+	savedFile := filepath.Join(tempDir, "document.txt")
+	if err := os.WriteFile(savedFile, []byte("initial content"), 0o644); err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+
+	h := startMonitor(t, &files.MonitorOpts{
+		RootPath:    tempDir,
+		TrackWrites: true,
+	})
+
+	// A plain move within the tree
+	newName := filepath.Join(tempDir, "new_name.txt")
+	if err := os.Rename(movedFile, newName); err != nil {
+		t.Fatalf("failed to rename file: %v", err)
+	}
+
+	// An atomic save: write a temp file, then rename it over the original
+	tempFile := filepath.Join(tempDir, "document.txt.tmp")
+	if err := os.WriteFile(tempFile, []byte("updated content"), 0o644); err != nil {
+		t.Fatalf("failed to create temp file: %v", err)
+	}
+
+	if err := os.Rename(tempFile, savedFile); err != nil {
+		t.Fatalf("failed to rename temp file over original: %v", err)
+	}
+
+	// Paired renames don't wait for the pending delete timeout, so the clock is never advanced
+	h.sync()
+
+	stats := h.stop()
+
+	// A move is neither a delete nor a create
+	if len(stats.DeletedFiles) > 0 || len(stats.NewFiles) > 0 {
+		t.Errorf("expected no deleted or new files, got %v and %v", stats.DeletedFiles, stats.NewFiles)
+	}
+
+	if stats.NumFilesMoved != 1 {
+		t.Errorf("expected 1 file moved, got %d", stats.NumFilesMoved)
+	}
+
+	if writes := stats.WrittenFiles[savedFile]; writes != 1 {
+		t.Errorf("expected 1 write to %s, got %d", savedFile, writes)
+	}
+}
+
+func TestMonitor_Reconcile(t *testing.T) {
+	t.Parallel()
+
+	root := filepath.FromSlash("/project")
+
+	h, simFS, watcher := startSimulated(t, root, &files.MonitorOpts{
+		TrackWrites:       true,
+		ReconcileInterval: time.Millisecond * 100,
+	})
+
+	deepDir := filepath.Join(root, "nested", "deep")
+	lostFile := filepath.Join(deepDir, "lost.go")
+
+	// Only the root is watched, so the events for everything under "nested" are lost until it's walked
+	if err := simFS.MkdirAll(deepDir); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+
+	if err := simFS.WriteFile(lostFile, []byte("package lost")); err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+
+	h.sync()
+
+	// Rescan before the delayed walk of the new directory
+	h.clock.Advance(time.Millisecond * 100)
+
+	h.waitFor(func(event files.Event) bool {
+		return event.Name == lostFile && event.Type() == files.EventTypeCreate
+	})
+
+	if !slices.Contains(watcher.WatchList(), deepDir) {
+		t.Errorf("expected directory found by rescan to be watched, got %v", watcher.WatchList())
+	}
+
+	stats := h.stop()
+
+	if !slices.Contains(stats.NewFiles, lostFile) {
+		t.Errorf("expected file found by rescan in NewFiles, got %v", stats.NewFiles)
+	}
+}
+
+func TestMonitor_Polling(t *testing.T) {
+	t.Parallel()
+
+	root := filepath.FromSlash("/project")
+	simFS := montest.NewFS(root)
+	barrier := filepath.Join(root, "barrier")
+
+	if err := simFS.WriteFile(barrier, nil); err != nil {
+		t.Fatalf("failed to create barrier file: %v", err)
+	}
+
+	// No watcher is attached to the FS, so changes are only seen by polling, and the harness's barrier can't be used
+	// until a poll is triggered
+	h := start(t, &files.MonitorOpts{RootPath: root, FS: simFS, TrackWrites: true, PollInterval: time.Second}, barrier,
+		func() error { return simFS.WriteFile(barrier, []byte{'.'}) })
+
+	srcDir := filepath.Join(root, "src")
+	fileName := filepath.Join(root, "notes.txt")
+
+	poll := func(name string, eventType files.EventType) {
+		t.Helper()
+
+		h.clock.Advance(time.Second)
+		h.waitFor(func(event files.Event) bool { return event.Name == name && event.Type() == eventType })
+	}
+
+	if err := simFS.WriteFile(fileName, []byte("notes")); err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+
+	poll(fileName, files.EventTypeCreate)
+
+	if err := simFS.WriteFile(fileName, []byte("more notes")); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	poll(fileName, files.EventTypeWrite)
+
+	if written := h.monitor.Stats(true).RawWrittenFiles; written[fileName] != 1 {
+		t.Errorf("expected 1 write to %q, got %v", fileName, written)
+	}
+
+	if err := simFS.MkdirAll(srcDir); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+
+	poll(srcDir, files.EventTypeCreate)
+
+	if err := simFS.Remove(fileName); err != nil {
+		t.Fatalf("failed to remove file: %v", err)
+	}
+
+	// A poll reports removes before other changes, so once the barrier's write comes out, the remove is pending
+	if err := simFS.WriteFile(barrier, []byte{'.'}); err != nil {
+		t.Fatalf("failed to write barrier file: %v", err)
+	}
+
+	poll(barrier, files.EventTypeWrite)
+
+	// Fake ticks block until they're received, so the second Advance returns only once the first tick is handled
+	h.clock.Advance(time.Second)
+	h.clock.Advance(time.Second)
+	h.waitFor(func(event files.Event) bool { return event.Name == fileName && event.Type() == files.EventTypeRemove })
+
+	stats := h.stop()
+
+	// The file was created and removed during the session, so only the directory is left
+	if expected := []string{srcDir}; !slices.Equal(stats.NewFiles, expected) {
+		t.Errorf("expected NewFiles %v, got %v", expected, stats.NewFiles)
+	}
+}
+
+func TestMonitor_PermissionChanges(t *testing.T) {
+	t.Parallel()
+
+	root := filepath.FromSlash("/project")
+
+	h, simFS, _ := startSimulated(t, root, &files.MonitorOpts{})
+
+	script := filepath.Join(root, "build.sh")
+
+	if err := simFS.WriteFile(script, []byte("#!/bin/sh\n")); err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+
+	// Chmod events that leave the mode alone, like the ones from a touch, aren't permission changes
+	if err := simFS.Chmod(script, 0o644); err != nil {
+		t.Fatalf("failed to chmod file: %v", err)
+	}
+
+	h.sync()
+
+	if changes := h.monitor.Stats(false).NumPermissionChanges; changes != 0 {
+		t.Errorf("expected no permission changes before the mode changed, got %d", changes)
+	}
+
+	if err := simFS.Chmod(script, 0o755); err != nil {
+		t.Fatalf("failed to chmod file: %v", err)
+	}
+
+	h.sync()
+
+	stats := h.stop()
+
+	if stats.NumPermissionChanges != 1 {
+		t.Errorf("expected 1 permission change, got %d", stats.NumPermissionChanges)
+	}
+
+	if mode, ok := stats.ChangedPermissions[script]; !ok || mode != 0o755 {
+		t.Errorf("expected %s to be listed with mode %v, got %v", script, fs.FileMode(0o755), stats.ChangedPermissions)
+	}
+}
+
+func TestMonitor_TopNewFilesBySize(t *testing.T) {
+	t.Parallel()
+
+	root := filepath.FromSlash("/project")
+
+	h, simFS, _ := startSimulated(t, root, &files.MonitorOpts{TopNewFiles: 2})
+
+	small := filepath.Join(root, "small.txt")
+	large := filepath.Join(root, "large.bin")
+	medium := filepath.Join(root, "medium.json")
+
+	for path, size := range map[string]int{small: 10, large: 4096, medium: 100} {
+		if err := simFS.WriteFile(path, make([]byte, size)); err != nil {
+			t.Fatalf("failed to create file: %v", err)
+		}
+	}
+
+	h.sync()
+
+	if top := h.monitor.Stats(false).TopNewFilesBySize; top != nil {
+		t.Errorf("expected no largest files outside final stats, got %v", top)
+	}
+
+	want := []files.FileSize{{Path: large, Size: 4096}, {Path: medium, Size: 100}}
+	if top := h.stop().TopNewFilesBySize; !slices.Equal(top, want) {
+		t.Errorf("expected the largest files to be %v, got %v", want, top)
+	}
+}
+
+func TestMonitor_Simulated(t *testing.T) { //nolint:cyclop // one scenario, checked step by step
+	t.Parallel()
+
+	root := filepath.FromSlash("/project")
+
+	h, simFS, watcher := startSimulated(t, root, &files.MonitorOpts{TrackWrites: true})
+
+	srcDir := filepath.Join(root, "src")
+	mainFile := filepath.Join(srcDir, "main.go")
+	doomedFile := filepath.Join(root, "doomed.txt")
+
+	if err := simFS.MkdirAll(srcDir); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+
+	if err := simFS.WriteFile(doomedFile, []byte("bye")); err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+
+	// New directories are walked after a delay
+	h.sync()
+	h.clock.Advance(time.Second)
+	h.eventually(func() bool { return slices.Contains(watcher.WatchList(), srcDir) }, "new directory to be watched")
+
+	if err := simFS.WriteFile(mainFile, []byte("package main")); err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+
+	if err := simFS.WriteFile(mainFile, []byte("package main\n")); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	if err := simFS.Remove(doomedFile); err != nil {
+		t.Fatalf("failed to remove file: %v", err)
+	}
+
+	// Errors are handled separately from events, so the barrier doesn't cover them
+	watcher.Overflow()
+
+	select {
+	case <-h.monitor.Errors():
+	case <-time.After(testTimeout):
+		t.Fatalf("timed out waiting for the overflow to be reported")
+	}
+
+	h.expireDeletes()
+
+	stats := h.stop()
+
+	if want := []string{srcDir, mainFile}; !slices.Equal(stats.NewFiles, want) {
+		t.Errorf("expected NewFiles to be %v, got %v", want, stats.NewFiles)
+	}
+
+	// The file was created and deleted during the session, so it was never really there
+	if len(stats.DeletedFiles) != 0 {
+		t.Errorf("expected DeletedFiles to be empty, got %v", stats.DeletedFiles)
+	}
+
+	if writes := stats.WrittenFiles[mainFile]; writes != 2 {
+		t.Errorf("expected 2 writes to %s, got %d", mainFile, writes)
+	}
+
+	// The directory isn't counted, and neither is the deleted file, which wasn't there at the start
+	if byExt, want := stats.ByExtension[".go"], (files.ExtensionStats{Created: 1, Written: 1, Writes: 2}); byExt != want {
+		t.Errorf("expected .go stats %+v, got %+v", want, byExt)
+	}
+
+	if byExt, ok := stats.ByExtension[".txt"]; ok {
+		t.Errorf("expected no .txt stats, got %+v", byExt)
+	}
+
+	if byDir, want := stats.ByDirectory["src"], (files.DirectoryStats{Created: 1, Written: 1, Writes: 2}); byDir != want {
+		t.Errorf("expected src/ stats %+v, got %+v", want, byDir)
+	}
+
+	// Only the barrier file was written at the top of the project
+	if byDir := stats.ByDirectory["."]; byDir.Created != 0 || byDir.Deleted != 0 {
+		t.Errorf("expected nothing created or deleted at the top of the project, got %+v", byDir)
+	}
+
+	if stats.EventOverflows != 1 {
+		t.Errorf("expected 1 event overflow, got %d", stats.EventOverflows)
+	}
+}
+
+func TestMonitor_Moves(t *testing.T) {
+	t.Parallel()
+
+	root := filepath.FromSlash("/project")
+
+	// The simulated filesystem doesn't pair renames or have inodes, so moves are matched by size
+	h, simFS, watcher := startSimulated(t, root, &files.MonitorOpts{TrackWrites: true})
+
+	oldDir := filepath.Join(root, "old")
+	oldFile := filepath.Join(root, "notes.txt")
+
+	if err := simFS.MkdirAll(oldDir); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+
+	if err := simFS.WriteFile(filepath.Join(oldDir, "main.go"), []byte("package main")); err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+
+	if err := simFS.WriteFile(oldFile, []byte("notes")); err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+
+	h.sync()
+	h.clock.Advance(time.Second)
+	h.eventually(func() bool { return slices.Contains(watcher.WatchList(), oldDir) }, "new directory to be watched")
+
+	newDir := filepath.Join(root, "new")
+	newFile := filepath.Join(newDir, "notes.txt")
+
+	if err := simFS.Rename(oldDir, newDir); err != nil {
+		t.Fatalf("failed to move directory: %v", err)
+	}
+
+	h.waitFor(func(event files.Event) bool {
+		return event.Type() == files.EventTypeMove && event.OldName == oldDir && event.Name == newDir
+	})
+
+	h.clock.Advance(time.Second)
+	h.eventually(func() bool { return slices.Contains(watcher.WatchList(), newDir) }, "moved directory to be watched")
+
+	if err := simFS.Rename(oldFile, newFile); err != nil {
+		t.Fatalf("failed to move file: %v", err)
+	}
+
+	// Before writing, which would change the size it's matched by
+	h.waitFor(func(event files.Event) bool {
+		return event.Type() == files.EventTypeMove && event.OldName == oldFile && event.Name == newFile
+	})
+
+	if err := simFS.WriteFile(newFile, []byte("more notes")); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	h.expireDeletes()
+
+	stats := h.stop()
+
+	expected := []string{newDir, filepath.Join(newDir, "main.go"), newFile}
+	if !slices.Equal(stats.NewFiles, expected) {
+		t.Errorf("expected NewFiles to be %v, got %v", expected, stats.NewFiles)
+	}
+
+	if stats.NumFilesCreated != 3 || stats.NumFilesDeleted != 0 || stats.NumFilesMoved != 2 {
+		t.Errorf("expected 3 created, 0 deleted, and 2 moved, got %d, %d, and %d",
+			stats.NumFilesCreated, stats.NumFilesDeleted, stats.NumFilesMoved)
+	}
+
+	// The write that created the file moves with it
+	if writes := stats.WrittenFiles[newFile]; writes != 2 {
+		t.Errorf("expected 2 writes to %s, got %d", newFile, writes)
+	}
+}
+
+func TestMonitor_HashContents(t *testing.T) {
+	t.Parallel()
+
+	root := filepath.FromSlash("/project")
+
+	h, simFS, _ := startSimulated(t, root, &files.MonitorOpts{TrackWrites: true, HashContents: true})
+
+	path := filepath.Join(root, "main.go")
+
+	for _, content := range []string{"package main", "package main", "package main\n", "package main\n"} {
+		if err := simFS.WriteFile(path, []byte(content)); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+
+		// Contents are hashed when the write is handled, so each write has to be handled before the next
+		h.sync()
+	}
+
+	stats := h.stop()
+
+	if writes := stats.WrittenFiles[path]; writes != 4 {
+		t.Errorf("expected 4 writes to %s, got %d", path, writes)
+	}
+
+	// Plus the first write to the barrier file, which was empty before it
+	if stats.NumMeaningfulWrites != 3 {
+		t.Errorf("expected 3 meaningful writes, got %d", stats.NumMeaningfulWrites)
+	}
+}
+
+func TestMonitor_SaveState(t *testing.T) {
+	t.Parallel()
+
+	tempDir := t.TempDir()
+	statePath := filepath.Join(t.TempDir(), "state", "files.json")
+	keptFile := filepath.Join(tempDir, "kept.txt")
+	goneFile := filepath.Join(tempDir, "gone.txt")
+	newFile := filepath.Join(tempDir, "new.txt")
+
+	for _, fileName := range []string{keptFile, goneFile} {
+		if err := os.WriteFile(fileName, []byte("initial\n"), 0o644); err != nil {
+			t.Fatalf("failed to create %q: %v", fileName, err)
+		}
+	}
+
+	h := startMonitor(t, &files.MonitorOpts{RootPath: tempDir, TrackWrites: true})
+
+	if err := os.WriteFile(newFile, nil, 0o644); err != nil {
+		t.Fatalf("failed to create %q: %v", newFile, err)
+	}
+
+	if err := os.WriteFile(keptFile, []byte("changed\n"), 0o644); err != nil {
+		t.Fatalf("failed to write %q: %v", keptFile, err)
+	}
+
+	h.sync()
+
+	saved := h.monitor.Stats(true)
+
+	if err := h.monitor.SaveState(statePath); err != nil {
+		t.Fatalf("failed to save state: %v", err)
+	}
+
+	h.stop()
+
+	// Deleted while no monitor was running
+	if err := os.Remove(goneFile); err != nil {
+		t.Fatalf("failed to delete %q: %v", goneFile, err)
+	}
+
+	monitor, err := files.NewMonitor(&files.MonitorOpts{RootPath: tempDir, TrackWrites: true, WatchRoot: true})
+	if err != nil {
+		t.Fatalf("failed to set up file monitor: %v", err)
+	}
+
+	if err := monitor.LoadState(statePath); err != nil {
+		t.Fatalf("failed to load state: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+
+	go monitor.Run(ctx)
+
+	<-monitor.Ready()
+	cancel()
+	monitor.Close()
+
+	stats := monitor.Stats(true)
+
+	if stats.NumFilesCreated != 1 || !slices.Contains(stats.NewFiles, newFile) {
+		t.Errorf("expected the new file to still be counted as created, got %d: %v", stats.NumFilesCreated, stats.NewFiles)
+	}
+
+	if stats.NumFilesDeleted != 1 || !slices.Contains(stats.DeletedFiles, goneFile) {
+		t.Errorf("expected the file deleted while stopped to be counted, got %d: %v",
+			stats.NumFilesDeleted, stats.DeletedFiles)
+	}
+
+	if writes := stats.RawWrittenFiles[keptFile]; writes == 0 || writes != saved.RawWrittenFiles[keptFile] {
+		t.Errorf("expected %d writes to %q to be restored, got %d", saved.RawWrittenFiles[keptFile], keptFile, writes)
+	}
+
+	if err := monitor.LoadState(filepath.Join(tempDir, "missing.json")); err == nil {
+		t.Errorf("expected an error loading a missing state file")
+	}
+}
+
+func TestMonitor_LoadState_Simulated(t *testing.T) {
+	t.Parallel()
+
+	root := filepath.FromSlash("/project")
+	statePath := filepath.Join(t.TempDir(), "files.json")
+	keptFile := filepath.Join(root, "kept.txt")
+	goneFile := filepath.Join(root, "gone.txt")
+
+	h, simFS, _ := startSimulated(t, root, &files.MonitorOpts{TrackWrites: true})
+
+	for _, fileName := range []string{keptFile, goneFile} {
+		if err := simFS.WriteFile(fileName, []byte("initial\n")); err != nil {
+			t.Fatalf("failed to create %q: %v", fileName, err)
+		}
+	}
+
+	h.sync()
+
+	if err := h.monitor.SaveState(statePath); err != nil {
+		t.Fatalf("failed to save state: %v", err)
+	}
+
+	h.stop()
+
+	// Deleted while no monitor was running
+	if err := simFS.Remove(goneFile); err != nil {
+		t.Fatalf("failed to delete %q: %v", goneFile, err)
+	}
+
+	monitor, err := files.NewMonitor(&files.MonitorOpts{
+		RootPath:  root,
+		FS:        simFS,
+		Watcher:   simFS.NewWatcher(),
+		WatchRoot: true,
+	})
+	if err != nil {
+		t.Fatalf("failed to set up file monitor: %v", err)
+	}
+
+	// Restored from the simulated filesystem, where only the file deleted while stopped is gone
+	if err := monitor.LoadState(statePath); err != nil {
+		t.Fatalf("failed to load state: %v", err)
+	}
+
+	stats := monitor.Stats(true)
+
+	if !slices.Equal(stats.NewFiles, []string{keptFile}) {
+		t.Errorf("expected only %q to still be counted as created, got %v", keptFile, stats.NewFiles)
+	}
+
+	if len(stats.DeletedFiles) != 0 {
+		t.Errorf("expected a file created and deleted to not count as deleted, got %v", stats.DeletedFiles)
+	}
+}
+
+func TestMonitor_Pause(t *testing.T) {
+	t.Parallel()
+
+	root := filepath.FromSlash("/project")
+
+	h, simFS, _ := startSimulated(t, root, &files.MonitorOpts{TrackWrites: true})
+
+	scratch := filepath.Join(root, "scratch.txt")
+	lockfile := filepath.Join(root, "package-lock.json")
+
+	if err := simFS.WriteFile(scratch, []byte("notes")); err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+
+	h.sync()
+	h.monitor.Pause()
+
+	if !h.monitor.Paused() {
+		t.Fatalf("expected the monitor to be paused")
+	}
+
+	if err := simFS.WriteFile(lockfile, []byte("{}")); err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+
+	if err := simFS.Remove(scratch); err != nil {
+		t.Fatalf("failed to remove file: %v", err)
+	}
+
+	// Nothing is sent while paused, so there's no barrier to wait on
+	fileMap := h.monitor.FileMap()
+	h.eventually(func() bool {
+		return fileMap.IsInitial(lockfile) && !fileMap.Has(scratch)
+	}, "changes made while paused to be tracked")
+
+	h.monitor.Resume()
+
+	if err := simFS.WriteFile(lockfile, []byte(`{"lockfileVersion": 3}`)); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	h.sync()
+
+	stats := h.stop()
+
+	if stats.NumFilesCreated != 0 || stats.NumFilesDeleted != 0 {
+		t.Errorf("expected nothing created or deleted, got %d created and %d deleted", stats.NumFilesCreated,
+			stats.NumFilesDeleted)
+	}
+
+	if writes := stats.WrittenFiles[lockfile]; writes != 1 {
+		t.Errorf("expected only the write after resuming to count, got %d writes", writes)
+	}
+}
+
+func TestMonitor_Activity(t *testing.T) {
+	t.Parallel()
+
+	root := filepath.FromSlash("/project")
+
+	h, simFS, _ := startSimulated(t, root, &files.MonitorOpts{TrackWrites: true})
+
+	path := filepath.Join(root, "main.go")
+
+	if err := simFS.WriteFile(path, []byte("package main")); err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+
+	h.sync()
+	h.clock.Advance(2 * time.Minute)
+
+	if err := simFS.WriteFile(path, []byte("package main\n")); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	h.sync()
+
+	file, err := h.monitor.FileMap().Get(path)
+	if err != nil {
+		t.Fatalf("failed to get file: %v", err)
+	}
+
+	if elapsed := file.LastEvent.Sub(file.FirstEvent); elapsed != 2*time.Minute {
+		t.Errorf("expected 2m between the first and last events, got %s", elapsed)
+	}
+
+	activity := h.stop().Activity
+
+	if len(activity.Counts) != 3 {
+		t.Fatalf("expected 3 minutes of activity, got %v", activity.Counts)
+	}
+
+	if activity.Counts[0] == 0 || activity.Counts[1] != 0 || activity.Counts[2] == 0 {
+		t.Errorf("expected activity in the first and last minutes only, got %v", activity.Counts)
+	}
+}
+
+func TestMonitor_BinaryFiles(t *testing.T) {
+	t.Parallel()
+
+	root := filepath.FromSlash("/project")
+
+	h, simFS, _ := startSimulated(t, root, &files.MonitorOpts{TrackWrites: true})
+
+	image := filepath.Join(root, "logo.png")
+	source := filepath.Join(root, "main.go")
+
+	if err := simFS.WriteFile(image, []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")); err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+
+	if err := simFS.WriteFile(source, []byte("package main\n")); err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+
+	h.sync()
+
+	stats := h.stop()
+
+	if !slices.Equal(stats.BinaryFiles, []string{image}) {
+		t.Errorf("expected only %s to be binary, got %v", image, stats.BinaryFiles)
+	}
+}
+
+func TestMonitor_Unwatch(t *testing.T) {
+	t.Parallel()
+
+	root := filepath.FromSlash("/project")
+
+	h, simFS, watcher := startSimulated(t, root, &files.MonitorOpts{TrackWrites: true})
+
+	buildDir := filepath.Join(root, "build")
+	nestedDir := filepath.Join(buildDir, "cache")
+
+	if err := simFS.MkdirAll(nestedDir); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+
+	// New directories are walked after a delay
+	h.sync()
+	h.clock.Advance(time.Second)
+	h.eventually(func() bool {
+		return slices.Equal(h.monitor.WatchedDirs(), []string{root, buildDir, nestedDir})
+	}, "new directories to be watched")
+
+	if err := h.monitor.Unwatch(buildDir); err != nil {
+		t.Fatalf("failed to unwatch directory: %v", err)
+	}
+
+	if dirs := h.monitor.WatchedDirs(); !slices.Equal(dirs, []string{root}) {
+		t.Errorf("expected only the root to be watched, got %v", dirs)
+	}
+
+	if list := watcher.WatchList(); slices.Contains(list, buildDir) || slices.Contains(list, nestedDir) {
+		t.Errorf("expected the watcher to stop watching the subtree, got %v", list)
+	}
+
+	if err := h.monitor.Unwatch(root); !errors.Is(err, files.ErrNotInRoot) {
+		t.Errorf("expected unwatching the root to fail with ErrNotInRoot, got %v", err)
+	}
+
+	// A directory can be unwatched before it exists, and the root's watch would otherwise see it created
+	distDir := filepath.Join(root, "dist")
+
+	if err := h.monitor.Unwatch(distDir); err != nil {
+		t.Fatalf("failed to unwatch directory: %v", err)
+	}
+
+	if err := simFS.MkdirAll(distDir); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+
+	if err := simFS.WriteFile(filepath.Join(distDir, "out.js"), []byte("x")); err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+
+	h.sync()
+	h.clock.Advance(time.Second)
+
+	stats := h.stop()
+
+	if stats.NumFilesCreated != 2 {
+		t.Errorf("expected only the directories created before unwatching to count, got %d created",
+			stats.NumFilesCreated)
+	}
+
+	if dirs := h.monitor.WatchedDirs(); slices.Contains(dirs, distDir) {
+		t.Errorf("expected the unwatched directory not to be watched, got %v", dirs)
+	}
+}
+
+// startTree starts a monitor on a simulated tree with the given files already in it.
+func startTree(t *testing.T, root string, paths []string, opts *files.MonitorOpts) (*harness, *montest.FS) {
+	t.Helper()
+
+	simFS := montest.NewFS(root)
+	barrier := filepath.Join(root, "barrier")
+
+	for _, path := range append([]string{barrier}, paths...) {
+		if err := simFS.MkdirAll(filepath.Dir(path)); err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+
+		if err := simFS.WriteFile(path, nil); err != nil {
+			t.Fatalf("failed to create file: %v", err)
+		}
+	}
+
+	opts.RootPath = root
+	opts.FS = simFS
+	opts.Watcher = simFS.NewWatcher()
+
+	h := start(t, opts, barrier, func() error {
+		return simFS.WriteFile(barrier, []byte{'.'})
+	})
+
+	return h, simFS
+}
+
+func TestMonitor_ScanLimits(t *testing.T) {
+	t.Parallel()
+
+	root := filepath.FromSlash("/project")
+	srcDir := filepath.Join(root, "src")
+	libDir := filepath.Join(srcDir, "lib")
+	deepFile := filepath.Join(libDir, "deep.go")
+	lastFile := filepath.Join(root, "zeta.txt")
+
+	t.Run("depth", func(t *testing.T) {
+		t.Parallel()
+
+		h, simFS := startTree(t, root, []string{deepFile, lastFile}, &files.MonitorOpts{TrackWrites: true, MaxDepth: 2})
+		fileMap := h.monitor.FileMap()
+
+		if !fileMap.Has(libDir) || fileMap.Has(deepFile) || !fileMap.Has(lastFile) {
+			t.Errorf("expected only paths up to 2 levels deep to be tracked")
+		}
+
+		select {
+		case err := <-h.monitor.Errors():
+			if !errors.Is(err, files.ErrScanLimit) {
+				t.Errorf("expected ErrScanLimit to be reported, got %v", err)
+			}
+		case <-time.After(testTimeout):
+			t.Fatalf("timed out waiting for the scan limit to be reported")
+		}
+
+		for _, path := range []string{filepath.Join(libDir, "new.go"), filepath.Join(srcDir, "new.go")} {
+			if err := simFS.WriteFile(path, []byte("package lib")); err != nil {
+				t.Fatalf("failed to create file: %v", err)
+			}
+		}
+
+		h.sync()
+
+		if stats := h.stop(); !slices.Equal(stats.NewFiles, []string{filepath.Join(srcDir, "new.go")}) {
+			t.Errorf("expected only the new file within the depth limit to count, got %v", stats.NewFiles)
+		}
+	})
+
+	t.Run("tracked files", func(t *testing.T) {
+		t.Parallel()
+
+		// The scan goes root, barrier, src, src/lib, then stops before src/lib/deep.go
+		h, simFS := startTree(t, root, []string{deepFile, lastFile},
+			&files.MonitorOpts{TrackWrites: true, MaxTrackedFiles: 4})
+		fileMap := h.monitor.FileMap()
+
+		if !fileMap.Has(libDir) || fileMap.Has(deepFile) || fileMap.Has(lastFile) {
+			t.Errorf("expected the scan to stop after 4 paths")
+		}
+
+		// New paths before where the scan stopped are tracked, and ones after are left out
+		earlier := filepath.Join(srcDir, "a.go")
+		later := filepath.Join(srcDir, "z.go")
+
+		for _, path := range []string{earlier, later, lastFile} {
+			if err := simFS.WriteFile(path, []byte("package src")); err != nil {
+				t.Fatalf("failed to write file: %v", err)
+			}
+		}
+
+		h.sync()
+
+		stats := h.stop()
+
+		if !slices.Equal(stats.NewFiles, []string{earlier}) || stats.WrittenFiles[lastFile] != 0 {
+			t.Errorf("expected only changes before the cutoff to count, got %v new and %v written",
+				stats.NewFiles, stats.WrittenFiles)
+		}
+	})
+
+	t.Run("tracked files per root", func(t *testing.T) {
+		t.Parallel()
+
+		backend := filepath.FromSlash("/backend")
+		backendFiles := []string{filepath.Join(backend, "a.go"), filepath.Join(backend, "b.go")}
+		lastBackendFile := filepath.Join(backend, "c.go")
+
+		// Each root gets its own 3 paths: root, barrier, src for the first, and backend, a.go, b.go for the second
+		h, _ := startTree(t, root, append([]string{deepFile, lastBackendFile}, backendFiles...),
+			&files.MonitorOpts{TrackWrites: true, RootPaths: []string{backend}, MaxTrackedFiles: 3})
+		fileMap := h.monitor.FileMap()
+
+		if !fileMap.Has(srcDir) || fileMap.Has(libDir) {
+			t.Errorf("expected the scan of %q to stop after 3 paths", root)
+		}
+
+		if !fileMap.Has(backendFiles[0]) || !fileMap.Has(backendFiles[1]) || fileMap.Has(lastBackendFile) {
+			t.Errorf("expected the scan of %q to stop after 3 paths", backend)
+		}
+
+		for range 2 {
+			select {
+			case err := <-h.monitor.Errors():
+				if !errors.Is(err, files.ErrScanLimit) {
+					t.Errorf("expected ErrScanLimit to be reported, got %v", err)
+				}
+			case <-time.After(testTimeout):
+				t.Fatalf("timed out waiting for the scan limit to be reported for each root")
+			}
+		}
+
+		h.stop()
+	})
+}
+
+func TestMonitor_LazyWatch(t *testing.T) {
+	t.Parallel()
+
+	root := filepath.FromSlash("/project")
+	srcDir := filepath.Join(root, "src")
+	deepDir := filepath.Join(srcDir, "lib", "internal")
+
+	h, _ := startTree(t, root, []string{filepath.Join(deepDir, "deep.go")},
+		&files.MonitorOpts{TrackWrites: true, LazyWatchDepth: 1})
+
+	expected := []string{root, srcDir, filepath.Join(srcDir, "lib"), deepDir}
+	h.eventually(func() bool {
+		return slices.Equal(h.monitor.WatchedDirs(), expected)
+	}, "deep directories to be watched in the background")
+
+	if !h.monitor.FileMap().Has(filepath.Join(deepDir, "deep.go")) {
+		t.Errorf("expected deep files to be tracked by the initial scan")
+	}
+
+	h.stop()
+}
@@ -0,0 +1,23 @@
+//go:build unix
+
+package files
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// owner is the user and group that own a file.
+type owner struct {
+	uid, gid uint32
+}
+
+// fileOwner returns the owner of a file, if its stat has one.
+func fileOwner(info fs.FileInfo) (owner, bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return owner{}, false
+	}
+
+	return owner{uid: stat.Uid, gid: stat.Gid}, true
+}
@@ -0,0 +1,146 @@
+package files
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"maps"
+	"slices"
+)
+
+// Pause stops counting changes, so noisy operations like installing dependencies don't end up in the session's stats.
+// Watches stay in place, and the file map keeps up with what's on disk without counting it: files created while paused
+// are tracked as if they'd been there from the start, and files removed while paused are forgotten. No events are sent
+// on Events or to subscribers until Resume is called.
+func (m *Monitor) Pause() {
+	if !m.paused.Swap(true) {
+		slog.Info("paused counting file changes")
+	}
+}
+
+// Resume starts counting changes again after Pause.
+func (m *Monitor) Resume() {
+	if m.paused.Swap(false) {
+		slog.Info("resumed counting file changes")
+	}
+}
+
+// Paused reports whether changes aren't being counted.
+func (m *Monitor) Paused() bool {
+	return m.paused.Load()
+}
+
+// handlePausedEvent keeps the file map in step with an event while paused, without counting it or sending it on.
+func (m *Monitor) handlePausedEvent(event Event) {
+	switch event.Type() {
+	case EventTypeCreate:
+		m.dropPendingDelete(event.Name)
+
+		info, err := m.fileMap.addInitialPath(event.Name)
+		if errors.Is(err, ErrFileTracked) {
+			m.fileMap.refresh(event.Name)
+			return
+		} else if err != nil {
+			slog.Debug("failed to add path created while paused", "name", event.Name, "error", err)
+			return
+		}
+
+		if info.IsDir() || (info.Mode()&fs.ModeSymlink != 0 && m.opts.FollowSymlinks) {
+			m.watchNewDir(event.Name)
+		}
+	case EventTypeRemove, EventTypeRename:
+		m.dropWatches(event.Name)
+		m.dropPendingDelete(event.Name)
+		m.fileMap.forget(event.Name)
+		m.unfollow(event.Name)
+	case EventTypeWrite, EventTypeChmod:
+		m.fileMap.refresh(event.Name)
+	case EventTypeUnknown:
+	}
+}
+
+// dropPendingDelete forgets a delete that hasn't been counted yet.
+func (m *Monitor) dropPendingDelete(path string) {
+	m.pendingDeleteMutex.Lock()
+	delete(m.pendingDeletes, path)
+	m.pendingDeleteMutex.Unlock()
+}
+
+// addInitialPath stats a path and tracks it as if it had been there from the start. A deleted file is tracked again
+// without taking back its delete. Calling this with a tracked path will return ErrFileTracked.
+func (f *FileMap) addInitialPath(path string) (fs.FileInfo, error) {
+	if file, err := f.Get(path); err == nil && !file.WasDeleted {
+		return nil, ErrFileTracked
+	}
+
+	stat, err := lstat(f.fs, path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat %q: %w", path, err)
+	}
+
+	if err := f.AddFile(path, FileInfo{FileInfo: stat, FileType: FileTypeInitial}); err != nil {
+		return nil, err
+	}
+
+	return stat, nil
+}
+
+// refresh updates a tracked file's info and contents hash after a change that isn't counted, so it isn't counted
+// later either.
+func (f *FileMap) refresh(path string) {
+	stat, err := lstat(f.fs, path)
+	if err != nil {
+		return
+	}
+
+	var hash uint64
+	if !stat.IsDir() {
+		hash = f.contentHash(path)
+	}
+
+	shard, dir := f.lookup(path)
+
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
+
+	file, ok := shard.get(dir, path)
+	if !ok || file.WasDeleted {
+		return
+	}
+
+	file.FileInfo = stat
+	file.PendingSwap = false
+
+	if !stat.IsDir() {
+		file.hash = hash
+	}
+}
+
+// forget drops a path and everything below it without counting it as deleted. New files are taken back out of the
+// files created, as if they'd never been there. Files already counted as deleted are left as they are.
+func (f *FileMap) forget(path string) {
+	shard, dir := f.lookup(path)
+
+	shard.mutex.Lock()
+
+	if file, ok := shard.get(dir, path); ok && !file.WasDeleted {
+		f.remove(shard, dir, path)
+
+		if !file.IsInitial() {
+			f.countCreated(file, -1)
+		}
+	}
+
+	shard.mutex.Unlock()
+
+	childShard := f.shard(path)
+
+	childShard.mutex.RLock()
+	children := slices.Collect(maps.Keys(childShard.dirs[path]))
+	childShard.mutex.RUnlock()
+
+	for _, child := range children {
+		f.forget(child)
+	}
+}
@@ -0,0 +1,70 @@
+package files
+
+import (
+	"fmt"
+	"io/fs"
+)
+
+// permissionBits are the parts of a file's mode that chmod changes.
+const permissionBits = fs.ModePerm | fs.ModeSetuid | fs.ModeSetgid | fs.ModeSticky
+
+// AddPermissionChange checks a file's mode and owner after a chmod event, and counts a permission change if either
+// differs from what's tracked. Chmod events also come from changes that leave both alone, like a touch updating the
+// timestamps, so it reports whether anything changed. Symlinks and deleted files are left alone.
+func (f *FileMap) AddPermissionChange(path string) (bool, error) {
+	stat, err := lstat(f.fs, path)
+	if err != nil {
+		return false, fmt.Errorf("failed to stat %q: %w", path, err)
+	}
+
+	shard, dir := f.lookup(path)
+
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
+
+	file, ok := shard.get(dir, path)
+	if !ok {
+		return false, ErrUnknownFile
+	}
+
+	if file.WasDeleted || file.IsSymlink() || samePermissions(file.FileInfo, stat) {
+		return false, nil
+	}
+
+	file.FileInfo = stat
+	file.PermissionChanges++
+	f.permissionChanges.Add(1)
+	f.recordEvent(file)
+
+	return true, nil
+}
+
+// samePermissions reports whether two stats of a file have the same permission bits and, where it's known, owner.
+func samePermissions(old, current fs.FileInfo) bool {
+	if old.Mode()&permissionBits != current.Mode()&permissionBits {
+		return false
+	}
+
+	oldOwner, oldOK := fileOwner(old)
+	currentOwner, currentOK := fileOwner(current)
+
+	return !oldOK || !currentOK || oldOwner == currentOwner
+}
+
+// PermissionChanges returns how many times a file's permissions or owner changed, across every file.
+func (f *FileMap) PermissionChanges() int64 {
+	return f.permissionChanges.Load()
+}
+
+// ChangedPermissions returns the current mode of every file whose permissions or owner changed.
+func (f *FileMap) ChangedPermissions() map[string]fs.FileMode {
+	results := map[string]fs.FileMode{}
+
+	f.each(func(path string, info *FileInfo) {
+		if info.PermissionChanges > 0 {
+			results[path] = info.Mode()
+		}
+	})
+
+	return results
+}
@@ -0,0 +1,274 @@
+package files
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cneill/mon/pkg/clock"
+	"github.com/fsnotify/fsnotify"
+)
+
+// pollState is what's compared between scans to tell whether a path changed.
+type pollState struct {
+	dir     bool
+	size    int64
+	modTime time.Time
+}
+
+func newPollState(info fs.FileInfo) pollState {
+	return pollState{
+		dir:     info.IsDir(),
+		size:    info.Size(),
+		modTime: info.ModTime(),
+	}
+}
+
+// pollingWatcher is a Watcher that finds changes by scanning the watched paths on an interval and comparing them with
+// the previous scan, for filesystems that don't deliver inotify/kqueue events, like NFS, SSHFS, and some container
+// volume mounts. Like inotify, a watched directory reports changes to its direct children, and a watch ends when its
+// path is removed.
+type pollingWatcher struct {
+	fs FS
+
+	mutex   sync.Mutex
+	watched map[string]map[string]pollState // watched path -> state of the path itself (files) or its children (dirs)
+	closed  bool
+
+	events chan fsnotify.Event
+	errors chan error
+	done   chan struct{}
+}
+
+func newPollingWatcher(fileSystem FS, clk clock.Clock, interval time.Duration) *pollingWatcher {
+	watcher := &pollingWatcher{
+		fs:      fileSystem,
+		watched: map[string]map[string]pollState{},
+		events:  make(chan fsnotify.Event, eventBufferSize),
+		errors:  make(chan error, 64),
+		done:    make(chan struct{}),
+	}
+
+	go watcher.run(clk.NewTicker(interval))
+
+	return watcher
+}
+
+func (p *pollingWatcher) Add(path string) error {
+	path = filepath.Clean(path)
+
+	state, err := p.scan(path)
+	if err != nil {
+		return fmt.Errorf("failed to watch %s: %w", path, err)
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.closed {
+		return fsnotify.ErrClosed
+	}
+
+	p.watched[path] = state
+
+	return nil
+}
+
+func (p *pollingWatcher) Remove(path string) error {
+	path = filepath.Clean(path)
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if _, ok := p.watched[path]; !ok {
+		return fmt.Errorf("%w: %s", fsnotify.ErrNonExistentWatch, path)
+	}
+
+	delete(p.watched, path)
+
+	return nil
+}
+
+func (p *pollingWatcher) Close() error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.closed {
+		return nil
+	}
+
+	p.closed = true
+
+	close(p.done)
+	close(p.events)
+	close(p.errors)
+
+	return nil
+}
+
+func (p *pollingWatcher) Events() <-chan fsnotify.Event { return p.events }
+func (p *pollingWatcher) Errors() <-chan error          { return p.errors }
+
+func (p *pollingWatcher) run(ticker clock.Ticker) {
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C():
+			p.poll()
+		}
+	}
+}
+
+// poll rescans every watched path and sends events for the differences from the last scan.
+func (p *pollingWatcher) poll() {
+	p.mutex.Lock()
+	paths := slices.Sorted(maps.Keys(p.watched))
+	p.mutex.Unlock()
+
+	for _, path := range paths {
+		current, err := p.scan(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			current = nil
+		} else if err != nil {
+			p.sendError(fmt.Errorf("failed to scan %s: %w", path, err))
+			continue
+		}
+
+		p.mutex.Lock()
+
+		previous, ok := p.watched[path]
+		if !ok || p.closed {
+			// Removed or closed during the scan
+			p.mutex.Unlock()
+			continue
+		}
+
+		if current == nil {
+			delete(p.watched, path)
+		} else {
+			p.watched[path] = current
+		}
+
+		for _, event := range diffPollStates(previous, current) {
+			p.send(event)
+		}
+
+		p.mutex.Unlock()
+	}
+}
+
+// scan returns the state of a file, keyed by its own path, or of each direct child of a directory.
+func (p *pollingWatcher) scan(path string) (map[string]pollState, error) {
+	info, err := p.fs.Stat(path)
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	if !info.IsDir() {
+		return map[string]pollState{path: newPollState(info)}, nil
+	}
+
+	results := map[string]pollState{}
+
+	err = p.fs.WalkDir(path, func(walkPath string, entry fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) && walkPath != path {
+			// Removed since it was listed
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if walkPath == path {
+			return nil
+		}
+
+		childInfo, err := entry.Info()
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		} else if err != nil {
+			return err //nolint:wrapcheck
+		}
+
+		results[walkPath] = newPollState(childInfo)
+
+		if entry.IsDir() {
+			return filepath.SkipDir
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	return results, nil
+}
+
+// diffPollStates returns the events that turn previous into current: removes first, so a path replaced by a different
+// kind of file is removed before it's created again, then creates and writes, each in path order.
+func diffPollStates(previous, current map[string]pollState) []fsnotify.Event {
+	var results, removes []fsnotify.Event
+
+	for path, state := range current {
+		before, existed := previous[path]
+
+		switch {
+		case !existed:
+			results = append(results, fsnotify.Event{Name: path, Op: fsnotify.Create})
+		case before.dir != state.dir:
+			// Replaced by a different kind of file
+			removes = append(removes, fsnotify.Event{Name: path, Op: fsnotify.Remove})
+			results = append(results, fsnotify.Event{Name: path, Op: fsnotify.Create})
+		case !state.dir && (before.size != state.size || !before.modTime.Equal(state.modTime)):
+			results = append(results, fsnotify.Event{Name: path, Op: fsnotify.Write})
+		}
+	}
+
+	for path := range previous {
+		if _, exists := current[path]; !exists {
+			removes = append(removes, fsnotify.Event{Name: path, Op: fsnotify.Remove})
+		}
+	}
+
+	byName := func(a, b fsnotify.Event) int { return strings.Compare(a.Name, b.Name) }
+	slices.SortFunc(results, byName)
+	slices.SortFunc(removes, byName)
+
+	return append(removes, results...)
+}
+
+// send queues an event, reporting an overflow like a kernel queue would if the reader has fallen behind. The caller
+// must hold the lock.
+func (p *pollingWatcher) send(event fsnotify.Event) {
+	select {
+	case p.events <- event:
+	default:
+		p.sendErrorLocked(fsnotify.ErrEventOverflow)
+	}
+}
+
+func (p *pollingWatcher) sendError(err error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if !p.closed {
+		p.sendErrorLocked(err)
+	}
+}
+
+// sendErrorLocked reports an error without blocking. The caller must hold the lock.
+func (p *pollingWatcher) sendErrorLocked(err error) {
+	select {
+	case p.errors <- err:
+	default:
+	}
+}
@@ -0,0 +1,144 @@
+package files
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// ErrScanLimit is reported on Errors when MonitorOpts.MaxDepth or MaxTrackedFiles leaves part of a root out.
+var ErrScanLimit = errors.New("scan limit reached")
+
+// pathParts splits a path into its names relative to root, e.g. ["pkg", "files"] for root/pkg/files, and nil for root
+// itself.
+func pathParts(root, path string) []string {
+	rel := RelPath(root, path)
+	if rel == "." {
+		return nil
+	}
+
+	return strings.Split(filepath.ToSlash(rel), "/")
+}
+
+// pastLimits reports whether a path under root is left out by MaxDepth, or comes after where the initial scan stopped
+// for MaxTrackedFiles.
+func (m *Monitor) pastLimits(root, path string) bool {
+	parts := pathParts(root, path)
+
+	if m.opts.MaxDepth > 0 && len(parts) > m.opts.MaxDepth {
+		return true
+	}
+
+	// The scan walks each directory in lexical order, and visits a directory's contents right after it, so comparing
+	// names in order puts every path the scan didn't get to at or after the first one it skipped
+	cutoff, ok := m.scanCutoffs[root]
+
+	return ok && slices.Compare(parts, cutoff) >= 0
+}
+
+// checkScanLimits decides whether the initial scan of root should skip a path for MaxDepth or MaxTrackedFiles, given
+// how many paths under root it has tracked so far, returning the error to skip it with, if so. The first time each
+// limit leaves something out of a root, it's reported on Errors.
+func (m *Monitor) checkScanLimits(root, path string, entry fs.DirEntry, tracked int) error {
+	if path == root {
+		return nil
+	}
+
+	if _, ok := m.scanCutoffs[root]; ok {
+		// Followed symlinks are walked separately, and the walk they're in carries on after SkipAll
+		return filepath.SkipAll
+	}
+
+	parts := pathParts(root, path)
+
+	if m.opts.MaxDepth > 0 && len(parts) > m.opts.MaxDepth {
+		if !slices.Contains(m.deepRoots, root) {
+			m.deepRoots = append(m.deepRoots, root)
+			m.warnScanLimit(root, fmt.Errorf("%w: paths more than %d levels deep aren't tracked or watched",
+				ErrScanLimit, m.opts.MaxDepth))
+		}
+
+		return skipEntry(entry)
+	}
+
+	if m.opts.MaxTrackedFiles > 0 && tracked >= m.opts.MaxTrackedFiles {
+		m.scanCutoffs[root] = parts
+		m.warnScanLimit(root, fmt.Errorf("%w: stopped tracking at %d files and directories, before %q",
+			ErrScanLimit, m.opts.MaxTrackedFiles, RelPath(root, path)))
+
+		return filepath.SkipAll
+	}
+
+	return nil
+}
+
+func (m *Monitor) warnScanLimit(root string, err error) {
+	slog.Warn("part of the project isn't monitored", "root", root, "error", err)
+	m.reportError("scan", root, err)
+}
+
+// watchLevels watches the directories under root from minDepth to maxDepth levels below it, or all the way down if
+// maxDepth is zero. Directories that disappear before they're watched are skipped.
+func (m *Monitor) watchLevels(root string, minDepth, maxDepth int) error {
+	err := m.walk(root, func(path string, entry fs.DirEntry, err error, _ bool) error {
+		if errors.Is(err, fs.ErrNotExist) && path != root {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if !entry.IsDir() || m.ignored(path) {
+			return skipEntry(entry)
+		}
+
+		if entry.Name() == ".git" {
+			return filepath.SkipDir
+		}
+
+		depth := len(pathParts(root, path))
+
+		switch {
+		case maxDepth > 0 && depth > maxDepth:
+			return filepath.SkipDir
+		case depth < minDepth:
+			return nil
+		}
+
+		if err := m.watchDir(path); errors.Is(err, fs.ErrNotExist) && path != root {
+			return filepath.SkipDir
+		} else if err != nil {
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set up recursive directory watching for %q: %w", root, err)
+	}
+
+	return nil
+}
+
+// watchDeep watches the directories deeper than LazyWatchDepth, after Run has started watching the rest.
+func (m *Monitor) watchDeep(ctx context.Context) {
+	for _, root := range m.roots {
+		err := m.watchLevels(root, m.opts.LazyWatchDepth+1, 0)
+
+		switch {
+		case ctx.Err() != nil || errors.Is(err, fsnotify.ErrClosed):
+			return
+		case err != nil:
+			slog.Error("failed to watch deep directories", "root", root, "error", err)
+			m.reportError("watch directory", root, err)
+		}
+	}
+
+	slog.Debug("finished watching deep directories")
+}
@@ -0,0 +1,188 @@
+package files
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/cneill/mon/internal/atomicfile"
+)
+
+// MapState is a serializable snapshot of a FileMap's tracked files and counters, used to checkpoint and resume
+// sessions.
+type MapState struct {
+	Files            map[string]FileState `json:"files"`
+	FilesCreated     int64                `json:"files_created"`
+	FilesDeleted     int64                `json:"files_deleted"`
+	FilesMoved       int64                `json:"files_moved,omitempty"`
+	MeaningfulWrites int64                `json:"meaningful_writes,omitempty"`
+
+	SymlinksCreated int64 `json:"symlinks_created,omitempty"`
+	SymlinksDeleted int64 `json:"symlinks_deleted,omitempty"`
+
+	PermissionChanges int64 `json:"permission_changes,omitempty"`
+
+	Activity Timeline `json:"activity,omitzero"`
+}
+
+// FileState is the serializable form of a single FileInfo.
+type FileState struct {
+	FileType          FileType    `json:"file_type"`
+	WasDeleted        bool        `json:"was_deleted,omitempty"`
+	Writes            int64       `json:"writes,omitempty"`
+	RawWrites         int64       `json:"raw_writes,omitempty"`
+	PreSwapWrites     int64       `json:"pre_swap_writes,omitempty"`
+	MeaningfulWrites  int64       `json:"meaningful_writes,omitempty"`
+	PermissionChanges int64       `json:"permission_changes,omitempty"`
+	ContentHash       uint64      `json:"content_hash,omitempty"` // 0 if the contents weren't hashed
+	FirstEvent        time.Time   `json:"first_event,omitzero"`
+	LastEvent         time.Time   `json:"last_event,omitzero"`
+	Size              int64       `json:"size"`
+	Mode              fs.FileMode `json:"mode"`
+	ModTime           time.Time   `json:"mod_time"`
+}
+
+// State returns a snapshot of the map that can be passed to Restore later. Shards are captured one at a time, so files
+// changing concurrently may or may not be included.
+func (f *FileMap) State() MapState {
+	state := MapState{
+		Files:            map[string]FileState{},
+		FilesCreated:     f.filesCreated.Load(),
+		FilesDeleted:     f.filesDeleted.Load(),
+		FilesMoved:       f.filesMoved.Load(),
+		MeaningfulWrites: f.meaningfulWrites.Load(),
+
+		SymlinksCreated: f.symlinksCreated.Load(),
+		SymlinksDeleted: f.symlinksDeleted.Load(),
+
+		PermissionChanges: f.permissionChanges.Load(),
+
+		Activity: f.Activity(),
+	}
+
+	f.each(func(path string, file *FileInfo) {
+		state.Files[path] = FileState{
+			FileType:          file.FileType,
+			WasDeleted:        file.WasDeleted,
+			Writes:            file.Writes,
+			RawWrites:         file.RawWrites,
+			PreSwapWrites:     file.PreSwapWrites,
+			MeaningfulWrites:  file.MeaningfulWrites,
+			PermissionChanges: file.PermissionChanges,
+			ContentHash:       file.hash,
+			FirstEvent:        file.FirstEvent,
+			LastEvent:         file.LastEvent,
+			Size:              file.Size(),
+			Mode:              file.Mode(),
+			ModTime:           file.ModTime(),
+		}
+	})
+
+	return state
+}
+
+// Restore overlays a previously saved state onto the map. Files from the saved state that have since disappeared from
+// disk are treated as deleted. Files on disk that are unknown to the saved state keep whatever type they already have
+// in the map.
+func (f *FileMap) Restore(state MapState) {
+	f.filesCreated.Store(state.FilesCreated)
+	f.filesDeleted.Store(state.FilesDeleted)
+	f.filesMoved.Store(state.FilesMoved)
+	f.meaningfulWrites.Store(state.MeaningfulWrites)
+	f.symlinksCreated.Store(state.SymlinksCreated)
+	f.symlinksDeleted.Store(state.SymlinksDeleted)
+	f.permissionChanges.Store(state.PermissionChanges)
+	f.activity.restore(state.Activity)
+
+	for path, saved := range state.Files {
+		info := &FileInfo{
+			FileInfo:          savedFileInfo{name: filepath.Base(path), state: saved},
+			FileType:          saved.FileType,
+			WasDeleted:        saved.WasDeleted,
+			Writes:            saved.Writes,
+			RawWrites:         saved.RawWrites,
+			PreSwapWrites:     saved.PreSwapWrites,
+			MeaningfulWrites:  saved.MeaningfulWrites,
+			PermissionChanges: saved.PermissionChanges,
+			FirstEvent:        saved.FirstEvent,
+			LastEvent:         saved.LastEvent,
+			hash:              saved.ContentHash,
+		}
+
+		stat, err := lstat(f.fs, path)
+		shard, dir := f.lookup(path)
+
+		shard.mutex.Lock()
+
+		switch {
+		case err == nil:
+			info.FileInfo = stat
+			f.set(shard, dir, path, info)
+		case saved.WasDeleted:
+			// Already deleted when the state was saved, nothing to reconcile
+			f.set(shard, dir, path, info)
+		case saved.FileType == FileTypeNew:
+			if _, ok := shard.get(dir, path); ok {
+				f.remove(shard, dir, path)
+			}
+
+			f.countCreated(info, -1)
+		default:
+			info.WasDeleted = true
+			f.countDeleted(info, 1)
+			f.set(shard, dir, path, info)
+		}
+
+		shard.mutex.Unlock()
+	}
+}
+
+// SaveState writes the FileMap's state (see FileMap.State) to path as JSON, so a restarted monitor can resume its
+// counts with LoadState. It's written to a temporary file first and moved into place, so a crash mid-write never leaves
+// a truncated state behind.
+func (m *Monitor) SaveState(path string) error {
+	data, err := json.Marshal(m.fileMap.State())
+	if err != nil {
+		return fmt.Errorf("failed to serialize file state: %w", err)
+	}
+
+	if err := atomicfile.Write(path, data); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+
+	return nil
+}
+
+// LoadState restores the FileMap from a file written by SaveState, reconciling it with what's on disk now as in
+// FileMap.Restore. Call it before Run: the saved counts replace the current ones, including changes already counted.
+func (m *Monitor) LoadState(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read state file: %w", err)
+	}
+
+	state := MapState{}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return fmt.Errorf("failed to parse state file %q: %w", path, err)
+	}
+
+	m.fileMap.Restore(state)
+
+	return nil
+}
+
+// savedFileInfo implements fs.FileInfo for files restored from a saved state that no longer exist on disk.
+type savedFileInfo struct {
+	name  string
+	state FileState
+}
+
+func (s savedFileInfo) Name() string       { return s.name }
+func (s savedFileInfo) Size() int64        { return s.state.Size }
+func (s savedFileInfo) Mode() fs.FileMode  { return s.state.Mode }
+func (s savedFileInfo) ModTime() time.Time { return s.state.ModTime }
+func (s savedFileInfo) IsDir() bool        { return s.state.Mode.IsDir() }
+func (s savedFileInfo) Sys() any           { return nil }
@@ -1,24 +1,180 @@
 package files
 
+import (
+	"cmp"
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
 type Stats struct {
 	NumFilesCreated int64
 	NumFilesDeleted int64
+	NumFilesMoved   int64 // Files and directories moved within the tree, which aren't counted as created or deleted
 	NewFiles        []string
 	DeletedFiles    []string
-	WrittenFiles    map[string]int64
+	WrittenFiles    map[string]int64 // Coalesced saves per file
+	RawWrittenFiles map[string]int64 // Raw write events per file
+	ByExtension     map[string]ExtensionStats
+	ByRoot          map[string]RootStats // Keyed by root path, with every root present
+	// ByDirectory is keyed by top-level directory, as returned by TopDirectory.
+	ByDirectory map[string]DirectoryStats
+	// NumMeaningfulWrites counts saves that changed a file's contents, across every file. It's the number of saves
+	// unless MonitorOpts.HashContents is set, since changes can't be told apart from no-op writes without it.
+	NumMeaningfulWrites int64
+
+	// Symlinks are counted here rather than as files, whether or not they're followed (see MonitorOpts.FollowSymlinks)
+	NumSymlinksCreated int64
+	NumSymlinksDeleted int64
+
+	// NumPermissionChanges counts changes to files' permission bits or owners, e.g. by chmod +x.
+	NumPermissionChanges int64
+	// ChangedPermissions is the current mode of every file whose permissions or owner changed.
+	ChangedPermissions map[string]fs.FileMode
+	// TopNewFilesBySize lists the largest new files, biggest first, up to MonitorOpts.TopNewFiles of them.
+	TopNewFilesBySize []FileSize
+	// BinaryFiles are the new and written files whose contents are binary (have a NUL byte near the start), like
+	// images and compiled artifacts, sorted.
+	BinaryFiles []string
+	// Activity is the number of events in each minute of the session.
+	Activity Timeline
+
+	EventsDropped  int64 // Queued events dropped to make room for newer ones, because readers fell behind
+	EventsIgnored  int64 // Editor temp file events that were filtered out
+	EventOverflows int64 // Times the kernel event queue overflowed, losing an unknown number of events
 }
 
 func (m *Monitor) Stats(final bool) *Stats {
 	stats := &Stats{
 		NumFilesCreated: m.fileMap.FilesCreated(),
 		NumFilesDeleted: m.fileMap.FilesDeleted(),
+		NumFilesMoved:   m.fileMap.FilesMoved(),
+
+		NumMeaningfulWrites: m.fileMap.MeaningfulWrites(),
+
+		NumSymlinksCreated: m.fileMap.SymlinksCreated(),
+		NumSymlinksDeleted: m.fileMap.SymlinksDeleted(),
+
+		NumPermissionChanges: m.fileMap.PermissionChanges(),
+
+		EventsDropped:  m.droppedEvents.Load(),
+		EventsIgnored:  m.ignoredEvents.Load(),
+		EventOverflows: m.overflows.Load(),
 	}
 
 	if final {
 		stats.NewFiles = m.fileMap.NewFiles()
 		stats.DeletedFiles = m.fileMap.DeletedFiles()
+
+		slices.Sort(stats.NewFiles)
+		slices.Sort(stats.DeletedFiles)
 		stats.WrittenFiles = m.fileMap.WrittenFiles()
+		stats.RawWrittenFiles = m.fileMap.RawWrittenFiles()
+		stats.ChangedPermissions = m.fileMap.ChangedPermissions()
+		stats.TopNewFilesBySize = m.topNewFilesBySize(stats.NewFiles)
+		stats.BinaryFiles = m.binaryFiles(stats.NewFiles, stats.WrittenFiles)
+		stats.Activity = m.fileMap.Activity()
+		stats.ByExtension = m.fileMap.ByExtension()
+		stats.ByRoot = m.byRoot()
+		stats.ByDirectory = m.byDirectory()
 	}
 
 	return stats
 }
+
+// DefaultTopNewFiles is used when MonitorOpts.TopNewFiles is not set.
+const DefaultTopNewFiles = 5
+
+// FileSize is a file's path and size in bytes.
+type FileSize struct {
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+}
+
+// topNewFilesBySize returns the largest of the new files that are still there. They're statted again, since a file
+// is usually empty when it's created.
+func (m *Monitor) topNewFilesBySize(newFiles []string) []FileSize {
+	if m.topNewFiles < 0 {
+		return nil
+	}
+
+	results := []FileSize{}
+
+	for _, path := range newFiles {
+		info, err := lstat(m.fs, path)
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+
+		results = append(results, FileSize{Path: path, Size: info.Size()})
+	}
+
+	slices.SortStableFunc(results, func(a, b FileSize) int {
+		return cmp.Compare(b.Size, a.Size)
+	})
+
+	return results[:min(len(results), m.topNewFiles)]
+}
+
+// RootStats totals the activity under one of the monitor's roots.
+type RootStats = ExtensionStats
+
+func (m *Monitor) byRoot() map[string]RootStats {
+	results := make(map[string]RootStats, len(m.roots))
+	for _, root := range m.roots {
+		results[root] = RootStats{}
+	}
+
+	grouped := m.fileMap.groupStats(func(path string) string {
+		return RootOf(m.roots, path)
+	})
+
+	for root, stats := range grouped {
+		if root != "" { // External files
+			results[root] = stats
+		}
+	}
+
+	return results
+}
+
+// DirectoryStats totals the activity under one top-level directory.
+type DirectoryStats = ExtensionStats
+
+// TopDirectory returns the top-level directory that path is in, relative to the root it's under and prefixed with the
+// root's name if there's more than one, as in RelPathIn. Files directly in a root are under "." with a single root,
+// and under the root's name otherwise. It returns an empty string for paths outside the roots.
+func TopDirectory(roots []string, path string) string {
+	root := RootOf(roots, path)
+	if root == "" {
+		return ""
+	}
+
+	dir, _, nested := strings.Cut(filepath.ToSlash(RelPath(root, path)), "/")
+	if !nested {
+		dir = "."
+	}
+
+	if len(roots) > 1 {
+		return filepath.Join(filepath.Base(root), dir)
+	}
+
+	return dir
+}
+
+func (m *Monitor) byDirectory() map[string]DirectoryStats {
+	results := map[string]DirectoryStats{}
+
+	grouped := m.fileMap.groupStats(func(path string) string {
+		return TopDirectory(m.roots, path)
+	})
+
+	for dir, stats := range grouped {
+		if dir != "" { // External files
+			results[dir] = stats
+		}
+	}
+
+	return results
+}
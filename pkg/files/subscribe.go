@@ -0,0 +1,79 @@
+package files
+
+import "slices"
+
+// EventFilter picks the events a subscriber receives (see Monitor.Subscribe).
+type EventFilter func(Event) bool
+
+// EventTypes returns a filter for events of the given types.
+func EventTypes(types ...EventType) EventFilter {
+	return func(event Event) bool {
+		return slices.Contains(types, event.Type())
+	}
+}
+
+// subscription is one stream from Subscribe.
+type subscription struct {
+	events chan Event
+	filter EventFilter
+}
+
+// Subscribe returns a stream of the events that match filter, or of every event if filter is nil, along with a
+// function that ends the subscription and closes the stream. Each subscription has its own buffer of EventBufferSize
+// events, separate from Events and every other subscription: a subscriber that falls behind has its own oldest events
+// dropped (counted in Stats.EventsDropped), without holding up the monitor or taking events from anyone else. The
+// stream is also closed by Close.
+func (m *Monitor) Subscribe(filter EventFilter) (<-chan Event, func()) {
+	sub := &subscription{
+		events: make(chan Event, m.bufferSize),
+		filter: filter,
+	}
+
+	m.subscriberMutex.Lock()
+	defer m.subscriberMutex.Unlock()
+
+	if m.closed {
+		close(sub.events)
+		return sub.events, func() {}
+	}
+
+	m.subscribers[sub] = struct{}{}
+
+	return sub.events, func() { m.unsubscribe(sub) }
+}
+
+func (m *Monitor) unsubscribe(sub *subscription) {
+	m.subscriberMutex.Lock()
+	defer m.subscriberMutex.Unlock()
+
+	if _, ok := m.subscribers[sub]; ok {
+		delete(m.subscribers, sub)
+		close(sub.events)
+	}
+}
+
+// publish sends an event to the subscribers whose filters match it.
+func (m *Monitor) publish(event Event) {
+	// Held for reading while sending, so a subscription can't be closed in the middle. Sends never block.
+	m.subscriberMutex.RLock()
+	defer m.subscriberMutex.RUnlock()
+
+	for sub := range m.subscribers {
+		if sub.filter == nil || sub.filter(event) {
+			m.send(sub.events, event)
+		}
+	}
+}
+
+// closeSubscribers closes every subscription's stream, for Close.
+func (m *Monitor) closeSubscribers() {
+	m.subscriberMutex.Lock()
+	defer m.subscriberMutex.Unlock()
+
+	for sub := range m.subscribers {
+		close(sub.events)
+	}
+
+	clear(m.subscribers)
+	m.closed = true
+}
@@ -0,0 +1,114 @@
+package files
+
+import (
+	"io/fs"
+	"log/slog"
+	"maps"
+	"path/filepath"
+	"slices"
+)
+
+// walkFunc is called for each path in a walk, like fs.WalkDirFunc. linked is true for paths reached through a symlink
+// that the walk followed.
+type walkFunc func(path string, entry fs.DirEntry, err error, linked bool) error
+
+// walk walks the tree under root like fs.WalkDir, descending into symlinked directories with FollowSymlinks. Paths
+// under a followed symlink are reported under the link, and the link is reported twice: first as itself, then as the
+// directory it points to.
+func (m *Monitor) walk(root string, fn walkFunc) error {
+	return m.walkAs(root, root, false, fn)
+}
+
+// walkAs walks root, reporting its paths as if root were at reportedRoot.
+func (m *Monitor) walkAs(root, reportedRoot string, linked bool, fn walkFunc) error {
+	return m.fs.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		reported := filepath.Join(reportedRoot, RelPath(root, path))
+
+		if result := fn(reported, entry, err, linked); result != nil || err != nil {
+			return result
+		}
+
+		if entry.Type()&fs.ModeSymlink == 0 || m.ignored(reported) {
+			return nil
+		}
+
+		target, ok := m.followSymlink(reported)
+		if !ok {
+			return nil
+		}
+
+		return m.walkAs(target, reported, true, fn)
+	})
+}
+
+// followSymlink decides whether to follow the symlink at link with FollowSymlinks, returning the directory it points
+// to. Links to files are never followed, since they can't hold anything else to watch. Neither are links to
+// directories that overlap a root or a directory that's already followed, which would loop or count files twice.
+func (m *Monitor) followSymlink(link string) (string, bool) {
+	links, ok := m.fs.(symlinkFS)
+	if !m.opts.FollowSymlinks || !ok {
+		return "", false
+	}
+
+	target, err := links.EvalSymlinks(link)
+	if err != nil {
+		slog.Debug("not following broken symlink", "link", link, "error", err)
+		return "", false
+	}
+
+	if info, err := m.fs.Stat(target); err != nil || !info.IsDir() {
+		return "", false
+	}
+
+	m.symlinkMutex.Lock()
+	defer m.symlinkMutex.Unlock()
+
+	// Walked again, like when the initial scan is followed by setting up the watches
+	if m.followed[link] == target {
+		return target, true
+	}
+
+	covered := slices.Concat(m.realRoots, slices.Collect(maps.Values(m.followed)))
+	for _, dir := range covered {
+		if RootOf([]string{dir}, target) != "" || RootOf([]string{target}, dir) != "" {
+			slog.Debug("not following symlink into a directory that's already watched", "link", link, "target", target)
+			return "", false
+		}
+	}
+
+	m.followed[link] = target
+
+	return target, true
+}
+
+// unfollow forgets a removed symlink, and any followed symlinks under it, so their targets can be followed again.
+func (m *Monitor) unfollow(link string) {
+	m.symlinkMutex.Lock()
+	defer m.symlinkMutex.Unlock()
+
+	for path := range m.followed {
+		if RootOf([]string{link}, path) != "" {
+			delete(m.followed, path)
+		}
+	}
+}
+
+// resolveRoots returns the roots with any symlinks in them resolved, for comparing with symlink targets.
+func resolveRoots(fileSystem FS, roots []string) []string {
+	links, ok := fileSystem.(symlinkFS)
+	if !ok {
+		return roots
+	}
+
+	results := make([]string, 0, len(roots))
+
+	for _, root := range roots {
+		if resolved, err := links.EvalSymlinks(root); err == nil {
+			root = resolved
+		}
+
+		results = append(results, root)
+	}
+
+	return results
+}
@@ -0,0 +1,67 @@
+package files
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Watcher is the source of filesystem events for a Monitor. By default it is backed by fsnotify, but fakes (see the
+// montest package) can be used to drive a Monitor without a real filesystem.
+type Watcher interface {
+	Add(path string) error
+	Remove(path string) error
+	Close() error
+	Events() <-chan fsnotify.Event
+	Errors() <-chan error
+}
+
+// FS is the filesystem a Monitor reads from. Paths are OS paths, like the ones in events. File contents are only read
+// for MonitorOpts.HashContents and to tell binary files from text in final stats, and only if the FS also has a
+// ReadFile or Open method (see fileReader and fileOpener).
+type FS interface {
+	Stat(path string) (fs.FileInfo, error)
+	WalkDir(root string, fn fs.WalkDirFunc) error
+}
+
+// fileReader is implemented by FSs that can read a whole file, like os.ReadFile.
+type fileReader interface {
+	ReadFile(path string) ([]byte, error)
+}
+
+// symlinkFS is implemented by FSs with symbolic links, like the OS filesystem. Without it, symlinks look like whatever
+// they point to, and MonitorOpts.FollowSymlinks has no effect.
+type symlinkFS interface {
+	Lstat(path string) (fs.FileInfo, error)
+	EvalSymlinks(path string) (string, error)
+}
+
+// lstat stats a symlink itself rather than what it points to, if the FS has symlinks.
+func lstat(fileSystem FS, path string) (fs.FileInfo, error) {
+	if links, ok := fileSystem.(symlinkFS); ok {
+		return links.Lstat(path)
+	}
+
+	return fileSystem.Stat(path)
+}
+
+type fsnotifyWatcher struct {
+	watcher *fsnotify.Watcher
+}
+
+func (f *fsnotifyWatcher) Add(path string) error         { return f.watcher.Add(path) }
+func (f *fsnotifyWatcher) Remove(path string) error      { return f.watcher.Remove(path) }
+func (f *fsnotifyWatcher) Close() error                  { return f.watcher.Close() }
+func (f *fsnotifyWatcher) Events() <-chan fsnotify.Event { return f.watcher.Events }
+func (f *fsnotifyWatcher) Errors() <-chan error          { return f.watcher.Errors }
+
+type osFS struct{}
+
+func (osFS) Stat(path string) (fs.FileInfo, error)        { return os.Stat(path) }
+func (osFS) WalkDir(root string, fn fs.WalkDirFunc) error { return filepath.WalkDir(root, fn) }
+func (osFS) ReadFile(path string) ([]byte, error)         { return os.ReadFile(path) }
+func (osFS) Open(path string) (fs.File, error)            { return os.Open(path) }
+func (osFS) Lstat(path string) (fs.FileInfo, error)       { return os.Lstat(path) }
+func (osFS) EvalSymlinks(path string) (string, error)     { return filepath.EvalSymlinks(path) }
@@ -0,0 +1,101 @@
+package files
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"path/filepath"
+	"slices"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// ErrNotInRoot is returned by Unwatch for paths that aren't below one of the roots.
+var ErrNotInRoot = errors.New("path isn't below a monitored directory")
+
+// WatchedDirs returns the directories under the roots that are being watched, sorted. The directories watched to follow
+// external files (see WatchExternalFile) aren't included.
+func (m *Monitor) WatchedDirs() []string {
+	m.watchMutex.RLock()
+	defer m.watchMutex.RUnlock()
+
+	return slices.Sorted(maps.Keys(m.watchedDirs))
+}
+
+// Unwatch stops watching a directory below one of the roots and everything under it, e.g. to prune a noisy subtree
+// like a build directory mid-session. Changes under it are ignored from then on, as if it matched an ignore pattern,
+// even if it's removed and created again. What was already counted under it stays counted, and its files stay tracked
+// as they were.
+func (m *Monitor) Unwatch(path string) error {
+	path = filepath.Clean(path)
+
+	if root := RootOf(m.roots, path); root == "" || root == path {
+		return fmt.Errorf("failed to unwatch %q: %w", path, ErrNotInRoot)
+	}
+
+	m.watchMutex.Lock()
+	defer m.watchMutex.Unlock()
+
+	if !slices.Contains(m.unwatched, path) {
+		m.unwatched = append(m.unwatched, path)
+	}
+
+	var errs []error
+
+	for dir := range m.watchedDirs {
+		if RootOf([]string{path}, dir) == "" {
+			continue
+		}
+
+		delete(m.watchedDirs, dir)
+
+		if err := m.watcher.Remove(dir); err != nil && !errors.Is(err, fsnotify.ErrNonExistentWatch) {
+			errs = append(errs, fmt.Errorf("failed to stop monitoring directory %q: %w", dir, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// watchDir starts watching a directory under the roots, keeping track of it for WatchedDirs.
+func (m *Monitor) watchDir(path string) error {
+	if err := m.watcher.Add(path); err != nil {
+		return fmt.Errorf("failed to monitor directory %q: %w", path, err)
+	}
+
+	m.watchMutex.Lock()
+	m.watchedDirs[path] = struct{}{}
+	m.watchMutex.Unlock()
+
+	return nil
+}
+
+// dropWatches forgets the watches on a removed or renamed path and everything under it. The watcher ends the watch on a
+// removed directory by itself, but a renamed one would still be watched under its old name.
+func (m *Monitor) dropWatches(path string) {
+	m.watchMutex.Lock()
+	defer m.watchMutex.Unlock()
+
+	if _, ok := m.watchedDirs[path]; !ok {
+		return
+	}
+
+	for dir := range m.watchedDirs {
+		if RootOf([]string{path}, dir) == "" {
+			continue
+		}
+
+		delete(m.watchedDirs, dir)
+
+		// Already gone if the directory was removed
+		_ = m.watcher.Remove(dir)
+	}
+}
+
+// unwatchedPath reports whether a path is under a directory passed to Unwatch.
+func (m *Monitor) unwatchedPath(path string) bool {
+	m.watchMutex.RLock()
+	defer m.watchMutex.RUnlock()
+
+	return len(m.unwatched) > 0 && RootOf(m.unwatched, path) != ""
+}
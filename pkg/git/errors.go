@@ -0,0 +1,20 @@
+package git
+
+import "github.com/cneill/mon/pkg/files"
+
+// Errors returns a channel of problems that degrade git monitoring, like failed status updates. It also carries
+// errors from the file monitor watching the git logs. Errors are dropped if nobody reads them quickly enough.
+func (m *Monitor) Errors() <-chan error {
+	return m.errors
+}
+
+func (m *Monitor) reportError(op string, err error) {
+	m.forwardError(&files.MonitorError{Op: op, Err: err})
+}
+
+func (m *Monitor) forwardError(err error) {
+	select {
+	case m.errors <- err:
+	default:
+	}
+}
@@ -8,6 +8,13 @@ const (
 	EventTypeUnknown   EventType = "unknown"
 	EventTypeNewCommit EventType = "new commit"
 	EventTypePush      EventType = "push"
+	// EventTypeForcePush is a push that rewrote the remote branch's history.
+	EventTypeForcePush EventType = "force push"
+	// EventTypeFetch is an update of the remote-tracking branch to commits that aren't in HEAD yet, e.g. from a fetch
+	// or the first half of a pull.
+	EventTypeFetch EventType = "fetch"
+	// EventTypeBranchSwitch is a checkout of a different branch (or a detached HEAD).
+	EventTypeBranchSwitch EventType = "branch switch"
 )
 
 type Event struct {
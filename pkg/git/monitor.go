@@ -1,39 +1,85 @@
+// Package git follows a repository's commits, pushes, fetches, and branch switches through its reflogs, and computes
+// the session's line counts and commit statistics.
+//
+// Stability: stable
 package git
 
 import (
-	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
 	"path/filepath"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/cneill/mon/pkg/clock"
 	"github.com/cneill/mon/pkg/files"
 	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	fdiff "github.com/go-git/go-git/v5/plumbing/format/diff"
 )
 
 type MonitorOpts struct {
 	RootPath string
+
+	// InitialHash overrides the session baseline commit, e.g. when resuming a session. Defaults to the current HEAD.
+	InitialHash string
+
+	// MinUpdateInterval is the minimum time between git status updates. Triggers that arrive sooner are coalesced
+	// into one update. Defaults to DefaultMinUpdateInterval.
+	MinUpdateInterval time.Duration
+
+	// PollInterval makes the reflogs be checked for changes this often instead of with fsnotify, for filesystems that
+	// don't deliver change events. See files.MonitorOpts.
+	PollInterval time.Duration
+
+	// Clock is used to time updates. Nil uses real time.
+	Clock clock.Clock
 }
 
+// DefaultMinUpdateInterval is used when MonitorOpts.MinUpdateInterval is not set.
+const DefaultMinUpdateInterval = time.Millisecond * 500
+
+// updateSettleDelay is how long an update waits after being requested. Git appends to the reflog just before it moves
+// the ref, so updating immediately can miss the commit that triggered it.
+const updateSettleDelay = time.Millisecond * 100
+
 func (m *MonitorOpts) OK() error {
 	if m.RootPath == "" {
 		return fmt.Errorf("must supply root path")
 	}
 
+	if m.MinUpdateInterval < 0 {
+		return fmt.Errorf("minimum update interval must not be negative")
+	}
+
 	return nil
 }
 
 type Monitor struct {
-	FileEvents chan files.Event
-	GitEvents  chan Event
-
-	gitLogPath       string
+	GitEvents chan Event
+
+	rootPath      string
+	gitLogPath    string
+	fileMonitor   *files.Monitor
+	repo          *git.Repository
+	droppedEvents atomic.Int64 // GitEvents that timed out waiting for a reader
+	errors        chan error
+
+	// These follow the current branch, and are only accessed from Run. gitRemoteLogPath is empty while the branch has
+	// no remote log to watch.
+	branch           plumbing.ReferenceName
 	gitRemoteLogPath string
-	fileMonitor      *files.Monitor
-	repo             *git.Repository
+	remoteRef        plumbing.ReferenceName
+	lastRemoteHash   plumbing.Hash
+
+	// updateTrigger holds at most one pending update request, so a burst of triggers results in a single update
+	updateTrigger     chan struct{}
+	minUpdateInterval time.Duration
+	clock             clock.Clock
 
 	mutex             sync.RWMutex
 	initialHash       string
@@ -42,6 +88,7 @@ type Monitor struct {
 	linesAdded        int64
 	linesDeleted      int64
 	unstagedChanges   int64
+	unstagedFiles     []string
 	gitFiles          map[string]struct{}
 }
 
@@ -55,38 +102,103 @@ func NewMonitor(opts *MonitorOpts) (*Monitor, error) {
 		return nil, fmt.Errorf("failed to open git repo in project dir %q: %w", opts.RootPath, err)
 	}
 
-	initialHash, err := GetHEADSHA(repo)
+	initialHash := opts.InitialHash
+	if initialHash == "" {
+		initialHash, err = GetHEADSHA(repo)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get initial git HEAD SHA: %w", err)
+		}
+	}
+
+	currentBranch, err := CurrentBranch(repo)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get initial git HEAD SHA: %w", err)
+		return nil, fmt.Errorf("failed to get current branch: %w", err)
 	}
 
-	gitLogPath, err := filepath.Abs(filepath.Join(opts.RootPath, ".git", "logs", "HEAD"))
+	gitLogPath, gitRemoteLogPath, err := logPaths(opts.RootPath, currentBranch)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get git log path: %w", err)
+		return nil, err
 	}
 
-	if _, err := os.Stat(gitLogPath); err != nil {
-		return nil, fmt.Errorf("git logs not found at %s", gitLogPath)
+	remoteRef := plumbing.NewRemoteReferenceName(git.DefaultRemoteName, currentBranch.Short())
+
+	remoteHash, err := refHash(repo, remoteRef)
+	if err != nil {
+		return nil, err
 	}
 
-	currentBranch, err := CurrentBranch(repo)
+	fm, err := newLogMonitor(opts, gitLogPath, gitRemoteLogPath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get current branch: %w", err)
+		return nil, err
+	}
+
+	monitor := &Monitor{
+		GitEvents: make(chan Event, 10),
+		errors:    make(chan error, 64),
+
+		rootPath:    opts.RootPath,
+		gitLogPath:  gitLogPath,
+		fileMonitor: fm,
+		repo:        repo,
+
+		branch:           currentBranch,
+		gitRemoteLogPath: gitRemoteLogPath,
+		remoteRef:        remoteRef,
+		lastRemoteHash:   remoteHash,
+
+		updateTrigger:     make(chan struct{}, 1),
+		minUpdateInterval: opts.MinUpdateInterval,
+		clock:             clock.Or(opts.Clock),
+
+		initialHash: initialHash,
+		gitFiles:    map[string]struct{}{},
+	}
+
+	if monitor.minUpdateInterval == 0 {
+		monitor.minUpdateInterval = DefaultMinUpdateInterval
 	}
 
-	gitRemoteLogPath, err := filepath.Abs(filepath.Join(opts.RootPath, ".git", "logs", "refs", "remotes", git.DefaultRemoteName, currentBranch.Short()))
+	if err := monitor.updateTrackedFiles(); err != nil {
+		return nil, fmt.Errorf("failed to populate initial git files: %w", err)
+	}
+
+	monitor.RequestUpdate()
+
+	return monitor, nil
+}
+
+// logPaths returns the paths of the reflogs of HEAD and of the branch's remote-tracking branch, which both have to
+// exist.
+func logPaths(rootPath string, branch plumbing.ReferenceName) (string, string, error) {
+	gitLogPath, err := filepath.Abs(filepath.Join(rootPath, ".git", "logs", "HEAD"))
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get git log path: %w", err)
+	}
+
+	if _, err := os.Stat(gitLogPath); err != nil {
+		return "", "", fmt.Errorf("git logs not found at %s", gitLogPath)
+	}
+
+	gitRemoteLogPath, err := remoteLogPath(rootPath, branch)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get path to remote git log: %w", err)
+		return "", "", err
 	}
 
 	if _, err := os.Stat(gitRemoteLogPath); err != nil {
-		return nil, fmt.Errorf("git remote logs not found at %s", gitRemoteLogPath)
+		return "", "", fmt.Errorf("git remote logs not found at %s", gitRemoteLogPath)
 	}
 
+	return gitLogPath, gitRemoteLogPath, nil
+}
+
+// newLogMonitor sets up a file monitor that watches the reflogs for new commits and pushes.
+func newLogMonitor(opts *MonitorOpts, gitLogPath, gitRemoteLogPath string) (*files.Monitor, error) {
 	fm, err := files.NewMonitor(&files.MonitorOpts{
-		RootPath:    opts.RootPath,
-		WatchRoot:   false,
-		TrackWrites: false,
+		RootPath:     opts.RootPath,
+		WatchRoot:    false,
+		TrackWrites:  false,
+		PollInterval: opts.PollInterval,
+		Clock:        opts.Clock,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to set up file monitor to watch git log: %w", err)
@@ -100,36 +212,213 @@ func NewMonitor(opts *MonitorOpts) (*Monitor, error) {
 		return nil, fmt.Errorf("failed to set up monitoring for git remote log file: %w", err)
 	}
 
-	monitor := &Monitor{
-		FileEvents: make(chan files.Event, 10),
-		GitEvents:  make(chan Event, 10),
+	return fm, nil
+}
 
-		gitLogPath:       gitLogPath,
-		gitRemoteLogPath: gitRemoteLogPath,
-		fileMonitor:      fm,
-		repo:             repo,
+// RequestUpdate schedules a git status update without blocking. Requests made while one is already pending are
+// merged into it.
+func (m *Monitor) RequestUpdate() {
+	select {
+	case m.updateTrigger <- struct{}{}:
+	default:
+	}
+}
 
-		initialHash: initialHash,
-		gitFiles:    map[string]struct{}{},
+// remoteLogPath returns the path of the reflog for branch's remote-tracking branch.
+func remoteLogPath(rootPath string, branch plumbing.ReferenceName) (string, error) {
+	path, err := filepath.Abs(filepath.Join(rootPath, ".git", "logs", "refs", "remotes", git.DefaultRemoteName, filepath.FromSlash(branch.Short())))
+	if err != nil {
+		return "", fmt.Errorf("failed to get path to remote git log: %w", err)
 	}
 
-	if err := monitor.updateTrackedFiles(); err != nil {
-		return nil, fmt.Errorf("failed to populate initial git files: %w", err)
+	return path, nil
+}
+
+// checkBranch follows checkouts of other branches, so pushes keep being detected for whichever branch is current. A
+// branch that had no remote log when it was checked out (e.g. one that was never pushed) is checked again on every
+// HEAD update until it does.
+func (m *Monitor) checkBranch(ctx context.Context) {
+	branch, err := CurrentBranch(m.repo)
+	if err != nil {
+		slog.Error("failed to get current branch", "error", err)
+		m.reportError("read current branch", err)
+
+		return
 	}
 
-	go monitor.Update(context.Background())
+	if branch == m.branch {
+		if m.gitRemoteLogPath == "" && m.watchRemoteLog() {
+			m.checkRemote(ctx)
+		}
 
-	return monitor, nil
+		return
+	}
+
+	slog.Info("Branch switched", "from", m.branch.Short(), "to", branch.Short())
+
+	if m.gitRemoteLogPath != "" {
+		if err := m.fileMonitor.UnwatchFile(m.gitRemoteLogPath); err != nil {
+			slog.Error("failed to stop watching remote log", "path", m.gitRemoteLogPath, "error", err)
+			m.reportError("unwatch remote log", err)
+		}
+	}
+
+	m.branch = branch
+	m.gitRemoteLogPath = ""
+	m.remoteRef = ""
+	m.lastRemoteHash = plumbing.ZeroHash
+
+	if branch.IsBranch() {
+		m.remoteRef = plumbing.NewRemoteReferenceName(git.DefaultRemoteName, branch.Short())
+
+		m.lastRemoteHash, err = refHash(m.repo, m.remoteRef)
+		if err != nil {
+			slog.Error("failed to read remote-tracking branch", "ref", m.remoteRef, "error", err)
+			m.reportError("read remote branch", err)
+		}
+
+		m.watchRemoteLog()
+	}
+
+	go m.pushEvent(ctx, EventTypeBranchSwitch)
+}
+
+// watchRemoteLog starts watching the current branch's remote log, if it exists, and reports whether it is watched.
+func (m *Monitor) watchRemoteLog() bool {
+	if m.remoteRef == "" {
+		return false
+	}
+
+	path, err := remoteLogPath(m.rootPath, m.branch)
+	if err != nil {
+		slog.Error("failed to get remote log path", "branch", m.branch, "error", err)
+		m.reportError("find remote log", err)
+
+		return false
+	}
+
+	if _, err := os.Stat(path); err != nil {
+		slog.Debug("No remote log for branch yet", "branch", m.branch.Short(), "path", path)
+		return false
+	}
+
+	if err := m.fileMonitor.WatchFile(path, true); err != nil {
+		slog.Error("failed to watch remote log", "path", path, "error", err)
+		m.reportError("watch remote log", err)
+
+		return false
+	}
+
+	m.gitRemoteLogPath = path
+
+	return true
+}
+
+// checkRemote classifies a change to the remote-tracking branch, if it moved since the last check.
+func (m *Monitor) checkRemote(ctx context.Context) {
+	if m.remoteRef == "" {
+		return
+	}
+
+	newHash, err := refHash(m.repo, m.remoteRef)
+	if err != nil {
+		slog.Error("failed to read remote-tracking branch", "ref", m.remoteRef, "error", err)
+		m.reportError("read remote branch", err)
+
+		return
+	}
+
+	oldHash := m.lastRemoteHash
+	if newHash == oldHash {
+		return
+	}
+
+	m.lastRemoteHash = newHash
+
+	eventType, err := ClassifyRemoteUpdate(m.repo, oldHash, newHash)
+	if err != nil {
+		slog.Error("failed to classify remote update", "old", oldHash, "new", newHash, "error", err)
+		m.reportError("classify remote update", err)
+
+		return
+	}
+
+	slog.Debug("Remote-tracking branch moved", "ref", m.remoteRef, "old", oldHash, "new", newHash, "type", eventType)
+
+	if eventType != EventTypeUnknown {
+		go m.pushEvent(ctx, eventType)
+	}
+}
+
+// NotifyFileChange tells the monitor that a file in the worktree (given by its absolute path) changed, so the lines added/deleted and unstaged
+// change counts can be refreshed. Only files tracked by git (as of the latest commit) schedule an update; anything
+// else is ignored. Like RequestUpdate, it never blocks, and changes reported while an update is pending are merged
+// into it. It is safe to call from any goroutine, including before Run.
+func (m *Monitor) NotifyFileChange(path string) {
+	m.mutex.RLock()
+	_, tracked := m.gitFiles[path]
+	m.mutex.RUnlock()
+
+	if !tracked {
+		return
+	}
+
+	slog.Debug("Updating due to file change", "path", path)
+
+	m.RequestUpdate()
+}
+
+// updateLoop runs requested updates one at a time, at most once per minUpdateInterval.
+func (m *Monitor) updateLoop(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-m.updateTrigger:
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-m.clock.After(updateSettleDelay):
+		}
+
+		// Anything requested while settling is covered by this update
+		select {
+		case <-m.updateTrigger:
+		default:
+		}
+
+		m.Update(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-m.clock.After(m.minUpdateInterval):
+		}
+	}
 }
 
 func (m *Monitor) Run(ctx context.Context) {
 	go m.fileMonitor.Run(ctx)
+	go m.updateLoop(ctx)
+
+	// Like updates, branch checks wait for git to finish moving HEAD after writing its reflog
+	var branchCheck <-chan time.Time
 
 	for {
 		select {
 		case <-ctx.Done():
 			return
 
+		case <-branchCheck:
+			branchCheck = nil
+
+			m.checkBranch(ctx)
+
+		case err := <-m.fileMonitor.Errors():
+			m.forwardError(err)
+
 		// m.fileMonitor tracks file events on the git log file
 		case event, ok := <-m.fileMonitor.Events:
 			if !ok {
@@ -142,46 +431,20 @@ func (m *Monitor) Run(ctx context.Context) {
 				case m.gitLogPath:
 					slog.Debug("Updating due to git log update", "event", event)
 
-					go m.Update(ctx)
+					m.RequestUpdate()
+
+					branchCheck = m.clock.After(updateSettleDelay)
 
 					if err := m.updateTrackedFiles(); err != nil {
-						slog.Error("failed to update list of tracked files after git log update")
+						slog.Error("failed to update list of tracked files after git log update", "error", err)
+						m.reportError("list tracked files", err)
 					}
 				case m.gitRemoteLogPath:
 					slog.Debug("Got remote update, checking for push...")
 
-					contents, err := os.ReadFile(m.gitRemoteLogPath)
-					if err != nil {
-						slog.Error("failed to read git remote log file", "error", err)
-					}
-
-					lines := bytes.Split(bytes.TrimRight(contents, "\n"), []byte("\n"))
-					if bytes.Contains(lines[len(lines)-1], []byte("update by push")) { // default for push in reflog
-						go m.pushEvent(ctx, EventTypePush)
-					}
+					m.checkRemote(ctx)
 				}
 			}
-
-		// FileEvents come in from the broader file monitor, we use them to update the lines modified/etc stats for
-		// git-tracked files, if they are tracked by git
-		case event, ok := <-m.FileEvents:
-			if !ok {
-				slog.Info("file events channel closed")
-				return
-			}
-
-			m.mutex.RLock()
-
-			if _, tracked := m.gitFiles[event.Name]; !tracked {
-				m.mutex.RUnlock()
-				continue
-			}
-
-			m.mutex.RUnlock()
-
-			slog.Debug("Updating due to file event", "event", event)
-
-			go m.Update(ctx)
 		}
 	}
 }
@@ -196,6 +459,8 @@ func (m *Monitor) Update(ctx context.Context) {
 	commits, err := CommitsSince(m.repo, m.initialHash)
 	if err != nil {
 		slog.Error("failed to list commits since initialization", "error", err)
+		m.reportError("list commits", err)
+
 		return
 	}
 
@@ -210,12 +475,16 @@ func (m *Monitor) Update(ctx context.Context) {
 	newHash, err := GetHEADSHA(m.repo)
 	if err != nil {
 		slog.Error("failed to get new git SHA", "error", err)
+		m.reportError("read HEAD", err)
+
 		return
 	}
 
 	patch, err := PatchSince(m.repo, m.initialHash)
 	if err != nil {
-		slog.Error("failed to generate patch", "initial_hash", m.initialHash, "head_hash", newHash)
+		slog.Error("failed to generate patch", "initial_hash", m.initialHash, "head_hash", newHash, "error", err)
+		m.reportError("generate patch", err)
+
 		return
 	}
 
@@ -223,19 +492,97 @@ func (m *Monitor) Update(ctx context.Context) {
 	m.linesAdded = adds
 	m.linesDeleted = deletes
 
-	unstagedCount, err := UnstagedChangeCount(m.repo)
+	unstagedFiles, err := UnstagedFiles(m.repo)
 	if err != nil {
 		slog.Error("failed to check unstaged changes", "error", err)
+		m.reportError("check unstaged changes", err)
+
 		return
 	}
 
-	m.unstagedChanges = unstagedCount
+	m.unstagedChanges = int64(len(unstagedFiles))
+	m.unstagedFiles = unstagedFiles
 
 	m.lastProcessedHash = newHash
 }
 
+// SessionStartRef is the ref SnapshotStart records a session's baseline commit in. Refs outside refs/heads and
+// refs/tags don't show up in branch or tag listings, and aren't pushed by default.
+func SessionStartRef(sessionID string) plumbing.ReferenceName {
+	return plumbing.ReferenceName("refs/mon/session-" + sessionID)
+}
+
+// SessionEndRef is the ref SnapshotEnd records a session's final HEAD in.
+func SessionEndRef(sessionID string) plumbing.ReferenceName {
+	return plumbing.ReferenceName("refs/mon/session-" + sessionID + "-end")
+}
+
+// SnapshotStart points SessionStartRef at the session's baseline commit, so the pre-session state can be recovered
+// later. A ref left by an earlier run of the same (resumed) session is kept as it is.
+func (m *Monitor) SnapshotStart(sessionID string) (plumbing.ReferenceName, error) {
+	name := SessionStartRef(sessionID)
+
+	if _, err := m.repo.Reference(name, false); err == nil {
+		return name, nil
+	} else if !errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return "", fmt.Errorf("failed to check for existing session ref %s: %w", name, err)
+	}
+
+	if err := m.repo.Storer.SetReference(plumbing.NewHashReference(name, plumbing.NewHash(m.InitialHash()))); err != nil {
+		return "", fmt.Errorf("failed to create session ref %s: %w", name, err)
+	}
+
+	return name, nil
+}
+
+// SnapshotEnd points SessionEndRef at the current HEAD, replacing any earlier one.
+func (m *Monitor) SnapshotEnd(sessionID string) (plumbing.ReferenceName, error) {
+	name := SessionEndRef(sessionID)
+
+	head, err := m.repo.Head()
+	if err != nil {
+		return "", fmt.Errorf("failed to get HEAD: %w", err)
+	}
+
+	if err := m.repo.Storer.SetReference(plumbing.NewHashReference(name, head.Hash())); err != nil {
+		return "", fmt.Errorf("failed to create session ref %s: %w", name, err)
+	}
+
+	return name, nil
+}
+
+// InitialHash returns the commit SHA that the session's commits and patch are computed against.
+func (m *Monitor) InitialHash() string {
+	return m.initialHash
+}
+
+// InitialContents returns the contents of the file at an absolute path as of the commit the session started at.
+func (m *Monitor) InitialContents(path string) ([]byte, error) {
+	return FileAtCommit(m.repo, m.initialHash, path)
+}
+
+// HeadHash returns the commit HEAD points to now.
+func (m *Monitor) HeadHash() (string, error) {
+	return GetHEADSHA(m.repo)
+}
+
+// ExportCommit writes the files in a commit to dir, as in the package's ExportCommit.
+func (m *Monitor) ExportCommit(hash, dir string) error {
+	return ExportCommit(m.repo, hash, dir)
+}
+
+// SessionPatch returns the changes committed since the session started.
+func (m *Monitor) SessionPatch() (fdiff.Patch, error) {
+	return PatchSince(m.repo, m.initialHash)
+}
+
+// UncommittedPatch returns the changes in the worktree that haven't been committed, as in the package's
+// UncommittedPatch.
+func (m *Monitor) UncommittedPatch() (fdiff.Patch, error) {
+	return UncommittedPatch(m.repo)
+}
+
 func (m *Monitor) Close() {
-	// close(m.FileEvents)
 	close(m.GitEvents)
 	m.fileMonitor.Close()
 }
@@ -245,7 +592,7 @@ func (m *Monitor) pushEvent(ctx context.Context, eventType EventType) {
 	defer cancel()
 
 	gitEvent := Event{
-		Time: time.Now(),
+		Time: m.clock.Now(),
 		Type: eventType,
 	}
 
@@ -255,6 +602,10 @@ func (m *Monitor) pushEvent(ctx context.Context, eventType EventType) {
 			slog.Error("context error pushing event from git monitor", "error", err)
 		}
 
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			m.droppedEvents.Add(1)
+		}
+
 		return
 	case m.GitEvents <- gitEvent:
 	}
@@ -0,0 +1,234 @@
+package git_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cneill/mon/pkg/git"
+	"github.com/cneill/mon/pkg/montest"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+func TestMonitor_CommitsAndPushes(t *testing.T) {
+	t.Parallel()
+
+	repo, err := montest.NewGitRepo(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to build git repo: %v", err)
+	}
+
+	monitor, err := git.NewMonitor(&git.MonitorOpts{
+		RootPath:          repo.Dir,
+		MinUpdateInterval: time.Millisecond * 10,
+	})
+	if err != nil {
+		t.Fatalf("failed to start git monitor: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+
+	go monitor.Run(ctx)
+
+	// Waits for an event of the given type, skipping any others
+	waitFor := func(eventType git.EventType) {
+		t.Helper()
+
+		deadline := time.After(time.Second * 5)
+
+		for {
+			select {
+			case event := <-monitor.GitEvents:
+				if event.Type == eventType {
+					return
+				}
+			case <-deadline:
+				t.Fatalf("timed out waiting for %q event", eventType)
+			}
+		}
+	}
+
+	if _, err := repo.Commit("Add main", map[string]string{"main.go": "package main\n\nfunc main() {}\n"}); err != nil {
+		t.Fatalf("failed to commit: %v", err)
+	}
+
+	waitFor(git.EventTypeNewCommit)
+
+	if err := repo.Push(); err != nil {
+		t.Fatalf("failed to push: %v", err)
+	}
+
+	waitFor(git.EventTypePush)
+
+	// Rewrite the pushed commit and push over it
+	pushed, err := repo.Repository().Head()
+	if err != nil {
+		t.Fatalf("failed to get HEAD: %v", err)
+	}
+
+	if err := repo.Reset(monitor.InitialHash()); err != nil {
+		t.Fatalf("failed to reset: %v", err)
+	}
+
+	if _, err := repo.Commit("Add main, again", map[string]string{"main.go": "package main\n\nfunc main() {}\n"}); err != nil {
+		t.Fatalf("failed to commit: %v", err)
+	}
+
+	if err := repo.Push(); err != nil {
+		t.Fatalf("failed to push: %v", err)
+	}
+
+	waitFor(git.EventTypeForcePush)
+
+	// Someone else pushes the original commit back, and we fetch it
+	if err := repo.UpdateRemote(pushed.Hash().String(), "fetch: forced-update"); err != nil {
+		t.Fatalf("failed to fetch: %v", err)
+	}
+
+	waitFor(git.EventTypeFetch)
+
+	stats := monitor.Stats(true)
+
+	if stats.NumCommits != 1 {
+		t.Errorf("expected 1 commit, got %d", stats.NumCommits)
+	}
+
+	if stats.LinesAdded != 3 {
+		t.Errorf("expected 3 lines added, got %d", stats.LinesAdded)
+	}
+
+	if len(stats.CommitSizes) != 1 || stats.CommitSizes[0].Added != 3 || stats.CommitSizes[0].Deleted != 0 {
+		t.Errorf("expected one commit of 3 added lines, got %+v", stats.CommitSizes)
+	}
+}
+
+func TestMonitor_BranchSwitch(t *testing.T) {
+	t.Parallel()
+
+	repo, err := montest.NewGitRepo(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to build git repo: %v", err)
+	}
+
+	monitor, err := git.NewMonitor(&git.MonitorOpts{
+		RootPath:          repo.Dir,
+		MinUpdateInterval: time.Millisecond * 10,
+	})
+	if err != nil {
+		t.Fatalf("failed to start git monitor: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+
+	go monitor.Run(ctx)
+
+	waitFor := func(eventType git.EventType) {
+		t.Helper()
+
+		deadline := time.After(time.Second * 5)
+
+		for {
+			select {
+			case event := <-monitor.GitEvents:
+				if event.Type == eventType {
+					return
+				}
+			case <-deadline:
+				t.Fatalf("timed out waiting for %q event", eventType)
+			}
+		}
+	}
+
+	commitAndPush := func(message string) {
+		t.Helper()
+
+		if _, err := repo.Commit(message, map[string]string{"notes.txt": message + "\n"}); err != nil {
+			t.Fatalf("failed to commit: %v", err)
+		}
+
+		if err := repo.Push(); err != nil {
+			t.Fatalf("failed to push: %v", err)
+		}
+	}
+
+	if err := repo.Checkout("feature", true); err != nil {
+		t.Fatalf("failed to check out branch: %v", err)
+	}
+
+	waitFor(git.EventTypeBranchSwitch)
+
+	// The first push of a new branch creates its remote log, which is picked up on the next commit
+	commitAndPush("Start feature")
+
+	if _, err := repo.Commit("Continue feature", map[string]string{"notes.txt": "more\n"}); err != nil {
+		t.Fatalf("failed to commit: %v", err)
+	}
+
+	waitFor(git.EventTypePush)
+
+	if err := repo.Checkout("main", false); err != nil {
+		t.Fatalf("failed to check out branch: %v", err)
+	}
+
+	waitFor(git.EventTypeBranchSwitch)
+
+	commitAndPush("Fix main")
+
+	waitFor(git.EventTypePush)
+}
+
+func TestMonitor_SessionRefs(t *testing.T) {
+	t.Parallel()
+
+	repo, err := montest.NewGitRepo(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to build git repo: %v", err)
+	}
+
+	monitor, err := git.NewMonitor(&git.MonitorOpts{RootPath: repo.Dir})
+	if err != nil {
+		t.Fatalf("failed to start git monitor: %v", err)
+	}
+
+	// Resolves a ref, failing the test if it doesn't exist
+	resolve := func(name plumbing.ReferenceName) string {
+		t.Helper()
+
+		ref, err := repo.Repository().Reference(name, false)
+		if err != nil {
+			t.Fatalf("failed to resolve %s: %v", name, err)
+		}
+
+		return ref.Hash().String()
+	}
+
+	start, err := monitor.SnapshotStart("abc123")
+	if err != nil {
+		t.Fatalf("failed to snapshot session start: %v", err)
+	}
+
+	finalHash, err := repo.Commit("Add main", map[string]string{"main.go": "package main\n"})
+	if err != nil {
+		t.Fatalf("failed to commit: %v", err)
+	}
+
+	// A resumed session keeps its original starting point
+	if _, err := monitor.SnapshotStart("abc123"); err != nil {
+		t.Fatalf("failed to snapshot session start again: %v", err)
+	}
+
+	end, err := monitor.SnapshotEnd("abc123")
+	if err != nil {
+		t.Fatalf("failed to snapshot session end: %v", err)
+	}
+
+	if hash := resolve(start); hash != monitor.InitialHash() {
+		t.Errorf("expected %s to point at initial commit %s, got %s", start, monitor.InitialHash(), hash)
+	}
+
+	if hash := resolve(end); hash != finalHash {
+		t.Errorf("expected %s to point at final commit %s, got %s", end, finalHash, hash)
+	}
+}
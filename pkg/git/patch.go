@@ -0,0 +1,289 @@
+package git
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	fdiff "github.com/go-git/go-git/v5/plumbing/format/diff"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/utils/binary"
+	"github.com/go-git/go-git/v5/utils/diff"
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+// WritePatch writes a patch as a unified diff, like git diff does, with prefix (e.g. "api/") in front of every path.
+func WritePatch(w io.Writer, patch fdiff.Patch, prefix string) error {
+	if prefix != "" {
+		patch = &prefixedPatch{Patch: patch, prefix: prefix}
+	}
+
+	if err := fdiff.NewUnifiedEncoder(w, fdiff.DefaultContextLines).Encode(patch); err != nil {
+		return fmt.Errorf("failed to encode patch: %w", err)
+	}
+
+	return nil
+}
+
+// UncommittedPatch returns the changes in the worktree that haven't been committed, staged or not, as a patch against
+// HEAD. Untracked files that aren't ignored are included as new files. Submodules are left out.
+func UncommittedPatch(repo *git.Repository) (fdiff.Patch, error) {
+	wt, err := repo.Worktree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get repo worktree: %w", err)
+	}
+
+	status, err := wt.Status()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the status of the git worktree: %w", err)
+	}
+
+	var tree *object.Tree
+
+	if head, err := repo.Head(); err == nil {
+		commit, err := repo.CommitObject(head.Hash())
+		if err != nil {
+			return nil, fmt.Errorf("failed to get HEAD commit: %w", err)
+		}
+
+		if tree, err = commit.Tree(); err != nil {
+			return nil, fmt.Errorf("failed to get git tree from HEAD commit: %w", err)
+		}
+	} else if !errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return nil, fmt.Errorf("failed to get HEAD reference: %w", err)
+	}
+
+	paths := []string{}
+
+	for path, fileStatus := range status {
+		if fileStatus.Staging != git.Unmodified || fileStatus.Worktree != git.Unmodified {
+			paths = append(paths, path)
+		}
+	}
+
+	slices.Sort(paths)
+
+	result := &worktreePatch{}
+
+	for _, path := range paths {
+		filePatch, err := worktreeFilePatch(tree, filepath.Join(wt.Filesystem.Root(), filepath.FromSlash(path)), path)
+		if err != nil {
+			return nil, err
+		}
+
+		if filePatch != nil {
+			result.filePatches = append(result.filePatches, filePatch)
+		}
+	}
+
+	return result, nil
+}
+
+// worktreeFilePatch compares a file in tree (nil before the first commit) with the one at fullPath in the worktree. It
+// returns nil if they're the same, or if either one is a submodule.
+func worktreeFilePatch(tree *object.Tree, fullPath, path string) (*filePatch, error) {
+	var (
+		from, to               *patchFile
+		fromContent, toContent string
+		fromBinary, toBinary   bool
+	)
+
+	if tree != nil {
+		file, err := tree.File(path)
+		if err == nil {
+			if file.Mode == filemode.Submodule {
+				return nil, nil //nolint:nilnil
+			}
+
+			from = &patchFile{hash: file.Hash, mode: file.Mode, path: path}
+
+			if fromBinary, err = file.IsBinary(); err != nil {
+				return nil, fmt.Errorf("failed to read %q at HEAD: %w", path, err)
+			}
+
+			if !fromBinary {
+				if fromContent, err = file.Contents(); err != nil {
+					return nil, fmt.Errorf("failed to read %q at HEAD: %w", path, err)
+				}
+			}
+		} else if !errors.Is(err, object.ErrFileNotFound) {
+			return nil, fmt.Errorf("failed to find %q at HEAD: %w", path, err)
+		}
+	}
+
+	info, err := os.Lstat(fullPath)
+
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		return nil, fmt.Errorf("failed to stat %q: %w", fullPath, err)
+	case info.IsDir():
+		return nil, nil //nolint:nilnil
+	default:
+		data, err := readWorktreeFile(fullPath, info)
+		if err != nil {
+			return nil, err
+		}
+
+		mode, err := filemode.NewFromOSFileMode(info.Mode())
+		if err != nil {
+			return nil, fmt.Errorf("failed to get git file mode of %q: %w", fullPath, err)
+		}
+
+		to = &patchFile{hash: plumbing.ComputeHash(plumbing.BlobObject, data), mode: mode, path: path}
+
+		if toBinary, err = binary.IsBinary(bytes.NewReader(data)); err != nil {
+			return nil, fmt.Errorf("failed to read %q: %w", fullPath, err)
+		}
+
+		toContent = string(data)
+	}
+
+	switch {
+	case from == nil && to == nil:
+		return nil, nil //nolint:nilnil
+	case from != nil && to != nil && from.hash == to.hash && from.mode == to.mode:
+		return nil, nil //nolint:nilnil
+	}
+
+	result := &filePatch{from: from, to: to, binary: fromBinary || toBinary}
+	if !result.binary {
+		result.chunks = diffChunks(fromContent, toContent)
+	}
+
+	return result, nil
+}
+
+// readWorktreeFile reads a file in the worktree the way git stores it, so a symlink is its target.
+func readWorktreeFile(fullPath string, info fs.FileInfo) ([]byte, error) {
+	if info.Mode()&fs.ModeSymlink != 0 {
+		target, err := os.Readlink(fullPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read symlink %q: %w", fullPath, err)
+		}
+
+		return []byte(filepath.ToSlash(target)), nil
+	}
+
+	data, err := os.ReadFile(fullPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %q: %w", fullPath, err)
+	}
+
+	return data, nil
+}
+
+// diffChunks splits the differences between two versions of a text file into chunks, like go-git does for commits.
+func diffChunks(from, to string) []fdiff.Chunk {
+	results := []fdiff.Chunk{}
+
+	for _, change := range diff.Do(from, to) {
+		op := fdiff.Equal
+
+		switch change.Type {
+		case diffmatchpatch.DiffInsert:
+			op = fdiff.Add
+		case diffmatchpatch.DiffDelete:
+			op = fdiff.Delete
+		case diffmatchpatch.DiffEqual:
+		}
+
+		results = append(results, &chunk{content: change.Text, op: op})
+	}
+
+	return results
+}
+
+// worktreePatch is a patch from HEAD to the worktree.
+type worktreePatch struct {
+	filePatches []fdiff.FilePatch
+}
+
+func (p *worktreePatch) FilePatches() []fdiff.FilePatch { return p.filePatches }
+func (p *worktreePatch) Message() string                { return "" }
+
+type filePatch struct {
+	from, to *patchFile // nil for a created or deleted file
+	binary   bool
+	chunks   []fdiff.Chunk
+}
+
+func (p *filePatch) IsBinary() bool        { return p.binary }
+func (p *filePatch) Chunks() []fdiff.Chunk { return p.chunks }
+
+func (p *filePatch) Files() (fdiff.File, fdiff.File) {
+	// Typed nils would look like files to the encoder
+	var from, to fdiff.File
+	if p.from != nil {
+		from = p.from
+	}
+
+	if p.to != nil {
+		to = p.to
+	}
+
+	return from, to
+}
+
+type patchFile struct {
+	hash plumbing.Hash
+	mode filemode.FileMode
+	path string
+}
+
+func (f *patchFile) Hash() plumbing.Hash     { return f.hash }
+func (f *patchFile) Mode() filemode.FileMode { return f.mode }
+func (f *patchFile) Path() string            { return f.path }
+
+type chunk struct {
+	content string
+	op      fdiff.Operation
+}
+
+func (c *chunk) Content() string       { return c.content }
+func (c *chunk) Type() fdiff.Operation { return c.op }
+
+// prefixedPatch puts a prefix in front of the paths in a patch.
+type prefixedPatch struct {
+	fdiff.Patch
+
+	prefix string
+}
+
+func (p *prefixedPatch) FilePatches() []fdiff.FilePatch {
+	results := []fdiff.FilePatch{}
+
+	for _, filePatch := range p.Patch.FilePatches() {
+		results = append(results, &prefixedFilePatch{FilePatch: filePatch, prefix: p.prefix})
+	}
+
+	return results
+}
+
+type prefixedFilePatch struct {
+	fdiff.FilePatch
+
+	prefix string
+}
+
+func (p *prefixedFilePatch) Files() (fdiff.File, fdiff.File) {
+	from, to := p.FilePatch.Files()
+
+	prefix := func(file fdiff.File) fdiff.File {
+		if file == nil {
+			return nil
+		}
+
+		return &patchFile{hash: file.Hash(), mode: file.Mode(), path: p.prefix + file.Path()}
+	}
+
+	return prefix(from), prefix(to)
+}
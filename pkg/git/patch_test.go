@@ -0,0 +1,72 @@
+package git_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cneill/mon/pkg/git"
+	"github.com/cneill/mon/pkg/montest"
+)
+
+func TestUncommittedPatch(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	repo, err := montest.NewGitRepo(dir)
+	if err != nil {
+		t.Fatalf("failed to build git repo: %v", err)
+	}
+
+	if _, err := repo.Commit("Add files", map[string]string{
+		"main.go":   "package main\n",
+		"gone.go":   "package main\n",
+		"stays.txt": "unchanged\n",
+	}); err != nil {
+		t.Fatalf("failed to commit: %v", err)
+	}
+
+	for path, content := range map[string]string{
+		"main.go":       "package main\n\nfunc main() {}\n",
+		"untracked.txt": "new\n",
+	} {
+		if err := repo.WriteFile(path, content); err != nil {
+			t.Fatalf("failed to write %q: %v", path, err)
+		}
+	}
+
+	if err := os.Remove(filepath.Join(dir, "gone.go")); err != nil {
+		t.Fatalf("failed to remove file: %v", err)
+	}
+
+	patch, err := git.UncommittedPatch(repo.Repository())
+	if err != nil {
+		t.Fatalf("failed to get uncommitted patch: %v", err)
+	}
+
+	builder := &strings.Builder{}
+	if err := git.WritePatch(builder, patch, "proj/"); err != nil {
+		t.Fatalf("failed to write patch: %v", err)
+	}
+
+	output := builder.String()
+
+	for _, expected := range []string{
+		"diff --git a/proj/main.go b/proj/main.go\n",
+		"+func main() {}\n",
+		"deleted file mode 100644\n",
+		"--- a/proj/gone.go\n",
+		"new file mode 100644\n",
+		"+++ b/proj/untracked.txt\n",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("expected patch to contain %q, got:\n%s", expected, output)
+		}
+	}
+
+	if strings.Contains(output, "stays.txt") {
+		t.Errorf("expected unchanged file to be left out, got:\n%s", output)
+	}
+}
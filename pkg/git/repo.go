@@ -3,10 +3,15 @@ package git
 import (
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
 
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
 	"github.com/go-git/go-git/v5/plumbing/object"
 )
 
@@ -23,6 +28,29 @@ func OpenGitRepo(path string) (*git.Repository, error) {
 	return repo, nil
 }
 
+// FindRoot walks up from dir to the top of the enclosing git worktree, the way git itself does. It returns
+// ErrNotGitRepo if dir isn't inside one.
+func FindRoot(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to get absolute path of %q: %w", dir, err)
+	}
+
+	for {
+		// .git is a file rather than a directory in linked worktrees and submodules
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return dir, nil
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", ErrNotGitRepo
+		}
+
+		dir = parent
+	}
+}
+
 func CurrentBranch(repo *git.Repository) (plumbing.ReferenceName, error) {
 	head, err := repo.Head()
 	if err != nil {
@@ -41,8 +69,17 @@ func GetHEADSHA(repo *git.Repository) (string, error) {
 	return headRef.Hash().String(), nil
 }
 
+// ListFiles returns the absolute paths of every file in HEAD's tree, including those in subdirectories.
 func ListFiles(repo *git.Repository) ([]string, error) {
-	results := []string{}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get worktree: %w", err)
+	}
+
+	root, err := filepath.Abs(worktree.Filesystem.Root())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get worktree path: %w", err)
+	}
 
 	headRef, err := repo.Head()
 	if err != nil {
@@ -59,13 +96,201 @@ func ListFiles(repo *git.Repository) ([]string, error) {
 		return nil, fmt.Errorf("failed to get git tree from HEAD commit: %w", err)
 	}
 
-	for _, entry := range tree.Entries {
-		results = append(results, entry.Name)
+	results := []string{}
+
+	err = tree.Files().ForEach(func(file *object.File) error {
+		results = append(results, filepath.Join(root, filepath.FromSlash(file.Name)))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk git tree from HEAD commit: %w", err)
 	}
 
 	return results, nil
 }
 
+// FileAtCommit returns the contents of the file at an absolute path in the repo's worktree as of a commit. It returns
+// an error wrapping object.ErrFileNotFound if the file wasn't in that commit.
+func FileAtCommit(repo *git.Repository, hash, path string) ([]byte, error) {
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get worktree: %w", err)
+	}
+
+	root, err := filepath.Abs(worktree.Filesystem.Root())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get worktree path: %w", err)
+	}
+
+	relPath, err := filepath.Rel(root, path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get path of %q in the worktree: %w", path, err)
+	}
+
+	commit, err := repo.CommitObject(plumbing.NewHash(hash))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get commit for commit hash %q: %w", hash, err)
+	}
+
+	file, err := commit.File(filepath.ToSlash(relPath))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find %q in commit %s: %w", relPath, hash, err)
+	}
+
+	contents, err := file.Contents()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %q in commit %s: %w", relPath, hash, err)
+	}
+
+	return []byte(contents), nil
+}
+
+// ExportCommit writes the files in a commit's tree to dir, like a checkout without the .git directory. Submodules are
+// left out, and so are symlinks where the platform can't create them.
+func ExportCommit(repo *git.Repository, hash, dir string) error {
+	commit, err := repo.CommitObject(plumbing.NewHash(hash))
+	if err != nil {
+		return fmt.Errorf("failed to get commit for commit hash %q: %w", hash, err)
+	}
+
+	tree, err := commit.Tree()
+	if err != nil {
+		return fmt.Errorf("failed to get git tree from commit %s: %w", hash, err)
+	}
+
+	err = tree.Files().ForEach(func(file *object.File) error {
+		path := filepath.Join(dir, filepath.FromSlash(file.Name))
+
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return fmt.Errorf("failed to create directory for %q: %w", file.Name, err)
+		}
+
+		return exportFile(file, path)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to export commit %s: %w", hash, err)
+	}
+
+	return nil
+}
+
+// exportFile writes a file from a commit to path.
+func exportFile(file *object.File, path string) error {
+	switch file.Mode {
+	case filemode.Submodule:
+		return nil
+	case filemode.Symlink:
+		target, err := file.Contents()
+		if err != nil {
+			return fmt.Errorf("failed to read symlink %q: %w", file.Name, err)
+		}
+
+		if err := os.Symlink(target, path); err != nil {
+			slog.Debug("failed to create symlink from commit", "name", file.Name, "error", err)
+		}
+
+		return nil
+	}
+
+	perm := os.FileMode(0o644)
+	if file.Mode == filemode.Executable {
+		perm = 0o755
+	}
+
+	reader, err := file.Reader()
+	if err != nil {
+		return fmt.Errorf("failed to read %q: %w", file.Name, err)
+	}
+	defer reader.Close()
+
+	output, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return fmt.Errorf("failed to create %q: %w", path, err)
+	}
+
+	if _, err := io.Copy(output, reader); err != nil {
+		output.Close()
+		return fmt.Errorf("failed to write %q: %w", path, err)
+	}
+
+	if err := output.Close(); err != nil {
+		return fmt.Errorf("failed to write %q: %w", path, err)
+	}
+
+	return nil
+}
+
+// refHash returns the commit a reference points to, or the zero hash if it doesn't exist.
+func refHash(repo *git.Repository, name plumbing.ReferenceName) (plumbing.Hash, error) {
+	ref, err := repo.Reference(name, true)
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return plumbing.ZeroHash, nil
+	} else if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("failed to resolve %s: %w", name, err)
+	}
+
+	return ref.Hash(), nil
+}
+
+// ClassifyRemoteUpdate works out what moved a remote-tracking branch from oldHash to newHash by comparing both to HEAD,
+// rather than trusting reflog messages, which vary with git's language and transport. A branch that moves to a commit
+// HEAD already contains was pushed; if its old commit is no longer part of its history, the push was forced. A branch
+// that moves anywhere else was fetched. It returns EventTypeUnknown if the branch was deleted.
+func ClassifyRemoteUpdate(repo *git.Repository, oldHash, newHash plumbing.Hash) (EventType, error) {
+	if newHash.IsZero() {
+		return EventTypeUnknown, nil
+	}
+
+	headRef, err := repo.Head()
+	if err != nil {
+		return EventTypeUnknown, fmt.Errorf("failed to get HEAD reference: %w", err)
+	}
+
+	headCommit, err := repo.CommitObject(headRef.Hash())
+	if err != nil {
+		return EventTypeUnknown, fmt.Errorf("failed to get HEAD commit: %w", err)
+	}
+
+	newCommit, err := repo.CommitObject(newHash)
+	if err != nil {
+		return EventTypeUnknown, fmt.Errorf("failed to get commit for new remote hash %s: %w", newHash, err)
+	}
+
+	inHead := newHash == headRef.Hash()
+	if !inHead {
+		if inHead, err = newCommit.IsAncestor(headCommit); err != nil {
+			return EventTypeUnknown, fmt.Errorf("failed to compare remote hash %s to HEAD: %w", newHash, err)
+		}
+	}
+
+	if !inHead {
+		return EventTypeFetch, nil
+	}
+
+	if oldHash.IsZero() {
+		return EventTypePush, nil
+	}
+
+	oldCommit, err := repo.CommitObject(oldHash)
+	if errors.Is(err, plumbing.ErrObjectNotFound) {
+		// The old commit was rewritten and then garbage collected
+		return EventTypeForcePush, nil
+	} else if err != nil {
+		return EventTypeUnknown, fmt.Errorf("failed to get commit for old remote hash %s: %w", oldHash, err)
+	}
+
+	fastForward, err := oldCommit.IsAncestor(newCommit)
+	if err != nil {
+		return EventTypeUnknown, fmt.Errorf("failed to compare remote hashes %s and %s: %w", oldHash, newHash, err)
+	}
+
+	if !fastForward && oldHash != newHash {
+		return EventTypeForcePush, nil
+	}
+
+	return EventTypePush, nil
+}
+
 // CommitsSince returns all commits after (not including) the given hash.
 // It walks from HEAD backwards and stops when it reaches the given hash.
 func CommitsSince(repo *git.Repository, sinceHash string) ([]*object.Commit, error) {
@@ -145,26 +370,39 @@ func PatchAddsDeletes(patch *object.Patch) (added int64, deleted int64) { //noli
 // It counts files with Modified, Deleted, or Renamed status in the worktree.
 // Untracked files are ignored.
 func UnstagedChangeCount(repo *git.Repository) (int64, error) {
+	files, err := UnstagedFiles(repo)
+	if err != nil {
+		return 0, err
+	}
+
+	return int64(len(files)), nil
+}
+
+// UnstagedFiles returns the sorted, worktree-relative paths of tracked files with modifications that haven't been
+// staged.
+func UnstagedFiles(repo *git.Repository) ([]string, error) {
 	wt, err := repo.Worktree()
 	if err != nil {
-		return 0, fmt.Errorf("failed to get repo worktree: %w", err)
+		return nil, fmt.Errorf("failed to get repo worktree: %w", err)
 	}
 
 	status, err := wt.Status()
 	if err != nil {
-		return 0, fmt.Errorf("failed to get the status of the git worktree: %w", err)
+		return nil, fmt.Errorf("failed to get the status of the git worktree: %w", err)
 	}
 
-	var count int64
+	files := []string{}
 
 	for file, fileStatus := range status {
 		switch fileStatus.Worktree { //nolint:exhaustive
 		case git.Modified, git.Deleted, git.Renamed:
 			slog.Debug("unstaged change", "file", file, "status", string(fileStatus.Worktree))
 
-			count++
+			files = append(files, filepath.FromSlash(file))
 		}
 	}
 
-	return count, nil
+	slices.Sort(files)
+
+	return files, nil
 }
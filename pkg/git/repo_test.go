@@ -0,0 +1,178 @@
+package git_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/cneill/mon/pkg/git"
+	"github.com/cneill/mon/pkg/montest"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+func TestListFiles(t *testing.T) {
+	t.Parallel()
+
+	repo, err := montest.NewGitRepo(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to build git repo: %v", err)
+	}
+
+	if _, err := repo.Commit("Add files", map[string]string{
+		"main.go":              "package main\n",
+		"pkg/util/util.go":     "package util\n",
+		"web/src/app/index.js": "export {};\n",
+	}); err != nil {
+		t.Fatalf("failed to commit: %v", err)
+	}
+
+	files, err := git.ListFiles(repo.Repository())
+	if err != nil {
+		t.Fatalf("failed to list files: %v", err)
+	}
+
+	for _, path := range []string{"main.go", "pkg/util/util.go", "web/src/app/index.js"} {
+		expected := filepath.Join(repo.Dir, filepath.FromSlash(path))
+		if !slices.Contains(files, expected) {
+			t.Errorf("expected %q in tracked files %v", expected, files)
+		}
+	}
+
+	for _, path := range files {
+		if !filepath.IsAbs(path) {
+			t.Errorf("expected absolute path, got %q", path)
+		}
+	}
+}
+
+func TestFileAtCommit(t *testing.T) {
+	t.Parallel()
+
+	repo, err := montest.NewGitRepo(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to build git repo: %v", err)
+	}
+
+	first, err := repo.Commit("Add main", map[string]string{"main.go": "package main\n"})
+	if err != nil {
+		t.Fatalf("failed to commit: %v", err)
+	}
+
+	if _, err := repo.Commit("Change main", map[string]string{"main.go": "package main\n\nfunc main() {}\n"}); err != nil {
+		t.Fatalf("failed to commit: %v", err)
+	}
+
+	contents, err := git.FileAtCommit(repo.Repository(), first, filepath.Join(repo.Dir, "main.go"))
+	if err != nil {
+		t.Fatalf("failed to read file at commit: %v", err)
+	}
+
+	if string(contents) != "package main\n" {
+		t.Errorf("expected the first version of main.go, got %q", contents)
+	}
+
+	_, err = git.FileAtCommit(repo.Repository(), first, filepath.Join(repo.Dir, "missing.go"))
+	if !errors.Is(err, object.ErrFileNotFound) {
+		t.Errorf("expected ErrFileNotFound for a file that wasn't committed, got %v", err)
+	}
+}
+
+func TestExportCommit(t *testing.T) {
+	t.Parallel()
+
+	repo, err := montest.NewGitRepo(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to build git repo: %v", err)
+	}
+
+	first, err := repo.Commit("Add files", map[string]string{
+		"main.go":          "package main\n",
+		"pkg/util/util.go": "package util\n",
+	})
+	if err != nil {
+		t.Fatalf("failed to commit: %v", err)
+	}
+
+	if _, err := repo.Commit("Add more", map[string]string{"later.go": "package main\n"}); err != nil {
+		t.Fatalf("failed to commit: %v", err)
+	}
+
+	dir := t.TempDir()
+
+	if err := git.ExportCommit(repo.Repository(), first, dir); err != nil {
+		t.Fatalf("failed to export commit: %v", err)
+	}
+
+	contents, err := os.ReadFile(filepath.Join(dir, "pkg", "util", "util.go"))
+	if err != nil || string(contents) != "package util\n" {
+		t.Errorf("expected util.go to be exported, got %q (%v)", contents, err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "later.go")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected a file from a later commit to be left out, got %v", err)
+	}
+}
+
+func TestUnstagedFiles(t *testing.T) {
+	t.Parallel()
+
+	repo, err := montest.NewGitRepo(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to build git repo: %v", err)
+	}
+
+	if _, err := repo.Commit("Add files", map[string]string{
+		"main.go":          "package main\n",
+		"pkg/util/util.go": "package util\n",
+	}); err != nil {
+		t.Fatalf("failed to commit: %v", err)
+	}
+
+	for path, content := range map[string]string{
+		"pkg/util/util.go": "package util\n\nfunc Util() {}\n",
+		"main.go":          "package main\n\nfunc main() {}\n",
+		"untracked.txt":    "not tracked\n",
+	} {
+		if err := repo.WriteFile(path, content); err != nil {
+			t.Fatalf("failed to write %q: %v", path, err)
+		}
+	}
+
+	files, err := git.UnstagedFiles(repo.Repository())
+	if err != nil {
+		t.Fatalf("failed to list unstaged files: %v", err)
+	}
+
+	expected := []string{"main.go", filepath.FromSlash("pkg/util/util.go")}
+	if !slices.Equal(files, expected) {
+		t.Errorf("expected unstaged files %v, got %v", expected, files)
+	}
+}
+
+func TestFindRoot(t *testing.T) {
+	t.Parallel()
+
+	repo, err := montest.NewGitRepo(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to build git repo: %v", err)
+	}
+
+	if err := repo.WriteFile("pkg/util/util.go", "package util\n"); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	root, err := git.FindRoot(filepath.Join(repo.Dir, "pkg", "util"))
+	if err != nil {
+		t.Fatalf("failed to find root: %v", err)
+	}
+
+	if root != repo.Dir {
+		t.Errorf("expected root %q, got %q", repo.Dir, root)
+	}
+
+	if _, err := git.FindRoot(t.TempDir()); !errors.Is(err, git.ErrNotGitRepo) {
+		t.Errorf("expected ErrNotGitRepo outside a repo, got %v", err)
+	}
+}
@@ -2,6 +2,8 @@ package git
 
 import (
 	"log/slog"
+	"slices"
+	"strings"
 
 	"github.com/go-git/go-git/v5/plumbing/object"
 )
@@ -11,9 +13,52 @@ type Stats struct {
 	LinesAdded      int64
 	LinesDeleted    int64
 	UnstagedChanges int64
+	UnstagedFiles   []string // worktree-relative
+	EventsDropped   int64
 
-	Commits []*object.Commit
-	Patch   *object.Patch
+	Commits     []*object.Commit
+	CommitSizes []CommitSize // in the same order as Commits
+	Patch       *object.Patch
+}
+
+// CommitSize is how many lines a commit added and deleted, compared to its first parent.
+type CommitSize struct {
+	Hash    string `json:"hash"`
+	Summary string `json:"summary"`
+	Added   int64  `json:"added"`
+	Deleted int64  `json:"deleted"`
+}
+
+// Churn is the total number of lines the commit touched.
+func (c CommitSize) Churn() int64 {
+	return c.Added + c.Deleted
+}
+
+// CommitSizes measures each commit's insertions and deletions. Commits that can't be diffed are logged and counted as
+// empty.
+func CommitSizes(commits []*object.Commit) []CommitSize {
+	results := make([]CommitSize, 0, len(commits))
+
+	for _, commit := range commits {
+		size := CommitSize{
+			Hash:    commit.Hash.String(),
+			Summary: strings.SplitN(commit.Message, "\n", 2)[0],
+		}
+
+		fileStats, err := commit.Stats()
+		if err != nil {
+			slog.Error("failed to get commit stats", "hash", size.Hash, "error", err)
+		}
+
+		for _, fileStat := range fileStats {
+			size.Added += int64(fileStat.Addition)
+			size.Deleted += int64(fileStat.Deletion)
+		}
+
+		results = append(results, size)
+	}
+
+	return results
 }
 
 func (m *Monitor) Stats(final bool) *Stats {
@@ -25,6 +70,8 @@ func (m *Monitor) Stats(final bool) *Stats {
 		LinesAdded:      m.linesAdded,
 		LinesDeleted:    m.linesDeleted,
 		UnstagedChanges: m.unstagedChanges,
+		UnstagedFiles:   slices.Clone(m.unstagedFiles),
+		EventsDropped:   m.droppedEvents.Load(),
 	}
 
 	if final {
@@ -34,6 +81,7 @@ func (m *Monitor) Stats(final bool) *Stats {
 		}
 
 		stats.Commits = commits
+		stats.CommitSizes = CommitSizes(commits)
 
 		patch, err := PatchSince(m.repo, m.initialHash)
 		if err != nil {
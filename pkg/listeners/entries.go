@@ -0,0 +1,102 @@
+package listeners
+
+import (
+	"cmp"
+	"slices"
+)
+
+// Entry is a named, non-dependency manifest entry worth tracking, such as a go.mod replace directive.
+type Entry struct {
+	Name  string
+	Value string
+}
+
+func (e Entry) String() string {
+	if e.Value == "" {
+		return e.Name
+	}
+
+	return e.Name + " => " + e.Value
+}
+
+type UpdatedEntry struct {
+	Initial Entry
+	Latest  Entry
+}
+
+// EntryDiff describes the changes to one category of entries (e.g. "replace") in a single manifest file.
+type EntryDiff struct {
+	Path           string
+	Category       string
+	NewEntries     []Entry
+	DeletedEntries []Entry
+	UpdatedEntries []UpdatedEntry
+}
+
+func (e EntryDiff) IsEmpty() bool {
+	return len(e.NewEntries) == 0 &&
+		len(e.DeletedEntries) == 0 &&
+		len(e.UpdatedEntries) == 0
+}
+
+type EntryDiffs []EntryDiff
+
+// Sort orders the diffs by file path, then category.
+func (e EntryDiffs) Sort() {
+	slices.SortFunc(e, func(a, b EntryDiff) int {
+		return cmp.Or(cmp.Compare(a.Path, b.Path), cmp.Compare(a.Category, b.Category))
+	})
+}
+
+func (e EntryDiffs) AllEmpty() bool {
+	for _, diff := range e {
+		if !diff.IsEmpty() {
+			return false
+		}
+	}
+
+	return true
+}
+
+// DiffEntries compares the initial and latest entries of a category by name.
+func DiffEntries(path, category string, initial, latest []Entry) EntryDiff {
+	uniqueInitial := map[string]Entry{}
+	for _, entry := range initial {
+		uniqueInitial[entry.Name] = entry
+	}
+
+	uniqueLatest := map[string]Entry{}
+	for _, entry := range latest {
+		uniqueLatest[entry.Name] = entry
+	}
+
+	result := EntryDiff{
+		Path:     path,
+		Category: category,
+	}
+
+	for name, latestEntry := range uniqueLatest {
+		initialEntry, existed := uniqueInitial[name]
+		if !existed {
+			result.NewEntries = append(result.NewEntries, latestEntry)
+		} else if initialEntry.Value != latestEntry.Value {
+			result.UpdatedEntries = append(result.UpdatedEntries, UpdatedEntry{
+				Initial: initialEntry,
+				Latest:  latestEntry,
+			})
+		}
+	}
+
+	for name, initialEntry := range uniqueInitial {
+		if _, exists := uniqueLatest[name]; !exists {
+			result.DeletedEntries = append(result.DeletedEntries, initialEntry)
+		}
+	}
+
+	byName := func(a, b Entry) int { return cmp.Compare(a.Name, b.Name) }
+	slices.SortFunc(result.NewEntries, byName)
+	slices.SortFunc(result.DeletedEntries, byName)
+	slices.SortFunc(result.UpdatedEntries, func(a, b UpdatedEntry) int { return byName(a.Latest, b.Latest) })
+
+	return result
+}
@@ -49,15 +49,17 @@ func (l *Listener) LogEvent(event listeners.Event) error {
 
 	case listeners.EventWrite:
 		if base == "go.mod" {
+			l.mutex.Lock()
+
 			for _, modFile := range l.modFiles {
 				if modFile.Path == event.Name {
-					l.mutex.Lock()
 					slog.Debug("got write event for go.mod file", "path", event.Name)
-					l.mutex.Unlock()
 
 					modFile.LatestContent = event.Content
 				}
 			}
+
+			l.mutex.Unlock()
 		}
 	}
 
@@ -65,6 +67,9 @@ func (l *Listener) LogEvent(event listeners.Event) error {
 }
 
 func (l *Listener) Diff() listeners.Diff {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
 	result := listeners.Diff{
 		DependencyFileDiffs: deps.FileDiffs{},
 	}
@@ -73,8 +78,12 @@ func (l *Listener) Diff() listeners.Diff {
 		if diff := modFile.Diff(); diff != nil {
 			result.DependencyFileDiffs = append(result.DependencyFileDiffs, *diff)
 		}
+
+		result.EntryDiffs = append(result.EntryDiffs, modFile.DirectiveDiffs()...)
 	}
 
+	result.Sort()
+
 	return result
 }
 
@@ -102,10 +111,69 @@ func (m *ModFile) Diff() *deps.FileDiff {
 	}
 
 	diff := latestDeps.Diff(m.Path, initialDeps)
+	diff.Ecosystem = deps.EcosystemGo
 
 	return &diff
 }
 
+// DirectiveDiffs compares the replace and exclude directives of the initial and latest go.mod contents.
+func (m *ModFile) DirectiveDiffs() listeners.EntryDiffs {
+	if m.LatestContent == nil {
+		return nil
+	}
+
+	initialReplaces, initialExcludes, err := ParseDirectives(m.Path, m.InitialContent)
+	if err != nil {
+		slog.Error("initial go.mod file invalid", "error", err)
+		return nil
+	}
+
+	latestReplaces, latestExcludes, err := ParseDirectives(m.Path, m.LatestContent)
+	if err != nil {
+		slog.Error("latest go.mod file invalid", "error", err)
+		return nil
+	}
+
+	return listeners.EntryDiffs{
+		listeners.DiffEntries(m.Path, "replace", initialReplaces, latestReplaces),
+		listeners.DiffEntries(m.Path, "exclude", initialExcludes, latestExcludes),
+	}
+}
+
+// ParseDirectives returns the replace directives (old module => new module or local path) and exclude directives
+// (module@version) from a go.mod file.
+func ParseDirectives(modFilePath string, modFileContents []byte) ([]listeners.Entry, []listeners.Entry, error) {
+	parsedFile, err := modfile.Parse(modFilePath, modFileContents, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse go.mod file %q: %w", modFilePath, err)
+	}
+
+	replaces := make([]listeners.Entry, 0, len(parsedFile.Replace))
+	for _, replace := range parsedFile.Replace {
+		replaces = append(replaces, listeners.Entry{
+			Name:  moduleString(replace.Old.Path, replace.Old.Version),
+			Value: moduleString(replace.New.Path, replace.New.Version),
+		})
+	}
+
+	excludes := make([]listeners.Entry, 0, len(parsedFile.Exclude))
+	for _, exclude := range parsedFile.Exclude {
+		excludes = append(excludes, listeners.Entry{
+			Name: moduleString(exclude.Mod.Path, exclude.Mod.Version),
+		})
+	}
+
+	return replaces, excludes, nil
+}
+
+func moduleString(path, version string) string {
+	if version == "" {
+		return path
+	}
+
+	return path + "@" + version
+}
+
 func ParseDeps(modFilePath string, modFileContents []byte) (deps.Dependencies, error) {
 	parsedFile, err := modfile.Parse(modFilePath, modFileContents, nil)
 	if err != nil {
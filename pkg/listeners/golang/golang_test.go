@@ -0,0 +1,114 @@
+package golang_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/cneill/mon/pkg/listeners"
+	"github.com/cneill/mon/pkg/listeners/golang"
+	"github.com/cneill/mon/pkg/listeners/listenertest"
+)
+
+func TestParseDeps_Golden(t *testing.T) {
+	t.Parallel()
+
+	listenertest.Golden(t, "testdata", ".mod", golang.ParseDeps)
+}
+
+func TestModFile_DirectiveDiffs(t *testing.T) {
+	t.Parallel()
+
+	modFile := &golang.ModFile{
+		Path: "go.mod",
+		InitialContent: []byte(`module example.com/service
+
+go 1.25
+
+require (
+	github.com/lib/pq v1.10.9
+	golang.org/x/net v0.30.0
+)
+
+replace golang.org/x/net => golang.org/x/net v0.29.0
+
+replace github.com/lib/pq v1.10.9 => github.com/lib/pq v1.10.8
+
+exclude golang.org/x/net v0.28.0
+`),
+		LatestContent: []byte(`module example.com/service
+
+go 1.25
+
+require (
+	github.com/lib/pq v1.10.9
+	golang.org/x/net v0.30.0
+)
+
+replace golang.org/x/net => ../net
+
+replace github.com/spf13/cobra => ./third_party/cobra
+
+exclude golang.org/x/net v0.27.0
+`),
+	}
+
+	diffs := modFile.DirectiveDiffs()
+	if len(diffs) != 2 {
+		t.Fatalf("expected replace and exclude diffs, got %+v", diffs)
+	}
+
+	replaces, excludes := diffs[0], diffs[1]
+
+	if replaces.Category != "replace" || replaces.Path != "go.mod" {
+		t.Errorf("expected the first diff to be for replace directives in go.mod, got %+v", replaces)
+	}
+
+	expectEntries(t, "new replace", replaces.NewEntries, []listeners.Entry{
+		{Name: "github.com/spf13/cobra", Value: "./third_party/cobra"},
+	})
+	expectEntries(t, "deleted replace", replaces.DeletedEntries, []listeners.Entry{
+		{Name: "github.com/lib/pq@v1.10.9", Value: "github.com/lib/pq@v1.10.8"},
+	})
+
+	expectedUpdated := []listeners.UpdatedEntry{{
+		Initial: listeners.Entry{Name: "golang.org/x/net", Value: "golang.org/x/net@v0.29.0"},
+		Latest:  listeners.Entry{Name: "golang.org/x/net", Value: "../net"},
+	}}
+	if !slices.Equal(replaces.UpdatedEntries, expectedUpdated) {
+		t.Errorf("expected updated replace %+v, got %+v", expectedUpdated, replaces.UpdatedEntries)
+	}
+
+	if excludes.Category != "exclude" {
+		t.Errorf("expected the second diff to be for exclude directives, got %+v", excludes)
+	}
+
+	expectEntries(t, "new exclude", excludes.NewEntries, []listeners.Entry{{Name: "golang.org/x/net@v0.27.0"}})
+	expectEntries(t, "deleted exclude", excludes.DeletedEntries, []listeners.Entry{{Name: "golang.org/x/net@v0.28.0"}})
+
+	if len(excludes.UpdatedEntries) != 0 {
+		t.Errorf("expected excludes to never be updated, got %+v", excludes.UpdatedEntries)
+	}
+
+	modFile.LatestContent = modFile.InitialContent
+
+	if diffs := modFile.DirectiveDiffs(); !diffs.AllEmpty() {
+		t.Errorf("expected no directive changes for the same content, got %+v", diffs)
+	}
+}
+
+func expectEntries(t *testing.T, kind string, actual, expected []listeners.Entry) {
+	t.Helper()
+
+	if !slices.Equal(actual, expected) {
+		t.Errorf("expected %s entries %+v, got %+v", kind, expected, actual)
+	}
+}
+
+func FuzzParseDeps(f *testing.F) {
+	listenertest.Seed(f, "testdata", ".mod")
+
+	f.Fuzz(func(_ *testing.T, content []byte) {
+		_, _ = golang.ParseDeps("go.mod", content)
+		_, _, _ = golang.ParseDirectives("go.mod", content)
+	})
+}
@@ -0,0 +1,20 @@
+module github.com/example/service
+
+go 1.22
+
+require (
+	github.com/go-chi/chi/v5 v5.0.12
+	github.com/jackc/pgx/v5 v5.5.5
+	golang.org/x/sync v0.7.0
+	github.com/stretchr/testify v1.9.0
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/jackc/pgpassfile v1.0.0 // indirect
+	golang.org/x/text v0.14.0 // indirect
+)
+
+replace github.com/go-chi/chi/v5 => ../chi
+
+exclude golang.org/x/sync v0.6.0
@@ -0,0 +1,8 @@
+module example.com/tool
+
+go 1.21.0
+
+toolchain go1.22.2
+
+require github.com/urfave/cli/v3 v3.0.0-alpha9
+require gopkg.in/yaml.v3 v3.0.1 // indirect
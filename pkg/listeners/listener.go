@@ -1,3 +1,7 @@
+// Package listeners defines the interface for listeners, which parse the manifests of one ecosystem (go.mod,
+// package.json, ...) and report how their dependencies and entries changed during the session.
+//
+// Stability: stable
 package listeners
 
 import "github.com/cneill/mon/pkg/deps"
@@ -9,6 +13,12 @@ type Listener interface {
 	Diff() Diff
 }
 
+// PathWatcher is implemented by listeners that also follow specific files discovered at runtime, regardless of their
+// base name (e.g. requirements files included from another one with -r).
+type PathWatcher interface {
+	WatchedPaths() []string
+}
+
 type EventLogger func(event Event) error
 
 type Event struct {
@@ -26,10 +36,19 @@ const (
 
 type Diff struct {
 	DependencyFileDiffs deps.FileDiffs
+	// EntryDiffs tracks changes to other notable manifest entries, like go.mod replace directives.
+	EntryDiffs EntryDiffs
+}
+
+// Sort orders the file and entry diffs by path, so output is stable between runs regardless of the order manifests
+// were discovered in.
+func (d Diff) Sort() {
+	d.DependencyFileDiffs.Sort()
+	d.EntryDiffs.Sort()
 }
 
 func (d Diff) IsEmpty() bool {
-	return d.DependencyFileDiffs.AllEmpty()
+	return d.DependencyFileDiffs.AllEmpty() && d.EntryDiffs.AllEmpty()
 }
 
 type DiffMap map[string]Diff
@@ -0,0 +1,120 @@
+// Package listenertest is a test harness for listener manifest parsers. Each parser gets a directory of real-world
+// manifests (fixtures), each with a golden file holding the dependencies it's expected to produce, and the same
+// fixtures seed the parser's fuzz targets.
+package listenertest
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/cneill/mon/pkg/deps"
+)
+
+// ParseFunc parses a manifest's content into its dependencies. path is the fixture's path, for parsers that want a
+// file name.
+type ParseFunc func(path string, content []byte) (deps.Dependencies, error)
+
+// GoldenSuffix is appended to a fixture's path to name its golden file.
+const GoldenSuffix = ".golden.json"
+
+//nolint:gochecknoglobals
+var update = flag.Bool("update", false, "rewrite listener golden files with the parsers' current output")
+
+// Golden parses every fixture in dir whose name ends in ext and compares the dependencies found (sorted by package,
+// then version) with the fixture's golden file. Run the tests with -update to write the golden files instead.
+func Golden(t *testing.T, dir, ext string, parse ParseFunc) {
+	t.Helper()
+
+	fixtures := Fixtures(t, dir, ext)
+	if len(fixtures) == 0 {
+		t.Fatalf("no %s fixtures found in %s", ext, dir)
+	}
+
+	for _, path := range fixtures {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			content, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("failed to read fixture: %v", err)
+			}
+
+			dependencies, err := parse(path, content)
+			if err != nil {
+				t.Fatalf("failed to parse fixture: %v", err)
+			}
+
+			slices.SortFunc(dependencies, func(a, b deps.Dependency) int {
+				return cmp.Or(cmp.Compare(a.Package(), b.Package()), cmp.Compare(a.Version, b.Version))
+			})
+
+			actual, err := json.MarshalIndent(dependencies, "", "  ")
+			if err != nil {
+				t.Fatalf("failed to serialize dependencies: %v", err)
+			}
+
+			actual = append(actual, '\n')
+			goldenPath := path + GoldenSuffix
+
+			if *update {
+				if err := os.WriteFile(goldenPath, actual, 0o644); err != nil {
+					t.Fatalf("failed to write golden file: %v", err)
+				}
+
+				return
+			}
+
+			expected, err := os.ReadFile(goldenPath)
+			if err != nil {
+				t.Fatalf("failed to read golden file (run with -update to create it): %v", err)
+			}
+
+			if !bytes.Equal(actual, expected) {
+				t.Errorf("dependencies don't match %s (run with -update if the change is intended)\nexpected:\n%s\nactual:\n%s",
+					goldenPath, expected, actual)
+			}
+		})
+	}
+}
+
+// Seed adds the content of every fixture in dir whose name ends in ext to a fuzz target's corpus.
+func Seed(f *testing.F, dir, ext string) {
+	f.Helper()
+
+	for _, path := range Fixtures(f, dir, ext) {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			f.Fatalf("failed to read fixture: %v", err)
+		}
+
+		f.Add(content)
+	}
+}
+
+// Fixtures returns the paths of the fixtures in dir whose names end in ext, skipping golden files.
+func Fixtures(tb testing.TB, dir, ext string) []string {
+	tb.Helper()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		tb.Fatalf("failed to read fixture directory: %v", err)
+	}
+
+	var results []string
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ext) || strings.HasSuffix(name, GoldenSuffix) {
+			continue
+		}
+
+		results = append(results, filepath.Join(dir, name))
+	}
+
+	return results
+}
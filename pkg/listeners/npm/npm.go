@@ -2,9 +2,11 @@ package npm
 
 import (
 	"encoding/json"
+	"fmt"
 	"log/slog"
 	"net/url"
 	"path/filepath"
+	"strings"
 	"sync"
 
 	"github.com/cneill/mon/pkg/deps"
@@ -48,17 +50,17 @@ func (l *Listener) LogEvent(event listeners.Event) error {
 
 	case listeners.EventWrite:
 		if base == "package.json" {
+			l.mutex.Lock()
+
 			for _, pkgFile := range l.packageFiles {
 				if pkgFile.Path == event.Name {
 					slog.Debug("got write event for package.json file", "path", event.Name)
 
-					l.mutex.Lock()
-
 					pkgFile.LatestContent = event.Content
-
-					l.mutex.Unlock()
 				}
 			}
+
+			l.mutex.Unlock()
 		}
 	}
 
@@ -66,6 +68,9 @@ func (l *Listener) LogEvent(event listeners.Event) error {
 }
 
 func (l *Listener) Diff() listeners.Diff {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
 	result := listeners.Diff{
 		DependencyFileDiffs: deps.FileDiffs{},
 	}
@@ -74,8 +79,14 @@ func (l *Listener) Diff() listeners.Diff {
 		if diff := pkgFile.Diff(); diff != nil {
 			result.DependencyFileDiffs = append(result.DependencyFileDiffs, *diff)
 		}
+
+		if diff := pkgFile.ScriptsDiff(); diff != nil {
+			result.EntryDiffs = append(result.EntryDiffs, *diff)
+		}
 	}
 
+	result.Sort()
+
 	return result
 }
 
@@ -103,6 +114,27 @@ func (p *PackageFile) Diff() *deps.FileDiff {
 	latestDeps := parsedLatest.ToDeps()
 
 	diff := latestDeps.Diff(p.Path, initialDeps)
+	diff.Ecosystem = deps.EcosystemNPM
+
+	return &diff
+}
+
+// ScriptsDiff compares the "scripts" sections of the initial and latest package.json contents. Lifecycle scripts like
+// postinstall run arbitrary commands on install, so they are worth surfacing even though they aren't dependencies.
+func (p *PackageFile) ScriptsDiff() *listeners.EntryDiff {
+	var parsedInitial PackageJSON
+	if err := json.Unmarshal(p.InitialContent, &parsedInitial); err != nil {
+		slog.Error("initial package.json file invalid", "path", p.Path, "error", err)
+		return nil
+	}
+
+	var parsedLatest PackageJSON
+	if err := json.Unmarshal(p.LatestContent, &parsedLatest); err != nil {
+		slog.Error("latest package.json file invalid", "path", p.Path, "error", err)
+		return nil
+	}
+
+	diff := listeners.DiffEntries(p.Path, "scripts", parsedInitial.ScriptEntries(), parsedLatest.ScriptEntries())
 
 	return &diff
 }
@@ -119,7 +151,7 @@ type PackageJSON struct {
 	// Author          string            `json:"author"`
 	// License         string            `json:"license"`
 	// Homepage        string            `json:"homepage"`
-	// Scripts         map[string]string `json:"scripts"`
+	Scripts      map[string]string `json:"scripts"`
 	Dependencies map[string]string `json:"dependencies"`
 	// DevDependencies map[string]string `json:"devDependencies"`
 	// Repository      struct {
@@ -128,13 +160,40 @@ type PackageJSON struct {
 	// } `json:"repository"`
 }
 
+// ParsePackageJSON parses a package.json file into its dependencies.
+func ParsePackageJSON(content []byte) (deps.Dependencies, error) {
+	var parsed PackageJSON
+	if err := json.Unmarshal(content, &parsed); err != nil {
+		return nil, fmt.Errorf("failed to parse package.json: %w", err)
+	}
+
+	return parsed.ToDeps(), nil
+}
+
+func (p *PackageJSON) ScriptEntries() []listeners.Entry {
+	results := make([]listeners.Entry, 0, len(p.Scripts))
+	for name, command := range p.Scripts {
+		results = append(results, listeners.Entry{
+			Name:  name,
+			Value: command,
+		})
+	}
+
+	return results
+}
+
 func (p *PackageJSON) ToDeps() deps.Dependencies {
 	results := make(deps.Dependencies, len(p.Dependencies))
 	depIdx := 0
 
-	for identifier, version := range p.Dependencies {
+	for identifier, constraint := range p.Dependencies {
 		dep := deps.Dependency{
-			Version: version,
+			Constraint: constraint,
+		}
+
+		// Without a lockfile, only an exact version tells us what gets installed
+		if exact := strings.TrimPrefix(strings.TrimSpace(constraint), "="); isExactVersion(exact) {
+			dep.Version = strings.TrimPrefix(exact, "v")
 		}
 
 		parsedURL, err := url.Parse(identifier)
@@ -150,3 +209,25 @@ func (p *PackageJSON) ToDeps() deps.Dependencies {
 
 	return results
 }
+
+// isExactVersion reports whether a package.json version string names one semver release, like 1.2.3 or
+// 1.0.0-beta.1, rather than a range, tag, or direct reference.
+func isExactVersion(version string) bool {
+	version = strings.TrimPrefix(version, "v")
+
+	core, _, _ := strings.Cut(version, "-")
+	core, _, _ = strings.Cut(core, "+")
+
+	parts := strings.Split(core, ".")
+	if len(parts) != 3 {
+		return false
+	}
+
+	for _, part := range parts {
+		if part == "" || strings.Trim(part, "0123456789") != "" {
+			return false
+		}
+	}
+
+	return true
+}
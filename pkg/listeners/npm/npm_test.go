@@ -0,0 +1,76 @@
+package npm_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/cneill/mon/pkg/deps"
+	"github.com/cneill/mon/pkg/listeners"
+	"github.com/cneill/mon/pkg/listeners/listenertest"
+	"github.com/cneill/mon/pkg/listeners/npm"
+)
+
+func TestParsePackageJSON_Golden(t *testing.T) {
+	t.Parallel()
+
+	listenertest.Golden(t, "testdata", ".json", func(_ string, content []byte) (deps.Dependencies, error) {
+		return npm.ParsePackageJSON(content)
+	})
+}
+
+func TestPackageFile_ScriptsDiff(t *testing.T) {
+	t.Parallel()
+
+	pkgFile := &npm.PackageFile{
+		Path: "package.json",
+		InitialContent: []byte(`{
+  "name": "app",
+  "scripts": {"build": "tsc", "lint": "eslint .", "test": "jest"}
+}`),
+		LatestContent: []byte(`{
+  "name": "app",
+  "scripts": {"build": "tsc -p tsconfig.build.json", "postinstall": "node ./setup.js", "test": "jest"}
+}`),
+	}
+
+	diff := pkgFile.ScriptsDiff()
+	if diff == nil {
+		t.Fatalf("expected a scripts diff")
+	}
+
+	if diff.Category != "scripts" || diff.Path != "package.json" {
+		t.Errorf("expected a diff of the scripts in package.json, got %+v", diff)
+	}
+
+	expectedNew := []listeners.Entry{{Name: "postinstall", Value: "node ./setup.js"}}
+	if !slices.Equal(diff.NewEntries, expectedNew) {
+		t.Errorf("expected new scripts %+v, got %+v", expectedNew, diff.NewEntries)
+	}
+
+	expectedDeleted := []listeners.Entry{{Name: "lint", Value: "eslint ."}}
+	if !slices.Equal(diff.DeletedEntries, expectedDeleted) {
+		t.Errorf("expected deleted scripts %+v, got %+v", expectedDeleted, diff.DeletedEntries)
+	}
+
+	expectedUpdated := []listeners.UpdatedEntry{{
+		Initial: listeners.Entry{Name: "build", Value: "tsc"},
+		Latest:  listeners.Entry{Name: "build", Value: "tsc -p tsconfig.build.json"},
+	}}
+	if !slices.Equal(diff.UpdatedEntries, expectedUpdated) {
+		t.Errorf("expected updated scripts %+v, got %+v", expectedUpdated, diff.UpdatedEntries)
+	}
+
+	pkgFile.LatestContent = []byte(`{"scripts": `)
+
+	if diff := pkgFile.ScriptsDiff(); diff != nil {
+		t.Errorf("expected no scripts diff for invalid JSON, got %+v", diff)
+	}
+}
+
+func FuzzParsePackageJSON(f *testing.F) {
+	listenertest.Seed(f, "testdata", ".json")
+
+	f.Fuzz(func(_ *testing.T, content []byte) {
+		_, _ = npm.ParsePackageJSON(content)
+	})
+}
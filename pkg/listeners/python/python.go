@@ -5,6 +5,8 @@ import (
 	"bytes"
 	"fmt"
 	"log/slog"
+	"net/url"
+	"os"
 	"path/filepath"
 	"strings"
 	"sync"
@@ -36,20 +38,55 @@ func (l *Listener) WatchedFiles() []string {
 	}
 }
 
+// WatchedPaths returns every tracked requirements file, including the ones pulled in with -r/-c that aren't named
+// requirements.txt.
+func (l *Listener) WatchedPaths() []string {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	results := make([]string, 0, len(l.requirementsFiles))
+	for _, reqFile := range l.requirementsFiles {
+		results = append(results, reqFile.Path)
+	}
+
+	return results
+}
+
 func (l *Listener) LogEvent(event listeners.Event) error {
 	base := filepath.Base(event.Name)
 
-	switch base {
-	case "requirements.txt":
+	switch {
+	case base == "requirements.txt" || l.isRequirementsFile(event.Name):
 		return l.handleRequirementsTxt(event)
-	case "pyproject.toml":
+	case base == "pyproject.toml":
 		return l.handlePyProjectToml(event)
 	}
 
 	return nil
 }
 
+func (l *Listener) isRequirementsFile(path string) bool {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	return l.requirementsFile(path) != nil
+}
+
+// requirementsFile returns the tracked requirements file with the given path, if any. The caller must hold the lock.
+func (l *Listener) requirementsFile(path string) *RequirementsFile {
+	for _, reqFile := range l.requirementsFiles {
+		if reqFile.Path == path {
+			return reqFile
+		}
+	}
+
+	return nil
+}
+
 func (l *Listener) Diff() listeners.Diff {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
 	result := listeners.Diff{
 		DependencyFileDiffs: deps.FileDiffs{},
 	}
@@ -66,6 +103,8 @@ func (l *Listener) Diff() listeners.Diff {
 		}
 	}
 
+	result.Sort()
+
 	return result
 }
 
@@ -85,6 +124,7 @@ func (r *RequirementsFile) Diff() *deps.FileDiff {
 	latestDeps := ParseRequirementsTxt(r.LatestContent)
 
 	diff := latestDeps.Diff(r.Path, initialDeps)
+	diff.Ecosystem = deps.EcosystemPyPI
 
 	return &diff
 }
@@ -95,6 +135,11 @@ func (l *Listener) handleRequirementsTxt(event listeners.Event) error {
 
 	switch event.Type {
 	case listeners.EventInit:
+		if l.requirementsFile(event.Name) != nil {
+			// Already tracked, e.g. because another requirements file included it
+			return nil
+		}
+
 		slog.Debug("got init event for requirements.txt file", "path", event.Name)
 		l.requirementsFiles = append(l.requirementsFiles, &RequirementsFile{
 			Path:           event.Name,
@@ -103,17 +148,51 @@ func (l *Listener) handleRequirementsTxt(event listeners.Event) error {
 		})
 
 	case listeners.EventWrite:
-		for _, reqFile := range l.requirementsFiles {
-			if reqFile.Path == event.Name {
-				slog.Debug("got write event for requirements.txt file", "path", event.Name)
-				reqFile.LatestContent = event.Content
-			}
+		if reqFile := l.requirementsFile(event.Name); reqFile != nil {
+			slog.Debug("got write event for requirements.txt file", "path", event.Name)
+			reqFile.LatestContent = event.Content
 		}
 	}
 
+	l.trackIncludes(event.Name, event.Content)
+
 	return nil
 }
 
+// trackIncludes starts tracking the files referenced with -r/-c from a requirements file, and the files they
+// reference in turn. Files that are already tracked are skipped, which also guards against include cycles. Newly
+// discovered files start with their current content as the initial state. The caller must hold the lock.
+func (l *Listener) trackIncludes(path string, content []byte) {
+	dir := filepath.Dir(path)
+
+	for _, include := range ParseRequirementsIncludes(content) {
+		includePath := include
+		if !filepath.IsAbs(includePath) {
+			includePath = filepath.Join(dir, include)
+		}
+
+		if l.requirementsFile(includePath) != nil {
+			continue
+		}
+
+		includeContent, err := os.ReadFile(includePath)
+		if err != nil {
+			slog.Error("failed to read included requirements file", "path", includePath, "included_from", path, "error", err)
+			continue
+		}
+
+		slog.Debug("tracking included requirements file", "path", includePath, "included_from", path)
+
+		l.requirementsFiles = append(l.requirementsFiles, &RequirementsFile{
+			Path:           includePath,
+			InitialContent: includeContent,
+			LatestContent:  includeContent,
+		})
+
+		l.trackIncludes(includePath, includeContent)
+	}
+}
+
 func (l *Listener) handlePyProjectToml(event listeners.Event) error {
 	l.mutex.Lock()
 	defer l.mutex.Unlock()
@@ -163,13 +242,50 @@ func (p *PyProjectFile) Diff() *deps.FileDiff {
 	}
 
 	diff := latestDeps.Diff(p.Path, initialDeps)
+	diff.Ecosystem = deps.EcosystemPyPI
 
 	return &diff
 }
 
-// ParseRequirementsTxt parses a requirements.txt file into a list of dependencies.
+// ParseRequirementsIncludes returns the paths referenced by -r/--requirement and -c/--constraint lines in a
+// requirements file, as written in the file.
+func ParseRequirementsIncludes(content []byte) []string {
+	var results []string
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		for _, option := range []string{"--requirement", "--constraint", "-r", "-c"} {
+			rest, found := strings.CutPrefix(line, option)
+			if !found {
+				continue
+			}
+
+			// Long options need a separator; short ones may be glued to the path (-rdev.txt)
+			if strings.HasPrefix(option, "--") && rest != "" && rest[0] != '=' && rest[0] != ' ' && rest[0] != '\t' {
+				continue
+			}
+
+			rest = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(rest), "="))
+			if fields := strings.Fields(rest); len(fields) > 0 {
+				results = append(results, fields[0])
+			}
+
+			break
+		}
+	}
+
+	return results
+}
+
+// ParseRequirementsTxt parses a requirements.txt file into a list of dependencies. If the file points pip at a
+// package index other than PyPI, every dependency's Registry is set to it.
 func ParseRequirementsTxt(content []byte) deps.Dependencies {
-	var results deps.Dependencies
+	var (
+		results  deps.Dependencies
+		registry string
+	)
 
 	scanner := bufio.NewScanner(bytes.NewReader(content))
 	for scanner.Scan() {
@@ -180,6 +296,14 @@ func ParseRequirementsTxt(content []byte) deps.Dependencies {
 			continue
 		}
 
+		if indexURL, ok := parseIndexOption(line); ok {
+			if !isPyPI(indexURL) && registry == "" {
+				registry = indexURL
+			}
+
+			continue
+		}
+
 		if strings.HasPrefix(line, "-r") || strings.HasPrefix(line, "-c") || strings.HasPrefix(line, "-e") {
 			continue
 		}
@@ -194,9 +318,44 @@ func ParseRequirementsTxt(content []byte) deps.Dependencies {
 		}
 	}
 
+	for depIdx := range results {
+		results[depIdx].Registry = registry
+	}
+
 	return results
 }
 
+// parseIndexOption returns the URL from an --index-url/-i/--extra-index-url line in a requirements file.
+func parseIndexOption(line string) (string, bool) {
+	for _, option := range []string{"--index-url", "--extra-index-url", "-i"} {
+		rest, found := strings.CutPrefix(line, option)
+		if !found || (rest != "" && rest[0] != '=' && rest[0] != ' ' && rest[0] != '\t') {
+			continue
+		}
+
+		rest = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(rest), "="))
+		if fields := strings.Fields(rest); len(fields) > 0 {
+			return fields[0], true
+		}
+
+		return "", true
+	}
+
+	return "", false
+}
+
+// isPyPI reports whether an index URL points at the default Python package index.
+func isPyPI(indexURL string) bool {
+	parsed, err := url.Parse(indexURL)
+	if err != nil {
+		return false
+	}
+
+	host := strings.ToLower(parsed.Hostname())
+
+	return host == "pypi.org" || host == "pypi.python.org"
+}
+
 // pyProject represents the structure of pyproject.toml we care about.
 type pyProject struct {
 	Project struct {
@@ -228,43 +387,41 @@ func ParsePyProjectToml(content []byte) (deps.Dependencies, error) {
 //   - requests==2.28.0
 //   - requests>=2.0,<3.0
 //   - requests[security]>=2.0
+//   - requests (>=2.0)
 //   - git+https://github.com/user/repo.git@v1.0
 //   - package @ https://example.com/pkg.whl
 //   - requests>=2.0 ; python_version >= "3.8"
+//   - requests>=2.0;python_version>="3.8"
 func parsePEP508(line string) *deps.Dependency {
-	// Strip inline comments
-	if idx := strings.Index(line, "#"); idx != -1 {
-		line = line[:idx]
-	}
-
-	line = strings.TrimSpace(line)
+	line = strings.TrimSpace(stripComment(line))
 	if line == "" {
 		return nil
 	}
 
-	// Strip environment markers (everything after ;)
-	if idx := strings.Index(line, ";"); idx != -1 {
-		line = strings.TrimSpace(line[:idx])
-	}
-
 	// Handle URL-based dependencies (git+https://, https://, etc.)
 	if strings.HasPrefix(line, "git+") || strings.HasPrefix(line, "https://") || strings.HasPrefix(line, "http://") {
+		// URLs may contain semicolons, so their markers must be set off by whitespace
+		if idx := strings.Index(line, " ;"); idx != -1 {
+			line = strings.TrimSpace(line[:idx])
+		}
+
 		return parseURLDependency(line)
 	}
 
-	// Handle PEP 440 direct references: package @ URL
-	if name, url, found := strings.Cut(line, " @ "); found {
-		name := strings.TrimSpace(name)
-		url := strings.TrimSpace(url)
+	// Parse standard dependency: name[extras]versionspec, or name[extras] @ URL
+	return parseStandardDependency(line)
+}
 
-		return &deps.Dependency{
-			Name: name,
-			URL:  url,
+// stripComment removes a trailing comment from a requirements line. Like pip, it only treats # as the start of a
+// comment at the start of the line or after whitespace, so URL fragments like #egg=name survive.
+func stripComment(line string) string {
+	for idx := range len(line) {
+		if line[idx] == '#' && (idx == 0 || line[idx-1] == ' ' || line[idx-1] == '\t') {
+			return line[:idx]
 		}
 	}
 
-	// Parse standard dependency: name[extras]versionspec
-	return parseStandardDependency(line)
+	return line
 }
 
 // parseURLDependency handles git+ and direct URL dependencies.
@@ -288,39 +445,78 @@ func parseURLDependency(line string) *deps.Dependency {
 	return dep
 }
 
-// parseStandardDependency parses a standard name[extras]versionspec dependency.
+// isNameByte reports whether c may appear in a PEP 508 distribution name.
+func isNameByte(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c == '-' || c == '_' || c == '.'
+}
+
+// parseStandardDependency parses a standard name[extras]versionspec dependency, or a name[extras] @ URL direct
+// reference.
 func parseStandardDependency(line string) *deps.Dependency {
-	// Find where version specifier starts
-	// Version operators: ==, !=, <=, >=, <, >, ~=, ===
-	versionIdx := -1
-
-	for i := range len(line) {
-		c := line[i]
-		if c == '=' || c == '!' || c == '<' || c == '>' || c == '~' {
-			versionIdx = i
-			break
+	nameEnd := 0
+	for nameEnd < len(line) && isNameByte(line[nameEnd]) {
+		nameEnd++
+	}
+
+	if nameEnd == 0 {
+		return nil
+	}
+
+	dep := &deps.Dependency{Name: line[:nameEnd]}
+	rest := strings.TrimSpace(line[nameEnd:])
+
+	if extras, found := strings.CutPrefix(rest, "["); found {
+		extras, rest, _ = strings.Cut(extras, "]")
+		rest = strings.TrimSpace(rest)
+
+		for extra := range strings.SplitSeq(extras, ",") {
+			if extra = strings.TrimSpace(extra); extra != "" {
+				dep.Extras = append(dep.Extras, extra)
+			}
 		}
 	}
 
-	var name, version string
+	// PEP 440 direct reference: name @ URL, with any markers set off by whitespace
+	if rawURL, found := strings.CutPrefix(rest, "@"); found {
+		rawURL = strings.TrimSpace(rawURL)
+		if idx := strings.Index(rawURL, " ;"); idx != -1 {
+			rawURL = rawURL[:idx]
+		}
+
+		dep.URL = strings.TrimSpace(rawURL)
 
-	if versionIdx == -1 {
-		// No version specifier
-		name = line
-	} else {
-		name = line[:versionIdx]
-		version = line[versionIdx:]
+		return dep
 	}
 
-	name = strings.TrimSpace(name)
-	version, _ = strings.CutPrefix(strings.TrimSpace(version), "==")
+	// Environment markers follow a semicolon, with or without surrounding whitespace
+	if idx := strings.Index(rest, ";"); idx != -1 {
+		rest = rest[:idx]
+	}
 
-	if name == "" {
-		return nil
+	rest = strings.TrimSpace(rest)
+	if inner, found := strings.CutPrefix(rest, "("); found {
+		rest, _, _ = strings.Cut(inner, ")")
+	}
+
+	// Normalize the clauses so whitespace differences don't register as a change
+	var clauses []string
+
+	for clause := range strings.SplitSeq(rest, ",") {
+		if clause = strings.Join(strings.Fields(clause), ""); clause != "" {
+			clauses = append(clauses, clause)
+		}
 	}
 
-	return &deps.Dependency{
-		Name:    name,
-		Version: version,
+	dep.Constraint = strings.Join(clauses, ",")
+
+	// A single exact-match clause names the version itself, unless it's a prefix match like ==1.*
+	if len(clauses) == 1 && !strings.Contains(clauses[0], "*") {
+		if version, found := strings.CutPrefix(clauses[0], "==="); found {
+			dep.Version = version
+		} else if version, found := strings.CutPrefix(clauses[0], "=="); found {
+			dep.Version = version
+		}
 	}
+
+	return dep
 }
@@ -0,0 +1,155 @@
+package python //nolint:testpackage // fuzzes the unexported PEP 508 parser
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/cneill/mon/pkg/deps"
+	"github.com/cneill/mon/pkg/listeners"
+	"github.com/cneill/mon/pkg/listeners/listenertest"
+)
+
+func TestParseRequirementsTxt_Golden(t *testing.T) {
+	t.Parallel()
+
+	listenertest.Golden(t, "testdata/requirements", ".txt", func(_ string, content []byte) (deps.Dependencies, error) {
+		return ParseRequirementsTxt(content), nil
+	})
+}
+
+func TestParsePyProjectToml_Golden(t *testing.T) {
+	t.Parallel()
+
+	listenertest.Golden(t, "testdata/pyproject", ".toml", func(_ string, content []byte) (deps.Dependencies, error) {
+		return ParsePyProjectToml(content)
+	})
+}
+
+func TestParseRequirementsIncludes(t *testing.T) {
+	t.Parallel()
+
+	content := []byte(strings.Join([]string{
+		"-r base.txt",
+		"-rglued.txt",
+		"--requirement=long.txt",
+		"--requirement \tspaced.txt  # comment",
+		"-c constraints/pins.txt",
+		"--constraint=../shared.txt",
+		"--requirementnot-an-include.txt",
+		"--index-url https://pypi.org/simple",
+		"-r",
+		"requests==2.32.3",
+	}, "\n"))
+
+	expected := []string{"base.txt", "glued.txt", "long.txt", "spaced.txt", "constraints/pins.txt", "../shared.txt"}
+
+	if actual := ParseRequirementsIncludes(content); !slices.Equal(actual, expected) {
+		t.Errorf("expected includes %q, got %q", expected, actual)
+	}
+}
+
+func TestListener_Includes(t *testing.T) {
+	t.Parallel()
+
+	dir, err := filepath.Abs(filepath.Join("testdata", "includes"))
+	if err != nil {
+		t.Fatalf("failed to find test data: %v", err)
+	}
+
+	rootFile := filepath.Join(dir, "requirements.txt")
+	baseFile := filepath.Join(dir, "requirements", "base.txt")
+	commonFile := filepath.Join(dir, "requirements", "common.txt")
+	constraintsFile := filepath.Join(dir, "constraints.txt")
+
+	listener := New()
+
+	// common.txt includes both files that lead to it, so tracking them has to stop at the files already tracked
+	if err := listener.LogEvent(listeners.Event{
+		Name:    rootFile,
+		Type:    listeners.EventInit,
+		Content: mustRead(t, rootFile),
+	}); err != nil {
+		t.Fatalf("failed to log init event: %v", err)
+	}
+
+	expected := []string{rootFile, baseFile, commonFile, constraintsFile}
+	actual := listener.WatchedPaths()
+
+	slices.Sort(expected)
+	slices.Sort(actual)
+
+	if !slices.Equal(actual, expected) {
+		t.Fatalf("expected watched paths %q, got %q", expected, actual)
+	}
+
+	if err := listener.LogEvent(listeners.Event{
+		Name:    commonFile,
+		Type:    listeners.EventWrite,
+		Content: []byte("-r ../requirements.txt\n-rbase.txt\nsix==1.17.0\n"),
+	}); err != nil {
+		t.Fatalf("failed to log write event: %v", err)
+	}
+
+	var changed deps.FileDiffs
+
+	for _, fileDiff := range listener.Diff().DependencyFileDiffs {
+		if !fileDiff.IsEmpty() {
+			changed = append(changed, fileDiff)
+		}
+	}
+
+	if len(changed) != 1 || changed[0].Path != commonFile || len(changed[0].UpdatedDependencies) != 1 {
+		t.Fatalf("expected one updated dependency in %q, got %+v", commonFile, changed)
+	}
+
+	if updated := changed[0].UpdatedDependencies[0]; updated.Initial.Version != "1.16.0" ||
+		updated.Latest.Version != "1.17.0" {
+		t.Errorf("expected six to be updated from 1.16.0 to 1.17.0, got %+v", updated)
+	}
+}
+
+func FuzzParsePEP508(f *testing.F) {
+	for _, path := range listenertest.Fixtures(f, "testdata/requirements", ".txt") {
+		for _, line := range strings.Split(string(mustRead(f, path)), "\n") {
+			f.Add(line)
+		}
+	}
+
+	f.Fuzz(func(t *testing.T, line string) {
+		dep := parsePEP508(line)
+		if dep != nil && dep.Name == "" && dep.URL == "" {
+			t.Errorf("parsed %q into a dependency with neither a name nor a URL: %+v", line, dep)
+		}
+	})
+}
+
+func FuzzParseRequirementsTxt(f *testing.F) {
+	listenertest.Seed(f, "testdata/requirements", ".txt")
+
+	f.Fuzz(func(_ *testing.T, content []byte) {
+		ParseRequirementsTxt(content)
+		ParseRequirementsIncludes(content)
+	})
+}
+
+func FuzzParsePyProjectToml(f *testing.F) {
+	listenertest.Seed(f, "testdata/pyproject", ".toml")
+
+	f.Fuzz(func(_ *testing.T, content []byte) {
+		_, _ = ParsePyProjectToml(content)
+	})
+}
+
+func mustRead(tb testing.TB, path string) []byte {
+	tb.Helper()
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		tb.Fatalf("failed to read %s: %v", path, err)
+	}
+
+	return content
+}
@@ -0,0 +1,80 @@
+package listeners
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+// Scope restricts which directories a listener's manifests are honored in. Directories are relative to the project
+// directory and match themselves and everything beneath them. They may be doublestar globs, like "examples/**" or
+// "**/fixtures". An empty IncludeDirs allows the whole project.
+type Scope struct {
+	IncludeDirs []string `json:"include_dirs"`
+	ExcludeDirs []string `json:"exclude_dirs"`
+}
+
+func (s *Scope) OK() error {
+	for _, dir := range append(append([]string{}, s.IncludeDirs...), s.ExcludeDirs...) {
+		if filepath.IsAbs(dir) {
+			return fmt.Errorf("directory %q must be relative to the project directory", dir)
+		}
+
+		if !doublestar.ValidatePattern(filepath.ToSlash(dir)) {
+			return fmt.Errorf("directory %q is not a valid glob", dir)
+		}
+	}
+
+	return nil
+}
+
+// Allows reports whether a manifest at relPath (relative to the project directory) is in scope. Exclusions take
+// precedence over inclusions. A nil Scope allows everything.
+func (s *Scope) Allows(relPath string) bool {
+	if s == nil {
+		return true
+	}
+
+	relPath = filepath.Clean(relPath)
+
+	for _, dir := range s.ExcludeDirs {
+		if inDir(dir, relPath) {
+			return false
+		}
+	}
+
+	if len(s.IncludeDirs) == 0 {
+		return true
+	}
+
+	for _, dir := range s.IncludeDirs {
+		if inDir(dir, relPath) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// inDir reports whether relPath is in dir or one of the directories beneath it, or in any directory matching dir if it's
+// a glob.
+func inDir(dir, relPath string) bool {
+	dir = path.Clean(filepath.ToSlash(dir))
+	if dir == "." {
+		return true
+	}
+
+	for candidate := filepath.ToSlash(relPath); candidate != "." && candidate != "/"; candidate = path.Dir(candidate) {
+		if candidate == dir {
+			return true
+		}
+
+		if matched, _ := doublestar.Match(dir, candidate); matched {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,90 @@
+package listeners_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/cneill/mon/pkg/listeners"
+)
+
+func TestScope_Allows(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		scope    *listeners.Scope
+		relPath  string
+		expected bool
+	}{
+		{"nil scope", nil, "examples/package.json", true},
+		{"empty scope", &listeners.Scope{}, "examples/package.json", true},
+		{"empty scope at the root", &listeners.Scope{}, "package.json", true},
+
+		// Prefixes
+		{"excluded dir", &listeners.Scope{ExcludeDirs: []string{"examples"}}, "examples/package.json", false},
+		{"below excluded dir", &listeners.Scope{ExcludeDirs: []string{"examples"}}, "examples/app/package.json", false},
+		{"excluded dir with trailing slash", &listeners.Scope{ExcludeDirs: []string{"examples/"}}, "examples/package.json", false},
+		{"sibling with the same prefix", &listeners.Scope{ExcludeDirs: []string{"examples"}}, "examples2/package.json", true},
+		{"root not excluded", &listeners.Scope{ExcludeDirs: []string{"examples"}}, "package.json", true},
+		{"included dir", &listeners.Scope{IncludeDirs: []string{"web"}}, "web/package.json", true},
+		{"outside included dir", &listeners.Scope{IncludeDirs: []string{"web"}}, "package.json", false},
+		{"included project dir", &listeners.Scope{IncludeDirs: []string{"."}}, "package.json", true},
+		{"unclean include", &listeners.Scope{IncludeDirs: []string{"./web/../web"}}, "web/package.json", true},
+		{"unclean path", &listeners.Scope{IncludeDirs: []string{"web"}}, "./web/app/../package.json", true},
+		{
+			"nested fixture dir",
+			&listeners.Scope{IncludeDirs: []string{"web"}, ExcludeDirs: []string{"web/test/fixtures"}},
+			"web/test/fixtures/broken/package.json",
+			false,
+		},
+		{
+			"next to nested fixture dir",
+			&listeners.Scope{IncludeDirs: []string{"web"}, ExcludeDirs: []string{"web/test/fixtures"}},
+			"web/test/package.json",
+			true,
+		},
+		{
+			"exclusion wins",
+			&listeners.Scope{IncludeDirs: []string{"examples"}, ExcludeDirs: []string{"examples"}},
+			"examples/package.json",
+			false,
+		},
+
+		// Globs
+		{"double star", &listeners.Scope{ExcludeDirs: []string{"examples/**"}}, "examples/package.json", false},
+		{"double star nested", &listeners.Scope{ExcludeDirs: []string{"examples/**"}}, "examples/a/b/package.json", false},
+		{"double star elsewhere", &listeners.Scope{ExcludeDirs: []string{"examples/**"}}, "src/examples/package.json", true},
+		{"fixtures at any depth", &listeners.Scope{ExcludeDirs: []string{"**/fixtures"}}, "a/b/fixtures/c/package.json", false},
+		{"fixtures at the top", &listeners.Scope{ExcludeDirs: []string{"**/fixtures"}}, "fixtures/package.json", false},
+		{"not fixtures", &listeners.Scope{ExcludeDirs: []string{"**/fixtures"}}, "fixtures2/package.json", true},
+		{"single star", &listeners.Scope{IncludeDirs: []string{"services/*"}}, "services/api/package.json", true},
+		{"single star outside", &listeners.Scope{IncludeDirs: []string{"services/*"}}, "web/package.json", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			if actual := test.scope.Allows(filepath.FromSlash(test.relPath)); actual != test.expected {
+				t.Errorf("expected %q to be allowed by %+v: %t, got %t", test.relPath, test.scope, test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestScope_OK(t *testing.T) {
+	t.Parallel()
+
+	for _, scope := range []*listeners.Scope{
+		{IncludeDirs: []string{"/abs"}},
+		{ExcludeDirs: []string{"examples/[a"}},
+	} {
+		if err := scope.OK(); err == nil {
+			t.Errorf("expected an error for %+v", scope)
+		}
+	}
+
+	if err := (&listeners.Scope{IncludeDirs: []string{"web"}, ExcludeDirs: []string{"**/fixtures"}}).OK(); err != nil {
+		t.Errorf("expected a valid scope, got %v", err)
+	}
+}
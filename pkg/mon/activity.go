@@ -0,0 +1,67 @@
+package mon
+
+import (
+	"slices"
+	"strings"
+)
+
+// sparkBlocks are the levels of a sparkline, from lowest to highest.
+const sparkBlocks = "▁▂▃▄▅▆▇█"
+
+// sparklineWidth is how many cells the activity sparkline in the report takes up at most.
+const sparklineWidth = 60
+
+// squeeze sums neighboring counts so there are at most width of them, returning the new counts and how many of the
+// original counts went into each.
+func squeeze(counts []int64, width int) ([]int64, int) {
+	size := max((len(counts)+width-1)/width, 1)
+	results := make([]int64, 0, min(len(counts), width))
+
+	for chunk := range slices.Chunk(counts, size) {
+		var sum int64
+		for _, count := range chunk {
+			sum += count
+		}
+
+		results = append(results, sum)
+	}
+
+	return results, size
+}
+
+// sparkline draws each count as a bar scaled to the largest one. Zero counts are left blank, so idle stretches stand
+// out from quiet ones.
+func sparkline(counts []int64) string {
+	blocks := []rune(sparkBlocks)
+	highest := slices.Max(append([]int64{0}, counts...))
+
+	builder := &strings.Builder{}
+
+	for _, count := range counts {
+		if count <= 0 {
+			builder.WriteRune(' ')
+			continue
+		}
+
+		level := (count*int64(len(blocks)) + highest - 1) / highest
+		builder.WriteRune(blocks[level-1])
+	}
+
+	return builder.String()
+}
+
+// timelineString draws Activity for the report, e.g. "▁▃█ ▂ :: peak 42 events/min".
+func (s *StatusSnapshot) timelineString() string {
+	counts, size := squeeze(s.Activity.Counts, sparklineWidth)
+
+	parts := []string{
+		detailColor.Sprint(sparkline(counts)),
+		detailColor.Sprint("peak " + s.number(slices.Max(s.Activity.Counts)) + " events/min"),
+	}
+
+	if size > 1 {
+		parts = append(parts, detailColor.Sprint(s.number(int64(size))+" min per bar"))
+	}
+
+	return strings.Join(parts, s.separator())
+}
@@ -0,0 +1,41 @@
+package mon //nolint:testpackage // exercises the unexported sparkline drawing
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSparkline(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		counts   []int64
+		expected string
+	}{
+		{nil, ""},
+		{[]int64{0, 0}, "  "},
+		{[]int64{1, 0, 8, 4}, "▁ █▄"},
+		{[]int64{1, 100}, "▁█"},
+		{[]int64{3}, "█"},
+	}
+
+	for _, test := range tests {
+		if actual := sparkline(test.counts); actual != test.expected {
+			t.Errorf("expected %v to draw as %q, got %q", test.counts, test.expected, actual)
+		}
+	}
+}
+
+func TestSqueeze(t *testing.T) {
+	t.Parallel()
+
+	counts, size := squeeze([]int64{1, 2, 3, 4, 5}, 2)
+	if size != 3 || !slices.Equal(counts, []int64{6, 9}) {
+		t.Errorf("expected [6 9] at 3 per bar, got %v at %d", counts, size)
+	}
+
+	counts, size = squeeze([]int64{1, 2}, 60)
+	if size != 1 || !slices.Equal(counts, []int64{1, 2}) {
+		t.Errorf("expected counts to be left alone, got %v at %d", counts, size)
+	}
+}
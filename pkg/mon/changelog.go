@@ -0,0 +1,122 @@
+package mon
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// conventionalCommitRegex matches conventional commit subjects like "feat(parser)!: support extras".
+var conventionalCommitRegex = regexp.MustCompile(`^(\w+)(?:\([^)]*\))?(!)?:\s*(.+)$`)
+
+type changelogSection struct {
+	Type  string
+	Title string
+}
+
+// changelogSections lists the conventional commit types in the order they appear in a changelog fragment. Commits
+// with any other type (or no type at all) are grouped under "Other changes".
+//
+//nolint:gochecknoglobals
+var changelogSections = []changelogSection{
+	{Type: "feat", Title: "Features"},
+	{Type: "fix", Title: "Bug fixes"},
+	{Type: "perf", Title: "Performance"},
+	{Type: "refactor", Title: "Refactoring"},
+	{Type: "docs", Title: "Documentation"},
+	{Type: "test", Title: "Tests"},
+	{Type: "build", Title: "Build"},
+	{Type: "ci", Title: "CI"},
+	{Type: "chore", Title: "Chores"},
+}
+
+const otherChangesTitle = "Other changes"
+
+// Changelog renders the session's commits as a CHANGELOG-style Markdown fragment, grouped by conventional commit
+// type. Breaking changes are also listed in their own section at the top.
+func (s *StatusSnapshot) Changelog() string {
+	grouped := map[string][]string{}
+	breaking := []string{}
+
+	for _, commit := range s.Commits {
+		sectionType, entry, isBreaking := parseConventionalCommit(commit)
+		grouped[sectionType] = append(grouped[sectionType], entry)
+
+		if isBreaking {
+			breaking = append(breaking, entry)
+		}
+	}
+
+	builder := &strings.Builder{}
+	builder.Grow(256)
+
+	// Invisible when rendered, but lets tooling tie the fragment back to the session that produced it
+	fmt.Fprintf(builder, "<!-- mon session %s on %s (%s), mon %s, %s for %s -->\n\n",
+		s.Session.ID, s.Session.Hostname, s.Session.ProjectDir, s.Session.Version, s.times().Timestamp(s.StartTime),
+		durationString(s.Time.Sub(s.StartTime)))
+
+	writeSection := func(title string, entries []string) {
+		if len(entries) == 0 {
+			return
+		}
+
+		builder.WriteString("### " + title + "\n\n")
+
+		for _, entry := range entries {
+			builder.WriteString("- " + entry + "\n")
+		}
+
+		builder.WriteRune('\n')
+	}
+
+	writeSection("Breaking changes", breaking)
+
+	for _, section := range changelogSections {
+		writeSection(section.Title, grouped[section.Type])
+	}
+
+	writeSection(otherChangesTitle, grouped[""])
+
+	return strings.TrimRight(builder.String(), "\n") + "\n"
+}
+
+// WriteChangelog writes the output of Changelog to the given path.
+func (s *StatusSnapshot) WriteChangelog(path string) error {
+	if err := os.WriteFile(path, []byte(s.Changelog()), 0o644); err != nil {
+		return fmt.Errorf("failed to write changelog to %q: %w", path, err)
+	}
+
+	return nil
+}
+
+// parseConventionalCommit returns the changelog section type for the commit ("" for other changes), the rendered
+// entry, and whether the commit is marked as a breaking change.
+func parseConventionalCommit(commit *object.Commit) (string, string, bool) {
+	subject, body, _ := strings.Cut(strings.TrimSpace(commit.Message), "\n")
+	subject = strings.TrimSpace(subject)
+	shortHash := commit.Hash.String()[:7]
+
+	if subject == "" {
+		subject = "<empty message>"
+	}
+
+	matches := conventionalCommitRegex.FindStringSubmatch(subject)
+	if matches == nil {
+		return "", subject + " (" + shortHash + ")", false
+	}
+
+	commitType := strings.ToLower(matches[1])
+	isBreaking := matches[2] == "!" || strings.Contains(body, "BREAKING CHANGE")
+	entry := matches[3] + " (" + shortHash + ")"
+
+	for _, section := range changelogSections {
+		if section.Type == commitType {
+			return commitType, entry, isBreaking
+		}
+	}
+
+	return "", subject + " (" + shortHash + ")", isBreaking
+}
@@ -0,0 +1,124 @@
+package mon //nolint:testpackage // exercises the unexported conventional commit parser
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+func TestParseConventionalCommit(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		message     string
+		sectionType string
+		entry       string
+		breaking    bool
+	}{
+		{"feat: add extras", "feat", "add extras (0123456)", false},
+		{"feat(parser): support extras", "feat", "support extras (0123456)", false},
+		{"Fix(ui):   trim padding", "fix", "trim padding (0123456)", false},
+		{"feat!: drop Go 1.22", "feat", "drop Go 1.22 (0123456)", true},
+		{"refactor(api)!: rename Opts", "refactor", "rename Opts (0123456)", true},
+		{"fix: handle nil\n\nBREAKING CHANGE: returns an error now", "fix", "handle nil (0123456)", true},
+		{"fix: handle nil\n\nNot a breaking change", "fix", "handle nil (0123456)", false},
+		{"wip: half done", "", "wip: half done (0123456)", false},
+		{"wip!: half done", "", "wip!: half done (0123456)", true},
+		{"Update README", "", "Update README (0123456)", false},
+		{"feat : spaced colon", "", "feat : spaced colon (0123456)", false},
+		{"  \n", "", "<empty message> (0123456)", false},
+	}
+
+	hash := plumbing.NewHash("0123456789abcdef0123456789abcdef01234567")
+
+	for _, test := range tests {
+		sectionType, entry, breaking := parseConventionalCommit(&object.Commit{Hash: hash, Message: test.message})
+		if sectionType != test.sectionType || entry != test.entry || breaking != test.breaking {
+			t.Errorf("expected %q to parse as (%q, %q, %t), got (%q, %q, %t)", test.message, test.sectionType,
+				test.entry, test.breaking, sectionType, entry, breaking)
+		}
+	}
+}
+
+func TestRedactMessage(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		message  string
+		expected string
+	}{
+		{"feat(billing): charge the customer", "feat: " + redactedText},
+		{"fix!: stop charging", "fix!: " + redactedText},
+		{"chore: bump\n\nBREAKING CHANGE: needs Go 1.25", "chore!: " + redactedText},
+		{"Charge the customer", redactedText},
+	}
+
+	for _, test := range tests {
+		if actual := redactMessage(test.message); actual != test.expected {
+			t.Errorf("expected %q to be redacted to %q, got %q", test.message, test.expected, actual)
+		}
+	}
+}
+
+func TestStatusSnapshot_Changelog(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	commit := func(hash, message string) *object.Commit {
+		return &object.Commit{Hash: plumbing.NewHash(strings.Repeat(hash, 40)), Message: message}
+	}
+
+	snapshot := &StatusSnapshot{
+		Session:   SessionInfo{ID: "abc123", Hostname: "devbox", ProjectDir: "/src/app", Version: "v1.2.3"},
+		StartTime: start,
+		Time:      start.Add(time.Minute * 90),
+		Commits: []*object.Commit{
+			commit("1", "feat(parser): support extras"),
+			commit("2", "Update README"),
+			commit("3", "fix!: reject empty names"),
+			commit("4", "docs: explain scopes"),
+			commit("5", "feat: add --json\n\nBREAKING CHANGE: the old output is gone"),
+			commit("6", "wip: half done"),
+		},
+	}
+
+	header, changelog, found := strings.Cut(snapshot.Changelog(), "\n\n")
+	if !found {
+		t.Fatalf("expected a header comment, got %q", snapshot.Changelog())
+	}
+
+	if !strings.HasPrefix(header, "<!-- mon session abc123 on devbox (/src/app), mon v1.2.3, ") ||
+		!strings.HasSuffix(header, " for 1h 30m -->") {
+		t.Errorf("expected a header comment describing the session, got %q", header)
+	}
+
+	expected := `### Breaking changes
+
+- reject empty names (3333333)
+- add --json (5555555)
+
+### Features
+
+- support extras (1111111)
+- add --json (5555555)
+
+### Bug fixes
+
+- reject empty names (3333333)
+
+### Documentation
+
+- explain scopes (4444444)
+
+### Other changes
+
+- Update README (2222222)
+- wip: half done (6666666)
+`
+	if changelog != expected {
+		t.Errorf("expected changelog:\n%s\ngot:\n%s", expected, changelog)
+	}
+}
@@ -0,0 +1,122 @@
+package mon
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/cneill/mon/internal/atomicfile"
+	"github.com/cneill/mon/pkg/files"
+)
+
+// checkpoint is the on-disk state needed to resume a session after mon crashes or its terminal dies.
+type checkpoint struct {
+	SessionID   string            `json:"session_id"`
+	ProjectDir  string            `json:"project_dir"`
+	StartTime   time.Time         `json:"start_time"`
+	LastWrite   time.Time         `json:"last_write"`
+	Turns       []Turn            `json:"turns,omitempty"`
+	InitialHash string            `json:"initial_hash"`
+	Manifests   map[string][]byte `json:"manifests"` // initial content of listener manifests, keyed by path
+	Files       files.MapState    `json:"files"`
+
+	// InitialHashes are the session's starting commits in every project directory, keyed by directory, when there's
+	// more than one. InitialHash is the one for ProjectDir.
+	InitialHashes map[string]string `json:"initial_hashes,omitempty"`
+}
+
+func loadCheckpoint(path string) (*checkpoint, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint file: %w", err)
+	}
+
+	result := &checkpoint{}
+	if err := json.Unmarshal(data, result); err != nil {
+		return nil, fmt.Errorf("failed to parse checkpoint file %q: %w", path, err)
+	}
+
+	return result, nil
+}
+
+// initialHash returns the starting commit saved for a project directory, or an empty string if there isn't one.
+func (c *checkpoint) initialHash(dir string) string {
+	if hash, ok := c.InitialHashes[dir]; ok {
+		return hash
+	}
+
+	if dir == c.ProjectDir {
+		return c.InitialHash
+	}
+
+	return ""
+}
+
+func (m *Mon) checkpointLoop(ctx context.Context) {
+	if m.CheckpointPath == "" || m.CheckpointInterval <= 0 {
+		return
+	}
+
+	ticker := m.clock.NewTicker(m.CheckpointInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+			if err := m.writeCheckpoint(); err != nil {
+				slog.Error("failed to write session checkpoint", "path", m.CheckpointPath, "error", err)
+			}
+		}
+	}
+}
+
+// writeCheckpoint saves the current session state, writing to a temporary file first so a crash mid-write never
+// leaves a truncated checkpoint behind.
+func (m *Mon) writeCheckpoint() error {
+	cp := &checkpoint{
+		SessionID:   m.session.ID,
+		ProjectDir:  m.ProjectDir,
+		StartTime:   m.startTime,
+		LastWrite:   m.lastWriteTime(),
+		Turns:       m.turns.Turns(),
+		InitialHash: m.repos[0].git.InitialHash(),
+		Manifests:   m.manifests,
+		Files:       m.fileMonitor.FileMap().State(),
+	}
+
+	if len(m.repos) > 1 {
+		cp.InitialHashes = map[string]string{}
+		for _, repo := range m.repos {
+			cp.InitialHashes[repo.dir] = repo.git.InitialHash()
+		}
+	}
+
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return fmt.Errorf("failed to serialize checkpoint: %w", err)
+	}
+
+	if err := atomicfile.Write(m.CheckpointPath, data); err != nil {
+		return fmt.Errorf("failed to write checkpoint file: %w", err)
+	}
+
+	slog.Debug("wrote session checkpoint", "path", m.CheckpointPath)
+
+	return nil
+}
+
+// removeCheckpoint deletes the checkpoint after a clean shutdown, since there is nothing left to recover.
+func (m *Mon) removeCheckpoint() {
+	if m.CheckpointPath == "" {
+		return
+	}
+
+	if err := os.Remove(m.CheckpointPath); err != nil && !os.IsNotExist(err) {
+		slog.Error("failed to remove session checkpoint", "path", m.CheckpointPath, "error", err)
+	}
+}
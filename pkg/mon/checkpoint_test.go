@@ -0,0 +1,109 @@
+package mon //nolint:testpackage // exercises the unexported checkpoint
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/cneill/mon/pkg/files"
+	"github.com/cneill/mon/pkg/montest"
+)
+
+func TestCheckpoint_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	checkpointPath := filepath.Join(t.TempDir(), "checkpoint", "session.json")
+
+	repo, err := montest.NewGitRepo(dir)
+	if err != nil {
+		t.Fatalf("failed to build git repo: %v", err)
+	}
+
+	initialHash, err := repo.Commit("Add main.go", map[string]string{"main.go": "package main\n"})
+	if err != nil {
+		t.Fatalf("failed to commit: %v", err)
+	}
+
+	opts := &Opts{
+		ProjectDir:     dir,
+		DisplayMode:    DisplayModeQuiet,
+		CheckpointPath: checkpointPath,
+		DetailsOpts:    &DetailsOpts{},
+		ExportOpts:     &ExportOpts{},
+	}
+
+	original, err := New(opts)
+	if err != nil {
+		t.Fatalf("failed to create mon: %v", err)
+	}
+
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	newFile := filepath.Join(dir, "new.go")
+
+	if err := os.WriteFile(newFile, []byte("package main\n"), 0o644); err != nil {
+		t.Fatalf("failed to write %q: %v", newFile, err)
+	}
+
+	original.startTime = start
+	original.setLastWrite(start.Add(time.Minute * 3))
+	original.turns.Add(start.Add(time.Minute))
+	original.turns.Add(start.Add(time.Minute * 3))
+	original.fileMonitor.FileMap().Restore(files.MapState{
+		Files:        map[string]files.FileState{newFile: {FileType: files.FileTypeNew, Writes: 2}},
+		FilesCreated: 1,
+	})
+
+	if err := original.writeCheckpoint(); err != nil {
+		t.Fatalf("failed to write checkpoint: %v", err)
+	}
+
+	original.fileMonitor.Close()
+	original.closeRepos()
+
+	// Committed while mon was down, so it's part of the resumed session
+	if _, err := repo.Commit("Add new.go", map[string]string{"new.go": "package main\n"}); err != nil {
+		t.Fatalf("failed to commit: %v", err)
+	}
+
+	resumeOpts := *opts
+	resumeOpts.Resume = true
+
+	resumed, err := New(&resumeOpts)
+	if err != nil {
+		t.Fatalf("failed to resume mon: %v", err)
+	}
+
+	defer resumed.closeRepos()
+	defer resumed.fileMonitor.Close()
+
+	if resumed.session.ID != original.session.ID {
+		t.Errorf("expected session ID %q, got %q", original.session.ID, resumed.session.ID)
+	}
+
+	if !resumed.startTime.Equal(start) {
+		t.Errorf("expected start time %s, got %s", start, resumed.startTime)
+	}
+
+	if lastWrite := resumed.lastWriteTime(); !lastWrite.Equal(start.Add(time.Minute * 3)) {
+		t.Errorf("expected last write at %s, got %s", start.Add(time.Minute*3), lastWrite)
+	}
+
+	if turns := resumed.turns.Turns(); !slices.EqualFunc(turns, original.turns.Turns(), func(a, b Turn) bool {
+		return a.Start.Equal(b.Start) && a.End.Equal(b.End) && a.Writes == b.Writes
+	}) {
+		t.Errorf("expected turns %+v, got %+v", original.turns.Turns(), turns)
+	}
+
+	if hash := resumed.repos[0].git.InitialHash(); hash != initialHash {
+		t.Errorf("expected initial hash %s, got %s", initialHash, hash)
+	}
+
+	stats := resumed.fileMonitor.Stats(true)
+	if stats.NumFilesCreated != 1 || !slices.Contains(stats.NewFiles, newFile) {
+		t.Errorf("expected %q to still be counted as created, got %d: %v", newFile, stats.NumFilesCreated,
+			stats.NewFiles)
+	}
+}
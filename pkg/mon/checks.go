@@ -0,0 +1,213 @@
+package mon
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultCheckCommand builds and vets a Go project.
+const DefaultCheckCommand = "go build ./... && go vet ./..."
+
+const (
+	// checkTimeout is how long a check can run before it's stopped and counted as failed.
+	checkTimeout = 5 * time.Minute
+	// maxCheckOutput is how much of the end of a failed check's output is kept.
+	maxCheckOutput = 4096
+	// checkQueueSize is how many commits can wait to be checked. Commits made while it's full aren't checked.
+	checkQueueSize = 16
+)
+
+// CommitCheck is the result of running the check command on a commit.
+type CommitCheck struct {
+	Hash    string  `json:"hash"`
+	Passed  bool    `json:"passed"`
+	Seconds float64 `json:"seconds"`
+	// Output is the end of the command's combined output, if it failed.
+	Output string `json:"output,omitempty"`
+}
+
+type checkRequest struct {
+	repo *repo
+	hash string
+}
+
+// commitChecker runs a command, like a build, on each new commit in turn and keeps the results. Each commit's files are
+// exported to a temporary directory first, so uncommitted changes in the worktree don't affect the result.
+type commitChecker struct {
+	command string
+	queue   chan checkRequest
+
+	mutex   sync.Mutex
+	seen    map[string]struct{} // commits queued or checked
+	results []CommitCheck
+}
+
+func newCommitChecker(command string) *commitChecker {
+	return &commitChecker{
+		command: command,
+		queue:   make(chan checkRequest, checkQueueSize),
+		seen:    map[string]struct{}{},
+	}
+}
+
+// request queues the commit a repo's HEAD points to for checking, unless it's already been queued. Only HEAD is
+// checked, so when several commits show up at once, only the newest is.
+func (c *commitChecker) request(repo *repo) {
+	hash, err := repo.git.HeadHash()
+	if err != nil {
+		slog.Warn("failed to find the commit to check", "dir", repo.dir, "error", err)
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if _, ok := c.seen[hash]; ok {
+		return
+	}
+
+	select {
+	case c.queue <- checkRequest{repo: repo, hash: hash}:
+		c.seen[hash] = struct{}{}
+	default:
+		slog.Warn("too many commits waiting to be checked, skipping one", "hash", hash)
+	}
+}
+
+// run checks queued commits one at a time until ctx is done. A check still running then is stopped and not recorded.
+func (c *commitChecker) run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case req := <-c.queue:
+			result, ok := c.check(ctx, req)
+			if !ok {
+				continue
+			}
+
+			slog.Info("checked commit", "hash", result.Hash, "passed", result.Passed, "seconds", result.Seconds)
+
+			c.mutex.Lock()
+			c.results = append(c.results, result)
+			c.mutex.Unlock()
+		}
+	}
+}
+
+// check runs the command on a copy of a commit's files. It reports false if the check couldn't be run at all.
+func (c *commitChecker) check(ctx context.Context, req checkRequest) (CommitCheck, bool) {
+	dir, err := os.MkdirTemp("", "mon-check-*")
+	if err != nil {
+		slog.Error("failed to create a directory to check a commit in", "error", err)
+		return CommitCheck{}, false
+	}
+	defer os.RemoveAll(dir)
+
+	if err := req.repo.git.ExportCommit(req.hash, dir); err != nil {
+		slog.Error("failed to export commit to check", "hash", req.hash, "error", err)
+		return CommitCheck{}, false
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	output := &bytes.Buffer{}
+	cmd := shellCommand(ctx, c.command)
+	cmd.Dir = dir
+	cmd.Stdout = output
+	cmd.Stderr = output
+	// The shell's children may hold the output open after it's killed
+	cmd.WaitDelay = time.Second
+
+	start := time.Now()
+	err = cmd.Run()
+
+	if ctx.Err() != nil && !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return CommitCheck{}, false
+	}
+
+	result := CommitCheck{
+		Hash:    req.hash,
+		Passed:  err == nil,
+		Seconds: time.Since(start).Seconds(),
+	}
+
+	if !result.Passed {
+		result.Output = tail(output.String(), maxCheckOutput)
+		if result.Output == "" {
+			result.Output = err.Error()
+		}
+	}
+
+	return result, true
+}
+
+// tail returns at most the last size bytes of text, starting at a line.
+func tail(text string, size int) string {
+	text = strings.TrimSpace(text)
+	if len(text) <= size {
+		return text
+	}
+
+	cut := len(text) - size
+	if text[cut-1] == '\n' {
+		return text[cut:]
+	}
+
+	text = text[cut:]
+	if idx := strings.IndexByte(text, '\n'); idx >= 0 {
+		text = text[idx+1:]
+	}
+
+	return text
+}
+
+// Results returns the checks finished so far, in the order they finished.
+func (c *commitChecker) Results() []CommitCheck {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return slices.Clone(c.results)
+}
+
+// checksString counts the checks that passed and failed for the report, e.g. "3 checks passed :: 1 failed".
+func (s *StatusSnapshot) checksString() string {
+	var passed, failed int64
+
+	for _, check := range s.Checks {
+		if check.Passed {
+			passed++
+		} else {
+			failed++
+		}
+	}
+
+	result := addedColor.Sprint(s.plural(passed, "check") + " passed")
+	if failed > 0 {
+		result += s.separator() + removedColor.Sprint(s.number(failed)+" failed")
+	}
+
+	return result
+}
+
+// check describes the result of checking a commit for the list of commits, if it was checked.
+func (s *StatusSnapshot) check(hash string) (string, bool) {
+	idx := slices.IndexFunc(s.Checks, func(check CommitCheck) bool { return check.Hash == hash })
+	if idx < 0 {
+		return "", false
+	}
+
+	if !s.Checks[idx].Passed {
+		return removedColor.Sprint("check failed"), true
+	}
+
+	return addedColor.Sprint("check passed"), true
+}
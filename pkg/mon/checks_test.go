@@ -0,0 +1,29 @@
+package mon //nolint:testpackage // exercises the unexported output trimming
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTail(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		text     string
+		size     int
+		expected string
+	}{
+		{"", 10, ""},
+		{"ok\n", 10, "ok"},
+		{"first\nsecond\nthird\n", 12, "second\nthird"},
+		{"first\nsecond\nthird\n", 11, "third"},
+		{"first\nsecond\nthird", 100, "first\nsecond\nthird"},
+		{strings.Repeat("x", 20), 5, "xxxxx"},
+	}
+
+	for _, test := range tests {
+		if actual := tail(test.text, test.size); actual != test.expected {
+			t.Errorf("expected tail(%q, %d) to be %q, got %q", test.text, test.size, test.expected, actual)
+		}
+	}
+}
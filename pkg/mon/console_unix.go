@@ -0,0 +1,8 @@
+//go:build !windows
+
+package mon
+
+// terminalSupportsANSI reports whether the status line can be redrawn with ANSI escape sequences.
+func terminalSupportsANSI() bool {
+	return true
+}
@@ -0,0 +1,21 @@
+//go:build windows
+
+package mon
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// terminalSupportsANSI reports whether the status line can be redrawn with ANSI escape sequences. The color package
+// asks the console to enable virtual terminal processing at startup, which legacy consoles refuse. Output that isn't
+// a console (a pipe or file) gets escape sequences like it would anywhere else.
+func terminalSupportsANSI() bool {
+	var mode uint32
+	if err := windows.GetConsoleMode(windows.Handle(os.Stdout.Fd()), &mode); err != nil {
+		return true
+	}
+
+	return mode&windows.ENABLE_VIRTUAL_TERMINAL_PROCESSING != 0
+}
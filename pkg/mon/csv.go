@@ -0,0 +1,200 @@
+package mon
+
+import (
+	"encoding/csv"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cneill/mon/pkg/git"
+)
+
+// eventLogHeader is the first row of the events CSV file, with a column for each sessionEvent field.
+//
+//nolint:gochecknoglobals
+var eventLogHeader = []string{"time", "source", "type", "path", "detail"}
+
+// eventLog appends the session's events to a CSV file as they happen, for ExportOpts.EventsCSVPath.
+type eventLog struct {
+	mutex  sync.Mutex
+	file   *os.File
+	writer *csv.Writer
+}
+
+// openEventLog opens path for appending, writing the header first if the file is new or empty.
+func openEventLog(path string) (*eventLog, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open events CSV file: %w", err)
+	}
+
+	stat, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to stat events CSV file: %w", err)
+	}
+
+	log := &eventLog{
+		file:   file,
+		writer: csv.NewWriter(file),
+	}
+
+	if stat.Size() == 0 {
+		log.write(eventLogHeader)
+	}
+
+	return log, nil
+}
+
+func (l *eventLog) Record(event sessionEvent) {
+	l.write([]string{event.Time.Format(time.RFC3339Nano), event.Source, event.Type, event.Path, event.Detail})
+}
+
+func (l *eventLog) write(row []string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	// Flushed every row, so the file is complete even if mon is killed
+	if err := l.writer.Write(row); err == nil {
+		l.writer.Flush()
+	}
+
+	if err := l.writer.Error(); err != nil {
+		slog.Error("failed to write to events CSV file", "path", l.file.Name(), "error", err)
+	}
+}
+
+func (l *eventLog) Close() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.writer.Flush()
+
+	if err := l.file.Close(); err != nil {
+		return fmt.Errorf("failed to close events CSV file: %w", err)
+	}
+
+	return nil
+}
+
+// WriteCSV writes the session's final stats to dir as CSV tables, creating dir if needed: files.csv with each file's
+// status and writes, extensions.csv with the same totalled by file extension, and commits.csv with the session's
+// commits and their sizes.
+func (s *StatusSnapshot) WriteCSV(dir string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create CSV directory %q: %w", dir, err)
+	}
+
+	tables := []struct {
+		name string
+		rows [][]string
+	}{
+		{name: "files.csv", rows: s.fileRows()},
+		{name: "extensions.csv", rows: s.extensionRows()},
+		{name: "commits.csv", rows: s.commitRows()},
+	}
+
+	for _, table := range tables {
+		if err := writeCSVFile(filepath.Join(dir, table.name), table.rows); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func writeCSVFile(path string, rows [][]string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create %q: %w", path, err)
+	}
+	defer file.Close()
+
+	if err := csv.NewWriter(file).WriteAll(rows); err != nil {
+		return fmt.Errorf("failed to write %q: %w", path, err)
+	}
+
+	return nil
+}
+
+// fileRows lists every file that was created, deleted, or written, with its status, saves, and raw write events.
+func (s *StatusSnapshot) fileRows() [][]string {
+	statuses := map[string]string{}
+
+	for path := range s.WrittenFiles {
+		statuses[path] = "existing"
+	}
+
+	for _, path := range s.NewFiles {
+		statuses[path] = "new"
+	}
+
+	for _, path := range s.DeletedFiles {
+		statuses[path] = "deleted"
+	}
+
+	rows := [][]string{{"path", "status", "saves", "raw_writes"}}
+
+	for _, path := range slices.Sorted(maps.Keys(statuses)) {
+		rows = append(rows, []string{
+			s.relPath(path),
+			statuses[path],
+			strconv.FormatInt(s.WrittenFiles[path], 10),
+			strconv.FormatInt(s.RawWrittenFiles[path], 10),
+		})
+	}
+
+	return rows
+}
+
+// extensionRows lists ByExtension, with files without an extension under "(none)".
+func (s *StatusSnapshot) extensionRows() [][]string {
+	rows := [][]string{{"extension", "created", "deleted", "written", "saves"}}
+
+	for _, extension := range slices.Sorted(maps.Keys(s.ByExtension)) {
+		stats := s.ByExtension[extension]
+		rows = append(rows, []string{
+			extensionName(extension),
+			strconv.FormatInt(stats.Created, 10),
+			strconv.FormatInt(stats.Deleted, 10),
+			strconv.FormatInt(stats.Written, 10),
+			strconv.FormatInt(stats.Writes, 10),
+		})
+	}
+
+	return rows
+}
+
+// commitRows lists the session's commits, oldest first, with the lines each added and deleted.
+func (s *StatusSnapshot) commitRows() [][]string {
+	sizes := map[string]git.CommitSize{}
+	for _, size := range s.CommitSizes {
+		sizes[size.Hash] = size
+	}
+
+	rows := [][]string{{"hash", "time", "author", "email", "summary", "lines_added", "lines_deleted"}}
+
+	for _, commit := range s.Commits {
+		hash := commit.Hash.String()
+		summary, _, _ := strings.Cut(strings.TrimSpace(commit.Message), "\n")
+
+		rows = append(rows, []string{
+			hash,
+			commit.Author.When.Format(time.RFC3339),
+			commit.Author.Name,
+			commit.Author.Email,
+			strings.TrimSpace(summary),
+			strconv.FormatInt(sizes[hash].Added, 10),
+			strconv.FormatInt(sizes[hash].Deleted, 10),
+		})
+	}
+
+	return rows
+}
@@ -0,0 +1,118 @@
+package mon //nolint:testpackage // exercises the unexported event log
+
+import (
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/cneill/mon/pkg/files"
+	"github.com/cneill/mon/pkg/git"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+func readCSV(t *testing.T, path string) [][]string {
+	t.Helper()
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open %s: %v", path, err)
+	}
+	defer file.Close()
+
+	rows, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", path, err)
+	}
+
+	return rows
+}
+
+func TestStatusSnapshot_WriteCSV(t *testing.T) {
+	t.Parallel()
+
+	root := filepath.FromSlash("/project")
+	when := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	hash := plumbing.NewHash("0123456789abcdef0123456789abcdef01234567")
+
+	snapshot := &StatusSnapshot{
+		Session:         SessionInfo{ProjectDir: root},
+		NewFiles:        []string{filepath.Join(root, "main.go")},
+		DeletedFiles:    []string{filepath.Join(root, "README")},
+		WrittenFiles:    map[string]int64{filepath.Join(root, "main.go"): 2, filepath.Join(root, "util.go"): 1},
+		RawWrittenFiles: map[string]int64{filepath.Join(root, "main.go"): 5, filepath.Join(root, "util.go"): 1},
+		ByExtension: map[string]files.ExtensionStats{
+			"":    {Deleted: 1},
+			".go": {Created: 1, Written: 2, Writes: 3},
+		},
+		Commits: []*object.Commit{{
+			Hash:    hash,
+			Author:  object.Signature{Name: "Dev", Email: "dev@example.com", When: when},
+			Message: "Add main, with a comma\n\nBody",
+		}},
+		CommitSizes: []git.CommitSize{{Hash: hash.String(), Added: 10, Deleted: 2}},
+	}
+
+	dir := filepath.Join(t.TempDir(), "stats")
+	if err := snapshot.WriteCSV(dir); err != nil {
+		t.Fatalf("failed to write CSV: %v", err)
+	}
+
+	expected := map[string][][]string{
+		"files.csv": {
+			{"path", "status", "saves", "raw_writes"},
+			{"README", "deleted", "0", "0"},
+			{"main.go", "new", "2", "5"},
+			{"util.go", "existing", "1", "1"},
+		},
+		"extensions.csv": {
+			{"extension", "created", "deleted", "written", "saves"},
+			{"(none)", "0", "1", "0", "0"},
+			{".go", "1", "0", "2", "3"},
+		},
+		"commits.csv": {
+			{"hash", "time", "author", "email", "summary", "lines_added", "lines_deleted"},
+			{hash.String(), "2025-01-01T12:00:00Z", "Dev", "dev@example.com", "Add main, with a comma", "10", "2"},
+		},
+	}
+
+	for name, rows := range expected {
+		if got := readCSV(t, filepath.Join(dir, name)); !slices.EqualFunc(got, rows, slices.Equal) {
+			t.Errorf("%s: expected %v, got %v", name, rows, got)
+		}
+	}
+}
+
+func TestEventLog(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "events.csv")
+	when := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	// Reopening the log for a resumed session appends without repeating the header
+	for _, name := range []string{"a.go", "b.go"} {
+		log, err := openEventLog(path)
+		if err != nil {
+			t.Fatalf("failed to open event log: %v", err)
+		}
+
+		log.Record(sessionEvent{Time: when, Source: "files", Type: "write", Path: name})
+
+		if err := log.Close(); err != nil {
+			t.Fatalf("failed to close event log: %v", err)
+		}
+	}
+
+	expected := [][]string{
+		eventLogHeader,
+		{"2025-01-01T12:00:00Z", "files", "write", "a.go", ""},
+		{"2025-01-01T12:00:00Z", "files", "write", "b.go", ""},
+	}
+
+	if got := readCSV(t, path); !slices.EqualFunc(got, expected, slices.Equal) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
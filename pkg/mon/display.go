@@ -2,20 +2,28 @@ package mon
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"log/slog"
 	"maps"
+	"math"
 	"os"
+	"path/filepath"
 	"slices"
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
+	"github.com/cneill/mon/pkg/files"
+	"github.com/cneill/mon/pkg/git"
 	"github.com/cneill/mon/pkg/listeners"
 	"github.com/fatih/color"
 	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/mattn/go-isatty"
 )
 
-const clearLine = "\r\033[K" // Carriage return + clear to end of line
+const ansiClearLine = "\r\033[K" // Carriage return + clear to end of line
 
 //nolint:gochecknoglobals
 var (
@@ -24,19 +32,78 @@ var (
 	addedColor     = color.RGB(0, 255, 0)
 	removedColor   = color.RGB(255, 0, 0)
 	updatedColor   = color.RGB(255, 255, 0)
+	warningColor   = color.RGB(255, 0, 0).Add(color.Bold)
 	separatorColor = color.RGB(50, 50, 50).Add(color.Bold)
 	separator      = separatorColor.Sprint(" :: ")
 	detailColor    = color.RGB(26, 178, 255)
 	indent         = "  "
 )
 
+// DisplayMode is how mon shows live status while it runs.
+type DisplayMode string
+
+const (
+	// DisplayModeAuto uses DisplayModeLine when stdout is a terminal, and DisplayModeNDJSON otherwise.
+	DisplayModeAuto DisplayMode = ""
+	// DisplayModeLine redraws a status line in place, and prints the report as text.
+	DisplayModeLine DisplayMode = "line"
+	// DisplayModeNDJSON prints a line of JSON for every status change, and the report as a final line of JSON, so
+	// pipes and log files don't fill up with control sequences.
+	DisplayModeNDJSON DisplayMode = "ndjson"
+	// DisplayModeQuiet shows no live status, only the report as text.
+	DisplayModeQuiet DisplayMode = "quiet"
+	// DisplayModePlain prints status changes as labeled sentences on lines of their own, without colors or control
+	// sequences, for screen readers and dumb terminals. Updates are spaced out by at least DisplayInterval, which
+	// defaults to DefaultPlainDisplayInterval in this mode.
+	DisplayModePlain DisplayMode = "plain"
+)
+
+// stdoutIsTerminal reports whether stdout is a terminal, rather than e.g. a pipe or file.
+func stdoutIsTerminal() bool {
+	fd := os.Stdout.Fd()
+
+	return isatty.IsTerminal(fd) || isatty.IsCygwinTerminal(fd)
+}
+
+// DefaultDisplayInterval is used when Opts.DisplayInterval is not set.
+const DefaultDisplayInterval = time.Second
+
+// DefaultPlainDisplayInterval is used when Opts.DisplayInterval is not set in DisplayModePlain, so a screen reader
+// isn't kept talking through a busy session.
+const DefaultPlainDisplayInterval = time.Second * 15
+
+// minRedrawInterval caps how often the status line is redrawn, however busy the session is.
+const minRedrawInterval = time.Millisecond * 100
+
+// displayLoop redraws the status line when something triggers it, and checks for changes every DisplayInterval
+// otherwise. The line is only redrawn when it changed, so an idle session doesn't keep the terminal busy.
 func (m *Mon) displayLoop(ctx context.Context) {
-	ticker := time.NewTicker(time.Second)
+	if m.displayMode == DisplayModeQuiet {
+		return
+	}
+
+	interval := m.DisplayInterval
+	if interval <= 0 {
+		interval = DefaultDisplayInterval
+		if m.displayMode == DisplayModePlain {
+			interval = DefaultPlainDisplayInterval
+		}
+	}
+
+	// Plain updates each take a new line, so they're spaced out by the whole interval rather than redrawn as they happen
+	gap := minRedrawInterval
+	if m.displayMode == DisplayModePlain {
+		gap = interval
+	}
+
+	ticker := m.clock.NewTicker(interval)
 	defer ticker.Stop()
 
-	depTicker := time.NewTicker(time.Second * 5) // update dependencies at most every 5 seconds
+	depTicker := m.clock.NewTicker(time.Second * 5) // update dependencies at most every 5 seconds
 	defer depTicker.Stop()
 
+	lastLive := ""
+
 	for {
 		select {
 		case <-ctx.Done():
@@ -45,22 +112,99 @@ func (m *Mon) displayLoop(ctx context.Context) {
 			if !ok {
 				return
 			}
-		case <-ticker.C:
+		case <-ticker.C():
 		}
 
 		updateDeps := false
 
 		select {
-		case <-depTicker.C:
+		case <-depTicker.C():
 			updateDeps = true
 		default:
 		}
 
 		snapshot := m.GetStatusSnapshot(updateDeps, false)
 
-		fmt.Printf("%s%s", clearLine, snapshot.Live())
-		os.Stdout.Sync()
+		live := snapshot.Live()
+		if m.displayMode == DisplayModePlain {
+			live = snapshot.Plain()
+		}
+
+		if live != lastLive || m.redraw.Swap(false) {
+			m.printLive(snapshot, live)
+
+			lastLive = live
+		}
+
+		// Triggers that arrive in the meantime are merged into the next redraw
+		select {
+		case <-ctx.Done():
+			return
+		case <-m.clock.After(gap):
+		}
+	}
+}
+
+// printLive shows a changed status, as rendered by StatusSnapshot.Live.
+func (m *Mon) printLive(snapshot *StatusSnapshot, live string) {
+	switch m.displayMode { //nolint:exhaustive
+	case DisplayModeNDJSON:
+		m.printJSON(snapshot)
+		return
+	case DisplayModePlain:
+		fmt.Println(live)
+		return
+	}
+
+	fmt.Printf("%s%s", m.clearLine(), live)
+	os.Stdout.Sync()
+
+	m.liveWidth.Store(int64(utf8.RuneCountInString(live)))
+}
+
+// printReport prints the full session report.
+func (m *Mon) printReport(snapshot *StatusSnapshot) {
+	switch m.displayMode { //nolint:exhaustive
+	case DisplayModeNDJSON:
+		m.printJSON(snapshot)
+		return
+	case DisplayModePlain:
+		fmt.Println(snapshot.PlainFinal())
+		return
 	}
+
+	fmt.Println(m.clearLine() + snapshot.Final())
+}
+
+func (m *Mon) printJSON(snapshot *StatusSnapshot) {
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		slog.Error("failed to serialize status snapshot", "error", err)
+		return
+	}
+
+	fmt.Println(string(data))
+}
+
+// clearLine returns what to print to move back to the start of the status line and blank it. Only the status line
+// display mode draws one.
+func (m *Mon) clearLine() string {
+	if m.displayMode != DisplayModeLine {
+		return ""
+	}
+
+	if m.ansi {
+		return ansiClearLine
+	}
+
+	// Colors are disabled without ANSI support, so the previous line's length is its width on screen
+	return "\r" + strings.Repeat(" ", int(m.liveWidth.Load())) + "\r"
+}
+
+// redrawDisplay redraws the status line even if it hasn't changed, e.g. after something else was printed over it.
+func (m *Mon) redrawDisplay() {
+	m.redraw.Store(true)
+	m.triggerDisplay()
 }
 
 func (m *Mon) triggerDisplay() {
@@ -71,160 +215,760 @@ func (m *Mon) triggerDisplay() {
 }
 
 type StatusSnapshot struct {
-	*DetailsOpts
+	*DetailsOpts `json:"-"`
+
+	Session SessionInfo `json:"session"`
 
 	NumFilesCreated int64            `json:"num_files_created"`
 	NumFilesDeleted int64            `json:"num_files_deleted"`
+	NumFilesMoved   int64            `json:"num_files_moved"`
 	NewFiles        []string         `json:"new_file_paths"`
 	DeletedFiles    []string         `json:"deleted_file_paths"`
 	WrittenFiles    map[string]int64 `json:"file_writes"`
+	RawWrittenFiles map[string]int64 `json:"raw_file_writes"`
+	// ByExtension is only filled in for final snapshots, keyed by lowercased extension ("" for none).
+	ByExtension map[string]files.ExtensionStats `json:"by_extension,omitempty"`
+	// ByDirectory is only filled in for final snapshots, keyed by top-level directory as in files.TopDirectory.
+	ByDirectory map[string]files.DirectoryStats `json:"by_directory,omitempty"`
+	// Roots breaks the stats down by project directory. It's only filled in for final snapshots of sessions watching
+	// more than one.
+	Roots []RootStats `json:"roots,omitempty"`
+	// NumMeaningfulWrites counts saves that changed a file's contents. It only differs from the number of saves with
+	// content hashing on.
+	NumMeaningfulWrites int64 `json:"num_meaningful_writes"`
+	// Symlinks are counted separately from files.
+	NumSymlinksCreated int64 `json:"num_symlinks_created,omitempty"`
+	NumSymlinksDeleted int64 `json:"num_symlinks_deleted,omitempty"`
+	// ChangedPermissions is only filled in for final snapshots, with the current mode of each file (e.g. "-rwxr-xr-x").
+	NumPermissionChanges int64             `json:"num_permission_changes,omitempty"`
+	ChangedPermissions   map[string]string `json:"changed_permissions,omitempty"`
+	// NewCode estimates the lines of code in the files created during the session that are still there, keyed by
+	// language, whether or not they were committed. It's only filled in for final snapshots.
+	NewCode map[string]int64 `json:"new_code,omitempty"`
+	// TopNewFiles are the largest new files, biggest first. It's only filled in for final snapshots.
+	TopNewFiles []files.FileSize `json:"top_new_files,omitempty"`
+	// Generated totals the churn in generated files, which is left out of ByExtension, ByDirectory, and NewCode. It's
+	// only filled in for final snapshots, and nil if there wasn't any.
+	Generated *GeneratedStats `json:"generated,omitempty"`
+	// Binary totals the churn in binary files like images and compiled artifacts, which is also left out of
+	// ByExtension and ByDirectory. It's only filled in for final snapshots, and nil if there wasn't any.
+	Binary *FileGroupStats `json:"binary,omitempty"`
+	// Todos is the change in placeholder comments like TODO in the files changed during the session. It's only filled
+	// in for final snapshots, and nil if there wasn't any.
+	Todos *TodoStats `json:"todos,omitempty"`
+	// Activity counts the file events in each minute of the session. It's only filled in for final snapshots.
+	Activity files.Timeline `json:"activity,omitzero"`
+	// Paused is set while file changes aren't being counted (see files.Monitor.Pause).
+	Paused bool `json:"paused,omitempty"`
 
 	NumCommits      int64            `json:"num_commits"`
 	LinesAdded      int64            `json:"lines_added"`
 	LinesDeleted    int64            `json:"lines_deleted"`
 	UnstagedChanges int64            `json:"unstaged_changes"`
+	UnstagedFiles   []string         `json:"unstaged_files"`
 	Commits         []*object.Commit `json:"-"`
-	Patch           *object.Patch    `json:"-"`
+	SnapshotRefs    []string         `json:"snapshot_refs,omitempty"`
+	CommitSizes     []git.CommitSize `json:"commit_sizes,omitempty"`
+	PatchStats      object.FileStats `json:"-"`
+	// Checks are the results of running Opts.CheckCommand on the session's commits, in the order they finished. It's
+	// only filled in for final snapshots.
+	Checks []CommitCheck `json:"commit_checks,omitempty"`
 
+	WritesPerMinute int64 `json:"writes_per_minute"`
+	CommitsPerHour  int64 `json:"commits_per_hour"`
+
+	Time      time.Time `json:"time"` // when the snapshot was taken
 	StartTime time.Time `json:"start_time"`
 	LastWrite time.Time `json:"last_write"`
+	Turns     []Turn    `json:"turns,omitempty"`
 
 	ListenerDiffs listeners.DiffMap `json:"-"`
+
+	Health        HealthStats `json:"health"`
+	MonitorErrors []string    `json:"monitor_errors"`
+
+	// plain is set for the report from PlainFinal
+	plain bool
+}
+
+// HealthStats counts events that were dropped, ignored, or lost along the way, so silent data loss is at least
+// observable.
+type HealthStats struct {
+	FileEventsDropped  int64 `json:"file_events_dropped"`
+	FileEventsIgnored  int64 `json:"file_events_ignored"`
+	FileEventOverflows int64 `json:"file_event_overflows"`
+	GitEventsDropped   int64 `json:"git_events_dropped"`
+	WritesRateLimited  int64 `json:"writes_rate_limited"` // writes not forwarded to the git monitor or audio
+	AudioEventsDropped int64 `json:"audio_events_dropped"`
+}
+
+// Lossy reports whether any events were lost outright, as opposed to deliberately filtered or throttled.
+func (h HealthStats) Lossy() bool {
+	return h.FileEventsDropped > 0 || h.FileEventOverflows > 0 || h.GitEventsDropped > 0
 }
 
 func (m *Mon) GetStatusSnapshot(packages, final bool) *StatusSnapshot {
 	fileStats := m.fileMonitor.Stats(final)
-	slices.Sort(fileStats.NewFiles)
-	slices.Sort(fileStats.DeletedFiles)
 
-	gitStats := m.gitMonitor.Stats(final)
-	slices.Reverse(gitStats.Commits)
+	now := m.clock.Now()
 
 	snapshot := &StatusSnapshot{
 		DetailsOpts: m.DetailsOpts,
 
+		Session: m.session,
+
 		NumFilesCreated: fileStats.NumFilesCreated,
 		NumFilesDeleted: fileStats.NumFilesDeleted,
+		NumFilesMoved:   fileStats.NumFilesMoved,
 		NewFiles:        fileStats.NewFiles,
 		DeletedFiles:    fileStats.DeletedFiles,
 		WrittenFiles:    fileStats.WrittenFiles,
+		RawWrittenFiles: fileStats.RawWrittenFiles,
+		ByExtension:     fileStats.ByExtension,
+		ByDirectory:     fileStats.ByDirectory,
+
+		NumMeaningfulWrites: fileStats.NumMeaningfulWrites,
+		NumSymlinksCreated:  fileStats.NumSymlinksCreated,
+		NumSymlinksDeleted:  fileStats.NumSymlinksDeleted,
+
+		NumPermissionChanges: fileStats.NumPermissionChanges,
+		TopNewFiles:          fileStats.TopNewFilesBySize,
+		Activity:             fileStats.Activity,
+		Paused:               m.fileMonitor.Paused(),
 
-		NumCommits:      gitStats.NumCommits,
-		LinesAdded:      gitStats.LinesAdded,
-		LinesDeleted:    gitStats.LinesDeleted,
-		UnstagedChanges: gitStats.UnstagedChanges,
-		Commits:         gitStats.Commits,
-		Patch:           gitStats.Patch,
+		WritesPerMinute: m.writeRate.Count(now),
+		CommitsPerHour:  m.commitRate.Count(now),
 
+		Time:      now,
 		StartTime: m.startTime,
-		LastWrite: m.lastWrite,
+		LastWrite: m.lastWriteTime(),
+		Turns:     m.turns.Turns(),
+
+		Health: HealthStats{
+			FileEventsDropped:  fileStats.EventsDropped,
+			FileEventsIgnored:  fileStats.EventsIgnored,
+			FileEventOverflows: fileStats.EventOverflows,
+			WritesRateLimited:  m.writesRateLimited.Load(),
+		},
 
 		ListenerDiffs: listeners.DiffMap{},
 	}
 
-	if packages || final {
-		for _, listener := range m.listeners {
-			snapshot.ListenerDiffs[listener.Name()] = listener.Diff()
+	if len(fileStats.ChangedPermissions) > 0 {
+		snapshot.ChangedPermissions = make(map[string]string, len(fileStats.ChangedPermissions))
+		for path, mode := range fileStats.ChangedPermissions {
+			snapshot.ChangedPermissions[path] = mode.String()
 		}
+	}
 
-		m.listenerDiffsCached = snapshot.ListenerDiffs
-	} else {
-		snapshot.ListenerDiffs = m.listenerDiffsCached
+	m.addGitStats(snapshot, fileStats, final)
+
+	if final {
+		m.addFinalStats(snapshot, fileStats)
+	}
+
+	m.addListenerDiffs(snapshot, packages || final)
+
+	if m.AudioManager != nil {
+		snapshot.Health.AudioEventsDropped = m.AudioManager.Dropped()
 	}
 
+	m.monitorErrorMutex.Lock()
+	snapshot.MonitorErrors = slices.Clone(m.monitorErrors)
+	m.monitorErrorMutex.Unlock()
+
+	m.snapshotRefMutex.Lock()
+	snapshot.SnapshotRefs = slices.Clone(m.snapshotRefs)
+	m.snapshotRefMutex.Unlock()
+
 	return snapshot
 }
 
+// addFinalStats adds the stats that are only worth working out for the final report.
+func (m *Mon) addFinalStats(snapshot *StatusSnapshot, fileStats *files.Stats) {
+	snapshot.splitGenerated()
+	snapshot.splitBinary(fileStats.BinaryFiles)
+	snapshot.NewCode = countNewCode(snapshot.humanNewFiles())
+	snapshot.Todos = m.todoStats(snapshot)
+
+	if m.checker != nil {
+		snapshot.Checks = m.checker.Results()
+	}
+}
+
+// addListenerDiffs adds the dependency changes found by the listeners, asking them again if refresh is set, and using
+// the ones found last time otherwise.
+func (m *Mon) addListenerDiffs(snapshot *StatusSnapshot, refresh bool) {
+	if refresh {
+		for _, listener := range m.Listeners {
+			snapshot.ListenerDiffs[listener.Name()] = listener.Diff()
+		}
+	}
+
+	m.listenerMutex.Lock()
+	if refresh {
+		m.listenerDiffsCached = maps.Clone(snapshot.ListenerDiffs)
+	} else {
+		snapshot.ListenerDiffs = maps.Clone(m.listenerDiffsCached)
+	}
+	m.listenerMutex.Unlock()
+}
+
 func (s *StatusSnapshot) Live() string {
 	builder := &strings.Builder{}
 	builder.Grow(64)
 
 	builder.WriteString(labelColor.Sprint("[F] "))
-	builder.WriteString(addedColor.Sprint("+" + strconv.FormatInt(s.NumFilesCreated, 10)))
+	builder.WriteString(addedColor.Sprint("+" + s.number(s.NumFilesCreated)))
 	builder.WriteString(" / ")
-	builder.WriteString(removedColor.Sprint("-" + strconv.FormatInt(s.NumFilesDeleted, 10)))
-	builder.WriteString(separator)
+	builder.WriteString(removedColor.Sprint("-" + s.number(s.NumFilesDeleted)))
+	builder.WriteString(s.separator())
 	builder.WriteString(labelColor.Sprint("[L] "))
-	builder.WriteString(addedColor.Sprint("+" + strconv.FormatInt(s.LinesAdded, 10)))
+	builder.WriteString(addedColor.Sprint("+" + s.number(s.LinesAdded)))
 	builder.WriteString(" / ")
-	builder.WriteString(removedColor.Sprint("-" + strconv.FormatInt(s.LinesDeleted, 10)))
-	builder.WriteString(separator)
+	builder.WriteString(removedColor.Sprint("-" + s.number(s.LinesDeleted)))
+	builder.WriteString(s.separator())
 	builder.WriteString(labelColor.Sprint("[C] "))
-	builder.WriteString(addedColor.Sprint(s.NumCommits))
+	builder.WriteString(addedColor.Sprint(s.number(s.NumCommits)))
 
 	if !s.ListenerDiffs.IsEmpty() {
-		builder.WriteString(separator)
+		builder.WriteString(s.separator())
 		builder.WriteString(labelColor.Sprint("[D] "))
-		builder.WriteString(addedColor.Sprint("+" + strconv.FormatInt(s.ListenerDiffs.NumNewDependencies(), 10)))
+		builder.WriteString(addedColor.Sprint("+" + s.number(s.ListenerDiffs.NumNewDependencies())))
 		builder.WriteString(" / ")
-		builder.WriteString(removedColor.Sprint("-" + strconv.FormatInt(s.ListenerDiffs.NumDeletedDependencies(), 10)))
+		builder.WriteString(removedColor.Sprint("-" + s.number(s.ListenerDiffs.NumDeletedDependencies())))
 		builder.WriteString(" / ")
-		builder.WriteString(updatedColor.Sprint("~" + strconv.FormatInt(s.ListenerDiffs.NumUpdatedDependencies(), 10)))
+		builder.WriteString(updatedColor.Sprint("~" + s.number(s.ListenerDiffs.NumUpdatedDependencies())))
+	}
+
+	if s.WritesPerMinute > 0 || s.CommitsPerHour > 0 {
+		builder.WriteString(s.separator())
+		builder.WriteString(labelColor.Sprint("[R] "))
+		builder.WriteString(detailColor.Sprint(s.number(s.WritesPerMinute) + " w/m"))
+		builder.WriteString(" / ")
+		builder.WriteString(detailColor.Sprint(s.number(s.CommitsPerHour) + " c/h"))
+	}
+
+	s.writeGoals(builder)
+
+	if s.Paused {
+		builder.WriteString(s.separator())
+		builder.WriteString(labelColor.Sprint("[P] "))
+		builder.WriteString(warningColor.Sprint("paused"))
 	}
 
 	if s.UnstagedChanges > 0 {
-		builder.WriteString(separator)
+		builder.WriteString(s.separator())
 		builder.WriteString(labelColor.Sprint("[!] "))
-		builder.WriteString(addedColor.Sprint(s.UnstagedChanges))
+		builder.WriteString(addedColor.Sprint(s.number(s.UnstagedChanges)))
 	}
 
-	if since := time.Since(s.LastWrite); !s.LastWrite.IsZero() && since > time.Minute {
-		builder.WriteString(separator)
+	if since := s.Time.Sub(s.LastWrite); !s.LastWrite.IsZero() && since > time.Minute {
+		builder.WriteString(s.separator())
 		builder.WriteString(labelColor.Sprint("[~] "))
 		builder.WriteString(sublabelColor.Sprint(durationString(since)))
 	}
 
+	if len(s.MonitorErrors) > 0 {
+		builder.WriteString(s.separator())
+		// Keep the line short enough not to wrap, which would break redrawing it in place
+		latest := []rune(s.MonitorErrors[len(s.MonitorErrors)-1])
+		if len(latest) > 60 {
+			latest = append(latest[:59], '…')
+		}
+
+		builder.WriteString(warningColor.Sprint("[E] " + string(latest)))
+	}
+
 	return builder.String()
 }
 
+// Plain renders the status shown by Live as a sentence for DisplayModePlain, with every number labeled in words instead
+// of by symbols and colors. It leaves out the time since the last write, which would print a new line every minute of
+// an idle session.
+func (s *StatusSnapshot) Plain() string {
+	parts := []string{
+		s.plural(s.NumFilesCreated, "file") + " created, " + s.number(s.NumFilesDeleted) + " deleted",
+		s.plural(s.LinesAdded, "line") + " added, " + s.number(s.LinesDeleted) + " deleted",
+		s.plural(s.NumCommits, "commit"),
+	}
+
+	if !s.ListenerDiffs.IsEmpty() {
+		parts = append(parts, "dependencies "+s.number(s.ListenerDiffs.NumNewDependencies())+" added, "+
+			s.number(s.ListenerDiffs.NumDeletedDependencies())+" removed, "+
+			s.number(s.ListenerDiffs.NumUpdatedDependencies())+" updated")
+	}
+
+	if s.WritesPerMinute > 0 || s.CommitsPerHour > 0 {
+		parts = append(parts, s.plural(s.WritesPerMinute, "write")+" per minute, "+
+			s.plural(s.CommitsPerHour, "commit")+" per hour")
+	}
+
+	if !s.goals().IsEmpty() {
+		parts = append(parts, s.plainGoals())
+	}
+
+	if s.Paused {
+		parts = append(parts, "file changes paused")
+	}
+
+	if s.UnstagedChanges > 0 {
+		parts = append(parts, s.plural(s.UnstagedChanges, "uncommitted change"))
+	}
+
+	if len(s.MonitorErrors) > 0 {
+		parts = append(parts, "warning: "+s.MonitorErrors[len(s.MonitorErrors)-1])
+	}
+
+	return "Status: " + strings.Join(parts, "; ") + "."
+}
+
+// PlainFinal renders the report from Final for DisplayModePlain, for screen readers, which read separators, the padding
+// of table columns, and bars out loud. Parts of a line are separated by commas, and numbers are labeled in words.
+func (s *StatusSnapshot) PlainFinal() string {
+	plain := *s
+	plain.plain = true
+
+	return plain.Final()
+}
+
 func (s *StatusSnapshot) Final() string {
 	builder := &strings.Builder{}
-	builder.Grow(64)
+	builder.Grow(1024)
 
 	builder.WriteString(labelColor.Sprint("Session stats:\n"))
+	builder.WriteString(s.sessionString())
+	builder.WriteString(s.fileCountsString())
+	builder.WriteString(s.changesString())
+	builder.WriteString(s.worktreeString())
+
+	if s.ShowAllFiles {
+		builder.WriteString(s.filesString())
+	}
+
+	builder.WriteString(s.permissionsString())
+	builder.WriteString(s.largestFilesString())
+	builder.WriteString(s.rootsString())
+	builder.WriteString(s.extensionsString())
+	builder.WriteString(s.directoriesString())
+	builder.WriteString(s.patchString())
+	builder.WriteString(s.commitsString())
+	builder.WriteString(s.commitSizesString())
+	builder.WriteString(s.turnsString())
+	builder.WriteString(s.listenersString())
+	builder.WriteString(s.typosquatString())
+	builder.WriteString(s.unpinnedString())
+	builder.WriteString(s.externalSourcesString())
+	builder.WriteString(s.healthString())
+	builder.WriteString(s.monitorErrorsString())
+
+	return builder.String()
+}
+
+// sessionString shows which session this was, who worked in it, and when.
+func (s *StatusSnapshot) sessionString() string {
+	builder := &strings.Builder{}
+	builder.Grow(256)
+
+	builder.WriteString(indent)
+	builder.WriteString(sublabelColor.Sprint("Session: "))
+	builder.WriteString(detailColor.Sprint(s.Session.ID))
+	builder.WriteString(s.separator())
+	builder.WriteString(detailColor.Sprint(s.Session.Hostname))
+	builder.WriteString(s.separator())
+	builder.WriteString(detailColor.Sprint("mon " + s.Session.Version))
+	builder.WriteRune('\n')
+
+	builder.WriteString(indent)
+	builder.WriteString(sublabelColor.Sprint("Agents: "))
+	builder.WriteString(detailColor.Sprint(s.Session.AgentsString()))
+	builder.WriteRune('\n')
 
 	builder.WriteString(indent)
 	builder.WriteString(sublabelColor.Sprint("Duration: "))
-	builder.WriteString(detailColor.Sprint(durationString(time.Since(s.StartTime))))
+	builder.WriteString(detailColor.Sprint(durationString(s.Time.Sub(s.StartTime))))
+	builder.WriteString(s.separator())
+	builder.WriteString(sublabelColor.Sprint("started "))
+	builder.WriteString(detailColor.Sprint(s.times().Timestamp(s.StartTime)))
 	builder.WriteRune('\n')
 
+	if !s.Activity.IsEmpty() {
+		builder.WriteString(indent)
+		builder.WriteString(sublabelColor.Sprint("Activity: "))
+		builder.WriteString(s.timelineString())
+		builder.WriteRune('\n')
+	}
+
+	return builder.String()
+}
+
+// fileCountsString shows how many files and symlinks were created and deleted, and how many saves changed nothing.
+func (s *StatusSnapshot) fileCountsString() string {
+	builder := &strings.Builder{}
+	builder.Grow(256)
+
 	builder.WriteString(indent)
 	builder.WriteString(sublabelColor.Sprint("Files: "))
-	builder.WriteString(addedColor.Sprint(strconv.FormatInt(s.NumFilesCreated, 10) + " created"))
-	builder.WriteString(separator)
-	builder.WriteString(removedColor.Sprint(strconv.FormatInt(s.NumFilesDeleted, 10) + " deleted"))
+	builder.WriteString(addedColor.Sprint(s.number(s.NumFilesCreated) + " created"))
+	builder.WriteString(s.separator())
+	builder.WriteString(removedColor.Sprint(s.number(s.NumFilesDeleted) + " deleted"))
+
+	if s.NumFilesMoved > 0 {
+		builder.WriteString(s.separator())
+		builder.WriteString(detailColor.Sprint(s.number(s.NumFilesMoved) + " moved"))
+	}
+
 	builder.WriteRune('\n')
 
+	if s.NumSymlinksCreated > 0 || s.NumSymlinksDeleted > 0 {
+		builder.WriteString(indent)
+		builder.WriteString(sublabelColor.Sprint("Symlinks: "))
+		builder.WriteString(addedColor.Sprint(s.number(s.NumSymlinksCreated) + " created"))
+		builder.WriteString(s.separator())
+		builder.WriteString(removedColor.Sprint(s.number(s.NumSymlinksDeleted) + " deleted"))
+		builder.WriteRune('\n')
+	}
+
+	// Only with content hashing can saves that changed nothing be told apart
+	if saves := s.saves(); s.NumMeaningfulWrites < saves {
+		builder.WriteString(indent)
+		builder.WriteString(sublabelColor.Sprint("Saves: "))
+		builder.WriteString(detailColor.Sprint(s.number(saves)))
+		builder.WriteString(s.separator())
+		builder.WriteString(detailColor.Sprint(s.number(saves-s.NumMeaningfulWrites) + " changed nothing"))
+		builder.WriteRune('\n')
+	}
+
+	return builder.String()
+}
+
+// changesString shows what was committed and written: commits, lines, new code, TODOs, and generated and binary files.
+func (s *StatusSnapshot) changesString() string {
+	builder := &strings.Builder{}
+	builder.Grow(256)
+
 	builder.WriteString(indent)
 	builder.WriteString(sublabelColor.Sprint("Commits: "))
-	builder.WriteString(addedColor.Sprint(s.NumCommits))
+	builder.WriteString(addedColor.Sprint(s.number(s.NumCommits)))
+
+	if len(s.Checks) > 0 {
+		builder.WriteString(s.separator())
+		builder.WriteString(s.checksString())
+	}
+
 	builder.WriteRune('\n')
 
 	builder.WriteString(indent)
 	builder.WriteString(sublabelColor.Sprint("Lines: "))
-	builder.WriteString(addedColor.Sprint(strconv.FormatInt(s.LinesAdded, 10) + " added"))
-	builder.WriteString(separator)
-	builder.WriteString(removedColor.Sprint(strconv.FormatInt(s.LinesDeleted, 10) + " deleted"))
+	builder.WriteString(addedColor.Sprint(s.number(s.LinesAdded) + " added"))
+	builder.WriteString(s.separator())
+	builder.WriteString(removedColor.Sprint(s.number(s.LinesDeleted) + " deleted"))
 	builder.WriteRune('\n')
 
+	// Committed or not, unlike the lines above
+	if len(s.NewCode) > 0 {
+		builder.WriteString(indent)
+		builder.WriteString(sublabelColor.Sprint("New code: "))
+		builder.WriteString(addedColor.Sprint(s.newCodeString()))
+		builder.WriteRune('\n')
+	}
+
+	if s.Todos != nil {
+		builder.WriteString(indent)
+		builder.WriteString(sublabelColor.Sprint("TODOs: "))
+		builder.WriteString(s.todoString())
+		builder.WriteRune('\n')
+	}
+
+	if s.Generated != nil {
+		builder.WriteString(indent)
+		builder.WriteString(sublabelColor.Sprint("Generated: "))
+		builder.WriteString(s.generatedString())
+		builder.WriteRune('\n')
+	}
+
+	if s.Binary != nil {
+		builder.WriteString(indent)
+		builder.WriteString(sublabelColor.Sprint("Binary: "))
+		builder.WriteString(strings.Join(s.groupParts(*s.Binary), s.separator()))
+		builder.WriteRune('\n')
+	}
+
+	return builder.String()
+}
+
+// worktreeString shows what was left in the worktree: unstaged changes, and the refs the session recorded.
+func (s *StatusSnapshot) worktreeString() string {
+	builder := &strings.Builder{}
+	builder.Grow(256)
+
 	if s.UnstagedChanges > 0 {
 		builder.WriteString(indent)
 		builder.WriteString(sublabelColor.Sprint("Unstaged file changes: "))
-		builder.WriteString(addedColor.Sprint(s.UnstagedChanges))
+		builder.WriteString(addedColor.Sprint(s.number(s.UnstagedChanges)))
 		builder.WriteRune('\n')
+		builder.WriteString(s.unstagedFilesString())
 	}
 
-	if s.ShowAllFiles {
-		builder.WriteString(s.filesString())
+	if len(s.SnapshotRefs) > 0 {
+		builder.WriteString(indent)
+		builder.WriteString(sublabelColor.Sprint("Session refs: "))
+		builder.WriteString(detailColor.Sprint(strings.Join(s.SnapshotRefs, s.separator())))
+		builder.WriteRune('\n')
 	}
 
-	builder.WriteString(s.patchString())
-	builder.WriteString(s.commitsString())
-	builder.WriteString(s.listenersString())
+	return builder.String()
+}
+
+// rootsString shows each project directory's files and commits, for sessions watching more than one.
+func (s *StatusSnapshot) rootsString() string {
+	if len(s.Roots) == 0 {
+		return ""
+	}
+
+	width := 0
+	for _, root := range s.Roots {
+		width = max(width, len(filepath.Base(root.Dir)))
+	}
+
+	builder := &strings.Builder{}
+	builder.Grow(256)
+	builder.WriteString(labelColor.Sprint("\nProject directories:\n"))
+
+	for _, root := range s.Roots {
+		builder.WriteString(indent)
+		builder.WriteString(sublabelColor.Sprint(s.pad(filepath.Base(root.Dir), width)))
+		builder.WriteString(s.separator())
+		builder.WriteString(s.createdDeleted(root.Created, root.Deleted))
+		builder.WriteString(s.separator())
+		builder.WriteString(detailColor.Sprint(s.plural(root.Writes, "save") + " in " + s.plural(root.Written, "file")))
+		builder.WriteString(s.separator())
+		builder.WriteString(detailColor.Sprint(s.plural(root.NumCommits, "commit")))
+		builder.WriteString(s.separator())
+		builder.WriteString(s.addedDeleted(root.LinesAdded, root.LinesDeleted, " "))
+		builder.WriteRune('\n')
+	}
 
 	return builder.String()
 }
 
+// separator goes between the parts of a line in the report.
+func (s *StatusSnapshot) separator() string {
+	if s.plain {
+		return ", "
+	}
+
+	return separator
+}
+
+// pad pads a table cell to width, except in plain reports, where the padding would be read out loud.
+func (s *StatusSnapshot) pad(value string, width int) string {
+	if s.plain {
+		return value
+	}
+
+	return fmt.Sprintf("%-*s", width, value)
+}
+
+// createdDeleted shows how many files were created and deleted, as a table column, or in words in plain reports.
+func (s *StatusSnapshot) createdDeleted(created, deleted int64) string {
+	if s.plain {
+		return s.number(created) + " created, " + s.number(deleted) + " deleted"
+	}
+
+	return addedColor.Sprintf("+%-4s", s.number(created)) + removedColor.Sprintf("-%-4s", s.number(deleted))
+}
+
+// addedDeleted shows how many lines were added and deleted, with sep between them, or in words in plain reports.
+func (s *StatusSnapshot) addedDeleted(added, deleted int64, sep string) string {
+	if s.plain {
+		return s.plural(added, "line") + " added, " + s.number(deleted) + " deleted"
+	}
+
+	return addedColor.Sprint("+"+s.number(added)) + sep + removedColor.Sprint("-"+s.number(deleted))
+}
+
+// maxGroups is how many extensions or directories are listed in the final report, busiest first.
+const maxGroups = 10
+
+// plural formats a count followed by a word, adding an "s" to the word unless the count is one.
+func (s *StatusSnapshot) plural(count int64, word string) string {
+	if count != 1 {
+		word += "s"
+	}
+
+	return s.number(count) + " " + word
+}
+
+// extensionName labels an extension from ByExtension for people.
+func extensionName(extension string) string {
+	if extension == "" {
+		return "(none)"
+	}
+
+	return extension
+}
+
+// extensionsString shows what kinds of files were touched: how many of each were created and deleted, and how many
+// were written and how often.
+func (s *StatusSnapshot) extensionsString() string {
+	groups := make(map[string]files.ExtensionStats, len(s.ByExtension))
+	for extension, stats := range s.ByExtension {
+		groups[extensionName(extension)] = stats
+	}
+
+	return s.activityString("Files by extension", groups)
+}
+
+// directoryName labels a directory from ByDirectory for people.
+func directoryName(dir string) string {
+	return filepath.ToSlash(dir) + "/"
+}
+
+// directoriesString shows where in the project files were touched, totalled by top-level directory.
+func (s *StatusSnapshot) directoriesString() string {
+	groups := make(map[string]files.ExtensionStats, len(s.ByDirectory))
+	for dir, stats := range s.ByDirectory {
+		groups[directoryName(dir)] = stats
+	}
+
+	return s.activityString("Files by directory", groups)
+}
+
+// activityString lists the busiest groups of files by name, up to maxGroups, with how many of each were created and
+// deleted, and how many were written and how often.
+func (s *StatusSnapshot) activityString(title string, groups map[string]files.ExtensionStats) string {
+	if len(groups) == 0 {
+		return ""
+	}
+
+	activity := func(stats files.ExtensionStats) int64 { return stats.Created + stats.Deleted + stats.Writes }
+
+	keys := slices.SortedFunc(maps.Keys(groups), func(a, b string) int {
+		if diff := activity(groups[b]) - activity(groups[a]); diff != 0 {
+			return int(diff)
+		}
+
+		return strings.Compare(a, b)
+	})
+
+	width := 0
+	for _, key := range keys[:min(len(keys), maxGroups)] {
+		width = max(width, len(key))
+	}
+
+	builder := &strings.Builder{}
+	builder.Grow(256)
+	builder.WriteString(labelColor.Sprint("\n" + title + ":\n"))
+
+	for _, key := range keys[:min(len(keys), maxGroups)] {
+		stats := groups[key]
+
+		builder.WriteString(indent)
+		builder.WriteString(sublabelColor.Sprint(s.pad(key, width)))
+		builder.WriteString(s.separator())
+		builder.WriteString(s.createdDeleted(stats.Created, stats.Deleted))
+		builder.WriteString(s.separator())
+		builder.WriteString(detailColor.Sprint(s.plural(stats.Writes, "save") + " in " + s.plural(stats.Written, "file")))
+		builder.WriteRune('\n')
+	}
+
+	if extra := len(keys) - maxGroups; extra > 0 {
+		builder.WriteString(indent + sublabelColor.Sprint("+"+strconv.Itoa(extra)+" more") + "\n")
+	}
+
+	return builder.String()
+}
+
+// maxUnstagedFiles is how many files with unstaged changes are listed in the final report.
+const maxUnstagedFiles = 10
+
+// unstagedFilesString lists the files with unstaged changes under the count, so it's clear what was left uncommitted.
+func (s *StatusSnapshot) unstagedFilesString() string {
+	builder := &strings.Builder{}
+	builder.Grow(128)
+
+	for _, file := range s.UnstagedFiles[:min(len(s.UnstagedFiles), maxUnstagedFiles)] {
+		builder.WriteString(indent + indent + detailColor.Sprint(file) + "\n")
+	}
+
+	if extra := len(s.UnstagedFiles) - maxUnstagedFiles; extra > 0 {
+		builder.WriteString(indent + indent + sublabelColor.Sprint("+"+strconv.Itoa(extra)+" more") + "\n")
+	}
+
+	return builder.String()
+}
+
+func (s *StatusSnapshot) monitorErrorsString() string {
+	if len(s.MonitorErrors) == 0 {
+		return ""
+	}
+
+	builder := &strings.Builder{}
+	builder.Grow(128)
+
+	builder.WriteString(warningColor.Sprint("\nMonitoring was degraded during the session:\n"))
+
+	for _, message := range s.MonitorErrors {
+		builder.WriteString(indent + detailColor.Sprint(message) + "\n")
+	}
+
+	return builder.String()
+}
+
+// healthString reports lost events, if there were any. Throttled and ignored events are only listed alongside them
+// for context, since they're expected in normal operation.
+func (s *StatusSnapshot) healthString() string {
+	if !s.Health.Lossy() {
+		return ""
+	}
+
+	builder := &strings.Builder{}
+	builder.Grow(128)
+
+	builder.WriteString(warningColor.Sprint("\nWARNING: some events were lost, so these stats may be incomplete:\n"))
+
+	counts := []struct {
+		label string
+		count int64
+	}{
+		{"File events dropped", s.Health.FileEventsDropped},
+		{"File event queue overflows", s.Health.FileEventOverflows},
+		{"Git events dropped", s.Health.GitEventsDropped},
+		{"File events ignored", s.Health.FileEventsIgnored},
+		{"Writes rate-limited", s.Health.WritesRateLimited},
+		{"Audio events dropped", s.Health.AudioEventsDropped},
+	}
+
+	for _, count := range counts {
+		if count.count == 0 {
+			continue
+		}
+
+		builder.WriteString(indent + sublabelColor.Sprint(count.label+": "))
+		builder.WriteString(detailColor.Sprint(s.number(count.count)))
+		builder.WriteRune('\n')
+	}
+
+	return builder.String()
+}
+
+// relPath shortens an absolute path in the project for the report. The JSON output keeps absolute paths for tooling.
+func (s *StatusSnapshot) relPath(path string) string {
+	return files.RelPathIn(s.Session.projectDirs(), path)
+}
+
+// saves returns the number of saves across every written file.
+func (s *StatusSnapshot) saves() int64 {
+	var result int64
+
+	for _, writes := range s.WrittenFiles {
+		result += writes
+	}
+
+	return result
+}
+
 func (s *StatusSnapshot) filesString() string {
 	builder := &strings.Builder{}
 	builder.Grow(256)
@@ -233,7 +977,7 @@ func (s *StatusSnapshot) filesString() string {
 		builder.WriteString(labelColor.Sprint("\nNew files:\n"))
 
 		for _, file := range s.NewFiles {
-			builder.WriteString(indent + sublabelColor.Sprint(file) + "\n")
+			builder.WriteString(indent + sublabelColor.Sprint(s.relPath(file)) + "\n")
 		}
 	}
 
@@ -241,27 +985,104 @@ func (s *StatusSnapshot) filesString() string {
 		builder.WriteString(labelColor.Sprint("\nDeleted files:\n"))
 
 		for _, file := range s.DeletedFiles {
-			builder.WriteString(indent + sublabelColor.Sprint(file) + "\n")
+			builder.WriteString(indent + sublabelColor.Sprint(s.relPath(file)) + "\n")
+		}
+	}
+
+	if s.Generated != nil && len(s.Generated.Files) > 0 {
+		builder.WriteString(labelColor.Sprint("\nGenerated files:\n"))
+
+		for _, file := range s.Generated.Files {
+			builder.WriteString(indent + sublabelColor.Sprint(s.relPath(file)) + "\n")
+		}
+	}
+
+	if s.Binary != nil {
+		builder.WriteString(labelColor.Sprint("\nBinary files:\n"))
+
+		for _, file := range s.Binary.Files {
+			builder.WriteString(indent + sublabelColor.Sprint(s.relPath(file)) + "\n")
+		}
+	}
+
+	if s.Todos != nil && len(s.Todos.Files) > 0 {
+		builder.WriteString(labelColor.Sprint("\nTODO changes:\n"))
+
+		for _, path := range slices.Sorted(maps.Keys(s.Todos.Files)) {
+			builder.WriteString(indent + sublabelColor.Sprint(s.relPath(path)) + s.separator() +
+				detailColor.Sprint(s.signed(s.Todos.Files[path])) + "\n")
 		}
 	}
 
 	if len(s.WrittenFiles) > 0 {
 		builder.WriteString(labelColor.Sprint("\nWritten files:\n"))
 
-		files := slices.Collect(maps.Keys(s.WrittenFiles))
-		slices.Sort(files)
+		paths := slices.Collect(maps.Keys(s.WrittenFiles))
+		slices.Sort(paths)
 
-		for _, file := range files {
-			writes := strconv.FormatInt(s.WrittenFiles[file], 10)
-			builder.WriteString(indent + sublabelColor.Sprint(file) + separator + detailColor.Sprint(writes) + "\n")
+		for _, file := range paths {
+			writes := s.number(s.WrittenFiles[file])
+			if raw := s.RawWrittenFiles[file]; raw != s.WrittenFiles[file] {
+				writes += " (" + s.number(raw) + " raw)"
+			}
+
+			builder.WriteString(indent + sublabelColor.Sprint(s.relPath(file)) + s.separator())
+			builder.WriteString(detailColor.Sprint(writes) + "\n")
 		}
 	}
 
 	return builder.String()
 }
 
+// permissionsString lists the files whose permissions or owner changed, with their current mode. They're always listed,
+// since an agent marking scripts executable or loosening permissions is worth a look.
+func (s *StatusSnapshot) permissionsString() string {
+	if len(s.ChangedPermissions) == 0 {
+		return ""
+	}
+
+	builder := &strings.Builder{}
+	builder.Grow(256)
+	builder.WriteString(labelColor.Sprint("\nPermission changes: "))
+	builder.WriteString(detailColor.Sprint(s.number(s.NumPermissionChanges)))
+	builder.WriteRune('\n')
+
+	for _, file := range slices.Sorted(maps.Keys(s.ChangedPermissions)) {
+		builder.WriteString(indent + sublabelColor.Sprint(s.relPath(file)) + s.separator())
+		builder.WriteString(updatedColor.Sprint(s.ChangedPermissions[file]) + "\n")
+	}
+
+	return builder.String()
+}
+
+// largestFilesString lists the largest new files, to spot generated artifacts or dumps added to the project.
+func (s *StatusSnapshot) largestFilesString() string {
+	if len(s.TopNewFiles) == 0 {
+		return ""
+	}
+
+	width := 0
+	for _, file := range s.TopNewFiles {
+		width = max(width, utf8.RuneCountInString(s.relPath(file.Path)))
+	}
+
+	builder := &strings.Builder{}
+	builder.Grow(256)
+	builder.WriteString(labelColor.Sprint("\nLargest new files:\n"))
+
+	for _, file := range s.TopNewFiles {
+		builder.WriteString(indent)
+		builder.WriteString(sublabelColor.Sprint(s.pad(s.relPath(file.Path), width)))
+		builder.WriteString(s.separator())
+		builder.WriteString(detailColor.Sprint(s.byteSize(file.Size)))
+		builder.WriteRune('\n')
+	}
+
+	return builder.String()
+}
+
 func (s *StatusSnapshot) patchString() string {
-	if s.Patch == nil || s.NumCommits == 0 {
+	if len(s.PatchStats) == 0 || s.NumCommits == 0 {
 		return ""
 	}
 
@@ -276,7 +1097,7 @@ func (s *StatusSnapshot) patchString() string {
 		return 1 + (num * (maxChangeWidth - 1) / total)
 	}
 
-	stats := s.Patch.Stats()
+	stats := s.PatchStats
 
 	builder := &strings.Builder{}
 	builder.Grow(256)
@@ -284,11 +1105,17 @@ func (s *StatusSnapshot) patchString() string {
 
 	for _, fileStats := range stats {
 		totalChanges := fileStats.Addition + fileStats.Deletion
-		totalChangesStr := strconv.FormatInt(int64(totalChanges), 10)
+		totalChangesStr := s.number(int64(totalChanges))
 
 		builder.WriteString(indent)
 		builder.WriteString(sublabelColor.Sprint(fileStats.Name))
-		builder.WriteString(separator)
+		builder.WriteString(s.separator())
+
+		if s.plain {
+			builder.WriteString(s.addedDeleted(int64(fileStats.Addition), int64(fileStats.Deletion), "") + "\n")
+			continue
+		}
+
 		builder.WriteString(totalChangesStr)
 		builder.WriteRune(' ')
 
@@ -327,10 +1154,110 @@ func (s *StatusSnapshot) commitsString() string {
 
 		builder.WriteString(indent)
 		builder.WriteString(sublabelColor.Sprint(commit.ID().String()))
-		builder.WriteString(separator)
-		builder.WriteString(detailColor.Sprint(commit.Committer.When.Format(time.RFC3339)))
-		builder.WriteString(separator)
+		builder.WriteString(s.separator())
+		builder.WriteString(detailColor.Sprint(s.times().Timestamp(commit.Committer.When)))
+		builder.WriteString(s.separator())
 		builder.WriteString(msg)
+
+		if check, ok := s.check(commit.ID().String()); ok {
+			builder.WriteString(s.separator())
+			builder.WriteString(check)
+		}
+
+		builder.WriteRune('\n')
+	}
+
+	return builder.String()
+}
+
+// megaCommitLines is how many changed lines make a commit big enough that it likely needs splitting before review.
+const megaCommitLines = 500
+
+//nolint:gochecknoglobals
+var commitSizeBuckets = []struct {
+	label      string
+	plainLabel string // for plain reports, in words
+	maxLines   int64
+}{
+	{"0-10", "Up to 10 lines", 10},
+	{"11-50", "11 to 50 lines", 50},
+	{"51-200", "51 to 200 lines", 200},
+	{"201-500", "201 to 500 lines", megaCommitLines},
+	{"501+", "Over 500 lines", math.MaxInt64},
+}
+
+// commitSizesString shows how the session's commits are distributed by size, and calls out the largest one.
+func (s *StatusSnapshot) commitSizesString() string {
+	if len(s.CommitSizes) == 0 {
+		return ""
+	}
+
+	counts := make([]int, len(commitSizeBuckets))
+	largest := s.CommitSizes[0]
+	megaCommits := 0
+
+	for _, size := range s.CommitSizes {
+		churn := size.Churn()
+
+		for i, bucket := range commitSizeBuckets {
+			if churn <= bucket.maxLines {
+				counts[i]++
+				break
+			}
+		}
+
+		if churn > largest.Churn() {
+			largest = size
+		}
+
+		if churn > megaCommitLines {
+			megaCommits++
+		}
+	}
+
+	maxBarWidth := 40
+	maxCount := slices.Max(counts)
+
+	builder := &strings.Builder{}
+	builder.Grow(256)
+	builder.WriteString(labelColor.Sprint("\nCommit sizes (lines changed):\n"))
+
+	for i, bucket := range commitSizeBuckets {
+		if s.plain {
+			builder.WriteString(indent + bucket.plainLabel + s.separator() + s.plural(int64(counts[i]), "commit") + "\n")
+			continue
+		}
+
+		bar := counts[i]
+		if maxCount > maxBarWidth {
+			bar = counts[i] * maxBarWidth / maxCount
+		}
+
+		builder.WriteString(indent)
+		builder.WriteString(sublabelColor.Sprintf("%-7s", bucket.label))
+		builder.WriteString(s.separator())
+		builder.WriteString(detailColor.Sprintf("%3s", s.number(int64(counts[i]))))
+
+		if bar > 0 {
+			builder.WriteString(" " + strings.Repeat("#", bar))
+		}
+
+		builder.WriteRune('\n')
+	}
+
+	builder.WriteString(indent)
+	builder.WriteString(sublabelColor.Sprint("Largest: "))
+	builder.WriteString(detailColor.Sprint(largest.Hash[:min(len(largest.Hash), 10)]))
+	builder.WriteString(s.separator())
+	builder.WriteString(s.addedDeleted(largest.Added, largest.Deleted, " / "))
+	builder.WriteString(s.separator())
+	builder.WriteString(largest.Summary)
+	builder.WriteRune('\n')
+
+	if megaCommits > 0 {
+		builder.WriteString(indent)
+		builder.WriteString(warningColor.Sprintf("%s mega-commit(s) over %s lines changed; consider splitting before review",
+			s.number(int64(megaCommits)), s.number(megaCommitLines)))
 		builder.WriteRune('\n')
 	}
 
@@ -341,18 +1268,112 @@ func (s *StatusSnapshot) listenersString() string {
 	builder := &strings.Builder{}
 	builder.Grow(128)
 
-	for listener, diff := range s.ListenerDiffs {
+	for _, listener := range slices.Sorted(maps.Keys(s.ListenerDiffs)) {
+		diff := s.ListenerDiffs[listener]
 		if diff.IsEmpty() {
 			continue
 		}
 
-		builder.WriteString(labelColor.Sprint(listener + ":\n"))
+		fileDiffs := diff.DependencyFileDiffs
+
+		builder.WriteString(labelColor.Sprint("\n" + listener + " dependencies: "))
+		builder.WriteString(addedColor.Sprint("+" + s.number(fileDiffs.NumNewDependencies())))
+		builder.WriteString(" / ")
+		builder.WriteString(removedColor.Sprint("-" + s.number(fileDiffs.NumDeletedDependencies())))
+		builder.WriteString(" / ")
+		builder.WriteString(updatedColor.Sprint("~" + s.number(fileDiffs.NumUpdatedDependencies())))
+		builder.WriteRune('\n')
 		builder.WriteString(s.listenerDependencyString(diff))
+		builder.WriteString(s.listenerEntryString(diff))
 	}
 
 	return builder.String()
 }
 
+// typosquatString warns about new dependencies whose names are one typo away from a popular package.
+func (s *StatusSnapshot) typosquatString() string {
+	builder := &strings.Builder{}
+	builder.Grow(64)
+
+	for _, listener := range slices.Sorted(maps.Keys(s.ListenerDiffs)) {
+		for _, fileDiff := range s.ListenerDiffs[listener].DependencyFileDiffs {
+			for _, match := range fileDiff.PossibleTyposquats() {
+				builder.WriteString(indent)
+				builder.WriteString(sublabelColor.Sprint(s.relPath(fileDiff.Path)) + s.separator())
+				builder.WriteString(removedColor.Sprint(match.Dependency.Package()))
+				builder.WriteString(" looks like ")
+				builder.WriteString(addedColor.Sprint(match.Similar))
+				builder.WriteRune('\n')
+			}
+		}
+	}
+
+	if builder.Len() == 0 {
+		return ""
+	}
+
+	return removedColor.Add(color.Bold).Sprint("\nWARNING: possible typosquatted dependencies:\n") + builder.String()
+}
+
+// unpinnedString flags dependencies added or updated during the session that are not pinned to an exact version.
+func (s *StatusSnapshot) unpinnedString() string {
+	builder := &strings.Builder{}
+	builder.Grow(64)
+
+	for _, listener := range slices.Sorted(maps.Keys(s.ListenerDiffs)) {
+		for _, fileDiff := range s.ListenerDiffs[listener].DependencyFileDiffs {
+			for _, dep := range fileDiff.UnpinnedDependencies() {
+				version := dep.Requirement()
+				if version == "" {
+					version = "<no version>"
+				}
+
+				builder.WriteString(indent)
+				builder.WriteString(sublabelColor.Sprint(s.relPath(fileDiff.Path)) + s.separator())
+				builder.WriteString(detailColor.Sprint(dep.Package()) + " ")
+				builder.WriteString(updatedColor.Sprint(version))
+				builder.WriteRune('\n')
+			}
+		}
+	}
+
+	if builder.Len() == 0 {
+		return ""
+	}
+
+	return labelColor.Sprint("\nUnpinned dependencies:\n") + builder.String()
+}
+
+// externalSourcesString flags dependencies added or updated during the session that come from a non-default registry
+// or a direct URL.
+func (s *StatusSnapshot) externalSourcesString() string {
+	builder := &strings.Builder{}
+	builder.Grow(64)
+
+	for _, listener := range slices.Sorted(maps.Keys(s.ListenerDiffs)) {
+		for _, fileDiff := range s.ListenerDiffs[listener].DependencyFileDiffs {
+			for _, dep := range fileDiff.ExternalDependencies() {
+				builder.WriteString(indent)
+				builder.WriteString(sublabelColor.Sprint(s.relPath(fileDiff.Path)) + s.separator())
+				builder.WriteString(detailColor.Sprint(dep.Package()))
+
+				// URL dependencies already show their source as the package name
+				if source := dep.ExternalSource(); !strings.Contains(dep.Package(), source) {
+					builder.WriteString(" " + updatedColor.Sprint(source))
+				}
+
+				builder.WriteRune('\n')
+			}
+		}
+	}
+
+	if builder.Len() == 0 {
+		return ""
+	}
+
+	return labelColor.Sprint("\nDependencies from outside the default registries:\n") + builder.String()
+}
+
 func (s *StatusSnapshot) listenerDependencyString(diff listeners.Diff) string {
 	builder := &strings.Builder{}
 	builder.Grow(64)
@@ -362,7 +1383,7 @@ func (s *StatusSnapshot) listenerDependencyString(diff listeners.Diff) string {
 			continue
 		}
 
-		builder.WriteString(indent + sublabelColor.Sprint(fileDiff.Path) + ":\n")
+		builder.WriteString(indent + sublabelColor.Sprint(s.relPath(fileDiff.Path)) + ":\n")
 
 		if len(fileDiff.NewDependencies) > 0 {
 			for _, dep := range fileDiff.NewDependencies {
@@ -386,10 +1407,20 @@ func (s *StatusSnapshot) listenerDependencyString(diff listeners.Diff) string {
 			for _, dep := range fileDiff.UpdatedDependencies {
 				builder.WriteString(indent + indent)
 				builder.WriteString(updatedColor.Sprint("~") + " ")
-				builder.WriteString(detailColor.Sprint(dep.Initial.Package()) + separator)
-				builder.WriteString(removedColor.Sprint(dep.Initial.Version))
-				builder.WriteString(updatedColor.Sprint(" => "))
-				builder.WriteString(addedColor.Sprint(dep.Latest.Version))
+				builder.WriteString(detailColor.Sprint(dep.Initial.Package()) + s.separator())
+
+				if dep.ConstraintChanged() {
+					builder.WriteString(removedColor.Sprint(dep.Initial.Requirement()))
+					builder.WriteString(updatedColor.Sprint(" => "))
+					builder.WriteString(addedColor.Sprint(dep.Latest.Requirement()))
+				} else {
+					// Same requirement, different resolution
+					builder.WriteString(detailColor.Sprint(dep.Latest.Requirement() + " resolved "))
+					builder.WriteString(removedColor.Sprint(dep.Initial.Version))
+					builder.WriteString(updatedColor.Sprint(" => "))
+					builder.WriteString(addedColor.Sprint(dep.Latest.Version))
+				}
+
 				builder.WriteRune('\n')
 			}
 		}
@@ -398,30 +1429,41 @@ func (s *StatusSnapshot) listenerDependencyString(diff listeners.Diff) string {
 	return builder.String()
 }
 
-func durationString(duration time.Duration) string {
-	result := ""
-	days := int64(duration / (time.Hour * 24))
-	hours := int64((duration - (time.Duration(days) * time.Hour * 24)) / time.Hour)
-	minutes := int64((duration - (time.Duration(days) * time.Hour * 24) - (time.Duration(hours) * time.Hour)) / time.Minute)
-	seconds := int64(
-		(duration - (time.Duration(days) * time.Hour * 24) -
-			(time.Duration(hours) * time.Hour) -
-			(time.Duration(minutes) * time.Minute)) / time.Second,
-	)
+func (s *StatusSnapshot) listenerEntryString(diff listeners.Diff) string {
+	builder := &strings.Builder{}
+	builder.Grow(64)
 
-	if days > 0 {
-		result += strconv.FormatInt(days, 10) + "d"
-	}
+	for _, entryDiff := range diff.EntryDiffs {
+		if entryDiff.IsEmpty() {
+			continue
+		}
 
-	if hours > 0 {
-		result += strconv.FormatInt(hours, 10) + "h"
-	}
+		builder.WriteString(indent + sublabelColor.Sprint(s.relPath(entryDiff.Path)+" ("+entryDiff.Category+")") + ":\n")
 
-	if minutes > 0 {
-		result += strconv.FormatInt(minutes, 10) + "m"
-	}
+		for _, entry := range entryDiff.NewEntries {
+			builder.WriteString(indent + indent)
+			builder.WriteString(addedColor.Sprint("+") + " ")
+			builder.WriteString(detailColor.Sprint(entry.String()))
+			builder.WriteRune('\n')
+		}
 
-	result += strconv.FormatInt(seconds, 10) + "s"
+		for _, entry := range entryDiff.DeletedEntries {
+			builder.WriteString(indent + indent)
+			builder.WriteString(removedColor.Sprint("-") + " ")
+			builder.WriteString(detailColor.Sprint(entry.String()))
+			builder.WriteRune('\n')
+		}
 
-	return result
+		for _, entry := range entryDiff.UpdatedEntries {
+			builder.WriteString(indent + indent)
+			builder.WriteString(updatedColor.Sprint("~") + " ")
+			builder.WriteString(detailColor.Sprint(entry.Initial.Name) + s.separator())
+			builder.WriteString(removedColor.Sprint(entry.Initial.Value))
+			builder.WriteString(updatedColor.Sprint(" => "))
+			builder.WriteString(addedColor.Sprint(entry.Latest.Value))
+			builder.WriteRune('\n')
+		}
+	}
+
+	return builder.String()
 }
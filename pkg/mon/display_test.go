@@ -0,0 +1,106 @@
+package mon_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cneill/mon/pkg/files"
+	"github.com/cneill/mon/pkg/git"
+	"github.com/cneill/mon/pkg/mon"
+)
+
+func TestStatusSnapshot_Plain(t *testing.T) {
+	t.Parallel()
+
+	snapshot := &mon.StatusSnapshot{
+		NumFilesCreated: 1,
+		NumFilesDeleted: 2,
+		LinesAdded:      120,
+		LinesDeleted:    4,
+		WritesPerMinute: 3,
+		UnstagedChanges: 1,
+		MonitorErrors:   []string{"event queue overflowed"},
+	}
+
+	expected := "Status: 1 file created, 2 deleted; 120 lines added, 4 deleted; 0 commits; " +
+		"3 writes per minute, 0 commits per hour; 1 uncommitted change; warning: event queue overflowed."
+	if actual := snapshot.Plain(); actual != expected {
+		t.Errorf("expected %q, got %q", expected, actual)
+	}
+}
+
+func TestStatusSnapshot_PlainGoals(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2026, time.October, 17, 12, 0, 0, 0, time.UTC)
+	snapshot := &mon.StatusSnapshot{
+		DetailsOpts: &mon.DetailsOpts{Goals: mon.Goals{Commits: 5, TimeBudget: time.Hour}},
+		NumCommits:  3,
+		StartTime:   start,
+		Time:        start.Add(time.Minute * 27),
+	}
+
+	expected := "Status: 0 files created, 0 deleted; 0 lines added, 0 deleted; 3 commits; " +
+		"goals: 3 of 5 commits, 40 percent of the 1h time budget used."
+	if actual := snapshot.Plain(); actual != expected {
+		t.Errorf("expected %q, got %q", expected, actual)
+	}
+}
+
+func TestStatusSnapshot_FinalCommitSizes(t *testing.T) {
+	t.Parallel()
+
+	snapshot := &mon.StatusSnapshot{
+		DetailsOpts: &mon.DetailsOpts{Numbers: mon.NumberFormat{Separator: ","}},
+		NumCommits:  2,
+		CommitSizes: []git.CommitSize{
+			{Hash: "aaaaaaaaaaaa", Added: 4, Summary: "Small fix"},
+			{Hash: "bbbbbbbbbbbb", Added: 1200, Deleted: 30, Summary: "Big rewrite"},
+		},
+	}
+
+	output := snapshot.Final()
+
+	for _, expected := range []string{"0-10    ::   1 #\n", "11-50   ::   0\n", "501+    ::   1 #\n", "+1,200 / -30"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("expected report to contain %q, got:\n%s", expected, output)
+		}
+	}
+}
+
+func TestStatusSnapshot_PlainFinal(t *testing.T) {
+	t.Parallel()
+
+	snapshot := &mon.StatusSnapshot{
+		DetailsOpts: &mon.DetailsOpts{},
+		NumCommits:  1,
+		ByExtension: map[string]files.ExtensionStats{
+			".go":  {Created: 1, Writes: 4, Written: 2},
+			".txt": {Deleted: 12, Writes: 1, Written: 1},
+		},
+		TopNewFiles: []files.FileSize{{Path: "main.go", Size: 2048}},
+		CommitSizes: []git.CommitSize{{Hash: "aaaaaaaaaaaa", Added: 40, Deleted: 2, Summary: "Add main"}},
+	}
+
+	output := snapshot.PlainFinal()
+
+	for _, expected := range []string{
+		"  .go, 1 created, 0 deleted, 4 saves in 2 files\n",
+		"  .txt, 0 created, 12 deleted, 1 save in 1 file\n",
+		"  main.go, 2 KiB\n",
+		"  11 to 50 lines, 1 commit\n",
+		"  Over 500 lines, 0 commits\n",
+		"  Largest: aaaaaaaaaa, 40 lines added, 2 deleted, Add main\n",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("expected plain report to contain %q, got:\n%s", expected, output)
+		}
+	}
+
+	for _, symbol := range []string{"::", "#", "+", "  \n"} {
+		if strings.Contains(output, symbol) {
+			t.Errorf("expected plain report not to contain %q, got:\n%s", symbol, output)
+		}
+	}
+}
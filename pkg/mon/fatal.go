@@ -0,0 +1,59 @@
+package mon
+
+import (
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+)
+
+// guard runs fn, turning a panic into a fatal error that ends the session, so the summary collected so far is still
+// reported instead of being lost with the process.
+func (m *Mon) guard(name string, fn func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			slog.Error("recovered from panic", "in", name, "panic", r, "stack", string(debug.Stack()))
+			m.fail(fmt.Errorf("%s panicked: %v", name, r))
+		}
+	}()
+
+	fn()
+}
+
+// fail ends the session early because of err. Only the first failure is kept.
+func (m *Mon) fail(err error) {
+	select {
+	case m.fatal <- err:
+	default:
+	}
+}
+
+// flushAfterFailure reports everything collected before a fatal error, and saves a checkpoint so the session can be
+// picked up again with "mon resume". It never panics itself, since it runs while something has already gone wrong.
+func (m *Mon) flushAfterFailure(err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			slog.Error("failed to report session after fatal error", "panic", r)
+		}
+	}()
+
+	m.recordMonitorError("mon", err)
+
+	snapshot := m.GetStatusSnapshot(true, true)
+	m.printReport(snapshot)
+
+	m.writeExports(snapshot)
+
+	if m.CheckpointPath != "" {
+		if err := m.writeCheckpoint(); err != nil {
+			slog.Error("failed to write session checkpoint", "path", m.CheckpointPath, "error", err)
+			return
+		}
+
+		command := "mon resume " + m.ProjectDir
+		for _, dir := range m.ProjectDirs {
+			command += " --project-dir " + dir
+		}
+
+		fmt.Printf("Session saved; continue it with \"%s\"\n", command)
+	}
+}
@@ -0,0 +1,223 @@
+package mon
+
+import (
+	"io"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/cneill/mon/pkg/files"
+)
+
+// FileGroupStats totals the churn in a group of files that's left out of the per-extension and per-directory stats, so
+// it doesn't drown out the work done by hand.
+type FileGroupStats struct {
+	// Files are the files in the group that were created or written during the session.
+	Files   []string `json:"files"`
+	Created int64    `json:"created"`
+	Written int64    `json:"written"`
+	Saves   int64    `json:"saves"`
+}
+
+// GeneratedStats totals the churn in generated files, like lockfiles and code from generators, which is also left out
+// of the estimate of new code.
+type GeneratedStats struct {
+	FileGroupStats
+
+	// LinesAdded and LinesDeleted are the committed lines in generated files, which are also in the session's totals.
+	LinesAdded   int64 `json:"lines_added"`
+	LinesDeleted int64 `json:"lines_deleted"`
+}
+
+// generatedHeaderSize is how much of the start of a file is searched for generated-file markers.
+const generatedHeaderSize = 4096
+
+//nolint:gochecknoglobals
+var (
+	// lockfiles are generated by package managers, and not all of them say so in a header.
+	lockfiles = map[string]struct{}{
+		"package-lock.json": {}, "npm-shrinkwrap.json": {}, "yarn.lock": {}, "pnpm-lock.yaml": {}, "bun.lock": {},
+		"go.sum": {}, "Cargo.lock": {}, "poetry.lock": {}, "uv.lock": {}, "Pipfile.lock": {}, "Gemfile.lock": {},
+		"composer.lock": {}, "flake.lock": {},
+	}
+
+	// generatedMarkers match the comments generators start lines near the top of their output with, like Go's
+	// standard one for generated code, the one Cargo, Poetry, and Meta's tools use, and yarn's lockfile header.
+	generatedMarkers = regexp.MustCompile(`(?im)^\W*(code generated .*do not edit|@generated|auto-?generated|` +
+		`this file (is|was|has been) (automatically )?generated|yarn lockfile)`)
+)
+
+// isGenerated reports whether a file is a lockfile or has a generated-file marker near its top.
+func isGenerated(path string) bool {
+	if _, ok := lockfiles[filepath.Base(path)]; ok {
+		return true
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	header := make([]byte, generatedHeaderSize)
+
+	n, err := io.ReadFull(file, header)
+	if err != nil && n == 0 {
+		return false
+	}
+
+	return generatedMarkers.Match(header[:n])
+}
+
+// splitGenerated moves the churn in generated files out of a final snapshot's per-extension and per-directory stats
+// and its estimate of new code, and totals it in Generated instead.
+func (s *StatusSnapshot) splitGenerated() {
+	candidates := slices.Concat(s.NewFiles, slices.Collect(maps.Keys(s.WrittenFiles)))
+	slices.Sort(candidates)
+	candidates = slices.Compact(candidates)
+
+	generated := &GeneratedStats{FileGroupStats: s.splitFiles(slices.DeleteFunc(candidates, func(path string) bool {
+		return !isGenerated(path)
+	}))}
+
+	relPaths := map[string]struct{}{}
+	for _, path := range generated.Files {
+		relPaths[filepath.ToSlash(s.relPath(path))] = struct{}{}
+	}
+
+	for _, stat := range s.PatchStats {
+		_, lockfile := lockfiles[filepath.Base(stat.Name)]
+		if _, ok := relPaths[filepath.ToSlash(stat.Name)]; ok || lockfile {
+			generated.LinesAdded += int64(stat.Addition)
+			generated.LinesDeleted += int64(stat.Deletion)
+		}
+	}
+
+	if len(generated.Files) == 0 && generated.LinesAdded == 0 && generated.LinesDeleted == 0 {
+		return
+	}
+
+	slog.Debug("found generated files", "files", generated.Files)
+
+	s.Generated = generated
+}
+
+// splitBinary moves the churn in binary files, like images and compiled artifacts, out of a final snapshot's
+// per-extension and per-directory stats, and totals it in Binary instead. Generated files are already split out.
+func (s *StatusSnapshot) splitBinary(binaryFiles []string) {
+	if s.Generated != nil {
+		binaryFiles = slices.DeleteFunc(slices.Clone(binaryFiles), func(path string) bool {
+			return slices.Contains(s.Generated.Files, path)
+		})
+	}
+
+	if len(binaryFiles) == 0 {
+		return
+	}
+
+	binary := s.splitFiles(binaryFiles)
+	s.Binary = &binary
+}
+
+// splitFiles subtracts the given new or written files from ByExtension and ByDirectory, and totals them instead.
+func (s *StatusSnapshot) splitFiles(paths []string) FileGroupStats {
+	group := FileGroupStats{Files: []string{}}
+
+	for _, path := range paths {
+		created := slices.Contains(s.NewFiles, path)
+		saves := s.WrittenFiles[path]
+
+		group.Files = append(group.Files, path)
+
+		if created {
+			group.Created++
+		}
+
+		if saves > 0 {
+			group.Written++
+			group.Saves += saves
+		}
+
+		subtract := func(stats files.ExtensionStats) files.ExtensionStats {
+			if created {
+				stats.Created--
+			}
+
+			if saves > 0 {
+				stats.Written--
+				stats.Writes -= saves
+			}
+
+			return stats
+		}
+
+		extension := strings.ToLower(filepath.Ext(path))
+		if stats, ok := s.ByExtension[extension]; ok {
+			s.ByExtension[extension] = subtract(stats)
+			removeIfEmpty(s.ByExtension, extension)
+		}
+
+		dir := files.TopDirectory(s.Session.projectDirs(), path)
+		if stats, ok := s.ByDirectory[dir]; ok {
+			s.ByDirectory[dir] = subtract(stats)
+			removeIfEmpty(s.ByDirectory, dir)
+		}
+	}
+
+	return group
+}
+
+// groupParts summarizes a group of files for the report, e.g. ["2 files (1 new)", "14 saves"].
+func (s *StatusSnapshot) groupParts(group FileGroupStats) []string {
+	parts := []string{}
+
+	if numFiles := int64(len(group.Files)); numFiles > 0 {
+		part := s.plural(numFiles, "file")
+		if group.Created > 0 {
+			part += " (" + s.number(group.Created) + " new)"
+		}
+
+		parts = append(parts, detailColor.Sprint(part))
+	}
+
+	if group.Saves > 0 {
+		parts = append(parts, detailColor.Sprint(s.plural(group.Saves, "save")))
+	}
+
+	return parts
+}
+
+// generatedString summarizes Generated for the report, e.g. "2 files (1 new) :: 14 saves :: +900 / -20 lines".
+func (s *StatusSnapshot) generatedString() string {
+	parts := s.groupParts(s.Generated.FileGroupStats)
+
+	if s.Generated.LinesAdded > 0 || s.Generated.LinesDeleted > 0 {
+		parts = append(parts, addedColor.Sprint("+"+s.number(s.Generated.LinesAdded))+" / "+
+			removedColor.Sprint("-"+s.number(s.Generated.LinesDeleted))+detailColor.Sprint(" lines committed"))
+	}
+
+	return strings.Join(parts, s.separator())
+}
+
+// humanNewFiles returns the new files that weren't generated.
+func (s *StatusSnapshot) humanNewFiles() []string {
+	if s.Generated == nil {
+		return s.NewFiles
+	}
+
+	return slices.DeleteFunc(slices.Clone(s.NewFiles), func(path string) bool {
+		return slices.Contains(s.Generated.Files, path)
+	})
+}
+
+// removeIfEmpty drops a group that only had generated files in it.
+func removeIfEmpty[S comparable](groups map[string]S, key string) {
+	var empty S
+	if groups[key] == empty {
+		delete(groups, key)
+	}
+}
@@ -0,0 +1,129 @@
+package mon //nolint:testpackage // exercises the unexported detection of generated files
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/cneill/mon/pkg/files"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+func TestIsGenerated(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	tests := []struct {
+		name      string
+		content   string
+		generated bool
+	}{
+		{"stringer.go", "// Code generated by \"stringer -type=Op\"; DO NOT EDIT.\n\npackage ops\n", true},
+		{"schema.ts", "/**\n * @generated\n */\nexport type A = string;\n", true},
+		{"client.py", "# Auto-generated by openapi-generator\nimport json\n", true},
+		{"yarn.txt", "# THIS IS AN AUTOGENERATED FILE. DO NOT EDIT THIS FILE DIRECTLY.\n# yarn lockfile v1\n", true},
+		{"package-lock.json", "{}\n", true},
+		{"main.go", "package main\n\nfunc main() {}\n", false},
+		{"notes.md", "Mark code that's auto-generated with a header.\n", false},
+	}
+
+	for _, test := range tests {
+		path := filepath.Join(dir, test.name)
+		if err := os.WriteFile(path, []byte(test.content), 0o644); err != nil {
+			t.Fatalf("failed to write %q: %v", path, err)
+		}
+
+		if actual := isGenerated(path); actual != test.generated {
+			t.Errorf("%s: expected generated == %t, got %t", test.name, test.generated, actual)
+		}
+	}
+}
+
+func TestStatusSnapshot_SplitGenerated(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	handWritten := filepath.Join(dir, "api", "handler.go")
+	generatedCode := filepath.Join(dir, "api", "handler_gen.go")
+	lockfile := filepath.Join(dir, "go.sum")
+
+	contents := map[string]string{
+		handWritten:   "package api\n",
+		generatedCode: "// Code generated by mockgen. DO NOT EDIT.\npackage api\n",
+		lockfile:      "example.com/mod v1.0.0 h1:abc=\n",
+	}
+
+	for path, content := range contents {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("failed to write %q: %v", path, err)
+		}
+	}
+
+	snapshot := &StatusSnapshot{
+		Session:      SessionInfo{ProjectDir: dir},
+		NewFiles:     []string{handWritten, generatedCode},
+		WrittenFiles: map[string]int64{handWritten: 2, generatedCode: 3, lockfile: 1},
+		ByExtension: map[string]files.ExtensionStats{
+			".go":  {Created: 2, Written: 2, Writes: 5},
+			".sum": {Written: 1, Writes: 1},
+		},
+		ByDirectory: map[string]files.DirectoryStats{
+			"api": {Created: 2, Written: 2, Writes: 5},
+			".":   {Written: 1, Writes: 1},
+		},
+		PatchStats: object.FileStats{
+			{Name: "api/handler.go", Addition: 10},
+			{Name: "api/handler_gen.go", Addition: 200, Deletion: 4},
+			{Name: "go.sum", Addition: 12},
+		},
+	}
+
+	snapshot.splitGenerated()
+
+	want := &GeneratedStats{
+		FileGroupStats: FileGroupStats{Files: []string{generatedCode, lockfile}, Created: 1, Written: 2, Saves: 4},
+		LinesAdded:     212,
+		LinesDeleted:   4,
+	}
+
+	if got := snapshot.Generated; got == nil || !slices.Equal(got.Files, want.Files) ||
+		got.Created != want.Created || got.Written != want.Written || got.Saves != want.Saves ||
+		got.LinesAdded != want.LinesAdded || got.LinesDeleted != want.LinesDeleted {
+		t.Errorf("expected generated stats %+v, got %+v", want, got)
+	}
+
+	wantGo := files.ExtensionStats{Created: 1, Written: 1, Writes: 2}
+	if stats := snapshot.ByExtension[".go"]; stats != wantGo {
+		t.Errorf("expected .go stats %+v without generated files, got %+v", wantGo, stats)
+	}
+
+	if _, ok := snapshot.ByExtension[".sum"]; ok {
+		t.Errorf("expected .sum stats to be dropped with only a lockfile in them, got %+v", snapshot.ByExtension)
+	}
+
+	if _, ok := snapshot.ByDirectory["."]; ok {
+		t.Errorf("expected the top directory to be dropped with only a lockfile in it, got %+v", snapshot.ByDirectory)
+	}
+
+	if newFiles := snapshot.humanNewFiles(); !slices.Equal(newFiles, []string{handWritten}) {
+		t.Errorf("expected only the hand-written file to be new code, got %v", newFiles)
+	}
+
+	// Generated files are only counted once, even if they're binary too
+	snapshot.splitBinary([]string{generatedCode, handWritten})
+
+	if snapshot.Binary == nil || !slices.Equal(snapshot.Binary.Files, []string{handWritten}) {
+		t.Errorf("expected only %s to be split out as binary, got %+v", handWritten, snapshot.Binary)
+	}
+
+	if _, ok := snapshot.ByExtension[".go"]; ok {
+		t.Errorf("expected .go stats to be dropped with only binary and generated files left, got %+v",
+			snapshot.ByExtension)
+	}
+}
@@ -0,0 +1,131 @@
+package mon
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Goals are targets for a session that the status line shows progress toward. Zero values are unset.
+type Goals struct {
+	// Commits is the number of commits to make.
+	Commits int64
+	// TimeBudget is how long the session is meant to take.
+	TimeBudget time.Duration
+}
+
+func (g Goals) OK() error {
+	if g.Commits < 0 {
+		return fmt.Errorf("commit goal must not be negative")
+	}
+
+	if g.TimeBudget < 0 {
+		return fmt.Errorf("time budget must not be negative")
+	}
+
+	return nil
+}
+
+// IsEmpty reports whether no goals are set.
+func (g Goals) IsEmpty() bool {
+	return g.Commits == 0 && g.TimeBudget == 0
+}
+
+// meterWidth is how many cells a progress meter takes up in the status line.
+const meterWidth = 6
+
+// meterBlocks fill a cell of a meter from the left in eighths.
+const meterBlocks = " ▏▎▍▌▋▊▉█"
+
+// meter draws progress from 0 to 1 as a bar of width cells, to the nearest eighth of a cell, between thin borders.
+// Progress past 1 is shown as a full bar.
+func meter(progress float64, width int) string {
+	blocks := []rune(meterBlocks)
+	eighths := int(math.Round(min(max(progress, 0), 1) * float64(width*8)))
+
+	builder := &strings.Builder{}
+	builder.WriteRune('▕')
+
+	for range width {
+		builder.WriteRune(blocks[min(eighths, 8)])
+		eighths = max(eighths-8, 0)
+	}
+
+	builder.WriteRune('▏')
+
+	return builder.String()
+}
+
+// goals returns the session's goals.
+func (s *StatusSnapshot) goals() Goals {
+	if s.DetailsOpts == nil {
+		return Goals{}
+	}
+
+	return s.Goals
+}
+
+// elapsed is how long the session has run, for the time budget.
+func (s *StatusSnapshot) elapsed() time.Duration {
+	return s.Time.Sub(s.StartTime)
+}
+
+// writeGoals adds a meter for each goal to the status line.
+func (s *StatusSnapshot) writeGoals(builder *strings.Builder) {
+	goals := s.goals()
+	if goals.IsEmpty() {
+		return
+	}
+
+	builder.WriteString(s.separator())
+	builder.WriteString(labelColor.Sprint("[G] "))
+
+	if goals.Commits > 0 {
+		progress := float64(s.NumCommits) / float64(goals.Commits)
+
+		meterColor := detailColor
+		if progress >= 1 {
+			meterColor = addedColor
+		}
+
+		builder.WriteString(meterColor.Sprint(meter(progress, meterWidth)))
+		builder.WriteString(detailColor.Sprint(" " + s.number(s.NumCommits) + "/" + s.number(goals.Commits) + " c"))
+	}
+
+	if goals.TimeBudget > 0 {
+		if goals.Commits > 0 {
+			builder.WriteString(" / ")
+		}
+
+		progress := float64(s.elapsed()) / float64(goals.TimeBudget)
+
+		meterColor := detailColor
+		if progress > 1 {
+			meterColor = warningColor
+		}
+
+		builder.WriteString(meterColor.Sprint(meter(progress, meterWidth)))
+		builder.WriteString(detailColor.Sprint(" " + durationString(s.elapsed()) + "/" + durationString(goals.TimeBudget)))
+	}
+}
+
+// plainGoals describes progress toward each goal in words, for Plain. The time budget is given in tenths, so it doesn't
+// print a new line every minute.
+func (s *StatusSnapshot) plainGoals() string {
+	goals := s.goals()
+	parts := []string{}
+
+	if goals.Commits > 0 {
+		parts = append(parts, s.number(s.NumCommits)+" of "+s.plural(goals.Commits, "commit"))
+	}
+
+	if goals.TimeBudget > 0 {
+		tenths := int64(float64(s.elapsed()) / float64(goals.TimeBudget) * 10)
+		parts = append(parts, strconv.FormatInt(tenths*10, 10)+" percent of the "+durationString(goals.TimeBudget)+
+			" time budget used")
+	}
+
+	return "goals: " + strings.Join(parts, ", ")
+}
@@ -0,0 +1,26 @@
+package mon //nolint:testpackage // exercises the unexported meter
+
+import "testing"
+
+func TestMeter(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		progress float64
+		expected string
+	}{
+		{0, "▕    ▏"},
+		{-1, "▕    ▏"},
+		{0.5, "▕██  ▏"},
+		{9.0 / 32, "▕█▏  ▏"},
+		{1.0 / 32, "▕▏   ▏"},
+		{1, "▕████▏"},
+		{2.5, "▕████▏"},
+	}
+
+	for _, test := range tests {
+		if actual := meter(test.progress, 4); actual != test.expected {
+			t.Errorf("expected %v to be drawn as %q, got %q", test.progress, test.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package mon
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"io"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// language is how lines of code are told apart from comments in one language. It's a heuristic: comment markers inside
+// strings aren't recognized, and a line with code before a comment counts as code.
+type language struct {
+	name         string
+	lineComments []string
+	blockStart   string
+	blockEnd     string
+}
+
+//nolint:gochecknoglobals
+var (
+	cLike    = language{lineComments: []string{"//"}, blockStart: "/*", blockEnd: "*/"}
+	hashLike = language{lineComments: []string{"#"}}
+
+	// languages maps lowercased file extensions to the language of the file.
+	languages = map[string]language{
+		".go":    named(cLike, "Go"),
+		".rs":    named(cLike, "Rust"),
+		".c":     named(cLike, "C"),
+		".h":     named(cLike, "C"),
+		".cc":    named(cLike, "C++"),
+		".cpp":   named(cLike, "C++"),
+		".hpp":   named(cLike, "C++"),
+		".cs":    named(cLike, "C#"),
+		".java":  named(cLike, "Java"),
+		".kt":    named(cLike, "Kotlin"),
+		".swift": named(cLike, "Swift"),
+		".js":    named(cLike, "JavaScript"),
+		".jsx":   named(cLike, "JavaScript"),
+		".mjs":   named(cLike, "JavaScript"),
+		".cjs":   named(cLike, "JavaScript"),
+		".ts":    named(cLike, "TypeScript"),
+		".tsx":   named(cLike, "TypeScript"),
+		".css":   {name: "CSS", blockStart: "/*", blockEnd: "*/"},
+		".scss":  named(cLike, "SCSS"),
+		".py":    {name: "Python", lineComments: []string{"#"}, blockStart: `"""`, blockEnd: `"""`},
+		".rb":    named(hashLike, "Ruby"),
+		".sh":    named(hashLike, "Shell"),
+		".bash":  named(hashLike, "Shell"),
+		".zsh":   named(hashLike, "Shell"),
+		".yml":   named(hashLike, "YAML"),
+		".yaml":  named(hashLike, "YAML"),
+		".toml":  named(hashLike, "TOML"),
+		".tf":    {name: "Terraform", lineComments: []string{"#", "//"}, blockStart: "/*", blockEnd: "*/"},
+		".sql":   {name: "SQL", lineComments: []string{"--"}, blockStart: "/*", blockEnd: "*/"},
+		".lua":   {name: "Lua", lineComments: []string{"--"}},
+		".php":   {name: "PHP", lineComments: []string{"//", "#"}, blockStart: "/*", blockEnd: "*/"},
+		".html":  {name: "HTML", blockStart: "<!--", blockEnd: "-->"},
+		".vue":   {name: "Vue", lineComments: []string{"//"}, blockStart: "<!--", blockEnd: "-->"},
+		".json":  {name: "JSON"},
+		".md":    {name: "Markdown", blockStart: "<!--", blockEnd: "-->"},
+	}
+)
+
+// named returns a copy of a language's comment syntax under another name.
+func named(syntax language, name string) language {
+	syntax.name = name
+
+	return syntax
+}
+
+// maxCountedFileSize is the largest file whose lines are counted. Bigger ones are usually generated or vendored.
+const maxCountedFileSize = 4 << 20
+
+// countNewCode estimates the lines of code in each of the given files, by language. Files in languages it doesn't
+// know, binary files, and files that can no longer be read are skipped.
+func countNewCode(paths []string) map[string]int64 {
+	results := map[string]int64{}
+
+	for _, path := range paths {
+		lang, ok := languages[strings.ToLower(filepath.Ext(path))]
+		if !ok {
+			continue
+		}
+
+		data, ok := readSource(path)
+		if !ok {
+			continue
+		}
+
+		if lines := countCode(bytes.NewReader(data), lang); lines > 0 {
+			results[lang.name] += lines
+		}
+	}
+
+	return results
+}
+
+// readSource reads a file whose lines are to be counted. It reports false if the file is too big, binary, or can no
+// longer be read.
+func readSource(path string) ([]byte, bool) {
+	info, err := os.Stat(path)
+	if err != nil || !info.Mode().IsRegular() || info.Size() > maxCountedFileSize {
+		return nil, false
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		slog.Debug("failed to read file to count its lines", "path", path, "error", err)
+		return nil, false
+	}
+
+	return data, isText(data)
+}
+
+// isText reports whether data is small enough to count the lines of and isn't binary, by the same check as git's.
+func isText(data []byte) bool {
+	return len(data) <= maxCountedFileSize && bytes.IndexByte(data[:min(len(data), 8000)], 0) < 0
+}
+
+// countCode counts the lines that aren't blank or entirely comments.
+func countCode(reader io.Reader, lang language) int64 {
+	var (
+		result  int64
+		inBlock bool
+	)
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(nil, maxCountedFileSize)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		code := false
+
+		for line != "" && !code {
+			switch {
+			case inBlock:
+				end := strings.Index(line, lang.blockEnd)
+				if end < 0 {
+					line = ""
+					continue
+				}
+
+				line = strings.TrimSpace(line[end+len(lang.blockEnd):])
+				inBlock = false
+			case slices.ContainsFunc(lang.lineComments, func(prefix string) bool { return strings.HasPrefix(line, prefix) }):
+				line = ""
+			case lang.blockStart != "" && strings.HasPrefix(line, lang.blockStart):
+				line = strings.TrimSpace(line[len(lang.blockStart):])
+				inBlock = true
+			default:
+				code = true
+			}
+		}
+
+		if code {
+			result++
+		}
+	}
+
+	return result
+}
+
+// newCodeString summarizes NewCode, biggest language first, e.g. "~1,200 LOC Go, ~300 LOC YAML".
+func (s *StatusSnapshot) newCodeString() string {
+	names := slices.SortedFunc(maps.Keys(s.NewCode), func(a, b string) int {
+		return cmp.Or(cmp.Compare(s.NewCode[b], s.NewCode[a]), cmp.Compare(a, b))
+	})
+
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		parts = append(parts, "~"+s.number(s.NewCode[name])+" LOC "+name)
+	}
+
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,99 @@
+package mon //nolint:testpackage // exercises the unexported line counting
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCountCode(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		ext      string
+		content  string
+		expected int64
+	}{
+		{
+			name: "go",
+			ext:  ".go",
+			content: `// Package main is an example.
+package main
+
+/*
+	A block comment,
+	over several lines.
+*/
+func main() { /* inline */
+	println("hi") // trailing comment
+	/* one line */
+}
+`,
+			expected: 4,
+		},
+		{
+			name: "python",
+			ext:  ".py",
+			content: `"""A module docstring,
+over two lines."""
+
+# A comment
+def main():
+    """One line."""
+    return 1
+`,
+			expected: 2,
+		},
+		{"yaml", ".yaml", "# config\nname: mon\n\nitems:\n  - one\n", 3},
+		{"html", ".html", "<!-- header -->\n<p>hi</p>\n<!--\nmore\n--> <p>after</p>\n", 2},
+	}
+
+	for _, test := range tests {
+		if actual := countCode(strings.NewReader(test.content), languages[test.ext]); actual != test.expected {
+			t.Errorf("%s: expected %d lines of code, got %d", test.name, test.expected, actual)
+		}
+	}
+}
+
+func TestCountNewCode(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	contents := map[string]string{
+		"main.go":    "package main\n\nfunc main() {}\n",
+		"util.GO":    "package main\n",
+		"ci.yml":     "on: push\n",
+		"notes.xyz":  "not a language\n",
+		"binary.go":  "package main\x00\n",
+		"missing.go": "",
+	}
+
+	paths := []string{}
+
+	for name, content := range contents {
+		path := filepath.Join(dir, name)
+		paths = append(paths, path)
+
+		if name == "missing.go" {
+			continue
+		}
+
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("failed to write %q: %v", path, err)
+		}
+	}
+
+	results := countNewCode(paths)
+
+	if len(results) != 2 || results["Go"] != 3 || results["YAML"] != 1 {
+		t.Errorf("expected 3 lines of Go and 1 of YAML, got %v", results)
+	}
+
+	snapshot := &StatusSnapshot{NewCode: results}
+	if actual := snapshot.newCodeString(); actual != "~3 LOC Go, ~1 LOC YAML" {
+		t.Errorf("expected the biggest language first, got %q", actual)
+	}
+}
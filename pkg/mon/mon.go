@@ -1,19 +1,32 @@
+// Package mon ties the file and git monitors and the listeners together into a session, and renders its status and
+// reports.
+//
+// Stability: experimental
 package mon
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"log/slog"
+	"net"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"slices"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/cneill/mon/pkg/audio"
+	"github.com/cneill/mon/pkg/clock"
+	"github.com/cneill/mon/pkg/deps"
 	"github.com/cneill/mon/pkg/files"
 	"github.com/cneill/mon/pkg/git"
 	"github.com/cneill/mon/pkg/listeners"
+	"github.com/fatih/color"
+	"github.com/go-git/go-git/v5/plumbing"
 	"golang.org/x/time/rate"
 )
 
@@ -22,9 +35,74 @@ type Opts struct {
 	AudioEnabled bool
 	AudioConfig  *audio.Config
 	ProjectDir   string
-	Listeners    []listeners.Listener
+	// ProjectDirs are more project directories watched in the same session as ProjectDir, like a frontend and a
+	// backend repository an agent works on at once. Each must be a git repository, and none can be inside another.
+	ProjectDirs []string
+	// Version is the mon version stamped into session metadata.
+	Version   string
+	Listeners []listeners.Listener
+	// ListenerScopes restricts where each listener's manifests are honored, keyed by listener name.
+	ListenerScopes map[string]*listeners.Scope
+	// ExternalManifests are absolute paths to manifests outside ProjectDir (e.g. a shared constraints file) that are
+	// watched individually and fed to the listeners that handle their base names.
+	ExternalManifests []string
+	// DisplayMode is how live status is shown. Defaults to DisplayModeAuto.
+	DisplayMode DisplayMode
+	// DisplayInterval is how often the status line is checked for changes when nothing triggers a redraw. Defaults to
+	// DefaultDisplayInterval.
+	DisplayInterval time.Duration
+	// SaveWindow coalesces bursts of write events to the same file into a single save.
+	SaveWindow time.Duration
+	// HashContents only counts saves that change a file's contents as meaningful. See files.MonitorOpts.
+	HashContents bool
+	// TurnGap is how long writes have to pause before the next write starts a new turn. Defaults to DefaultTurnGap.
+	TurnGap time.Duration
+	// IgnorePatterns are globs for project paths to leave out of the file stats. See files.MonitorOpts.
+	IgnorePatterns []string
+	// DeleteTimeout, EditorProfiles, and TempPatterns tune how editors' saves are told apart from real creates and
+	// deletes. See files.MonitorOpts.
+	DeleteTimeout  time.Duration
+	EditorProfiles []string
+	TempPatterns   []string
+	// PollInterval finds file changes by rescanning this often instead of with fsnotify, e.g. on network mounts. Zero
+	// uses fsnotify.
+	PollInterval time.Duration
+	// DebounceWindow coalesces bursts of write events to the same file into one event, so a file being written many
+	// times a second doesn't redraw the display or play a sound for every write. See files.MonitorOpts.
+	DebounceWindow time.Duration
+	// FollowSymlinks watches the directories that symlinks in the project point to. See files.MonitorOpts.
+	FollowSymlinks bool
+	// TopNewFiles is how many of the largest new files the report lists. See files.MonitorOpts.
+	TopNewFiles int
+	// MaxDepth, MaxTrackedFiles, and LazyWatchDepth keep the initial scan of a huge project quick and small. See
+	// files.MonitorOpts.
+	MaxDepth        int
+	MaxTrackedFiles int
+	LazyWatchDepth  int
+	// Clock drives every timer, ticker, and rate limit in mon and its monitors. Nil uses real time.
+	Clock clock.Clock
+
+	// CheckpointPath is where session state is periodically saved so it can be resumed after a crash. Checkpointing
+	// is disabled if it is empty or CheckpointInterval is not positive.
+	CheckpointPath     string
+	CheckpointInterval time.Duration
+	// Resume continues the session saved at CheckpointPath instead of starting a new one.
+	Resume bool
+
+	// OverlayAddr is the address (e.g. ":8080") to serve a live-updating overlay page on, for capturing in streaming
+	// or recording software. Empty disables the overlay.
+	OverlayAddr string
+
+	// SnapshotRefs records the session's starting and final commits as refs under refs/mon/, so both states can be
+	// recovered after further local work.
+	SnapshotRefs bool
+
+	// CheckCommand is run with the system shell on a copy of the files in each new commit, e.g. DefaultCheckCommand,
+	// and whether it passed is recorded for the report. Empty disables checks.
+	CheckCommand string
 
 	DetailsOpts *DetailsOpts
+	ExportOpts  *ExportOpts
 }
 
 func (o *Opts) OK() error {
@@ -32,35 +110,115 @@ func (o *Opts) OK() error {
 		return fmt.Errorf("must supply project dir")
 	}
 
-	if _, err := os.Stat(o.ProjectDir); err != nil {
-		return fmt.Errorf("failed to stat project dir: %w", err)
+	for _, dir := range o.projectDirs() {
+		if _, err := os.Stat(dir); err != nil {
+			return fmt.Errorf("failed to stat project dir: %w", err)
+		}
 	}
 
 	if o.DetailsOpts == nil {
 		return fmt.Errorf("must supply details options")
 	}
 
+	switch o.DisplayMode {
+	case DisplayModeAuto, DisplayModeLine, DisplayModeNDJSON, DisplayModeQuiet, DisplayModePlain:
+	default:
+		return fmt.Errorf("unknown display mode %q", o.DisplayMode)
+	}
+
+	if err := o.DetailsOpts.Goals.OK(); err != nil {
+		return fmt.Errorf("invalid goals: %w", err)
+	}
+
+	if o.Resume && o.CheckpointPath == "" {
+		return fmt.Errorf("must supply checkpoint path to resume a session")
+	}
+
 	return nil
 }
 
 type DetailsOpts struct {
 	ShowAllFiles bool
+	// Numbers formats counters in the display and reports.
+	Numbers NumberFormat
+	// Times formats timestamps in the display and reports.
+	Times TimeFormat
+	// Goals are shown as progress meters in the status line.
+	Goals Goals
+}
+
+// ExportOpts configures files written at the end of a session. Empty paths disable the corresponding export.
+type ExportOpts struct {
+	ChangelogPath string
+
+	// StatusPath is a file that a status snapshot is appended to, as a line of JSON, every StatusInterval while the
+	// session runs, and once more when it ends.
+	StatusPath     string
+	StatusInterval time.Duration
+
+	// EventsCSVPath is a file that file, git, and dependency events are appended to as CSV rows while the session
+	// runs.
+	EventsCSVPath string
+	// CSVDir is a directory that the final per-file, per-extension, and per-commit stats are written to as CSV files.
+	CSVDir string
+	// EventsParquetDir is a directory that the same events are written to as a Parquet file, one per session, with
+	// each row tagged with the session ID and project. The file is complete once the session ends.
+	EventsParquetDir string
+	// Redaction, if set, hashes paths and strips commit details from all of the above.
+	Redaction *Redaction
+
+	// PatchPath is a file that the changes committed during the session are written to as a unified diff when it
+	// ends. The changes left uncommitted are written next to it, e.g. to "session.uncommitted.patch" for
+	// "session.patch". Redaction doesn't apply to them, since they're the code itself.
+	PatchPath string
 }
 
 type Mon struct {
 	*Opts
 
+	clock        clock.Clock
 	fileMonitor  *files.Monitor
-	gitMonitor   *git.Monitor
+	repos        []*repo // one for each project directory, in the order of projectDirs
 	AudioManager *audio.Manager
 	writeLimiter *rate.Limiter
-
-	displayChan chan struct{}
+	writeRate    *rateCounter
+	commitRate   *rateCounter
+	turns        *turnTracker
+	checker      *commitChecker // nil without CheckCommand
+	// writesRateLimited counts writes skipped by writeLimiter
+	writesRateLimited atomic.Int64
+
+	// recorders write events to the event exports in ExportOpts, if any are set
+	recorders []eventRecorder
+
+	// snapshotRefs are the session refs written so far, if SnapshotRefs is set
+	snapshotRefs     []string
+	snapshotRefMutex sync.Mutex
+
+	// monitorErrors are the distinct problems reported by the monitors, most recent last
+	monitorErrors     []string
+	monitorErrorMutex sync.Mutex
+
+	// ansi is false on consoles that don't understand escape sequences, where the status line is redrawn by
+	// overwriting it with spaces instead. liveWidth is the width of the last status line printed.
+	ansi        bool
+	displayMode DisplayMode // never DisplayModeAuto
+	liveWidth   atomic.Int64
+	displayChan chan struct{} // holds at most one pending redraw
+	redraw      atomic.Bool   // forces the next redraw, even if the status line didn't change
 	startTime   time.Time
-	lastWrite   time.Time
+	lastWrite   atomic.Int64 // unix nanoseconds, or 0 before the first write
+	session     SessionInfo
 
-	listeners           map[string]listeners.Listener
-	listenerDiffsCached map[string]listeners.Diff
+	// fatal receives the first error that ends the session early, like a panic in one of the monitors
+	fatal chan error
+
+	listeners           map[string][]listeners.Listener // keyed by watched file base name
+	listenerMutex       sync.Mutex
+	listenerDiffsCached listeners.DiffMap
+	manifests           map[string][]byte // initial listener manifest contents, keyed by path
+
+	resumed *checkpoint
 }
 
 func New(opts *Opts) (*Mon, error) {
@@ -68,85 +226,370 @@ func New(opts *Opts) (*Mon, error) {
 		return nil, fmt.Errorf("failed to configure mon: %w", err)
 	}
 
-	fileMonitor, err := files.NewMonitor(&files.MonitorOpts{
-		RootPath:    opts.ProjectDir,
-		WatchRoot:   true,
-		TrackWrites: true,
-	})
-	if err != nil {
-		return nil, fmt.Errorf("failed to set up file monitor: %w", err)
+	var resumed *checkpoint
+
+	if opts.Resume {
+		cp, err := loadCheckpoint(opts.CheckpointPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load session to resume: %w", err)
+		}
+
+		resumed = cp
 	}
 
-	gitMonitor, err := git.NewMonitor(&git.MonitorOpts{
-		RootPath: opts.ProjectDir,
-	})
+	fileMonitor, err := newFileMonitor(opts, resumed)
 	if err != nil {
-		return nil, fmt.Errorf("failed to set up git monitor: %w", err)
+		return nil, err
 	}
 
-	var audioManager *audio.Manager
-
-	if opts.AudioEnabled {
-		audioManager, err = audio.NewManager(opts.AudioConfig)
-		if err != nil {
-			slog.Error("failed to set up audio manager", "error", err)
-		}
+	repos, err := newRepos(opts, resumed)
+	if err != nil {
+		return nil, err
 	}
 
+	clk := clock.Or(opts.Clock)
+
 	mon := &Mon{
 		Opts: opts,
 
+		clock:        clk,
 		fileMonitor:  fileMonitor,
-		gitMonitor:   gitMonitor,
+		repos:        repos,
 		writeLimiter: rate.NewLimiter(3, 1),
-		AudioManager: audioManager,
+		writeRate:    newRateCounter(time.Minute),
+		commitRate:   newRateCounter(time.Hour),
+		turns:        newTurnTracker(opts.TurnGap),
+		AudioManager: newAudioManager(opts),
+
+		startTime:   clk.Now(),
+		session:     newSessionInfo(opts.projectDirs(), opts.Version),
+		ansi:        terminalSupportsANSI(),
+		displayMode: opts.DisplayMode,
+		displayChan: make(chan struct{}, 1),
+		fatal:       make(chan error, 1),
+
+		listeners:           map[string][]listeners.Listener{},
+		listenerDiffsCached: listeners.DiffMap{},
+		manifests:           map[string][]byte{},
 
-		startTime:   time.Now(),
-		displayChan: make(chan struct{}),
+		resumed: resumed,
+	}
 
-		listeners:           map[string]listeners.Listener{},
-		listenerDiffsCached: listeners.DiffMap{},
+	if opts.CheckCommand != "" {
+		mon.checker = newCommitChecker(opts.CheckCommand)
+	}
+
+	mon.setupDisplay()
+
+	if resumed != nil {
+		mon.resume(resumed)
 	}
 
 	if err := mon.setupListeners(); err != nil {
 		return nil, fmt.Errorf("failed to set up listeners: %w", err)
 	}
 
+	if err := mon.openRecorders(); err != nil {
+		return nil, err
+	}
+
 	return mon, nil
 }
 
+// newFileMonitor sets up the file monitor for the project directories, with the files of the session being resumed, if
+// any.
+func newFileMonitor(opts *Opts, resumed *checkpoint) (*files.Monitor, error) {
+	fileMonitor, err := files.NewMonitor(&files.MonitorOpts{
+		RootPath:          opts.ProjectDir,
+		RootPaths:         opts.ProjectDirs,
+		WatchRoot:         true,
+		TrackWrites:       true,
+		SaveWindow:        opts.SaveWindow,
+		HashContents:      opts.HashContents,
+		IgnorePatterns:    opts.IgnorePatterns,
+		DeleteTimeout:     opts.DeleteTimeout,
+		EditorProfiles:    opts.EditorProfiles,
+		TempPatterns:      opts.TempPatterns,
+		ReconcileInterval: reconcileInterval,
+		PollInterval:      opts.PollInterval,
+		FollowSymlinks:    opts.FollowSymlinks,
+		DebounceWindow:    opts.DebounceWindow,
+		TopNewFiles:       opts.TopNewFiles,
+		MaxDepth:          opts.MaxDepth,
+		MaxTrackedFiles:   opts.MaxTrackedFiles,
+		LazyWatchDepth:    opts.LazyWatchDepth,
+		Clock:             opts.Clock,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up file monitor: %w", err)
+	}
+
+	if resumed != nil {
+		fileMonitor.FileMap().Restore(resumed.Files)
+	}
+
+	return fileMonitor, nil
+}
+
+// newAudioManager sets up sounds, if they're enabled. If that fails, the session goes on without them.
+func newAudioManager(opts *Opts) *audio.Manager {
+	if !opts.AudioEnabled {
+		return nil
+	}
+
+	audioManager, err := audio.NewManager(opts.AudioConfig)
+	if err != nil {
+		slog.Error("failed to set up audio manager", "error", err)
+	}
+
+	return audioManager
+}
+
+// setupDisplay picks the display mode for DisplayModeAuto, and turns colors off where they can't be shown.
+func (m *Mon) setupDisplay() {
+	terminal := stdoutIsTerminal()
+
+	if m.displayMode == DisplayModeAuto {
+		m.displayMode = DisplayModeNDJSON
+		if terminal {
+			m.displayMode = DisplayModeLine
+		}
+	}
+
+	if !m.ansi || !terminal || m.displayMode == DisplayModePlain {
+		color.NoColor = true
+	}
+}
+
+// resume carries over the start time, turns, and ID of the session saved in cp.
+func (m *Mon) resume(cp *checkpoint) {
+	m.startTime = cp.StartTime
+	m.setLastWrite(cp.LastWrite)
+	m.turns.Restore(cp.Turns)
+
+	if cp.SessionID != "" {
+		m.session.ID = cp.SessionID
+	}
+}
+
 func (m *Mon) Run(ctx context.Context) error {
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	go m.fileMonitor.Run(ctx)
+	// A panic on this goroutine still gets the session reported before it crashes mon
+	defer func() {
+		if r := recover(); r != nil {
+			cancel()
+			m.flushAfterFailure(fmt.Errorf("mon panicked: %v", r))
+			panic(r)
+		}
+	}()
+
+	defer m.closeRecorders()
+
+	// Bound before anything starts, so the monitors can be closed right away if the address is taken
+	var overlayListener net.Listener
+
+	if m.OverlayAddr != "" {
+		listener, err := net.Listen("tcp", m.OverlayAddr)
+		if err != nil {
+			m.fileMonitor.Close()
+			m.closeRepos()
+
+			return fmt.Errorf("failed to start overlay server: %w", err)
+		}
+
+		overlayListener = listener
+	}
+
+	go m.guard("file monitor", func() { m.fileMonitor.Run(ctx) })
 	defer m.fileMonitor.Close()
 
-	go m.gitMonitor.Run(ctx)
-	defer m.gitMonitor.Close()
+	if overlayListener != nil {
+		go m.guard("overlay server", func() { m.serveOverlay(ctx, overlayListener) })
+	}
+
+	if m.SnapshotRefs {
+		for _, repo := range m.repos {
+			m.snapshotRef(repo, repo.git.SnapshotStart)
+		}
+	}
+
+	if m.checker != nil {
+		go m.guard("commit checks", func() { m.checker.run(ctx) })
+	}
+
+	for _, repo := range m.repos {
+		go m.guard("git monitor", func() { repo.git.Run(ctx) })
+		go m.guard("git event handler", func() { m.handleGitEvents(ctx, repo) })
+	}
+
+	defer m.closeRepos()
+
+	go m.guard("event handler", func() { m.handleEvents(ctx) })
 
-	go m.handleEvents(ctx)
+	// Manifest writes get their own stream, so a flood of other writes can't crowd them out of Events
+	manifestWrites, unsubscribe := m.fileMonitor.Subscribe(m.isManifestWrite)
+	defer unsubscribe()
 
-	go m.displayLoop(ctx)
+	go m.guard("listener event handler", func() { m.handleManifestWrites(ctx, manifestWrites) })
+
+	go m.guard("display", func() { m.displayLoop(ctx) })
+
+	go m.guard("checkpointing", func() { m.checkpointLoop(ctx) })
+
+	go m.guard("status file", func() { m.statusFileLoop(ctx) })
 
 	m.triggerDisplay()
+	m.sendAudioEvent(ctx, audio.EventSessionStart)
+
+	fatalErr := m.waitForSignals(ctx)
+
+	cancel() // Cancel context first so goroutines can exit before Close() waits on them
+
+	if fatalErr != nil {
+		m.flushAfterFailure(fatalErr)
+
+		return fmt.Errorf("session ended early: %w", fatalErr)
+	}
+
+	m.endSession()
 
+	return nil
+}
+
+// waitForSignals listens for the signals that end, snapshot, and pause the session, and waits for it to end with
+// waitForShutdown.
+func (m *Mon) waitForSignals(ctx context.Context) error {
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
-	select {
-	case <-sigChan:
-		slog.Debug("Got SIGINT/SIGTERM")
-	case <-ctx.Done():
-		slog.Debug("Context cancelled")
+	snapshotChan := make(chan os.Signal, 1)
+	if sigs := snapshotSignals(); len(sigs) > 0 {
+		signal.Notify(snapshotChan, sigs...)
+		defer signal.Stop(snapshotChan)
 	}
 
-	cancel() // Cancel context first so goroutines can exit before Close() waits on them
+	pauseChan := make(chan os.Signal, 1)
+	if sigs := pauseSignals(); len(sigs) > 0 {
+		signal.Notify(pauseChan, sigs...)
+		defer signal.Stop(pauseChan)
+	}
+
+	return m.waitForShutdown(ctx, sigChan, snapshotChan, pauseChan)
+}
+
+// endSession records the final session refs, prints the final report, and writes the exports after a session ends
+// normally.
+func (m *Mon) endSession() {
+	if m.SnapshotRefs {
+		for _, repo := range m.repos {
+			m.snapshotRef(repo, repo.git.SnapshotEnd)
+		}
+	}
 
 	snapshot := m.GetStatusSnapshot(true, true)
-	fmt.Println(clearLine + snapshot.Final())
+	m.printReport(snapshot)
 
-	return nil
+	m.writeExports(snapshot)
+	m.removeCheckpoint()
+	m.playSessionEnd(snapshot)
+}
+
+// waitForShutdown blocks until the session should end, printing an intermediate report whenever a snapshot signal
+// (SIGUSR2) arrives and pausing or resuming file counting on a pause signal (SIGUSR1). It returns the error that ended
+// the session, if it didn't end normally.
+func (m *Mon) waitForShutdown(ctx context.Context, sigChan, snapshotChan, pauseChan <-chan os.Signal) error {
+	for {
+		select {
+		case <-sigChan:
+			slog.Debug("Got SIGINT/SIGTERM")
+			return nil
+		case <-ctx.Done():
+			slog.Debug("Context cancelled")
+			return nil
+		case err := <-m.fatal:
+			slog.Error("Ending session after fatal error", "error", err)
+			return err
+		case <-snapshotChan:
+			slog.Debug("Got snapshot signal")
+
+			snapshot := m.GetStatusSnapshot(true, true)
+			m.printReport(snapshot)
+
+			m.redrawDisplay()
+		case <-pauseChan:
+			slog.Debug("Got pause signal")
+
+			m.togglePause()
+		}
+	}
+}
+
+// togglePause pauses counting file changes, or resumes it if it's paused, so noisy operations like installing
+// dependencies can be left out of the session's stats.
+func (m *Mon) togglePause() {
+	if m.fileMonitor.Paused() {
+		m.fileMonitor.Resume()
+	} else {
+		m.fileMonitor.Pause()
+	}
+
+	m.redrawDisplay()
+}
+
+// snapshotRef writes one of the session's refs in a repo with write, keeping track of it for the final report.
+func (m *Mon) snapshotRef(repo *repo, write func(sessionID string) (plumbing.ReferenceName, error)) {
+	ref, err := write(m.session.ID)
+	if err != nil {
+		slog.Error("failed to write session ref", "dir", repo.dir, "error", err)
+		m.recordMonitorError("git", err)
+
+		return
+	}
+
+	slog.Debug("Wrote session ref", "dir", repo.dir, "ref", ref)
+
+	name := ref.String()
+	if label := m.repoLabel(repo); label != "" {
+		name = label + ": " + name
+	}
+
+	m.snapshotRefMutex.Lock()
+	m.snapshotRefs = append(m.snapshotRefs, name)
+	m.snapshotRefMutex.Unlock()
+}
+
+func (m *Mon) writeExports(snapshot *StatusSnapshot) {
+	if m.ExportOpts == nil {
+		return
+	}
+
+	if path := m.ExportOpts.PatchPath; path != "" {
+		if err := m.writePatches(path); err != nil {
+			slog.Error("failed to export patch", "error", err)
+		}
+	}
+
+	snapshot = m.exportSnapshot(snapshot)
+
+	if path := m.ExportOpts.ChangelogPath; path != "" {
+		if err := snapshot.WriteChangelog(path); err != nil {
+			slog.Error("failed to export changelog", "error", err)
+		}
+	}
+
+	if path := m.ExportOpts.StatusPath; path != "" {
+		if err := snapshot.AppendJSON(path); err != nil {
+			slog.Error("failed to write final status snapshot", "path", path, "error", err)
+		}
+	}
+
+	if dir := m.ExportOpts.CSVDir; dir != "" {
+		if err := snapshot.WriteCSV(dir); err != nil {
+			slog.Error("failed to export CSV stats", "error", err)
+		}
+	}
 }
 
 func (m *Mon) Teardown() {
@@ -158,10 +601,15 @@ func (m *Mon) setupListeners() error {
 
 	for _, listener := range m.Listeners {
 		for _, file := range listener.WatchedFiles() {
-			m.listeners[file] = listener
+			m.listeners[file] = append(m.listeners[file], listener)
 
 			initialFiles := fileMap.FilePathsByBase(file)
 			for _, path := range initialFiles {
+				if !m.listenerAllows(listener, path) {
+					slog.Debug("file out of scope for listener", "listener", listener.Name(), "path", path)
+					continue
+				}
+
 				slog.Debug("found file for listener", "listener", listener.Name(), "path", path)
 
 				content, err := os.ReadFile(path)
@@ -169,18 +617,120 @@ func (m *Mon) setupListeners() error {
 					return fmt.Errorf("failed to read file %q for listener %q: %w", path, listener.Name(), err)
 				}
 
-				logErr := listener.LogEvent(listeners.Event{
-					Name:    path,
-					Type:    listeners.EventInit,
-					Content: content,
-				})
-				if logErr != nil {
-					return fmt.Errorf("failed to log initializing event for file %q for listener %q: %w", path, listener.Name(), logErr)
+				if err := m.initListener(listener, path, content); err != nil {
+					return err
 				}
 			}
 		}
 	}
 
+	if err := m.setupExternalManifests(); err != nil {
+		return err
+	}
+
+	for _, listener := range m.Listeners {
+		m.watchExternalPaths(listener)
+	}
+
+	return nil
+}
+
+// setupExternalManifests starts watching the configured manifests outside the project directory and initializes the
+// listeners that handle them.
+func (m *Mon) setupExternalManifests() error {
+	for _, path := range m.ExternalManifests {
+		path = filepath.Clean(path)
+
+		if !m.isExternal(path) {
+			slog.Debug("configured manifest is inside the project directory", "path", path)
+			continue
+		}
+
+		matched := m.listeners[filepath.Base(path)]
+		if len(matched) == 0 {
+			slog.Warn("no listener handles configured manifest", "path", path)
+			continue
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read external manifest %q: %w", path, err)
+		}
+
+		for _, listener := range matched {
+			if err := m.initListener(listener, path, content); err != nil {
+				return err
+			}
+		}
+
+		if err := m.fileMonitor.WatchExternalFile(path); err != nil {
+			return fmt.Errorf("failed to watch external manifest: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// watchExternalPaths watches any files a listener follows outside the project directory, like a requirements file
+// included with "-r ../shared/constraints.txt", which the recursive watch of the project directory doesn't cover.
+func (m *Mon) watchExternalPaths(listener listeners.Listener) {
+	pathWatcher, ok := listener.(listeners.PathWatcher)
+	if !ok {
+		return
+	}
+
+	for _, path := range pathWatcher.WatchedPaths() {
+		if !m.isExternal(path) {
+			continue
+		}
+
+		if err := m.fileMonitor.WatchExternalFile(path); err != nil {
+			slog.Warn("failed to watch file outside project directory", "listener", listener.Name(), "path", path,
+				"error", err)
+		}
+	}
+}
+
+// isExternal reports whether path is outside every project directory.
+func (m *Mon) isExternal(path string) bool {
+	return files.RootOf(m.projectDirs(), path) == ""
+}
+
+// initListener feeds a manifest's initial content to a listener. When resuming a session, the content saved in the
+// checkpoint is used as the initial state and the current content is logged as a write on top of it.
+func (m *Mon) initListener(listener listeners.Listener, path string, content []byte) error {
+	initial := content
+
+	if m.resumed != nil {
+		if saved, ok := m.resumed.Manifests[path]; ok {
+			initial = saved
+		}
+	}
+
+	m.manifests[path] = initial
+
+	logErr := listener.LogEvent(listeners.Event{
+		Name:    path,
+		Type:    listeners.EventInit,
+		Content: initial,
+	})
+	if logErr != nil {
+		return fmt.Errorf("failed to log initializing event for file %q for listener %q: %w", path, listener.Name(), logErr)
+	}
+
+	if bytes.Equal(initial, content) {
+		return nil
+	}
+
+	logErr = listener.LogEvent(listeners.Event{
+		Name:    path,
+		Type:    listeners.EventWrite,
+		Content: content,
+	})
+	if logErr != nil {
+		return fmt.Errorf("failed to log resumed content for file %q for listener %q: %w", path, listener.Name(), logErr)
+	}
+
 	return nil
 }
 
@@ -200,10 +750,37 @@ func (m *Mon) sendAudioEvent(ctx context.Context, eventType audio.EventType) {
 
 	m.AudioManager.SendEvent(ctx, audio.Event{
 		Type: eventType,
-		Time: time.Now(),
+		Time: m.clock.Now(),
 	})
 }
 
+// reconcileInterval is how often the project is rescanned for files that were created without an event, e.g. in a
+// directory created by "mkdir -p" before it could be watched.
+const reconcileInterval = time.Second * 30
+
+// sessionEndSoundTimeout is the longest mon waits on the session end sound and summary before exiting.
+const sessionEndSoundTimeout = time.Second * 10
+
+// playSessionEnd plays the session end sound, followed by the summary chimes if they're enabled, and waits for them so
+// that mon doesn't exit in the middle.
+func (m *Mon) playSessionEnd(snapshot *StatusSnapshot) {
+	if m.AudioManager == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), sessionEndSoundTimeout)
+	defer cancel()
+
+	if err := m.AudioManager.PlayEvent(ctx, audio.EventSessionEnd); err != nil {
+		slog.Error("failed to play session end sound", "error", err)
+		return
+	}
+
+	if err := m.AudioManager.PlaySummary(ctx, snapshot.NumCommits); err != nil {
+		slog.Error("failed to play session summary", "error", err)
+	}
+}
+
 func (m *Mon) handleEvents(ctx context.Context) {
 	for {
 		select {
@@ -216,100 +793,322 @@ func (m *Mon) handleEvents(ctx context.Context) {
 				return
 			}
 
+			// Don't spin up goroutines for events that handleFileEvent ignores anyway
+			if eventType := event.Type(); eventType == files.EventTypeChmod || eventType == files.EventTypeUnknown {
+				continue
+			}
+
+			m.recordFileEvent(event)
+
 			go m.handleFileEvent(ctx, event)
 
-		case event, ok := <-m.gitMonitor.GitEvents:
+		case err := <-m.fileMonitor.Errors():
+			m.recordMonitorError("files", err)
+		}
+	}
+}
+
+// handleGitEvents handles the events and errors from one repo's git monitor until it shuts down.
+func (m *Mon) handleGitEvents(ctx context.Context, repo *repo) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case err := <-repo.git.Errors():
+			m.recordMonitorError("git", err)
+
+		case event, ok := <-repo.git.GitEvents:
 			if !ok {
-				slog.Info("git monitor shut down")
+				slog.Info("git monitor shut down", "dir", repo.dir)
 				return
 			}
 
+			m.recordEvent(sessionEvent{Time: event.Time, Source: "git", Type: string(event.Type), Path: m.repoLabel(repo)})
+
 			switch event.Type { //nolint:exhaustive
 			case git.EventTypeNewCommit:
+				m.commitRate.Add(event.Time)
 				m.sendAudioEvent(ctx, audio.EventGitCommitCreate)
 				m.triggerDisplay()
+
+				if m.checker != nil {
+					m.checker.request(repo)
+				}
 			case git.EventTypePush:
 				m.sendAudioEvent(ctx, audio.EventGitCommitPush)
+			case git.EventTypeForcePush:
+				slog.Info("remote branch history was rewritten by a force push", "dir", repo.dir)
+				m.sendAudioEvent(ctx, audio.EventGitCommitPush)
 			}
 		}
 	}
 }
 
+// maxMonitorErrors is how many distinct monitor errors are kept to show the user.
+const maxMonitorErrors = 20
+
+// recordMonitorError keeps a monitor's error around so it can be shown in the UI instead of only in the debug log.
+func (m *Mon) recordMonitorError(source string, err error) {
+	message := source + ": " + err.Error()
+
+	slog.Warn("monitor degraded", "source", source, "error", err)
+
+	m.monitorErrorMutex.Lock()
+	defer m.monitorErrorMutex.Unlock()
+
+	if slices.Contains(m.monitorErrors, message) {
+		return
+	}
+
+	m.monitorErrors = append(m.monitorErrors, message)
+	if len(m.monitorErrors) > maxMonitorErrors {
+		m.monitorErrors = m.monitorErrors[1:]
+	}
+
+	go m.triggerDisplay()
+}
+
+// recordFileEvent passes a file event to the event exports.
+func (m *Mon) recordFileEvent(event files.Event) {
+	var oldPath string
+	if event.OldName != "" {
+		oldPath = files.RelPathIn(m.projectDirs(), event.OldName)
+	}
+
+	m.recordEvent(sessionEvent{
+		Time:   m.clock.Now(),
+		Source: "files",
+		Type:   string(event.Type()),
+		Path:   event.RelPath,
+		Detail: oldPath,
+	})
+}
+
+// lastWriteTime returns when the last file write was seen, or the zero time if there hasn't been one. It's safe to call
+// from any goroutine.
+func (m *Mon) lastWriteTime() time.Time {
+	nanos := m.lastWrite.Load()
+	if nanos == 0 {
+		return time.Time{}
+	}
+
+	return time.Unix(0, nanos)
+}
+
+func (m *Mon) setLastWrite(when time.Time) {
+	if when.IsZero() {
+		m.lastWrite.Store(0)
+		return
+	}
+
+	m.lastWrite.Store(when.UnixNano())
+}
+
 func (m *Mon) handleFileEvent(ctx context.Context, event files.Event) {
 	switch event.Type() { //nolint:exhaustive
-	case files.EventTypeCreate, files.EventTypeRemove, files.EventTypeRename:
+	case files.EventTypeCreate, files.EventTypeRemove, files.EventTypeRename, files.EventTypeMove:
 		m.sendFileAudioEvent(ctx, event)
 
 		go m.triggerDisplay()
 	case files.EventTypeWrite:
-		m.lastWrite = time.Now()
+		now := m.clock.Now()
+		m.setLastWrite(now)
+		m.writeRate.AddN(now, max(event.Count, 1))
+		m.turns.Add(now)
+
+		// Most writes in a burst (e.g. npm install) need nothing more than the rate bookkeeping above
+		if !m.writeLimiter.AllowN(now, 1) {
+			m.writesRateLimited.Add(1)
+			return
+		}
 
-		time.Sleep(time.Millisecond * 250) // allow write+delete pairs to settle before checking
+		m.clock.Sleep(time.Millisecond * 250) // allow write+delete pairs to settle before checking
 
-		if m.writeLimiter.Allow() {
-			m.writeLimiter.Reserve()
-			m.sendAudioEvent(ctx, audio.EventFileWrite)
+		m.writeLimiter.ReserveN(m.clock.Now(), 1)
+		m.sendAudioEvent(ctx, audio.EventFileWrite)
 
-			select {
-			case <-ctx.Done():
+		if repo := m.repoFor(event.Name); repo != nil {
+			repo.git.NotifyFileChange(event.Name)
+		}
+	}
+}
+
+// isManifestWrite is the filter for the file monitor subscription that feeds the listeners.
+func (m *Mon) isManifestWrite(event files.Event) bool {
+	return event.Type() == files.EventTypeWrite && len(m.matchingListeners(event.Name)) > 0
+}
+
+// handleManifestWrites delivers writes to manifests to the listeners watching them, until events is closed.
+func (m *Mon) handleManifestWrites(ctx context.Context, events <-chan files.Event) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case event, ok := <-events:
+			if !ok {
 				return
-			case m.gitMonitor.FileEvents <- event:
 			}
+
+			go func() {
+				m.clock.Sleep(time.Millisecond * 250) // allow write+delete pairs to settle before checking
+				m.notifyListeners(ctx, event.Name, m.matchingListeners(event.Name))
+			}()
 		}
+	}
+}
 
-		base := filepath.Base(event.Name)
-		for file, listener := range m.listeners {
-			if base == file {
-				content, err := os.ReadFile(event.Name)
-				if err != nil {
-					slog.Error("failed to read contents of file for listener", "name", event.Name, "error", err, "listener", listener.Name())
-					continue
-				}
+// notifyListeners delivers a write to the listeners matching the file. Listeners run concurrently, and
+// an error or panic in one listener doesn't keep the others from seeing the event.
+func (m *Mon) notifyListeners(ctx context.Context, path string, matched []listeners.Listener) {
+	if len(matched) == 0 {
+		return
+	}
 
-				oldDiff := m.listenerDiffsCached[listener.Name()]
+	content, err := os.ReadFile(path)
+	if err != nil {
+		slog.Error("failed to read contents of file for listeners", "name", path, "error", err)
+		return
+	}
 
-				logErr := listener.LogEvent(listeners.Event{
-					Name:    event.Name,
-					Type:    listeners.EventWrite,
-					Content: content,
-				})
-				if logErr != nil {
-					slog.Error("failed to log event for listener", "listener", listener.Name(), "error", logErr)
-					continue
+	var wg sync.WaitGroup
+
+	for _, listener := range matched {
+		if !m.listenerAllows(listener, path) {
+			continue
+		}
+
+		wg.Go(func() {
+			defer func() {
+				if r := recover(); r != nil {
+					slog.Error("listener panicked while handling event", "listener", listener.Name(), "path", path, "panic", r)
 				}
+			}()
 
-				newDiff := listener.Diff()
-				m.sendListenerAudioEvents(ctx, oldDiff, newDiff)
-				m.listenerDiffsCached[listener.Name()] = newDiff
+			m.notifyListener(ctx, listener, path, content)
+		})
+	}
 
-				slog.Debug("logged update to listened file", "listener", listener.Name(), "path", event.Name)
-			}
+	wg.Wait()
+}
+
+// matchingListeners returns the listeners watching the file's base name, plus any listeners following that exact path.
+func (m *Mon) matchingListeners(path string) []listeners.Listener {
+	matched := slices.Clone(m.listeners[filepath.Base(path)])
+
+	for _, listener := range m.Listeners {
+		pathWatcher, ok := listener.(listeners.PathWatcher)
+		if !ok || slices.Contains(matched, listener) {
+			continue
+		}
+
+		if slices.Contains(pathWatcher.WatchedPaths(), path) {
+			matched = append(matched, listener)
 		}
 	}
+
+	return matched
 }
 
-func (m *Mon) sendListenerAudioEvents(ctx context.Context, oldDiff, newDiff listeners.Diff) {
-	if m.AudioManager == nil {
+// listenerAllows reports whether the manifest at path is within the listener's configured scope.
+func (m *Mon) listenerAllows(listener listeners.Listener, path string) bool {
+	scope, ok := m.ListenerScopes[listener.Name()]
+	if !ok || m.isExternal(path) {
+		// Scopes are relative to the manifest's project directory, and files outside them were asked for explicitly
+		return true
+	}
+
+	relPath, err := filepath.Rel(files.RootOf(m.projectDirs(), path), path)
+	if err != nil {
+		return true
+	}
+
+	return scope.Allows(relPath)
+}
+
+func (m *Mon) notifyListener(ctx context.Context, listener listeners.Listener, path string, content []byte) {
+	logErr := listener.LogEvent(listeners.Event{
+		Name:    path,
+		Type:    listeners.EventWrite,
+		Content: content,
+	})
+	if logErr != nil {
+		slog.Error("failed to log event for listener", "listener", listener.Name(), "error", logErr)
 		return
 	}
 
-	oldNew := oldDiff.DependencyFileDiffs.NumNewDependencies()
-	oldDel := oldDiff.DependencyFileDiffs.NumDeletedDependencies()
-	oldUpd := oldDiff.DependencyFileDiffs.NumUpdatedDependencies()
+	newDiff := listener.Diff()
+
+	m.listenerMutex.Lock()
+	oldDiff := m.listenerDiffsCached[listener.Name()]
+	m.listenerDiffsCached[listener.Name()] = newDiff
+	m.listenerMutex.Unlock()
 
-	newNew := newDiff.DependencyFileDiffs.NumNewDependencies()
-	newDel := newDiff.DependencyFileDiffs.NumDeletedDependencies()
-	newUpd := newDiff.DependencyFileDiffs.NumUpdatedDependencies()
+	m.handleDependencyChanges(ctx, listener.Name(), oldDiff, newDiff)
+	m.watchExternalPaths(listener)
+
+	slog.Debug("logged update to listened file", "listener", listener.Name(), "path", path)
+}
 
-	if newNew > oldNew {
+// handleDependencyChanges reports the dependency changes made by the latest write to a listener's manifests as they
+// happen, rather than only in the final diff.
+func (m *Mon) handleDependencyChanges(ctx context.Context, listenerName string, oldDiff, newDiff listeners.Diff) {
+	changes := newDiff.DependencyFileDiffs.ChangesSince(oldDiff.DependencyFileDiffs)
+	changeTypes := map[deps.ChangeType]bool{}
+
+	for _, change := range changes {
+		slog.Info("dependency changed", "listener", listenerName, "path", change.Path, "type", change.Type,
+			"dependency", change.Dependency.String())
+		m.recordEvent(sessionEvent{
+			Time:   m.clock.Now(),
+			Source: "deps",
+			Type:   string(change.Type),
+			Path:   files.RelPathIn(m.projectDirs(), change.Path),
+			Detail: change.Dependency.String(),
+		})
+
+		changeTypes[change.Type] = true
+	}
+
+	m.warnTyposquats(newDiff.DependencyFileDiffs, changes)
+
+	if changeTypes[deps.ChangeCreate] {
 		m.sendAudioEvent(ctx, audio.EventPackageCreate)
 	}
 
-	if newUpd > oldUpd {
+	// Reverts have no sound of their own, and the upgrade sound would be misleading
+	if changeTypes[deps.ChangeUpgrade] {
 		m.sendAudioEvent(ctx, audio.EventPackageUpgrade)
 	}
 
-	if newDel > oldDel {
+	if changeTypes[deps.ChangeRemove] {
 		m.sendAudioEvent(ctx, audio.EventPackageRemove)
 	}
 }
+
+// warnTyposquats warns as soon as a dependency is added whose name is one typo away from a popular package's. The
+// final summary lists them again.
+func (m *Mon) warnTyposquats(fileDiffs deps.FileDiffs, changes []deps.Change) {
+	for _, fileDiff := range fileDiffs {
+		for _, match := range fileDiff.PossibleTyposquats() {
+			created := slices.ContainsFunc(changes, func(change deps.Change) bool {
+				return change.Type == deps.ChangeCreate && change.Path == fileDiff.Path &&
+					change.Dependency.Equal(match.Dependency)
+			})
+			if !created {
+				continue
+			}
+
+			slog.Warn("new dependency looks like a typosquat", "path", fileDiff.Path,
+				"dependency", match.Dependency.Package(), "similar", match.Similar)
+			m.recordEvent(sessionEvent{
+				Time:   m.clock.Now(),
+				Source: "deps",
+				Type:   "typosquat",
+				Path:   files.RelPathIn(m.projectDirs(), fileDiff.Path),
+				Detail: match.Dependency.Package() + " looks like " + match.Similar,
+			})
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package mon_test
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/cneill/mon/pkg/mon"
+	"github.com/cneill/mon/pkg/montest"
+)
+
+func TestMon_Run_OverlayAddrInUse(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	if _, err := montest.NewGitRepo(dir); err != nil {
+		t.Fatalf("failed to build git repo: %v", err)
+	}
+
+	taken, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	defer taken.Close()
+
+	monitor, err := mon.New(&mon.Opts{
+		ProjectDir:  dir,
+		DisplayMode: mon.DisplayModeQuiet,
+		OverlayAddr: taken.Addr().String(),
+		DetailsOpts: &mon.DetailsOpts{},
+		ExportOpts:  &mon.ExportOpts{},
+	})
+	if err != nil {
+		t.Fatalf("failed to create mon: %v", err)
+	}
+
+	result := make(chan error, 1)
+
+	go func() { result <- monitor.Run(t.Context()) }()
+
+	select {
+	case err := <-result:
+		if err == nil {
+			t.Error("expected an error for an overlay address that's in use")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run didn't return after failing to start the overlay server")
+	}
+}
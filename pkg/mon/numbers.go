@@ -0,0 +1,168 @@
+package mon
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+// NumberFormat controls how counters are written in the display and reports. The zero value writes plain numbers.
+type NumberFormat struct {
+	// Separator is placed between groups of thousands, e.g. "," for 12,400.
+	Separator string
+	// Decimal is the decimal mark used by compact units, e.g. "." for 12.4k. Defaults to ".".
+	Decimal string
+	// Compact abbreviates numbers of a thousand or more with units, e.g. 12.4k or 3.1M.
+	Compact bool
+}
+
+// localeSeparators maps language codes to their thousands separator and decimal mark. Languages that aren't listed
+// use English conventions.
+//
+//nolint:gochecknoglobals
+var localeSeparators = map[string][2]string{
+	"da": {".", ","}, "de": {".", ","}, "el": {".", ","}, "es": {".", ","}, "id": {".", ","}, "it": {".", ","},
+	"nl": {".", ","}, "pt": {".", ","}, "ro": {".", ","}, "tr": {".", ","}, "vi": {".", ","},
+
+	"bg": {"\u202f", ","}, "cs": {"\u202f", ","}, "fi": {"\u202f", ","}, "fr": {"\u202f", ","}, "hu": {"\u202f", ","},
+	"nb": {"\u202f", ","}, "no": {"\u202f", ","}, "pl": {"\u202f", ","}, "ru": {"\u202f", ","}, "sk": {"\u202f", ","},
+	"sv": {"\u202f", ","}, "uk": {"\u202f", ","},
+}
+
+// LocaleFromEnv returns the locale numbers should be formatted for, from LC_ALL, LC_NUMERIC, or LANG, like the C
+// library does.
+func LocaleFromEnv() string {
+	for _, name := range []string{"LC_ALL", "LC_NUMERIC", "LANG"} {
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+	}
+
+	return ""
+}
+
+// NumberFormatForLocale returns the separators used by a POSIX-style locale like "de_DE.UTF-8". Unknown and unset
+// locales (including "C") get English conventions.
+func NumberFormatForLocale(locale string, compact bool) NumberFormat {
+	locale, _, _ = strings.Cut(locale, ".")
+	locale, _, _ = strings.Cut(locale, "@")
+	language, region, _ := strings.Cut(locale, "_")
+
+	result := NumberFormat{Separator: ",", Decimal: ".", Compact: compact}
+
+	if separators, ok := localeSeparators[strings.ToLower(language)]; ok {
+		result.Separator, result.Decimal = separators[0], separators[1]
+	}
+
+	if strings.EqualFold(region, "CH") && !strings.EqualFold(language, "fr") {
+		result.Separator, result.Decimal = "'", "."
+	}
+
+	return result
+}
+
+// number formats a counter for display.
+func (s *StatusSnapshot) number(n int64) string {
+	if s.DetailsOpts == nil {
+		return strconv.FormatInt(n, 10)
+	}
+
+	return s.Numbers.Format(n)
+}
+
+// byteSize formats a size in bytes with binary units, e.g. "12.4 MiB".
+func (s *StatusSnapshot) byteSize(n int64) string {
+	units := []string{"KiB", "MiB", "GiB", "TiB"}
+	if n < 1024 {
+		return strconv.FormatInt(n, 10) + " B"
+	}
+
+	divisor := int64(1024)
+	unit := 0
+
+	for unit < len(units)-1 && n >= divisor*1024 {
+		divisor *= 1024
+		unit++
+	}
+
+	decimal := "."
+	if s.DetailsOpts != nil && s.Numbers.Decimal != "" {
+		decimal = s.Numbers.Decimal
+	}
+
+	tenths := (n*10 + divisor/2) / divisor
+
+	result := strconv.FormatInt(tenths/10, 10)
+	if tenths%10 != 0 {
+		result += decimal + strconv.FormatInt(tenths%10, 10)
+	}
+
+	return result + " " + units[unit]
+}
+
+// Format writes n with thousands separators, or with a compact unit if enabled.
+func (f NumberFormat) Format(n int64) string {
+	sign := ""
+	if n < 0 {
+		sign = "-"
+		n = -n
+	}
+
+	if f.Compact && n >= 1000 {
+		return sign + f.compact(n)
+	}
+
+	digits := strconv.FormatInt(n, 10)
+	if f.Separator == "" || len(digits) <= 3 {
+		return sign + digits
+	}
+
+	builder := &strings.Builder{}
+	builder.WriteString(sign)
+
+	first := len(digits) % 3
+	if first == 0 {
+		first = 3
+	}
+
+	builder.WriteString(digits[:first])
+
+	for i := first; i < len(digits); i += 3 {
+		builder.WriteString(f.Separator)
+		builder.WriteString(digits[i : i+3])
+	}
+
+	return builder.String()
+}
+
+// compact writes n (at least 1000) with one decimal place and a unit, dropping the decimal when it's zero.
+func (f NumberFormat) compact(n int64) string {
+	units := []string{"k", "M", "B", "T"}
+	divisor := int64(1000)
+	unit := 0
+
+	for unit < len(units)-1 && n >= divisor*1000 {
+		divisor *= 1000
+		unit++
+	}
+
+	// Round to a tenth, carrying into the next unit when that reaches 1000 (e.g. 999,960 -> 1M)
+	tenths := (n*10 + divisor/2) / divisor
+	if tenths >= 10000 && unit < len(units)-1 {
+		divisor *= 1000
+		unit++
+		tenths = (n*10 + divisor/2) / divisor
+	}
+
+	decimal := f.Decimal
+	if decimal == "" {
+		decimal = "."
+	}
+
+	result := strconv.FormatInt(tenths/10, 10)
+	if tenths%10 != 0 {
+		result += decimal + strconv.FormatInt(tenths%10, 10)
+	}
+
+	return result + units[unit]
+}
@@ -0,0 +1,43 @@
+package mon_test
+
+import (
+	"testing"
+
+	"github.com/cneill/mon/pkg/mon"
+)
+
+func TestNumberFormat_Format(t *testing.T) {
+	t.Parallel()
+
+	english := mon.NumberFormatForLocale("en_US.UTF-8", false)
+	german := mon.NumberFormatForLocale("de_DE.UTF-8", false)
+	swiss := mon.NumberFormatForLocale("de_CH", false)
+	compact := mon.NumberFormatForLocale("", true)
+	compactGerman := mon.NumberFormatForLocale("de_DE", true)
+
+	tests := []struct {
+		format   mon.NumberFormat
+		number   int64
+		expected string
+	}{
+		{mon.NumberFormat{}, 1234567, "1234567"},
+		{english, 999, "999"},
+		{english, 1000, "1,000"},
+		{english, 1234567, "1,234,567"},
+		{english, -12400, "-12,400"},
+		{german, 1234567, "1.234.567"},
+		{swiss, 1234567, "1'234'567"},
+		{compact, 999, "999"},
+		{compact, 1000, "1k"},
+		{compact, 12400, "12.4k"},
+		{compact, 999960, "1M"},
+		{compact, 3100000, "3.1M"},
+		{compactGerman, 12400, "12,4k"},
+	}
+
+	for _, test := range tests {
+		if actual := test.format.Format(test.number); actual != test.expected {
+			t.Errorf("expected %d to format as %q with %+v, got %q", test.number, test.expected, test.format, actual)
+		}
+	}
+}
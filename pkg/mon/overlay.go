@@ -0,0 +1,145 @@
+package mon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+)
+
+// overlayStatus is the subset of a status snapshot shown by the streaming overlay.
+type overlayStatus struct {
+	Duration        string `json:"duration"`
+	FilesCreated    int64  `json:"files_created"`
+	FilesDeleted    int64  `json:"files_deleted"`
+	LinesAdded      int64  `json:"lines_added"`
+	LinesDeleted    int64  `json:"lines_deleted"`
+	Commits         int64  `json:"commits"`
+	DepsAdded       int64  `json:"deps_added"`
+	DepsRemoved     int64  `json:"deps_removed"`
+	DepsUpdated     int64  `json:"deps_updated"`
+	WritesPerMinute int64  `json:"writes_per_minute"`
+	CommitsPerHour  int64  `json:"commits_per_hour"`
+	UnstagedChanges int64  `json:"unstaged_changes"`
+}
+
+// serveOverlay serves a page showing the live counters, meant to be captured as a browser source by streaming and
+// recording software, until ctx is done.
+func (m *Mon) serveOverlay(ctx context.Context, listener net.Listener) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+		if _, err := w.Write([]byte(overlayPage)); err != nil {
+			slog.Debug("failed to write overlay page", "error", err)
+		}
+	})
+	mux.HandleFunc("GET /status.json", func(w http.ResponseWriter, _ *http.Request) {
+		snapshot := m.GetStatusSnapshot(false, false)
+
+		status := overlayStatus{
+			Duration:        durationString(snapshot.Time.Sub(snapshot.StartTime)),
+			FilesCreated:    snapshot.NumFilesCreated,
+			FilesDeleted:    snapshot.NumFilesDeleted,
+			LinesAdded:      snapshot.LinesAdded,
+			LinesDeleted:    snapshot.LinesDeleted,
+			Commits:         snapshot.NumCommits,
+			DepsAdded:       snapshot.ListenerDiffs.NumNewDependencies(),
+			DepsRemoved:     snapshot.ListenerDiffs.NumDeletedDependencies(),
+			DepsUpdated:     snapshot.ListenerDiffs.NumUpdatedDependencies(),
+			WritesPerMinute: snapshot.WritesPerMinute,
+			CommitsPerHour:  snapshot.CommitsPerHour,
+			UnstagedChanges: snapshot.UnstagedChanges,
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+
+		if err := json.NewEncoder(w).Encode(status); err != nil {
+			slog.Debug("failed to write overlay status", "error", err)
+		}
+	})
+
+	server := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: time.Second * 5,
+	}
+
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+
+	slog.Info("Serving overlay", "addr", listener.Addr().String())
+
+	if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		slog.Error("overlay server failed", "error", err)
+		m.recordMonitorError("overlay", err)
+	}
+}
+
+// overlayPage polls status.json and shows the counters on a transparent background, so it can be layered over a
+// screen capture.
+const overlayPage = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>mon</title>
+<style>
+  body { margin: 0; background: transparent; font: bold 28px/1.4 ui-monospace, Menlo, Consolas, monospace; color: #fff; }
+  #mon { display: inline-flex; gap: 1.2em; padding: 0.4em 0.8em; background: rgba(0, 0, 0, 0.6); border-radius: 0.4em; }
+  .label { color: #aaa; }
+  .added { color: #0f0; }
+  .removed { color: #f33; }
+  .updated { color: #ff0; }
+  .detail { color: #1ab2ff; }
+  .stale { opacity: 0.4; }
+</style>
+</head>
+<body>
+<div id="mon">
+  <span><span class="label">time</span> <span class="detail" id="duration">-</span></span>
+  <span><span class="label">files</span> <span class="added" id="files_created">+0</span> <span class="removed" id="files_deleted">-0</span></span>
+  <span><span class="label">lines</span> <span class="added" id="lines_added">+0</span> <span class="removed" id="lines_deleted">-0</span></span>
+  <span><span class="label">commits</span> <span class="added" id="commits">0</span></span>
+  <span><span class="label">deps</span> <span class="added" id="deps_added">+0</span> <span class="removed" id="deps_removed">-0</span>
+    <span class="updated" id="deps_updated">~0</span></span>
+  <span><span class="label">rate</span> <span class="detail" id="writes_per_minute">0</span> w/m
+    <span class="detail" id="commits_per_hour">0</span> c/h</span>
+</div>
+<script>
+  const signs = {
+    files_created: "+", lines_added: "+", deps_added: "+",
+    files_deleted: "-", lines_deleted: "-", deps_removed: "-",
+    deps_updated: "~",
+  };
+
+  async function refresh() {
+    const mon = document.getElementById("mon");
+
+    try {
+      const response = await fetch("status.json", { cache: "no-store" });
+      const status = await response.json();
+
+      for (const [key, value] of Object.entries(status)) {
+        const element = document.getElementById(key);
+        if (element) {
+          element.textContent = (signs[key] || "") + value;
+        }
+      }
+
+      mon.classList.remove("stale");
+    } catch (err) {
+      mon.classList.add("stale"); // mon has exited
+    }
+  }
+
+  refresh();
+  setInterval(refresh, 1000);
+</script>
+</body>
+</html>
+`
@@ -0,0 +1,98 @@
+package mon
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/xitongsys/parquet-go/writer"
+)
+
+// parquetRow is a row of the events Parquet file. Every row carries the session and project, so files from many
+// sessions can be queried together, e.g. with DuckDB's read_parquet('dir/*.parquet').
+type parquetRow struct {
+	SessionID string `parquet:"name=session_id, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY"`
+	Project   string `parquet:"name=project, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY"`
+	Hostname  string `parquet:"name=hostname, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY"`
+	Time      int64  `parquet:"name=time, type=INT64, convertedtype=TIMESTAMP_MICROS"`
+	Source    string `parquet:"name=source, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY"`
+	Type      string `parquet:"name=type, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY"`
+	Path      string `parquet:"name=path, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY"`
+	Detail    string `parquet:"name=detail, type=BYTE_ARRAY, convertedtype=UTF8"`
+}
+
+// parquetLog writes the session's events to a Parquet file, for ExportOpts.EventsParquetDir. Unlike the events CSV
+// file, the file isn't readable until it's closed, since Parquet keeps its metadata in a footer.
+type parquetLog struct {
+	mutex   sync.Mutex
+	file    *os.File
+	writer  *writer.ParquetWriter
+	session SessionInfo
+}
+
+// openParquetLog creates a Parquet file in dir, creating dir if needed. The file is named after the session and its
+// start, so a resumed session gets a new file rather than replacing the one it wrote before.
+func openParquetLog(dir string, session SessionInfo, start time.Time) (*parquetLog, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create events Parquet directory %q: %w", dir, err)
+	}
+
+	name := fmt.Sprintf("%s-%s.parquet", session.ID, start.UTC().Format("20060102T150405"))
+
+	file, err := os.OpenFile(filepath.Join(dir, name), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create events Parquet file: %w", err)
+	}
+
+	parquetWriter, err := writer.NewParquetWriterFromWriter(file, new(parquetRow), 1)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to create events Parquet writer: %w", err)
+	}
+
+	return &parquetLog{
+		file:    file,
+		writer:  parquetWriter,
+		session: session,
+	}, nil
+}
+
+func (l *parquetLog) Record(event sessionEvent) {
+	row := parquetRow{
+		SessionID: l.session.ID,
+		Project:   l.session.ProjectDir,
+		Hostname:  l.session.Hostname,
+		Time:      event.Time.UnixMicro(),
+		Source:    event.Source,
+		Type:      event.Type,
+		Path:      event.Path,
+		Detail:    event.Detail,
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if err := l.writer.Write(row); err != nil {
+		slog.Error("failed to write to events Parquet file", "path", l.file.Name(), "error", err)
+	}
+}
+
+// Close writes the file's footer. A file that's never closed, e.g. because mon was killed, can't be read.
+func (l *parquetLog) Close() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if err := l.writer.WriteStop(); err != nil {
+		l.file.Close()
+		return fmt.Errorf("failed to finish events Parquet file: %w", err)
+	}
+
+	if err := l.file.Close(); err != nil {
+		return fmt.Errorf("failed to close events Parquet file: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,95 @@
+package mon //nolint:testpackage // exercises the unexported Parquet log
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/xitongsys/parquet-go/reader"
+	"github.com/xitongsys/parquet-go/source"
+)
+
+// parquetFile adapts an *os.File to the reader's source.ParquetFile. The reader opens the file again for each column,
+// with an empty name.
+type parquetFile struct {
+	*os.File
+}
+
+func (p parquetFile) Open(name string) (source.ParquetFile, error) {
+	if name == "" {
+		name = p.Name()
+	}
+
+	file, err := os.Open(name)
+	return parquetFile{file}, err //nolint:wrapcheck
+}
+
+func (p parquetFile) Create(name string) (source.ParquetFile, error) {
+	file, err := os.Create(name)
+	return parquetFile{file}, err //nolint:wrapcheck
+}
+
+func TestParquetLog(t *testing.T) {
+	t.Parallel()
+
+	dir := filepath.Join(t.TempDir(), "events")
+	session := SessionInfo{ID: "abc123", Hostname: "host", ProjectDir: "/src/project"}
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	log, err := openParquetLog(dir, session, start)
+	if err != nil {
+		t.Fatalf("failed to open Parquet log: %v", err)
+	}
+
+	events := []sessionEvent{
+		{Time: start.Add(time.Second), Source: "files", Type: "move", Path: "b.go", Detail: "a.go"},
+		{Time: start.Add(2 * time.Second), Source: "git", Type: "commit_create"},
+	}
+
+	for _, event := range events {
+		log.Record(event)
+	}
+
+	if err := log.Close(); err != nil {
+		t.Fatalf("failed to close Parquet log: %v", err)
+	}
+
+	// A resumed session with the same start can't clobber the file
+	if _, err := openParquetLog(dir, session, start); err == nil {
+		t.Errorf("expected an error reopening an existing Parquet file")
+	}
+
+	file, err := os.Open(filepath.Join(dir, "abc123-20250101T120000.parquet"))
+	if err != nil {
+		t.Fatalf("failed to open Parquet file: %v", err)
+	}
+	defer file.Close()
+
+	parquetReader, err := reader.NewParquetReader(parquetFile{file}, new(parquetRow), 1)
+	if err != nil {
+		t.Fatalf("failed to read Parquet file: %v", err)
+	}
+	defer parquetReader.ReadStop()
+
+	rows := make([]parquetRow, parquetReader.GetNumRows())
+	if err := parquetReader.Read(&rows); err != nil {
+		t.Fatalf("failed to read Parquet rows: %v", err)
+	}
+
+	expected := []parquetRow{
+		{
+			SessionID: "abc123", Project: "/src/project", Hostname: "host", Time: events[0].Time.UnixMicro(),
+			Source: "files", Type: "move", Path: "b.go", Detail: "a.go",
+		},
+		{
+			SessionID: "abc123", Project: "/src/project", Hostname: "host", Time: events[1].Time.UnixMicro(),
+			Source: "git", Type: "commit_create",
+		},
+	}
+
+	if !slices.Equal(rows, expected) {
+		t.Errorf("expected %+v, got %+v", expected, rows)
+	}
+}
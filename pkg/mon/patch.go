@@ -0,0 +1,61 @@
+package mon
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cneill/mon/pkg/git"
+	fdiff "github.com/go-git/go-git/v5/plumbing/format/diff"
+)
+
+// writePatches writes the changes committed during the session to path as a unified diff, and the changes left
+// uncommitted to the path from uncommittedPatchPath, so the whole session can be reviewed or applied elsewhere. With
+// several project directories, both files have every repo's changes, with paths prefixed by the directory's name.
+func (m *Mon) writePatches(path string) error {
+	if err := m.writePatch(path, (*git.Monitor).SessionPatch); err != nil {
+		return err
+	}
+
+	return m.writePatch(uncommittedPatchPath(path), (*git.Monitor).UncommittedPatch)
+}
+
+// writePatch writes the patch get returns for each repo to path.
+func (m *Mon) writePatch(path string, get func(*git.Monitor) (fdiff.Patch, error)) error {
+	output, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create patch file %q: %w", path, err)
+	}
+	defer output.Close()
+
+	for _, repo := range m.repos {
+		patch, err := get(repo.git)
+		if err != nil {
+			return fmt.Errorf("failed to get patch for %q: %w", repo.dir, err)
+		}
+
+		prefix := ""
+		if label := m.repoLabel(repo); label != "" {
+			prefix = label + "/"
+		}
+
+		if err := git.WritePatch(output, patch, prefix); err != nil {
+			return fmt.Errorf("failed to write patch to %q: %w", path, err)
+		}
+	}
+
+	if err := output.Close(); err != nil {
+		return fmt.Errorf("failed to close patch file %q: %w", path, err)
+	}
+
+	return nil
+}
+
+// uncommittedPatchPath is where the uncommitted changes are written next to the patch at path, e.g.
+// "session.uncommitted.patch" for "session.patch".
+func uncommittedPatchPath(path string) string {
+	ext := filepath.Ext(path)
+
+	return strings.TrimSuffix(path, ext) + ".uncommitted" + ext
+}
@@ -0,0 +1,59 @@
+package mon
+
+import (
+	"sync"
+	"time"
+)
+
+// rateCounter counts events within a rolling time window.
+type rateCounter struct {
+	mutex  sync.Mutex
+	window time.Duration
+	events []rateEvent
+	total  int64 // sum of the counts in events
+}
+
+// rateEvent is one or more events that happened at the same time.
+type rateEvent struct {
+	when  time.Time
+	count int64
+}
+
+func newRateCounter(window time.Duration) *rateCounter {
+	return &rateCounter{
+		window: window,
+		events: []rateEvent{},
+	}
+}
+
+// Add records an event that happened at the given time.
+func (r *rateCounter) Add(when time.Time) {
+	r.AddN(when, 1)
+}
+
+// AddN records n events that happened at the given time, like a burst of writes coalesced into one file event.
+func (r *rateCounter) AddN(when time.Time, n int64) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.events = append(r.events, rateEvent{when: when, count: n})
+	r.total += n
+}
+
+// Count returns the number of events within the window ending at now, discarding any older events.
+func (r *rateCounter) Count(now time.Time) int64 {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	cutoff := now.Add(-r.window)
+	expired := 0
+
+	for expired < len(r.events) && !r.events[expired].when.After(cutoff) {
+		r.total -= r.events[expired].count
+		expired++
+	}
+
+	r.events = r.events[expired:]
+
+	return r.total
+}
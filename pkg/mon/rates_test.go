@@ -0,0 +1,48 @@
+package mon //nolint:testpackage // exercises the unexported rate counter
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateCounter(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	counter := newRateCounter(time.Minute)
+
+	if count := counter.Count(start); count != 0 {
+		t.Errorf("expected no events before any were added, got %d", count)
+	}
+
+	counter.Add(start)
+	counter.AddN(start.Add(time.Second*30), 5)
+	counter.Add(start.Add(time.Second * 45))
+
+	tests := []struct {
+		now      time.Time
+		expected int64
+	}{
+		{start.Add(time.Second * 45), 7},
+		{start.Add(time.Minute - time.Nanosecond), 7},
+		// An event exactly one window old has expired
+		{start.Add(time.Minute), 6},
+		{start.Add(time.Second * 90), 1},
+		{start.Add(time.Second * 105), 0},
+		// Expired events are discarded, not just left out
+		{start, 0},
+	}
+
+	for _, test := range tests {
+		if count := counter.Count(test.now); count != test.expected {
+			t.Errorf("expected %d events in the minute before %s, got %d", test.expected, test.now.Format(time.TimeOnly),
+				count)
+		}
+	}
+
+	counter.AddN(start.Add(time.Minute*2), 3)
+
+	if count := counter.Count(start.Add(time.Minute * 2)); count != 3 {
+		t.Errorf("expected only the events added after the others expired, got %d", count)
+	}
+}
@@ -0,0 +1,68 @@
+package mon
+
+import (
+	"log/slog"
+	"time"
+)
+
+// sessionEvent is a file, git, or dependency event as the event exports record it.
+type sessionEvent struct {
+	Time   time.Time
+	Source string // "files", "git", or "deps"
+	Type   string
+	Path   string // relative to the project, if the event has one
+	Detail string // the old path of a move, or the dependency that changed
+}
+
+// eventRecorder writes events to one of the event exports as they happen.
+type eventRecorder interface {
+	Record(event sessionEvent)
+	Close() error
+}
+
+// recordEvent passes an event to every event export, redacted if ExportOpts.Redaction is set.
+func (m *Mon) recordEvent(event sessionEvent) {
+	if redaction := m.redaction(); redaction != nil {
+		event = redaction.event(event)
+	}
+
+	for _, recorder := range m.recorders {
+		recorder.Record(event)
+	}
+}
+
+func (m *Mon) closeRecorders() {
+	for _, recorder := range m.recorders {
+		if err := recorder.Close(); err != nil {
+			slog.Error("failed to close event export", "error", err)
+		}
+	}
+}
+
+// openRecorders opens the event exports set in ExportOpts.
+func (m *Mon) openRecorders() error {
+	if m.ExportOpts == nil {
+		return nil
+	}
+
+	if path := m.ExportOpts.EventsCSVPath; path != "" {
+		log, err := openEventLog(path)
+		if err != nil {
+			return err
+		}
+
+		m.recorders = append(m.recorders, log)
+	}
+
+	if dir := m.ExportOpts.EventsParquetDir; dir != "" {
+		log, err := openParquetLog(dir, m.session, m.startTime)
+		if err != nil {
+			m.closeRecorders()
+			return err
+		}
+
+		m.recorders = append(m.recorders, log)
+	}
+
+	return nil
+}
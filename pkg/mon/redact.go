@@ -0,0 +1,283 @@
+package mon
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"path/filepath"
+	"strings"
+
+	"github.com/cneill/mon/pkg/files"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// redactedText replaces commit messages and monitor errors in redacted exports.
+const redactedText = "[redacted]"
+
+// Redaction strips project details from exports, so session metrics can be shared without revealing what was worked
+// on. It applies to every file in ExportOpts except the patches, and not to the terminal display.
+type Redaction struct {
+	// HashPaths replaces file and project directory paths with a hash, keeping file extensions. The same path always
+	// hashes the same way with the same Salt, so files can still be followed across exports.
+	HashPaths bool `json:"hash_paths"`
+	// Salt is mixed into path hashes, so paths can't be recovered by hashing likely names.
+	Salt string `json:"salt"`
+	// StripCommitMessages replaces commit messages, keeping only a conventional commit type like "feat!".
+	StripCommitMessages bool `json:"strip_commit_messages"`
+	// StripAuthorEmails removes the author and committer email addresses from commits.
+	StripAuthorEmails bool `json:"strip_author_emails"`
+}
+
+// redaction returns ExportOpts.Redaction, or nil if it isn't set.
+func (m *Mon) redaction() *Redaction {
+	if m.ExportOpts == nil {
+		return nil
+	}
+
+	return m.ExportOpts.Redaction
+}
+
+// exportSnapshot returns the snapshot as the exports should see it, redacted if ExportOpts.Redaction is set.
+func (m *Mon) exportSnapshot(snapshot *StatusSnapshot) *StatusSnapshot {
+	if redaction := m.redaction(); redaction != nil {
+		return redaction.snapshot(snapshot)
+	}
+
+	return snapshot
+}
+
+// hashPath hashes a path relative to the project directories, keeping its extension.
+func (r *Redaction) hashPath(path string) string {
+	if path == "" {
+		return ""
+	}
+
+	sum := sha256.Sum256([]byte(r.Salt + filepath.ToSlash(path)))
+
+	return hex.EncodeToString(sum[:6]) + filepath.Ext(path)
+}
+
+// event redacts an event before it's recorded.
+func (r *Redaction) event(event sessionEvent) sessionEvent {
+	if !r.HashPaths {
+		return event
+	}
+
+	event.Path = r.hashPath(event.Path)
+
+	// The detail of a file event is the old path of a move, while for a dependency event it's the public name
+	if event.Source == "files" {
+		event.Detail = r.hashPath(event.Detail)
+	}
+
+	return event
+}
+
+// snapshot returns a redacted copy of a snapshot for the exports. The original is left alone, so it can still be
+// displayed.
+func (r *Redaction) snapshot(snapshot *StatusSnapshot) *StatusSnapshot {
+	result := *snapshot
+
+	if r.HashPaths {
+		r.hashSnapshotPaths(&result)
+	}
+
+	if r.StripCommitMessages || r.StripAuthorEmails {
+		result.Commits = make([]*object.Commit, 0, len(snapshot.Commits))
+
+		for _, commit := range snapshot.Commits {
+			result.Commits = append(result.Commits, r.commit(commit))
+		}
+	}
+
+	return &result
+}
+
+func (r *Redaction) hashSnapshotPaths(snapshot *StatusSnapshot) {
+	r.hashFilePaths(snapshot)
+	r.hashDirectoryPaths(snapshot)
+	redactOutput(snapshot)
+
+	// Project directories are hashed last, since the file paths above are made relative to them first
+	r.hashProjectDirs(snapshot)
+}
+
+// hashFile hashes the path of a file in the project, relative to its project directory.
+func (r *Redaction) hashFile(snapshot *StatusSnapshot, path string) string {
+	return r.hashPath(snapshot.relPath(path))
+}
+
+// hashFiles hashes a list of file paths.
+func (r *Redaction) hashFiles(snapshot *StatusSnapshot, paths []string) []string {
+	results := make([]string, 0, len(paths))
+	for _, path := range paths {
+		results = append(results, r.hashFile(snapshot, path))
+	}
+
+	return results
+}
+
+// hashCounts hashes the file paths that counts are keyed by.
+func (r *Redaction) hashCounts(snapshot *StatusSnapshot, counts map[string]int64) map[string]int64 {
+	results := make(map[string]int64, len(counts))
+	for path, count := range counts {
+		results[r.hashFile(snapshot, path)] = count
+	}
+
+	return results
+}
+
+// hashFilePaths hashes the paths in the lists of files.
+func (r *Redaction) hashFilePaths(snapshot *StatusSnapshot) {
+	snapshot.NewFiles = r.hashFiles(snapshot, snapshot.NewFiles)
+	snapshot.DeletedFiles = r.hashFiles(snapshot, snapshot.DeletedFiles)
+	snapshot.WrittenFiles = r.hashCounts(snapshot, snapshot.WrittenFiles)
+	snapshot.RawWrittenFiles = r.hashCounts(snapshot, snapshot.RawWrittenFiles)
+
+	if snapshot.Generated != nil {
+		generated := *snapshot.Generated
+		generated.Files = r.hashFiles(snapshot, generated.Files)
+		snapshot.Generated = &generated
+	}
+
+	if snapshot.Binary != nil {
+		binary := *snapshot.Binary
+		binary.Files = r.hashFiles(snapshot, binary.Files)
+		snapshot.Binary = &binary
+	}
+
+	if snapshot.Todos != nil {
+		todos := *snapshot.Todos
+		todos.Files = r.hashCounts(snapshot, todos.Files)
+		snapshot.Todos = &todos
+	}
+
+	if snapshot.TopNewFiles != nil {
+		topNewFiles := make([]files.FileSize, 0, len(snapshot.TopNewFiles))
+		for _, file := range snapshot.TopNewFiles {
+			topNewFiles = append(topNewFiles, files.FileSize{Path: r.hashFile(snapshot, file.Path), Size: file.Size})
+		}
+
+		snapshot.TopNewFiles = topNewFiles
+	}
+
+	if snapshot.ChangedPermissions != nil {
+		changedPermissions := make(map[string]string, len(snapshot.ChangedPermissions))
+		for path, mode := range snapshot.ChangedPermissions {
+			changedPermissions[r.hashFile(snapshot, path)] = mode
+		}
+
+		snapshot.ChangedPermissions = changedPermissions
+	}
+}
+
+// hashDirectoryPaths hashes the paths in the per-directory stats and the unstaged files, which are already relative.
+func (r *Redaction) hashDirectoryPaths(snapshot *StatusSnapshot) {
+	// "." says nothing about the project
+	byDirectory := make(map[string]files.DirectoryStats, len(snapshot.ByDirectory))
+	for dir, stats := range snapshot.ByDirectory {
+		if dir != "." {
+			dir = r.hashPath(dir)
+		}
+
+		byDirectory[dir] = stats
+	}
+
+	snapshot.ByDirectory = byDirectory
+
+	unstagedFiles := make([]string, 0, len(snapshot.UnstagedFiles))
+	for _, path := range snapshot.UnstagedFiles {
+		unstagedFiles = append(unstagedFiles, r.hashPath(path))
+	}
+
+	snapshot.UnstagedFiles = unstagedFiles
+}
+
+// hashProjectDirs hashes the project directories, and the labels made from their names.
+func (r *Redaction) hashProjectDirs(snapshot *StatusSnapshot) {
+	snapshot.Session.ProjectDir = r.hashPath(snapshot.Session.ProjectDir)
+
+	if len(snapshot.Session.ProjectDirs) > 0 {
+		projectDirs := make([]string, 0, len(snapshot.Session.ProjectDirs))
+		for _, dir := range snapshot.Session.ProjectDirs {
+			projectDirs = append(projectDirs, r.hashPath(dir))
+		}
+
+		snapshot.Session.ProjectDirs = projectDirs
+	}
+
+	snapshot.Roots = append([]RootStats{}, snapshot.Roots...)
+	for idx := range snapshot.Roots {
+		snapshot.Roots[idx].Dir = r.hashPath(snapshot.Roots[idx].Dir)
+	}
+
+	// Refs are labeled with their project directory's name when there's more than one
+	refs := make([]string, 0, len(snapshot.SnapshotRefs))
+	for _, ref := range snapshot.SnapshotRefs {
+		if label, name, ok := strings.Cut(ref, ": "); ok {
+			ref = r.hashPath(label) + ": " + name
+		}
+
+		refs = append(refs, ref)
+	}
+
+	snapshot.SnapshotRefs = refs
+}
+
+// redactOutput strips the output of failed checks and the details of monitor errors, which are full of paths.
+func redactOutput(snapshot *StatusSnapshot) {
+	if snapshot.Checks != nil {
+		checks := make([]CommitCheck, 0, len(snapshot.Checks))
+		for _, check := range snapshot.Checks {
+			if check.Output != "" {
+				check.Output = redactedText
+			}
+
+			checks = append(checks, check)
+		}
+
+		snapshot.Checks = checks
+	}
+
+	// Only the source of each error is kept
+	monitorErrors := make([]string, 0, len(snapshot.MonitorErrors))
+	for _, message := range snapshot.MonitorErrors {
+		source, _, _ := strings.Cut(message, ": ")
+		monitorErrors = append(monitorErrors, source+": "+redactedText)
+	}
+
+	snapshot.MonitorErrors = monitorErrors
+}
+
+// commit returns a redacted copy of a commit.
+func (r *Redaction) commit(commit *object.Commit) *object.Commit {
+	result := *commit
+
+	if r.StripCommitMessages {
+		result.Message = redactMessage(commit.Message)
+	}
+
+	if r.StripAuthorEmails {
+		result.Author.Email = ""
+		result.Committer.Email = ""
+	}
+
+	return &result
+}
+
+// redactMessage replaces a commit message, keeping the type and breaking change marker of a conventional commit so
+// changelogs are still grouped.
+func redactMessage(message string) string {
+	subject, body, _ := strings.Cut(strings.TrimSpace(message), "\n")
+
+	matches := conventionalCommitRegex.FindStringSubmatch(strings.TrimSpace(subject))
+	if matches == nil {
+		return redactedText
+	}
+
+	breaking := matches[2]
+	if strings.Contains(body, "BREAKING CHANGE") {
+		breaking = "!"
+	}
+
+	return matches[1] + breaking + ": " + redactedText
+}
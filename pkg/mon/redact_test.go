@@ -0,0 +1,80 @@
+package mon //nolint:testpackage // exercises the unexported snapshot redaction
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+func TestRedaction_Snapshot(t *testing.T) {
+	t.Parallel()
+
+	root := filepath.FromSlash("/work/secret-project")
+	mainFile := filepath.Join(root, "cmd", "main.go")
+
+	snapshot := &StatusSnapshot{
+		Session:       SessionInfo{ProjectDir: root},
+		NewFiles:      []string{mainFile},
+		WrittenFiles:  map[string]int64{mainFile: 3},
+		UnstagedFiles: []string{filepath.Join("cmd", "main.go")},
+		Commits: []*object.Commit{
+			{
+				Hash:    plumbing.NewHash("0123456789abcdef0123456789abcdef01234567"),
+				Author:  object.Signature{Name: "Dev", Email: "dev@example.com"},
+				Message: "feat(billing): charge the secret customer\n\nBREAKING CHANGE: prices went up",
+			},
+			{
+				Hash:    plumbing.NewHash("89abcdef0123456789abcdef0123456789abcdef"),
+				Message: "Fix the secret thing",
+			},
+		},
+		MonitorErrors: []string{"files: failed to watch " + mainFile},
+	}
+
+	redaction := &Redaction{HashPaths: true, Salt: "pepper", StripCommitMessages: true, StripAuthorEmails: true}
+	redacted := redaction.snapshot(snapshot)
+
+	hashed := redacted.NewFiles[0]
+	if strings.Contains(hashed, "main") || filepath.Ext(hashed) != ".go" {
+		t.Errorf("expected a hashed path keeping its extension, got %q", hashed)
+	}
+
+	if redacted.WrittenFiles[hashed] != 3 || !slices.Equal(redacted.UnstagedFiles, []string{hashed}) {
+		t.Errorf("expected the same file to hash the same everywhere, got %v and %v", redacted.WrittenFiles,
+			redacted.UnstagedFiles)
+	}
+
+	if (&Redaction{HashPaths: true}).hashPath(filepath.Join("cmd", "main.go")) == hashed {
+		t.Error("expected the salt to change the hash")
+	}
+
+	if strings.Contains(redacted.Session.ProjectDir, "secret") {
+		t.Errorf("expected the project directory to be hashed, got %q", redacted.Session.ProjectDir)
+	}
+
+	if want := []string{"files: " + redactedText}; !slices.Equal(redacted.MonitorErrors, want) {
+		t.Errorf("got monitor errors %v, expected %v", redacted.MonitorErrors, want)
+	}
+
+	messages := []string{redacted.Commits[0].Message, redacted.Commits[1].Message}
+	if want := []string{"feat!: " + redactedText, redactedText}; !slices.Equal(messages, want) {
+		t.Errorf("got commit messages %q, expected %q", messages, want)
+	}
+
+	if email := redacted.Commits[0].Author.Email; email != "" {
+		t.Errorf("expected the author email to be stripped, got %q", email)
+	}
+
+	// The original is still displayed as it was
+	if snapshot.NewFiles[0] != mainFile || snapshot.Commits[0].Author.Email != "dev@example.com" {
+		t.Error("expected the original snapshot to be left alone")
+	}
+
+	if changelog := redacted.Changelog(); strings.Contains(changelog, "secret") {
+		t.Errorf("expected nothing secret in the changelog, got:\n%s", changelog)
+	}
+}
@@ -0,0 +1,225 @@
+package mon
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/fatih/color"
+	"github.com/mattn/go-isatty"
+)
+
+// RemoteOpts configures watching a mon session that runs on another machine over SSH.
+type RemoteOpts struct {
+	// Host is the SSH destination, e.g. "user@devbox" or an alias from ~/.ssh/config.
+	Host string
+	// ProjectDir is the project directory on the remote machine.
+	ProjectDir string
+	// SSHCommand is the local ssh client to run. Defaults to "ssh".
+	SSHCommand string
+	// RemoteCommand is how mon is invoked on the remote machine. Defaults to "mon".
+	RemoteCommand string
+
+	DetailsOpts *DetailsOpts
+}
+
+func (o *RemoteOpts) OK() error {
+	if o.Host == "" {
+		return fmt.Errorf("must supply remote host")
+	}
+
+	if o.ProjectDir == "" {
+		return fmt.Errorf("must supply remote project dir")
+	}
+
+	if o.DetailsOpts == nil {
+		return fmt.Errorf("must supply details options")
+	}
+
+	return nil
+}
+
+// ParseRemoteTarget splits an scp-style target like "user@devbox:/src/project" into its SSH destination and remote
+// project directory.
+func ParseRemoteTarget(target string) (host, dir string, err error) { //nolint:nonamedreturns
+	host, dir, found := strings.Cut(target, ":")
+	if !found || host == "" || dir == "" {
+		return "", "", fmt.Errorf("remote target %q must look like [user@]host:/path/to/project", target)
+	}
+
+	return host, dir, nil
+}
+
+// maxRemoteLine is the longest status snapshot accepted from the remote mon.
+const maxRemoteLine = 16 * 1024 * 1024
+
+// RunRemote runs a headless mon on a remote machine over SSH and shows its status locally, as if it were running
+// here. The remote mon gets a pseudo-terminal so that interrupting the local one (Ctrl-C) ends the remote session
+// cleanly, and its final report is shown once it exits. Dependency changes aren't part of the remote snapshots, so
+// they're left out of the local display.
+func RunRemote(ctx context.Context, opts *RemoteOpts) error {
+	if err := opts.OK(); err != nil {
+		return fmt.Errorf("failed to configure remote session: %w", err)
+	}
+
+	sshCommand := opts.SSHCommand
+	if sshCommand == "" {
+		sshCommand = "ssh"
+	}
+
+	remoteCommand := opts.RemoteCommand
+	if remoteCommand == "" {
+		remoteCommand = "mon"
+	}
+
+	// ssh joins its arguments into a single command for the remote shell, so the directory has to be quoted
+	cmd := exec.CommandContext(ctx, sshCommand, "-tt", opts.Host, "--",
+		remoteCommand, "--display", string(DisplayModeNDJSON), shellQuote(opts.ProjectDir))
+	cmd.Stderr = os.Stderr
+
+	// With a terminal on stdin, ssh puts it in raw mode and passes Ctrl-C through to the remote pseudo-terminal by
+	// itself. Otherwise, ssh gets its own process group, so that only mon is interrupted, and passes the interrupt on.
+	interactive := stdinIsTerminal()
+
+	var stdin io.Writer
+
+	if interactive {
+		cmd.Stdin = os.Stdin
+	} else {
+		separateProcessGroup(cmd)
+
+		pipe, err := cmd.StdinPipe()
+		if err != nil {
+			return fmt.Errorf("failed to set up ssh input: %w", err)
+		}
+
+		stdin = pipe
+	}
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("failed to set up ssh output: %w", err)
+	}
+
+	terminal := stdoutIsTerminal()
+	if !terminal || !terminalSupportsANSI() {
+		color.NoColor = true
+	}
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start ssh: %w", err)
+	}
+
+	if !interactive {
+		defer passInput(stdin)()
+	}
+
+	final := renderRemote(stdout, opts.DetailsOpts, terminal)
+
+	// Off a terminal, the remote's final snapshot was already passed through as JSON
+	if final != nil && terminal {
+		fmt.Println(final.Final())
+	}
+
+	if err := cmd.Wait(); err != nil {
+		return fmt.Errorf("remote session on %s failed: %w", opts.Host, err)
+	}
+
+	return nil
+}
+
+// stdinIsTerminal reports whether mon's input is a terminal that ssh can read from directly.
+func stdinIsTerminal() bool {
+	fd := os.Stdin.Fd()
+
+	return isatty.IsTerminal(fd) || isatty.IsCygwinTerminal(fd)
+}
+
+// passInput copies mon's input to ssh, and turns a local interrupt into one on the remote pseudo-terminal. It returns
+// a function that stops catching interrupts.
+func passInput(stdin io.Writer) func() {
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt)
+
+	go func() {
+		for range sigChan {
+			_, _ = stdin.Write([]byte{0x03})
+		}
+	}()
+
+	go func() {
+		_, _ = io.Copy(stdin, os.Stdin)
+	}()
+
+	return func() { signal.Stop(sigChan) }
+}
+
+// renderRemote reads status snapshots from the remote mon until it exits, and returns the last one. On a terminal,
+// each is drawn as the status line; otherwise they're passed through as NDJSON. Lines that aren't snapshots, like
+// error messages, are always passed through.
+func renderRemote(reader io.Reader, details *DetailsOpts, terminal bool) *StatusSnapshot {
+	var (
+		last  *StatusSnapshot
+		ansi  = terminalSupportsANSI()
+		width int // of the status line on screen, or 0 if there isn't one
+	)
+
+	clearLine := func() string {
+		switch {
+		case width == 0:
+			return ""
+		case ansi:
+			return ansiClearLine
+		default:
+			// Colors are disabled without ANSI support, so the line's length is its width on screen
+			return "\r" + strings.Repeat(" ", width) + "\r"
+		}
+	}
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxRemoteLine)
+
+	for scanner.Scan() {
+		// The remote pseudo-terminal turns line endings into \r\n
+		line := strings.TrimRight(scanner.Text(), "\r")
+
+		snapshot := &StatusSnapshot{}
+		if !strings.HasPrefix(line, "{") || json.Unmarshal([]byte(line), snapshot) != nil {
+			fmt.Println(clearLine() + line)
+			width = 0
+
+			continue
+		}
+
+		snapshot.DetailsOpts = details
+		last = snapshot
+
+		if !terminal {
+			fmt.Println(line)
+			continue
+		}
+
+		live := snapshot.Live()
+		fmt.Print(clearLine() + live)
+
+		width = max(1, utf8.RuneCountInString(live))
+	}
+
+	if terminal && width > 0 {
+		fmt.Println()
+	}
+
+	return last
+}
+
+// shellQuote quotes a string for a POSIX shell.
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
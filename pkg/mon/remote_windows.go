@@ -0,0 +1,13 @@
+//go:build windows
+
+package mon
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// separateProcessGroup starts a command in its own process group, so a Ctrl-C in the console doesn't reach it.
+func separateProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{CreationFlags: syscall.CREATE_NEW_PROCESS_GROUP}
+}
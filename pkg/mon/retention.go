@@ -0,0 +1,123 @@
+package mon
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// RetentionPolicy limits the interrupted sessions kept in the checkpoint directory for "mon resume". Checkpoints are
+// removed when a session ends cleanly, so these are sessions that crashed or were killed and were never resumed. Zero
+// values don't limit anything.
+type RetentionPolicy struct {
+	MaxSessions int           // most checkpoints kept
+	MaxAge      time.Duration // checkpoints not written to for longer than this are removed
+	MaxBytes    int64         // most bytes kept across all checkpoints
+}
+
+// DefaultRetentionPolicy keeps at most 50 interrupted sessions from the last 30 days, in up to 100 MiB.
+func DefaultRetentionPolicy() RetentionPolicy {
+	return RetentionPolicy{
+		MaxSessions: 50,
+		MaxAge:      time.Hour * 24 * 30,
+		MaxBytes:    100 << 20,
+	}
+}
+
+func (r RetentionPolicy) OK() error {
+	switch {
+	case r.MaxSessions < 0:
+		return fmt.Errorf("max sessions must not be negative")
+	case r.MaxAge < 0:
+		return fmt.Errorf("max age must not be negative")
+	case r.MaxBytes < 0:
+		return fmt.Errorf("max size must not be negative")
+	}
+
+	return nil
+}
+
+// PruneSessions removes the checkpoints in dir that are over the policy's limits, keeping the most recently written
+// ones. The checkpoint at keep, if any, is never removed, though it counts against the limits. It returns the paths of
+// the removed checkpoints. A missing dir has nothing to prune.
+func PruneSessions(dir string, policy RetentionPolicy, keep string, now time.Time) ([]string, error) {
+	if err := policy.OK(); err != nil {
+		return nil, fmt.Errorf("invalid retention policy: %w", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to list sessions directory: %w", err)
+	}
+
+	type savedSession struct {
+		path  string
+		size  int64
+		mtime time.Time
+	}
+
+	sessions := []savedSession{}
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			continue // removed since it was listed
+		}
+
+		sessions = append(sessions, savedSession{
+			path:  filepath.Join(dir, entry.Name()),
+			size:  info.Size(),
+			mtime: info.ModTime(),
+		})
+	}
+
+	// Newest first, with the one to keep ahead of everything
+	slices.SortFunc(sessions, func(a, b savedSession) int {
+		switch {
+		case a.path == keep:
+			return -1
+		case b.path == keep:
+			return 1
+		}
+
+		return b.mtime.Compare(a.mtime)
+	})
+
+	var (
+		removed   []string
+		kept      int
+		keptBytes int64
+	)
+
+	for _, session := range sessions {
+		expired := policy.MaxAge > 0 && now.Sub(session.mtime) > policy.MaxAge
+		tooMany := policy.MaxSessions > 0 && kept >= policy.MaxSessions
+		tooBig := policy.MaxBytes > 0 && keptBytes+session.size > policy.MaxBytes
+
+		if session.path == keep || !(expired || tooMany || tooBig) {
+			kept++
+			keptBytes += session.size
+
+			continue
+		}
+
+		if err := os.Remove(session.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return removed, fmt.Errorf("failed to remove saved session %q: %w", session.path, err)
+		}
+
+		removed = append(removed, session.path)
+	}
+
+	return removed, nil
+}
@@ -0,0 +1,104 @@
+package mon_test
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cneill/mon/pkg/mon"
+)
+
+func TestPruneSessions(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	// Saved sessions by name, with how long ago they were last written
+	saved := map[string]time.Duration{
+		"a.json": time.Hour,
+		"b.json": time.Hour * 2,
+		"c.json": time.Hour * 3,
+		"d.json": time.Hour * 24 * 10,
+	}
+
+	tests := []struct {
+		name    string
+		policy  mon.RetentionPolicy
+		keep    string
+		removed []string
+	}{
+		{name: "no limits", policy: mon.RetentionPolicy{}},
+		{name: "max age", policy: mon.RetentionPolicy{MaxAge: time.Hour * 24}, removed: []string{"d.json"}},
+		{name: "max sessions", policy: mon.RetentionPolicy{MaxSessions: 2}, removed: []string{"c.json", "d.json"}},
+		{name: "max bytes", policy: mon.RetentionPolicy{MaxBytes: 250}, removed: []string{"c.json", "d.json"}},
+		{
+			name:    "keep",
+			policy:  mon.RetentionPolicy{MaxSessions: 2, MaxAge: time.Hour * 24},
+			keep:    "d.json",
+			removed: []string{"b.json", "c.json"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+
+			for name, age := range saved {
+				path := filepath.Join(dir, name)
+				if err := os.WriteFile(path, []byte(strings.Repeat("x", 100)), 0o600); err != nil {
+					t.Fatal(err)
+				}
+
+				if err := os.Chtimes(path, now.Add(-age), now.Add(-age)); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			// Not a saved session, whatever its age
+			if err := os.WriteFile(filepath.Join(dir, "notes.txt"), nil, 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			keep := ""
+			if test.keep != "" {
+				keep = filepath.Join(dir, test.keep)
+			}
+
+			removed, err := mon.PruneSessions(dir, test.policy, keep, now)
+			if err != nil {
+				t.Fatalf("failed to prune: %v", err)
+			}
+
+			names := []string{}
+			for _, path := range removed {
+				names = append(names, filepath.Base(path))
+			}
+
+			slices.Sort(names)
+
+			if !slices.Equal(names, test.removed) {
+				t.Errorf("removed %v, expected %v", names, test.removed)
+			}
+
+			for _, name := range names {
+				if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+					t.Errorf("%s still exists", name)
+				}
+			}
+		})
+	}
+}
+
+func TestPruneSessions_MissingDir(t *testing.T) {
+	t.Parallel()
+
+	removed, err := mon.PruneSessions(filepath.Join(t.TempDir(), "missing"), mon.DefaultRetentionPolicy(), "", time.Now())
+	if err != nil || len(removed) > 0 {
+		t.Errorf("got %v, %v, expected nothing", removed, err)
+	}
+}
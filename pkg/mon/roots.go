@@ -0,0 +1,155 @@
+package mon
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+
+	"github.com/cneill/mon/pkg/files"
+	"github.com/cneill/mon/pkg/git"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// repo is one of the session's project directories, with the git monitor following its repository.
+type repo struct {
+	dir string
+	git *git.Monitor
+}
+
+// RootStats is one project directory's share of a session that watches several.
+type RootStats struct {
+	Dir string `json:"dir"`
+	files.RootStats
+
+	NumCommits   int64 `json:"num_commits"`
+	LinesAdded   int64 `json:"lines_added"`
+	LinesDeleted int64 `json:"lines_deleted"`
+}
+
+// projectDirs returns ProjectDir followed by ProjectDirs.
+func (o *Opts) projectDirs() []string {
+	return append([]string{o.ProjectDir}, o.ProjectDirs...)
+}
+
+// newRepos sets up a git monitor for each project directory, starting from the commits saved in the checkpoint when
+// resuming a session.
+func newRepos(opts *Opts, resumed *checkpoint) ([]*repo, error) {
+	results := []*repo{}
+
+	for _, dir := range opts.projectDirs() {
+		gitOpts := &git.MonitorOpts{
+			RootPath:     dir,
+			PollInterval: opts.PollInterval,
+			Clock:        opts.Clock,
+		}
+
+		if resumed != nil {
+			gitOpts.InitialHash = resumed.initialHash(dir)
+		}
+
+		monitor, err := git.NewMonitor(gitOpts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to set up git monitor for %q: %w", dir, err)
+		}
+
+		results = append(results, &repo{dir: dir, git: monitor})
+	}
+
+	return results, nil
+}
+
+// repoFor returns the repo of the project directory that path is in, or nil if it's outside all of them.
+func (m *Mon) repoFor(path string) *repo {
+	root := files.RootOf(m.projectDirs(), path)
+
+	for _, repo := range m.repos {
+		if repo.dir == root {
+			return repo
+		}
+	}
+
+	return nil
+}
+
+// repoLabel is the name a repo's git events and refs are reported under: empty with a single project directory, and
+// the directory's name otherwise, as in files.RelPathIn.
+func (m *Mon) repoLabel(repo *repo) string {
+	if len(m.repos) == 1 {
+		return ""
+	}
+
+	return filepath.Base(repo.dir)
+}
+
+func (m *Mon) closeRepos() {
+	for _, repo := range m.repos {
+		repo.git.Close()
+	}
+}
+
+// addGitStats totals the git stats of every repo into the snapshot. With several project directories, paths are
+// prefixed with their directory's name like file paths are, and final snapshots get per-directory stats in Roots.
+func (m *Mon) addGitStats(snapshot *StatusSnapshot, fileStats *files.Stats, final bool) {
+	for _, repo := range m.repos {
+		stats := repo.git.Stats(final)
+		label := m.repoLabel(repo)
+
+		slices.Reverse(stats.Commits)
+		slices.Reverse(stats.CommitSizes)
+
+		snapshot.NumCommits += stats.NumCommits
+		snapshot.LinesAdded += stats.LinesAdded
+		snapshot.LinesDeleted += stats.LinesDeleted
+		snapshot.UnstagedChanges += stats.UnstagedChanges
+		snapshot.Commits = append(snapshot.Commits, stats.Commits...)
+		snapshot.CommitSizes = append(snapshot.CommitSizes, stats.CommitSizes...)
+		snapshot.Health.GitEventsDropped += stats.EventsDropped
+
+		if label == "" {
+			snapshot.UnstagedFiles = stats.UnstagedFiles
+		} else {
+			for _, file := range stats.UnstagedFiles {
+				snapshot.UnstagedFiles = append(snapshot.UnstagedFiles, filepath.Join(label, file))
+			}
+		}
+
+		if stats.Patch != nil {
+			for _, fileStat := range stats.Patch.Stats() {
+				fileStat.Name = filepath.Join(label, fileStat.Name)
+				snapshot.PatchStats = append(snapshot.PatchStats, fileStat)
+			}
+		}
+
+		if final && label != "" {
+			snapshot.Roots = append(snapshot.Roots, RootStats{
+				Dir:          repo.dir,
+				RootStats:    fileStats.ByRoot[repo.dir],
+				NumCommits:   stats.NumCommits,
+				LinesAdded:   stats.LinesAdded,
+				LinesDeleted: stats.LinesDeleted,
+			})
+		}
+	}
+
+	if len(m.repos) > 1 {
+		sortCommits(snapshot.Commits, snapshot.CommitSizes)
+	}
+}
+
+// sortCommits sorts commits from several repos oldest first, keeping sizes in the same order.
+func sortCommits(commits []*object.Commit, sizes []git.CommitSize) {
+	byHash := map[string]git.CommitSize{}
+	for _, size := range sizes {
+		byHash[size.Hash] = size
+	}
+
+	slices.SortStableFunc(commits, func(a, b *object.Commit) int {
+		return a.Committer.When.Compare(b.Committer.When)
+	})
+
+	for idx, commit := range commits {
+		if idx < len(sizes) {
+			sizes[idx] = byHash[commit.Hash.String()]
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package mon
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// SessionInfo identifies a single run of mon, so data exported from the same run can be correlated downstream.
+type SessionInfo struct {
+	ID         string   `json:"id"`
+	Hostname   string   `json:"hostname"`
+	ProjectDir string   `json:"project_dir"`
+	Version    string   `json:"version"`
+	Agents     []string `json:"agents"`
+
+	// ProjectDirs lists every project directory, starting with ProjectDir, if the session watches more than one.
+	ProjectDirs []string `json:"project_dirs,omitempty"`
+}
+
+// agentMarkers maps files or directories that coding agents keep in a project to the agent that uses them.
+//
+//nolint:gochecknoglobals
+var agentMarkers = []struct {
+	Path  string
+	Agent string
+}{
+	{Path: "CLAUDE.md", Agent: "claude"},
+	{Path: ".claude", Agent: "claude"},
+	{Path: "AGENTS.md", Agent: "codex"},
+	{Path: ".codex", Agent: "codex"},
+	{Path: "GEMINI.md", Agent: "gemini"},
+	{Path: ".gemini", Agent: "gemini"},
+	{Path: ".cursor", Agent: "cursor"},
+	{Path: ".cursorrules", Agent: "cursor"},
+	{Path: ".aider.conf.yml", Agent: "aider"},
+	{Path: ".github/copilot-instructions.md", Agent: "copilot"},
+}
+
+func newSessionInfo(projectDirs []string, version string) SessionInfo {
+	hostname, err := os.Hostname()
+	if err != nil {
+		slog.Error("Failed to determine hostname", "error", err)
+	}
+
+	info := SessionInfo{
+		ID:         newSessionID(),
+		Hostname:   hostname,
+		ProjectDir: projectDirs[0],
+		Version:    version,
+		Agents:     []string{},
+	}
+
+	if len(projectDirs) > 1 {
+		info.ProjectDirs = projectDirs
+	}
+
+	for _, dir := range projectDirs {
+		for _, agent := range detectAgents(dir) {
+			if !slices.Contains(info.Agents, agent) {
+				info.Agents = append(info.Agents, agent)
+			}
+		}
+	}
+
+	return info
+}
+
+// projectDirs returns every project directory of the session.
+func (s SessionInfo) projectDirs() []string {
+	if len(s.ProjectDirs) > 0 {
+		return s.ProjectDirs
+	}
+
+	return []string{s.ProjectDir}
+}
+
+// newSessionID returns a random 128-bit hex session ID.
+func newSessionID() string {
+	var id [16]byte
+
+	_, _ = rand.Read(id[:]) // crypto/rand.Read never returns an error
+
+	return hex.EncodeToString(id[:])
+}
+
+// detectAgents guesses which coding agents work in the project from the instruction and config files they leave
+// behind.
+func detectAgents(projectDir string) []string {
+	results := []string{}
+
+	for _, marker := range agentMarkers {
+		if _, err := os.Stat(filepath.Join(projectDir, filepath.FromSlash(marker.Path))); err != nil {
+			continue
+		}
+
+		if !slices.Contains(results, marker.Agent) {
+			results = append(results, marker.Agent)
+		}
+	}
+
+	return results
+}
+
+// AgentsString returns the detected agents as a comma-separated list, or "none" if there aren't any.
+func (s SessionInfo) AgentsString() string {
+	if len(s.Agents) == 0 {
+		return "none"
+	}
+
+	return strings.Join(s.Agents, ", ")
+}
@@ -0,0 +1,13 @@
+//go:build windows
+
+package mon
+
+import (
+	"context"
+	"os/exec"
+)
+
+// shellCommand runs a command line with the system shell.
+func shellCommand(ctx context.Context, command string) *exec.Cmd {
+	return exec.CommandContext(ctx, "cmd", "/C", command)
+}
@@ -0,0 +1,18 @@
+//go:build !windows
+
+package mon
+
+import (
+	"os"
+	"syscall"
+)
+
+// snapshotSignals returns the signals that trigger an intermediate session report.
+func snapshotSignals() []os.Signal {
+	return []os.Signal{syscall.SIGUSR2}
+}
+
+// pauseSignals returns the signals that pause counting file changes, or resume it if it's paused.
+func pauseSignals() []os.Signal {
+	return []os.Signal{syscall.SIGUSR1}
+}
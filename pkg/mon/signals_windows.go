@@ -0,0 +1,16 @@
+//go:build windows
+
+package mon
+
+import "os"
+
+// snapshotSignals returns the signals that trigger an intermediate session report. Windows has no SIGUSR2.
+func snapshotSignals() []os.Signal {
+	return nil
+}
+
+// pauseSignals returns the signals that pause counting file changes, or resume it if it's paused. Windows has no
+// SIGUSR1.
+func pauseSignals() []os.Signal {
+	return nil
+}
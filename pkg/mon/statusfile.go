@@ -0,0 +1,53 @@
+package mon
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+)
+
+// statusFileLoop appends a status snapshot to ExportOpts.StatusPath every ExportOpts.StatusInterval, so other tools
+// (e.g. a streaming overlay) can follow the session while the terminal display runs.
+func (m *Mon) statusFileLoop(ctx context.Context) {
+	if m.ExportOpts == nil || m.ExportOpts.StatusPath == "" || m.ExportOpts.StatusInterval <= 0 {
+		return
+	}
+
+	ticker := m.clock.NewTicker(m.ExportOpts.StatusInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+			snapshot := m.exportSnapshot(m.GetStatusSnapshot(false, false))
+
+			if err := snapshot.AppendJSON(m.ExportOpts.StatusPath); err != nil {
+				slog.Error("failed to write status snapshot", "path", m.ExportOpts.StatusPath, "error", err)
+			}
+		}
+	}
+}
+
+// AppendJSON appends the snapshot to path as a single line of JSON, creating the file if needed.
+func (s *StatusSnapshot) AppendJSON(path string) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("failed to serialize status snapshot: %w", err)
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open status file: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write status file: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,91 @@
+package mon
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// TimeFormat controls how timestamps are written in the display and reports. The zero value writes them in the local
+// time zone. Machine-readable exports like the status file and CSVs always use RFC 3339 instead.
+type TimeFormat struct {
+	// Location is the time zone timestamps are shown in. Nil uses the local time zone.
+	Location *time.Location
+}
+
+// TimeFormatForZone returns the format for a time zone name: "Local", "UTC", or an IANA name like "Europe/Berlin". An
+// empty name means local time.
+func TimeFormatForZone(name string) (TimeFormat, error) {
+	if name == "" || name == "Local" {
+		return TimeFormat{}, nil
+	}
+
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return TimeFormat{}, fmt.Errorf("unknown time zone %q: %w", name, err)
+	}
+
+	return TimeFormat{Location: location}, nil
+}
+
+func (f TimeFormat) in(t time.Time) time.Time {
+	if f.Location == nil {
+		return t.Local()
+	}
+
+	return t.In(f.Location)
+}
+
+// Timestamp writes a date and time with its zone, e.g. "2026-10-17 14:02 CEST".
+func (f TimeFormat) Timestamp(t time.Time) string {
+	return f.in(t).Format("2006-01-02 15:04 MST")
+}
+
+// Clock writes a time of day, e.g. "14:02:31", for times within a session that's already been dated.
+func (f TimeFormat) Clock(t time.Time) string {
+	return f.in(t).Format(time.TimeOnly)
+}
+
+// times returns the time format for the display and reports.
+func (s *StatusSnapshot) times() TimeFormat {
+	if s.DetailsOpts == nil {
+		return TimeFormat{}
+	}
+
+	return s.Times
+}
+
+// durationString humanizes a duration to its two largest units, e.g. "1h 12m" or "45s". The second is left out when
+// it's zero, as in "2d".
+func durationString(duration time.Duration) string {
+	units := []struct {
+		suffix string
+		size   time.Duration
+	}{
+		{"d", time.Hour * 24},
+		{"h", time.Hour},
+		{"m", time.Minute},
+		{"s", time.Second},
+	}
+
+	last := len(units) - 1
+
+	for idx, unit := range units {
+		if duration < unit.size && idx < last {
+			continue
+		}
+
+		result := strconv.FormatInt(int64(duration/unit.size), 10) + unit.suffix
+
+		if idx < last {
+			next := units[idx+1]
+			if rest := (duration % unit.size) / next.size; rest > 0 {
+				result += " " + strconv.FormatInt(int64(rest), 10) + next.suffix
+			}
+		}
+
+		return result
+	}
+
+	return ""
+}
@@ -0,0 +1,62 @@
+package mon //nolint:testpackage // exercises the unexported duration formatting
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDurationString(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		duration time.Duration
+		expected string
+	}{
+		{0, "0s"},
+		{time.Millisecond * 900, "0s"},
+		{time.Second * 45, "45s"},
+		{time.Second * 90, "1m 30s"},
+		{time.Minute * 5, "5m"},
+		{time.Hour + time.Minute*12 + time.Second*5, "1h 12m"},
+		{time.Hour*48 + time.Minute*3, "2d"},
+		{time.Hour*50 + time.Minute*3, "2d 2h"},
+	}
+
+	for _, test := range tests {
+		if actual := durationString(test.duration); actual != test.expected {
+			t.Errorf("expected %s to format as %q, got %q", test.duration, test.expected, actual)
+		}
+	}
+}
+
+func TestTimeFormat(t *testing.T) {
+	t.Parallel()
+
+	when := time.Date(2026, time.October, 17, 12, 2, 31, 0, time.UTC)
+
+	utc, err := TimeFormatForZone("UTC")
+	if err != nil {
+		t.Fatalf("failed to load UTC: %v", err)
+	}
+
+	if actual := utc.Timestamp(when); actual != "2026-10-17 12:02 UTC" {
+		t.Errorf("expected UTC timestamp, got %q", actual)
+	}
+
+	berlin, err := TimeFormatForZone("Europe/Berlin")
+	if err != nil {
+		t.Skipf("no time zone database: %v", err)
+	}
+
+	if actual := berlin.Timestamp(when); actual != "2026-10-17 14:02 CEST" {
+		t.Errorf("expected Berlin timestamp, got %q", actual)
+	}
+
+	if actual := berlin.Clock(when); actual != "14:02:31" {
+		t.Errorf("expected Berlin time of day, got %q", actual)
+	}
+
+	if _, err := TimeFormatForZone("Mars/Olympus_Mons"); err == nil {
+		t.Errorf("expected an error for an unknown time zone")
+	}
+}
@@ -0,0 +1,169 @@
+package mon
+
+import (
+	"errors"
+	"io/fs"
+	"log/slog"
+	"maps"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/cneill/mon/pkg/files"
+)
+
+// TodoStats is the change in placeholder comments (TODO, FIXME, and HACK) in the files changed during the session,
+// since agents often leave them behind in place of finished work.
+type TodoStats struct {
+	// Added and Removed total the increases and decreases in each file's count of placeholders.
+	Added   int64 `json:"added"`
+	Removed int64 `json:"removed"`
+	// ByMarker is the net change in each kind of placeholder, e.g. {"TODO": 3}.
+	ByMarker map[string]int64 `json:"by_marker"`
+	// Files is the net change in each file whose count of placeholders changed.
+	Files map[string]int64 `json:"files"`
+}
+
+//nolint:gochecknoglobals
+var (
+	// todoKinds are the placeholders that are counted.
+	todoKinds   = []string{"TODO", "FIXME", "HACK"}
+	todoMarkers = regexp.MustCompile(`\b(` + strings.Join(todoKinds, "|") + `)\b`)
+)
+
+// countTodos counts the placeholders in data by kind.
+func countTodos(data []byte) map[string]int64 {
+	results := map[string]int64{}
+
+	for _, match := range todoMarkers.FindAll(data, -1) {
+		results[string(match)]++
+	}
+
+	return results
+}
+
+// todoStats compares the placeholders in each file created, written, or deleted during the session with the file as it
+// was at the commit the session started at, and returns nil if nothing changed. Generated files are left out, and so
+// are files that weren't committed when the session started, since there's nothing to compare them with. Changes that
+// weren't committed when it started count as the session's.
+func (m *Mon) todoStats(snapshot *StatusSnapshot) *TodoStats {
+	candidates := slices.Concat(snapshot.humanNewFiles(), snapshot.DeletedFiles,
+		slices.Collect(maps.Keys(snapshot.WrittenFiles)))
+	slices.Sort(candidates)
+	candidates = slices.Compact(candidates)
+
+	stats := &TodoStats{ByMarker: map[string]int64{}, Files: map[string]int64{}}
+
+	for _, path := range candidates {
+		if snapshot.Generated != nil && slices.Contains(snapshot.Generated.Files, path) {
+			continue
+		}
+
+		before := map[string]int64{}
+
+		if !slices.Contains(snapshot.NewFiles, path) {
+			data, ok := m.initialContents(path)
+			if !ok {
+				continue
+			}
+
+			before = countTodos(data)
+		}
+
+		after := map[string]int64{}
+
+		if _, err := os.Lstat(path); err == nil {
+			data, ok := readSource(path)
+			if !ok {
+				continue
+			}
+
+			after = countTodos(data)
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+
+		var net int64
+
+		for _, marker := range todoKinds {
+			change := after[marker] - before[marker]
+			if change == 0 {
+				continue
+			}
+
+			stats.ByMarker[marker] += change
+			net += change
+
+			if change > 0 {
+				stats.Added += change
+			} else {
+				stats.Removed -= change
+			}
+		}
+
+		if net != 0 {
+			stats.Files[path] = net
+		}
+	}
+
+	if stats.Added == 0 && stats.Removed == 0 {
+		return nil
+	}
+
+	for marker, change := range stats.ByMarker {
+		if change == 0 {
+			delete(stats.ByMarker, marker)
+		}
+	}
+
+	return stats
+}
+
+// initialContents returns a file's contents as of the commit the session started at in its repo, if it was committed
+// and isn't binary.
+func (m *Mon) initialContents(path string) ([]byte, bool) {
+	for _, repo := range m.repos {
+		if files.RootOf([]string{repo.dir}, path) == "" {
+			continue
+		}
+
+		data, err := repo.git.InitialContents(path)
+		if err != nil {
+			slog.Debug("no committed version of file to compare placeholders with", "path", path, "error", err)
+			return nil, false
+		}
+
+		return data, isText(data)
+	}
+
+	return nil, false
+}
+
+// todoString summarizes Todos for the report, e.g. "+7 / -2 :: TODO +4, FIXME +1".
+func (s *StatusSnapshot) todoString() string {
+	parts := []string{
+		addedColor.Sprint("+"+s.number(s.Todos.Added)) + " / " + removedColor.Sprint("-"+s.number(s.Todos.Removed)),
+	}
+
+	if len(s.Todos.ByMarker) > 0 {
+		markers := []string{}
+
+		for _, marker := range slices.Sorted(maps.Keys(s.Todos.ByMarker)) {
+			markers = append(markers, marker+" "+s.signed(s.Todos.ByMarker[marker]))
+		}
+
+		parts = append(parts, detailColor.Sprint(strings.Join(markers, ", ")))
+	}
+
+	return strings.Join(parts, s.separator())
+}
+
+// signed formats a change with its sign, e.g. "+4" or "-1".
+func (s *StatusSnapshot) signed(change int64) string {
+	if change < 0 {
+		return "-" + s.number(-change)
+	}
+
+	return "+" + s.number(change)
+}
@@ -0,0 +1,26 @@
+package mon //nolint:testpackage // exercises the unexported placeholder counting
+
+import (
+	"maps"
+	"testing"
+)
+
+func TestCountTodos(t *testing.T) {
+	t.Parallel()
+
+	source := `package main
+
+// TODO: handle errors
+// TODO(someone): and retries
+func main() {
+	panic("FIXME") // HACK around the missing config
+}
+
+// Not counted: todo, TODOs, XTODO
+`
+
+	expected := map[string]int64{"TODO": 2, "FIXME": 1, "HACK": 1}
+	if actual := countTodos([]byte(source)); !maps.Equal(actual, expected) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+}
@@ -0,0 +1,124 @@
+package mon
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultTurnGap is used when Opts.TurnGap is not set.
+const DefaultTurnGap = time.Second * 20
+
+// Turn is a burst of file writes with no pause longer than the turn gap. Agents tend to work this way, editing a batch
+// of files and then waiting on a tool run or the user, so each turn roughly matches one iteration.
+type Turn struct {
+	Start  time.Time `json:"start"`
+	End    time.Time `json:"end"` // time of the last write
+	Writes int64     `json:"writes"`
+}
+
+func (t Turn) Duration() time.Duration {
+	return t.End.Sub(t.Start)
+}
+
+// turnTracker splits the session's writes into turns.
+type turnTracker struct {
+	mutex sync.Mutex
+	gap   time.Duration
+	turns []Turn
+}
+
+func newTurnTracker(gap time.Duration) *turnTracker {
+	if gap <= 0 {
+		gap = DefaultTurnGap
+	}
+
+	return &turnTracker{
+		gap:   gap,
+		turns: []Turn{},
+	}
+}
+
+// Add records a write, extending the latest turn or starting a new one if the writes paused for longer than the gap.
+func (t *turnTracker) Add(when time.Time) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if last := len(t.turns) - 1; last >= 0 && when.Sub(t.turns[last].End) <= t.gap {
+		t.turns[last].End = when
+		t.turns[last].Writes++
+
+		return
+	}
+
+	t.turns = append(t.turns, Turn{Start: when, End: when, Writes: 1})
+}
+
+// Turns returns every turn so far, oldest first. The latest one may still be in progress.
+func (t *turnTracker) Turns() []Turn {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	return slices.Clone(t.turns)
+}
+
+// Restore replaces the tracked turns, e.g. with those saved in a checkpoint.
+func (t *turnTracker) Restore(turns []Turn) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.turns = slices.Clone(turns)
+}
+
+// maxTurns is how many of the latest turns are listed in the final report.
+const maxTurns = 10
+
+// turnsString summarizes how many iterations the session took and lists the latest turns as a timeline.
+func (s *StatusSnapshot) turnsString() string {
+	if len(s.Turns) == 0 {
+		return ""
+	}
+
+	builder := &strings.Builder{}
+	builder.Grow(256)
+
+	var (
+		active time.Duration
+		writes int64
+	)
+
+	for _, turn := range s.Turns {
+		active += turn.Duration()
+		writes += turn.Writes
+	}
+
+	builder.WriteString(labelColor.Sprint("\nTurns: "))
+	builder.WriteString(detailColor.Sprint(s.number(int64(len(s.Turns)))))
+	builder.WriteString(s.separator())
+	builder.WriteString(sublabelColor.Sprint("active "))
+	builder.WriteString(detailColor.Sprint(durationString(active)))
+	builder.WriteString(s.separator())
+	builder.WriteString(sublabelColor.Sprint("avg "))
+	builder.WriteString(detailColor.Sprint(s.number(writes/int64(len(s.Turns))) + " writes"))
+	builder.WriteRune('\n')
+
+	start := max(0, len(s.Turns)-maxTurns)
+	if start > 0 {
+		builder.WriteString(indent + sublabelColor.Sprint(strconv.Itoa(start)+" earlier turns") + "\n")
+	}
+
+	for idx, turn := range s.Turns[start:] {
+		builder.WriteString(indent)
+		builder.WriteString(sublabelColor.Sprint(strconv.Itoa(start+idx+1) + ". "))
+		builder.WriteString(detailColor.Sprint(s.times().Clock(turn.Start)))
+		builder.WriteString(s.separator())
+		builder.WriteString(detailColor.Sprint(durationString(turn.Duration())))
+		builder.WriteString(s.separator())
+		builder.WriteString(addedColor.Sprint(s.number(turn.Writes) + " writes"))
+		builder.WriteRune('\n')
+	}
+
+	return builder.String()
+}
@@ -0,0 +1,31 @@
+package mon //nolint:testpackage // exercises the unexported turn tracker
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTurnTracker(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	tracker := newTurnTracker(time.Second * 20)
+
+	// Two bursts of writes, separated by a pause longer than the gap
+	for _, offset := range []time.Duration{0, time.Second * 5, time.Second * 25, time.Minute * 2, time.Minute*2 + time.Second} {
+		tracker.Add(start.Add(offset))
+	}
+
+	turns := tracker.Turns()
+	if len(turns) != 2 {
+		t.Fatalf("expected 2 turns, got %d: %+v", len(turns), turns)
+	}
+
+	if turns[0].Writes != 3 || turns[0].Duration() != time.Second*25 {
+		t.Errorf("expected first turn of 3 writes over 25s, got %d writes over %s", turns[0].Writes, turns[0].Duration())
+	}
+
+	if turns[1].Writes != 2 || !turns[1].Start.Equal(start.Add(time.Minute*2)) {
+		t.Errorf("expected second turn of 2 writes starting at 12:02, got %d writes starting at %s", turns[1].Writes, turns[1].Start)
+	}
+}
@@ -0,0 +1,379 @@
+// Package montest provides fakes for driving mon's monitors in tests: an in-memory filesystem whose changes are
+// reported to fake watchers, and a builder for git repositories with the reflogs the git monitor reads.
+package montest
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// FS is an in-memory filesystem that satisfies files.FS. Changes made through its methods are reported to the
+// Watchers created with NewWatcher, the way inotify would report them: events for a path go to watchers of the path
+// itself or of its parent directory.
+//
+// Renames are reported as a Rename of the old path followed by a Create of the new one, without the pairing that
+// fsnotify provides on some platforms.
+type FS struct {
+	mutex    sync.RWMutex
+	nodes    map[string]*node // keyed by clean path
+	watchers []*Watcher
+	modTime  time.Time
+}
+
+type node struct {
+	path    string
+	dir     bool
+	mode    fs.FileMode
+	content []byte
+	modTime time.Time
+}
+
+// NewFS returns a filesystem containing only the directory root (and its parents).
+func NewFS(root string) *FS {
+	result := &FS{
+		nodes:   map[string]*node{},
+		modTime: time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	for dir := filepath.Clean(root); ; dir = filepath.Dir(dir) {
+		result.nodes[dir] = &node{path: dir, dir: true, mode: fs.ModeDir | 0o755, modTime: result.modTime}
+
+		if filepath.Dir(dir) == dir {
+			break
+		}
+	}
+
+	return result
+}
+
+// NewWatcher returns a watcher for changes made to the filesystem. It doesn't watch anything until Add is called.
+func (f *FS) NewWatcher() *Watcher {
+	watcher := &Watcher{
+		fs:      f,
+		watched: map[string]struct{}{},
+		events:  make(chan fsnotify.Event, watcherBufferSize),
+		errors:  make(chan error, watcherBufferSize),
+	}
+
+	f.mutex.Lock()
+	f.watchers = append(f.watchers, watcher)
+	f.mutex.Unlock()
+
+	return watcher
+}
+
+// MkdirAll creates a directory along with any missing parents, like os.MkdirAll.
+func (f *FS) MkdirAll(path string) error {
+	f.mutex.Lock()
+
+	var (
+		created []string
+		err     error
+	)
+
+	for dir := filepath.Clean(path); ; dir = filepath.Dir(dir) {
+		if existing, ok := f.nodes[dir]; ok {
+			if !existing.dir {
+				err = fmt.Errorf("mkdir %s: %w", dir, fs.ErrExist)
+			}
+
+			break
+		}
+
+		created = append(created, dir)
+	}
+
+	if err == nil {
+		slices.Reverse(created)
+
+		for _, dir := range created {
+			f.nodes[dir] = &node{path: dir, dir: true, mode: fs.ModeDir | 0o755, modTime: f.tick()}
+		}
+	}
+
+	f.mutex.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	for _, dir := range created {
+		f.notify(dir, fsnotify.Create)
+	}
+
+	return nil
+}
+
+// WriteFile replaces the content of a file, creating it if needed, like os.WriteFile.
+func (f *FS) WriteFile(path string, content []byte) error {
+	path = filepath.Clean(path)
+
+	f.mutex.Lock()
+
+	if err := f.checkParent(path); err != nil {
+		f.mutex.Unlock()
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+
+	existing, exists := f.nodes[path]
+	if exists && existing.dir {
+		f.mutex.Unlock()
+		return fmt.Errorf("write %s: is a directory", path)
+	}
+
+	f.nodes[path] = &node{path: path, mode: 0o644, content: slices.Clone(content), modTime: f.tick()}
+
+	f.mutex.Unlock()
+
+	if !exists {
+		f.notify(path, fsnotify.Create)
+	}
+
+	if !exists && len(content) == 0 {
+		return nil
+	}
+
+	f.notify(path, fsnotify.Write)
+
+	return nil
+}
+
+// Remove deletes a file, or a directory and everything below it, like os.RemoveAll.
+func (f *FS) Remove(path string) error {
+	path = filepath.Clean(path)
+
+	f.mutex.Lock()
+
+	if _, ok := f.nodes[path]; !ok {
+		f.mutex.Unlock()
+		return fmt.Errorf("remove %s: %w", path, fs.ErrNotExist)
+	}
+
+	removed := f.subtree(path)
+	for _, removedPath := range removed {
+		delete(f.nodes, removedPath)
+	}
+
+	f.mutex.Unlock()
+
+	// Children go before their parents, like a recursive delete
+	slices.Reverse(removed)
+
+	for _, removedPath := range removed {
+		f.notify(removedPath, fsnotify.Remove)
+	}
+
+	return nil
+}
+
+// Rename moves a file or directory, replacing anything at the new path, like os.Rename.
+func (f *FS) Rename(oldPath, newPath string) error {
+	oldPath, newPath = filepath.Clean(oldPath), filepath.Clean(newPath)
+
+	f.mutex.Lock()
+
+	if _, ok := f.nodes[oldPath]; !ok {
+		f.mutex.Unlock()
+		return fmt.Errorf("rename %s: %w", oldPath, fs.ErrNotExist)
+	}
+
+	if err := f.checkParent(newPath); err != nil {
+		f.mutex.Unlock()
+		return fmt.Errorf("rename %s to %s: %w", oldPath, newPath, err)
+	}
+
+	for _, replaced := range f.subtree(newPath) {
+		delete(f.nodes, replaced)
+	}
+
+	for _, movedPath := range f.subtree(oldPath) {
+		moved := f.nodes[movedPath]
+		delete(f.nodes, movedPath)
+
+		moved.path = newPath + strings.TrimPrefix(movedPath, oldPath)
+		f.nodes[moved.path] = moved
+	}
+
+	f.mutex.Unlock()
+
+	f.notify(oldPath, fsnotify.Rename)
+	f.notify(newPath, fsnotify.Create)
+
+	return nil
+}
+
+// Chmod changes the permission bits of a file.
+func (f *FS) Chmod(path string, mode fs.FileMode) error {
+	path = filepath.Clean(path)
+
+	f.mutex.Lock()
+
+	existing, ok := f.nodes[path]
+	if ok {
+		existing.mode = existing.mode.Type() | mode.Perm()
+	}
+
+	f.mutex.Unlock()
+
+	if !ok {
+		return fmt.Errorf("chmod %s: %w", path, fs.ErrNotExist)
+	}
+
+	f.notify(path, fsnotify.Chmod)
+
+	return nil
+}
+
+// ReadFile returns the content of a file.
+func (f *FS) ReadFile(path string) ([]byte, error) {
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+
+	existing, ok := f.nodes[filepath.Clean(path)]
+	if !ok {
+		return nil, fmt.Errorf("read %s: %w", path, fs.ErrNotExist)
+	}
+
+	return slices.Clone(existing.content), nil
+}
+
+// Stat implements files.FS.
+func (f *FS) Stat(path string) (fs.FileInfo, error) {
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+
+	existing, ok := f.nodes[filepath.Clean(path)]
+	if !ok {
+		return nil, &fs.PathError{Op: "stat", Path: path, Err: fs.ErrNotExist}
+	}
+
+	return existing.info(), nil
+}
+
+// WalkDir implements files.FS, visiting paths in lexical order like filepath.WalkDir.
+func (f *FS) WalkDir(root string, fn fs.WalkDirFunc) error {
+	root = filepath.Clean(root)
+
+	f.mutex.RLock()
+
+	if _, ok := f.nodes[root]; !ok {
+		f.mutex.RUnlock()
+		return fn(root, nil, &fs.PathError{Op: "lstat", Path: root, Err: fs.ErrNotExist})
+	}
+
+	paths := f.subtree(root)
+	infos := make(map[string]fs.FileInfo, len(paths))
+
+	for _, path := range paths {
+		infos[path] = f.nodes[path].info()
+	}
+
+	f.mutex.RUnlock()
+
+	var skipped []string
+
+	for _, path := range paths {
+		if slices.ContainsFunc(skipped, func(dir string) bool { return inDir(dir, path) }) {
+			continue
+		}
+
+		err := fn(path, fs.FileInfoToDirEntry(infos[path]), nil)
+
+		switch {
+		case err == nil:
+		case errors.Is(err, fs.SkipDir) && infos[path].IsDir():
+			skipped = append(skipped, path)
+		case errors.Is(err, fs.SkipDir):
+			// Skip the rest of the file's directory
+			skipped = append(skipped, filepath.Dir(path))
+		case errors.Is(err, fs.SkipAll):
+			return nil
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// subtree returns path and everything below it, sorted. The caller must hold the lock.
+func (f *FS) subtree(path string) []string {
+	results := []string{}
+
+	for candidate := range f.nodes {
+		if candidate == path || inDir(path, candidate) {
+			results = append(results, candidate)
+		}
+	}
+
+	slices.Sort(results)
+
+	return results
+}
+
+// checkParent returns an error if path's parent isn't an existing directory. The caller must hold the lock.
+func (f *FS) checkParent(path string) error {
+	parent, ok := f.nodes[filepath.Dir(path)]
+	if !ok {
+		return fs.ErrNotExist
+	}
+
+	if !parent.dir {
+		return fmt.Errorf("%s is not a directory", parent.path)
+	}
+
+	return nil
+}
+
+// tick returns a new modification time, so every change is visible in file info. The caller must hold the lock.
+func (f *FS) tick() time.Time {
+	f.modTime = f.modTime.Add(time.Second)
+
+	return f.modTime
+}
+
+func (f *FS) notify(path string, op fsnotify.Op) {
+	f.mutex.RLock()
+	watchers := slices.Clone(f.watchers)
+	f.mutex.RUnlock()
+
+	for _, watcher := range watchers {
+		watcher.deliver(fsnotify.Event{Name: path, Op: op})
+	}
+}
+
+func (n *node) info() fs.FileInfo {
+	return fileInfo{
+		name:    filepath.Base(n.path),
+		size:    int64(len(n.content)),
+		mode:    n.mode,
+		modTime: n.modTime,
+	}
+}
+
+// inDir reports whether path is somewhere below dir.
+func inDir(dir, path string) bool {
+	return strings.HasPrefix(path, strings.TrimSuffix(dir, string(filepath.Separator))+string(filepath.Separator))
+}
+
+type fileInfo struct {
+	name    string
+	size    int64
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+func (f fileInfo) Name() string       { return f.name }
+func (f fileInfo) Size() int64        { return f.size }
+func (f fileInfo) Mode() fs.FileMode  { return f.mode }
+func (f fileInfo) ModTime() time.Time { return f.modTime }
+func (f fileInfo) IsDir() bool        { return f.mode.IsDir() }
+func (f fileInfo) Sys() any           { return nil }
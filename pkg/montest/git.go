@@ -0,0 +1,253 @@
+package montest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// GitRepo builds a git repository on disk for the git monitor to watch. The git monitor reads the repository itself
+// and watches its reflogs, which go-git doesn't maintain, so GitRepo writes them the way the git CLI does.
+//
+// Commits are made with a fixed author and timestamps that advance a minute per commit, so their hashes are the same
+// on every run.
+type GitRepo struct {
+	Dir string
+
+	repo   *git.Repository
+	branch string
+	when   time.Time
+}
+
+// gitRemote is the remote GitRepo pushes to. Nothing is ever sent to its URL.
+const gitRemote = "origin"
+
+// NewGitRepo initializes a repository in dir on branch "main" with an initial commit that has already been pushed.
+func NewGitRepo(dir string) (*GitRepo, error) {
+	repo, err := git.PlainInitWithOptions(dir, &git.PlainInitOptions{
+		InitOptions: git.InitOptions{DefaultBranch: plumbing.NewBranchReferenceName("main")},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize git repo: %w", err)
+	}
+
+	_, err = repo.CreateRemote(&config.RemoteConfig{
+		Name: gitRemote,
+		URLs: []string{"https://example.invalid/montest.git"},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to add remote: %w", err)
+	}
+
+	result := &GitRepo{
+		Dir:    dir,
+		repo:   repo,
+		branch: "main",
+		when:   time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	if _, err := result.Commit("Initial commit", map[string]string{"README.md": "# montest\n"}); err != nil {
+		return nil, err
+	}
+
+	if err := result.Push(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// Repository returns the underlying go-git repository.
+func (g *GitRepo) Repository() *git.Repository {
+	return g.repo
+}
+
+// WriteFile writes a file in the worktree without committing it. Paths are relative to the repository root.
+func (g *GitRepo) WriteFile(path, content string) error {
+	fullPath := filepath.Join(g.Dir, filepath.FromSlash(path))
+
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0o755); err != nil {
+		return fmt.Errorf("failed to create directory for %q: %w", path, err)
+	}
+
+	if err := os.WriteFile(fullPath, []byte(content), 0o644); err != nil {
+		return fmt.Errorf("failed to write %q: %w", path, err)
+	}
+
+	return nil
+}
+
+// Commit writes the given files (relative path -> content), stages them, and commits them, returning the new commit's
+// hash.
+func (g *GitRepo) Commit(message string, changes map[string]string) (string, error) {
+	worktree, err := g.repo.Worktree()
+	if err != nil {
+		return "", fmt.Errorf("failed to open worktree: %w", err)
+	}
+
+	for path, content := range changes {
+		if err := g.WriteFile(path, content); err != nil {
+			return "", err
+		}
+
+		if _, err := worktree.Add(path); err != nil {
+			return "", fmt.Errorf("failed to stage %q: %w", path, err)
+		}
+	}
+
+	oldHash := plumbing.ZeroHash
+	if head, err := g.repo.Head(); err == nil {
+		oldHash = head.Hash()
+	}
+
+	g.when = g.when.Add(time.Minute)
+
+	hash, err := worktree.Commit(message, &git.CommitOptions{
+		Author:            g.signature(),
+		AllowEmptyCommits: true,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to commit: %w", err)
+	}
+
+	action := "commit"
+	if oldHash.IsZero() {
+		action = "commit (initial)"
+	}
+
+	entry := action + ": " + strings.SplitN(message, "\n", 2)[0]
+
+	if err := g.appendReflog(filepath.Join("refs", "heads", g.branch), oldHash, hash, entry); err != nil {
+		return "", err
+	}
+
+	if err := g.appendReflog("HEAD", oldHash, hash, entry); err != nil {
+		return "", err
+	}
+
+	return hash.String(), nil
+}
+
+// Push points the remote-tracking branch at HEAD, as a successful push (forced or not) would.
+func (g *GitRepo) Push() error {
+	head, err := g.repo.Head()
+	if err != nil {
+		return fmt.Errorf("failed to get HEAD: %w", err)
+	}
+
+	return g.UpdateRemote(head.Hash().String(), "update by push")
+}
+
+// UpdateRemote points the remote-tracking branch at the given commit, logging message in its reflog. A fetch that
+// brings in new commits would use e.g. "fetch: fast-forward".
+func (g *GitRepo) UpdateRemote(hash, message string) error {
+	refName := plumbing.NewRemoteReferenceName(gitRemote, g.branch)
+	newHash := plumbing.NewHash(hash)
+
+	oldHash := plumbing.ZeroHash
+	if ref, err := g.repo.Reference(refName, true); err == nil {
+		oldHash = ref.Hash()
+	}
+
+	if err := g.repo.Storer.SetReference(plumbing.NewHashReference(refName, newHash)); err != nil {
+		return fmt.Errorf("failed to update remote-tracking branch: %w", err)
+	}
+
+	return g.appendReflog(refName.String(), oldHash, newHash, message)
+}
+
+// Checkout switches to branch, creating it at HEAD first if create is set, like "git checkout [-b]".
+func (g *GitRepo) Checkout(branch string, create bool) error {
+	worktree, err := g.repo.Worktree()
+	if err != nil {
+		return fmt.Errorf("failed to open worktree: %w", err)
+	}
+
+	head, err := g.repo.Head()
+	if err != nil {
+		return fmt.Errorf("failed to get HEAD: %w", err)
+	}
+
+	if err := worktree.Checkout(&git.CheckoutOptions{
+		Branch: plumbing.NewBranchReferenceName(branch),
+		Create: create,
+	}); err != nil {
+		return fmt.Errorf("failed to check out %q: %w", branch, err)
+	}
+
+	newHead, err := g.repo.Head()
+	if err != nil {
+		return fmt.Errorf("failed to get HEAD: %w", err)
+	}
+
+	entry := fmt.Sprintf("checkout: moving from %s to %s", g.branch, branch)
+	g.branch = branch
+
+	return g.appendReflog("HEAD", head.Hash(), newHead.Hash(), entry)
+}
+
+// Reset hard-resets the current branch and worktree to the given commit, like "git reset --hard".
+func (g *GitRepo) Reset(hash string) error {
+	worktree, err := g.repo.Worktree()
+	if err != nil {
+		return fmt.Errorf("failed to open worktree: %w", err)
+	}
+
+	head, err := g.repo.Head()
+	if err != nil {
+		return fmt.Errorf("failed to get HEAD: %w", err)
+	}
+
+	newHash := plumbing.NewHash(hash)
+
+	if err := worktree.Reset(&git.ResetOptions{Commit: newHash, Mode: git.HardReset}); err != nil {
+		return fmt.Errorf("failed to reset to %s: %w", hash, err)
+	}
+
+	entry := "reset: moving to " + hash
+
+	if err := g.appendReflog(filepath.Join("refs", "heads", g.branch), head.Hash(), newHash, entry); err != nil {
+		return err
+	}
+
+	return g.appendReflog("HEAD", head.Hash(), newHash, entry)
+}
+
+func (g *GitRepo) signature() *object.Signature {
+	return &object.Signature{
+		Name:  "montest",
+		Email: "montest@example.invalid",
+		When:  g.when,
+	}
+}
+
+// appendReflog adds an entry to the reflog for ref, in the format git itself writes.
+func (g *GitRepo) appendReflog(ref string, oldHash, newHash plumbing.Hash, message string) error {
+	path := filepath.Join(g.Dir, ".git", "logs", filepath.FromSlash(ref))
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create reflog directory: %w", err)
+	}
+
+	sig := g.signature()
+	line := fmt.Sprintf("%s %s %s <%s> %d +0000\t%s\n", oldHash, newHash, sig.Name, sig.Email, sig.When.Unix(), message)
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open reflog for %s: %w", ref, err)
+	}
+	defer file.Close()
+
+	if _, err := file.WriteString(line); err != nil {
+		return fmt.Errorf("failed to write reflog for %s: %w", ref, err)
+	}
+
+	return nil
+}
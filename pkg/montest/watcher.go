@@ -0,0 +1,151 @@
+package montest
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+
+	"github.com/cneill/mon/pkg/files"
+	"github.com/fsnotify/fsnotify"
+)
+
+// watcherBufferSize is how many events a Watcher holds before it overflows, like a kernel event queue.
+const watcherBufferSize = 4096
+
+// Watcher is a fake files.Watcher that receives events for changes made through an FS.
+type Watcher struct {
+	fs *FS
+
+	mutex   sync.Mutex
+	watched map[string]struct{}
+	closed  bool
+	events  chan fsnotify.Event
+	errors  chan error
+}
+
+// Add starts watching a file or directory, which must exist in the FS.
+func (w *Watcher) Add(path string) error {
+	path = filepath.Clean(path)
+
+	if _, err := w.fs.Stat(path); err != nil {
+		return fmt.Errorf("failed to watch %s: %w", path, err)
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.closed {
+		return fsnotify.ErrClosed
+	}
+
+	w.watched[path] = struct{}{}
+
+	return nil
+}
+
+// Remove stops watching a path.
+func (w *Watcher) Remove(path string) error {
+	path = filepath.Clean(path)
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if _, ok := w.watched[path]; !ok {
+		return fmt.Errorf("%w: %s", fsnotify.ErrNonExistentWatch, path)
+	}
+
+	delete(w.watched, path)
+
+	return nil
+}
+
+// Close stops the watcher and closes its channels.
+func (w *Watcher) Close() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.closed {
+		return nil
+	}
+
+	w.closed = true
+
+	close(w.events)
+	close(w.errors)
+
+	return nil
+}
+
+func (w *Watcher) Events() <-chan fsnotify.Event { return w.events }
+func (w *Watcher) Errors() <-chan error          { return w.errors }
+
+// Overflow reports a lost batch of events, as the kernel does when its queue fills up.
+func (w *Watcher) Overflow() {
+	w.sendError(fsnotify.ErrEventOverflow)
+}
+
+// WatchList returns the paths being watched.
+func (w *Watcher) WatchList() []string {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	results := make([]string, 0, len(w.watched))
+	for path := range w.watched {
+		results = append(results, path)
+	}
+
+	return results
+}
+
+// deliver sends the event if the path or its directory is watched. Like a kernel queue, a full buffer drops the event
+// and reports an overflow rather than blocking the change that caused it.
+func (w *Watcher) deliver(event fsnotify.Event) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.closed {
+		return
+	}
+
+	_, watchedPath := w.watched[event.Name]
+	_, watchedDir := w.watched[filepath.Dir(event.Name)]
+
+	if !watchedPath && !watchedDir {
+		return
+	}
+
+	// A removed or renamed watch stops reporting, like inotify's IN_IGNORED
+	if event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename) {
+		delete(w.watched, event.Name)
+	}
+
+	select {
+	case w.events <- event:
+	default:
+		w.sendErrorLocked(fsnotify.ErrEventOverflow)
+	}
+}
+
+func (w *Watcher) sendError(err error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.closed {
+		return
+	}
+
+	w.sendErrorLocked(err)
+}
+
+// sendErrorLocked sends an error without blocking. The caller must hold the lock.
+func (w *Watcher) sendErrorLocked(err error) {
+	select {
+	case w.errors <- err:
+	default:
+	}
+}
+
+var (
+	_ files.FS      = (*FS)(nil)
+	_ files.Watcher = (*Watcher)(nil)
+)